@@ -0,0 +1,166 @@
+// Command kv-recover rebuilds the data as it was at a point in time, to undo
+// an accidental change such as a mass delete. It loads a backup taken with
+// kvctl backup, replays the WAL segments written after it up to the chosen
+// revision or time, and writes the result as a new backup file:
+//
+//	kv-recover -config config.yaml -backup FILE -to-time 2026-10-19T14:03:00Z -o FILE
+//
+// The archive and the encryption keys are read from the storage section of
+// the configuration. Only flushed segments are archived: the ones still in
+// the data directory of the configuration, the live one included, are
+// replayed after them, so run it on the host of the node to recover up to
+// its last write. Without the data directory the writes not flushed yet are
+// missing, kv-recover warns about it. Load the result into an empty leader with kvctl
+// restore, which seeds its replicas as well. Without -backup the archive is
+// replayed from its start, without a target up to its end.
+package main
+
+import (
+	"flag"
+	"log"
+	"os"
+	"time"
+
+	"github.com/Na322Pr/kv-storage-service/internal/backup"
+	"github.com/Na322Pr/kv-storage-service/internal/config"
+	"github.com/Na322Pr/kv-storage-service/internal/storage"
+	"github.com/Na322Pr/kv-storage-service/internal/storage/lsm"
+)
+
+func main() {
+	var (
+		in       = flag.String("backup", "", "backup file to start from")
+		out      = flag.String("o", "", "backup file to write")
+		revision = flag.Int64("to-revision", 0, "last revision to replay")
+		at       = flag.String("to-time", "", "time of the last write to replay, RFC 3339")
+	)
+	cfg := config.MustLoad()
+	if !flag.Parsed() {
+		flag.Parse()
+	}
+	if *out == "" {
+		log.Fatal("-o is required")
+	}
+
+	var target storage.RecoveryTarget
+	target.Revision = *revision
+	if *at != "" {
+		t, err := time.Parse(time.RFC3339Nano, *at)
+		if err != nil {
+			log.Fatalf("invalid -to-time: %v", err)
+		}
+		target.Time = t
+	}
+
+	start := time.Now()
+	recovery, err := rebuild(cfg.Storage, *in, *out, target)
+	if err != nil {
+		log.Fatalf("failed to recover: %v", err)
+	}
+	if !recovery.Live {
+		log.Printf("warning: data directory %s not found, the writes after the last archived WAL segment are missing", cfg.Storage.DataDir)
+	}
+	if (target.Revision > 0 || !target.Time.IsZero()) && !recovery.Reached {
+		log.Printf("warning: the WAL ends at revision %d, before the target", recovery.Revision)
+	}
+	log.Printf("data at revision %d written to %s in %s", recovery.Revision, *out, time.Since(start).Round(time.Millisecond))
+}
+
+// rebuild rebuilds the data in a temporary lsm tree and returns where the
+// replay ended.
+func rebuild(cfg config.Storage, in, out string, target storage.RecoveryTarget) (storage.Recovery, error) {
+	keyring, err := storage.LoadKeyring(cfg.Encryption)
+	if err != nil {
+		return storage.Recovery{}, err
+	}
+	work, err := os.MkdirTemp("", "kv-recover-")
+	if err != nil {
+		return storage.Recovery{}, err
+	}
+	defer os.RemoveAll(work)
+
+	engine, err := storage.OpenLSMEngine(work, lsm.Options{Keyring: keyring})
+	if err != nil {
+		return storage.Recovery{}, err
+	}
+	defer engine.Close()
+
+	storeCfg := config.Storage{EvictionPolicy: "noeviction"}
+	store, err := storage.NewStore(engine, storeCfg)
+	if err != nil {
+		return storage.Recovery{}, err
+	}
+
+	var base int64
+	if in != "" {
+		if base, err = loadBackup(store, in); err != nil {
+			return storage.Recovery{}, err
+		}
+		log.Printf("backup %s loaded at revision %d", in, base)
+	}
+
+	recovery, err := storage.ReplayArchive(engine, cfg, base, target)
+	if err != nil {
+		return storage.Recovery{}, err
+	}
+
+	// The replayed writes went to the engine, a new store sees them.
+	if store, err = storage.NewStore(engine, storeCfg); err != nil {
+		return storage.Recovery{}, err
+	}
+	return recovery, writeBackup(store, out)
+}
+
+func loadBackup(store *storage.Store, path string) (int64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	r, err := backup.NewReader(f)
+	if err != nil {
+		return 0, err
+	}
+	header, err := backup.Load(r, store)
+	if err != nil {
+		return 0, err
+	}
+	return header.Revision, nil
+}
+
+// writeBackup writes the backup through a temporary file, a failure leaves
+// no file behind.
+func writeBackup(store *storage.Store, path string) error {
+	dump, err := store.Dump()
+	if err != nil {
+		return err
+	}
+	defer dump.Release()
+
+	tmp := path + ".tmp"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp)
+	defer f.Close()
+
+	w, err := backup.NewWriter(f)
+	if err != nil {
+		return err
+	}
+	if _, err := backup.Stream(dump, "kv-recover", w.Write); err != nil {
+		return err
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
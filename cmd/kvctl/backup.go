@@ -0,0 +1,92 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/Na322Pr/kv-storage-service/internal/backup"
+	desc "github.com/Na322Pr/kv-storage-service/pkg/api"
+)
+
+// runBackup writes the snapshot to a temporary file and renames it once the
+// trailer is checked, a failed backup leaves no file behind.
+func runBackup(ctx context.Context, args []string) error {
+	var (
+		conn connFlags
+		out  string
+	)
+	fs := flag.NewFlagSet("backup", flag.ContinueOnError)
+	conn.register(fs)
+	fs.StringVar(&out, "o", "", "backup file to write")
+	if err := parse(fs, args); err != nil {
+		return err
+	}
+	if out == "" {
+		return fmt.Errorf("-o is required")
+	}
+
+	cc, ctx, err := conn.dial(ctx)
+	if err != nil {
+		return err
+	}
+	defer cc.Close()
+
+	stream, err := desc.NewKeyValueStorageClient(cc).Backup(ctx, &desc.BackupRequest{})
+	if err != nil {
+		return err
+	}
+
+	tmp := out + ".tmp"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp)
+	defer f.Close()
+
+	w, err := backup.NewWriter(f)
+	if err != nil {
+		return err
+	}
+	checker := backup.NewChecker()
+	for {
+		record, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		if err := checker.Check(record); err != nil {
+			return err
+		}
+		if err := w.Write(record); err != nil {
+			return err
+		}
+	}
+	if err := checker.Done(); err != nil {
+		return err
+	}
+
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, out); err != nil {
+		return err
+	}
+
+	header := checker.Header()
+	fmt.Printf("backup of node %s at revision %d: %d namespaces, %d entries\n",
+		header.NodeId, header.Revision, checker.Namespaces(), checker.Entries())
+	return nil
+}
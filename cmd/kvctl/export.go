@@ -0,0 +1,113 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	desc "github.com/Na322Pr/kv-storage-service/pkg/api"
+)
+
+// listFlag collects a flag given several times or as a comma-separated list.
+type listFlag []string
+
+func (l *listFlag) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *listFlag) Set(value string) error {
+	*l = append(*l, strings.Split(value, ",")...)
+	return nil
+}
+
+// runExport writes the keys to a temporary file and renames it once the
+// stream ends, a failed export leaves no file behind.
+func runExport(ctx context.Context, args []string) error {
+	var (
+		conn       connFlags
+		out        string
+		format     string
+		prefix     string
+		namespaces listFlag
+	)
+	fs := flag.NewFlagSet("export", flag.ContinueOnError)
+	conn.register(fs)
+	fs.StringVar(&out, "o", "", "file to write")
+	fs.StringVar(&format, "format", formatJSONL, "file format: jsonl, csv or binary")
+	fs.StringVar(&prefix, "prefix", "", "export only the keys with this prefix")
+	fs.Var(&namespaces, "namespace", "namespace to export, repeatable, all by default")
+	if err := parse(fs, args); err != nil {
+		return err
+	}
+	if out == "" {
+		return fmt.Errorf("-o is required")
+	}
+
+	tmp := out + ".tmp"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp)
+	defer f.Close()
+
+	w, err := newRecordWriter(format, f)
+	if err != nil {
+		return err
+	}
+
+	cc, ctx, err := conn.dial(ctx)
+	if err != nil {
+		return err
+	}
+	defer cc.Close()
+
+	stream, err := desc.NewKeyValueStorageClient(cc).Export(ctx, &desc.ExportRequest{
+		Namespaces: namespaces,
+		Prefix:     prefix,
+	})
+	if err != nil {
+		return err
+	}
+
+	var keys, skipped int64
+	for {
+		resp, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		for _, record := range resp.Records {
+			if err := w.Write(record); err != nil {
+				return err
+			}
+		}
+		keys += int64(len(resp.Records))
+		skipped += resp.SkippedLarge
+	}
+
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, out); err != nil {
+		return err
+	}
+
+	fmt.Printf("exported %d keys\n", keys)
+	if skipped > 0 {
+		fmt.Printf("skipped %d large values, use kvctl backup for them\n", skipped)
+	}
+	return nil
+}
@@ -0,0 +1,331 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"strconv"
+	"time"
+	"unicode/utf8"
+
+	desc "github.com/Na322Pr/kv-storage-service/pkg/api"
+	"google.golang.org/protobuf/proto"
+)
+
+// The formats of kvctl export and import. JSONL and CSV have one key per
+// line or row with the columns
+//
+//	namespace, key, value, value_base64, content_type, expires_at, revision
+//
+// A value that is not valid UTF-8 is written base64 encoded to value_base64
+// instead of value. expires_at is the RFC 3339 deadline of the key, empty
+// without TTL. revision is the revision of the last change, import ignores
+// it. CSV files start with a header row naming the columns, a file to
+// import may leave out any column but key and order them freely.
+//
+// The binary format starts with the 8 bytes "KVEXPORT" and a version byte,
+// followed by records as in backup files:
+//
+//	uvarint length | kv_storage_service.KeyValueRecord protobuf | crc32c
+const (
+	formatJSONL  = "jsonl"
+	formatCSV    = "csv"
+	formatBinary = "binary"
+
+	binaryMagic   = "KVEXPORT"
+	binaryVersion = 1
+	// maxRecordSize bounds a binary record, exported values are inline.
+	maxRecordSize = 64 << 20
+)
+
+var columns = []string{"namespace", "key", "value", "value_base64", "content_type", "expires_at", "revision"}
+
+var castagnoli = crc32.MakeTable(crc32.Castagnoli)
+
+type recordWriter interface {
+	Write(record *desc.KeyValueRecord) error
+	Flush() error
+}
+
+type recordReader interface {
+	// Read returns the next record, io.EOF after the last one.
+	Read() (*desc.KeyValueRecord, error)
+}
+
+func newRecordWriter(format string, w io.Writer) (recordWriter, error) {
+	switch format {
+	case formatJSONL:
+		bw := bufio.NewWriter(w)
+		enc := json.NewEncoder(bw)
+		enc.SetEscapeHTML(false)
+		return &jsonlWriter{w: bw, enc: enc}, nil
+	case formatCSV:
+		cw := csv.NewWriter(w)
+		if err := cw.Write(columns); err != nil {
+			return nil, err
+		}
+		return &csvWriter{w: cw}, nil
+	case formatBinary:
+		bw := bufio.NewWriter(w)
+		if _, err := bw.WriteString(binaryMagic); err != nil {
+			return nil, err
+		}
+		if err := bw.WriteByte(binaryVersion); err != nil {
+			return nil, err
+		}
+		return &binaryWriter{w: bw}, nil
+	}
+	return nil, fmt.Errorf("unknown format %q", format)
+}
+
+func newRecordReader(format string, r io.Reader) (recordReader, error) {
+	switch format {
+	case formatJSONL:
+		return &jsonlReader{r: bufio.NewReader(r)}, nil
+	case formatCSV:
+		cr := csv.NewReader(r)
+		cr.ReuseRecord = true
+		header, err := cr.Read()
+		if err != nil {
+			return nil, fmt.Errorf("header: %w", err)
+		}
+		index := map[string]int{}
+		for i, name := range header {
+			index[name] = i
+		}
+		if _, ok := index["key"]; !ok {
+			return nil, errors.New("header has no key column")
+		}
+		return &csvReader{r: cr, index: index}, nil
+	case formatBinary:
+		br := bufio.NewReader(r)
+		header := make([]byte, len(binaryMagic)+1)
+		if _, err := io.ReadFull(br, header); err != nil || string(header[:len(binaryMagic)]) != binaryMagic {
+			return nil, errors.New("not an export file")
+		}
+		if header[len(binaryMagic)] != binaryVersion {
+			return nil, fmt.Errorf("unsupported export format version %d", header[len(binaryMagic)])
+		}
+		return &binaryReader{r: br}, nil
+	}
+	return nil, fmt.Errorf("unknown format %q", format)
+}
+
+// row is a record in the columns of the text formats.
+type row struct {
+	Namespace   string `json:"namespace,omitempty"`
+	Key         string `json:"key"`
+	Value       string `json:"value,omitempty"`
+	ValueBase64 string `json:"value_base64,omitempty"`
+	ContentType string `json:"content_type,omitempty"`
+	ExpiresAt   string `json:"expires_at,omitempty"`
+	Revision    int64  `json:"revision,omitempty"`
+}
+
+func toRow(record *desc.KeyValueRecord) row {
+	r := row{
+		Namespace:   record.Namespace,
+		Key:         record.Key,
+		ContentType: record.ContentType,
+		Revision:    record.Revision,
+	}
+	if utf8.Valid(record.Value) {
+		r.Value = string(record.Value)
+	} else {
+		r.ValueBase64 = base64.StdEncoding.EncodeToString(record.Value)
+	}
+	if record.Expiration > 0 {
+		r.ExpiresAt = time.Unix(0, record.Expiration).UTC().Format(time.RFC3339Nano)
+	}
+	return r
+}
+
+func (r row) record() (*desc.KeyValueRecord, error) {
+	if r.Key == "" {
+		return nil, errors.New("key is required")
+	}
+	record := &desc.KeyValueRecord{
+		Namespace:   r.Namespace,
+		Key:         r.Key,
+		Value:       []byte(r.Value),
+		ContentType: r.ContentType,
+		Revision:    r.Revision,
+	}
+	if r.ValueBase64 != "" {
+		if r.Value != "" {
+			return nil, errors.New("both value and value_base64 are set")
+		}
+		value, err := base64.StdEncoding.DecodeString(r.ValueBase64)
+		if err != nil {
+			return nil, fmt.Errorf("value_base64: %w", err)
+		}
+		record.Value = value
+	}
+	if r.ExpiresAt != "" {
+		t, err := time.Parse(time.RFC3339Nano, r.ExpiresAt)
+		if err != nil {
+			return nil, fmt.Errorf("expires_at: %w", err)
+		}
+		record.Expiration = t.UnixNano()
+	}
+	return record, nil
+}
+
+type jsonlWriter struct {
+	w   *bufio.Writer
+	enc *json.Encoder
+}
+
+func (w *jsonlWriter) Write(record *desc.KeyValueRecord) error {
+	return w.enc.Encode(toRow(record))
+}
+
+func (w *jsonlWriter) Flush() error {
+	return w.w.Flush()
+}
+
+type jsonlReader struct {
+	r    *bufio.Reader
+	line int
+}
+
+func (r *jsonlReader) Read() (*desc.KeyValueRecord, error) {
+	for {
+		line, err := r.r.ReadBytes('\n')
+		if len(line) == 0 && err != nil {
+			return nil, err
+		}
+		if err != nil && !errors.Is(err, io.EOF) {
+			return nil, err
+		}
+		r.line++
+		if len(bytes.TrimSpace(line)) == 0 {
+			continue
+		}
+
+		var rw row
+		if err := json.Unmarshal(line, &rw); err != nil {
+			return nil, fmt.Errorf("line %d: %w", r.line, err)
+		}
+		record, err := rw.record()
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", r.line, err)
+		}
+		return record, nil
+	}
+}
+
+type csvWriter struct {
+	w *csv.Writer
+}
+
+func (w *csvWriter) Write(record *desc.KeyValueRecord) error {
+	r := toRow(record)
+	revision := ""
+	if r.Revision != 0 {
+		revision = strconv.FormatInt(r.Revision, 10)
+	}
+	return w.w.Write([]string{r.Namespace, r.Key, r.Value, r.ValueBase64, r.ContentType, r.ExpiresAt, revision})
+}
+
+func (w *csvWriter) Flush() error {
+	w.w.Flush()
+	return w.w.Error()
+}
+
+type csvReader struct {
+	r     *csv.Reader
+	index map[string]int
+}
+
+func (r *csvReader) Read() (*desc.KeyValueRecord, error) {
+	fields, err := r.r.Read()
+	if err != nil {
+		return nil, err
+	}
+	line, _ := r.r.FieldPos(0)
+	column := func(name string) string {
+		if i, ok := r.index[name]; ok && i < len(fields) {
+			return fields[i]
+		}
+		return ""
+	}
+
+	rw := row{
+		Namespace:   column("namespace"),
+		Key:         column("key"),
+		Value:       column("value"),
+		ValueBase64: column("value_base64"),
+		ContentType: column("content_type"),
+		ExpiresAt:   column("expires_at"),
+	}
+	if revision := column("revision"); revision != "" {
+		if rw.Revision, err = strconv.ParseInt(revision, 10, 64); err != nil {
+			return nil, fmt.Errorf("line %d: revision: %w", line, err)
+		}
+	}
+	record, err := rw.record()
+	if err != nil {
+		return nil, fmt.Errorf("line %d: %w", line, err)
+	}
+	return record, nil
+}
+
+type binaryWriter struct {
+	w   *bufio.Writer
+	buf []byte
+}
+
+func (w *binaryWriter) Write(record *desc.KeyValueRecord) error {
+	data, err := proto.Marshal(record)
+	if err != nil {
+		return err
+	}
+	w.buf = binary.AppendUvarint(w.buf[:0], uint64(len(data)))
+	w.buf = append(w.buf, data...)
+	w.buf = binary.BigEndian.AppendUint32(w.buf, crc32.Checksum(data, castagnoli))
+	_, err = w.w.Write(w.buf)
+	return err
+}
+
+func (w *binaryWriter) Flush() error {
+	return w.w.Flush()
+}
+
+type binaryReader struct {
+	r      *bufio.Reader
+	record int
+}
+
+func (r *binaryReader) Read() (*desc.KeyValueRecord, error) {
+	n, err := binary.ReadUvarint(r.r)
+	if errors.Is(err, io.EOF) {
+		return nil, io.EOF
+	}
+	r.record++
+	if err != nil || n > maxRecordSize {
+		return nil, fmt.Errorf("record %d is corrupt", r.record)
+	}
+	data := make([]byte, n+4)
+	if _, err := io.ReadFull(r.r, data); err != nil {
+		return nil, fmt.Errorf("record %d is corrupt", r.record)
+	}
+	if crc32.Checksum(data[:n], castagnoli) != binary.BigEndian.Uint32(data[n:]) {
+		return nil, fmt.Errorf("record %d is corrupt", r.record)
+	}
+	var record desc.KeyValueRecord
+	if err := proto.Unmarshal(data[:n], &record); err != nil {
+		return nil, fmt.Errorf("record %d is corrupt: %v", r.record, err)
+	}
+	if record.Key == "" {
+		return nil, fmt.Errorf("record %d: key is required", r.record)
+	}
+	return &record, nil
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	desc "github.com/Na322Pr/kv-storage-service/pkg/api"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestFormatsRoundTrip(t *testing.T) {
+	records := []*desc.KeyValueRecord{
+		{Namespace: "team", Key: "a", Value: []byte("hello, \"world\"\n<x>"), ContentType: "text/plain", Expiration: 1760000000123456789, Revision: 5},
+		{Key: "b", Value: []byte{0xff, 0, 2}},
+		{Key: "c", Value: []byte{}},
+	}
+	for _, format := range []string{"jsonl", "csv", "binary"} {
+		var buf bytes.Buffer
+		w, err := newRecordWriter(format, &buf)
+		if err != nil {
+			t.Fatalf("%s: new writer: %v", format, err)
+		}
+		for _, record := range records {
+			if err := w.Write(record); err != nil {
+				t.Fatalf("%s: write: %v", format, err)
+			}
+		}
+		if err := w.Flush(); err != nil {
+			t.Fatalf("%s: flush: %v", format, err)
+		}
+
+		r, err := newRecordReader(format, &buf)
+		if err != nil {
+			t.Fatalf("%s: new reader: %v", format, err)
+		}
+		for i := 0; ; i++ {
+			record, err := r.Read()
+			if errors.Is(err, io.EOF) {
+				if i != len(records) {
+					t.Fatalf("%s: read %d records, want %d", format, i, len(records))
+				}
+				break
+			}
+			if err != nil {
+				t.Fatalf("%s: read: %v", format, err)
+			}
+			if i >= len(records) || !proto.Equal(record, records[i]) {
+				t.Fatalf("%s: record %d is %v", format, i, record)
+			}
+		}
+	}
+}
+
+func TestCSVColumns(t *testing.T) {
+	r, err := newRecordReader("csv", strings.NewReader("key,value\nx,1\n"))
+	if err != nil {
+		t.Fatalf("new reader: %v", err)
+	}
+	record, err := r.Read()
+	if err != nil || record.Key != "x" || string(record.Value) != "1" {
+		t.Fatalf("read: %v %v", record, err)
+	}
+}
+
+func TestReadErrors(t *testing.T) {
+	// Errors name the line of the record, blank lines included.
+	for format, input := range map[string]string{
+		"jsonl": "{\"key\":\"a\"}\n\n{\"key\":\"\"}\n",
+		"csv":   "key,expires_at\na,\nb,xx\n",
+	} {
+		r, err := newRecordReader(format, strings.NewReader(input))
+		if err != nil {
+			t.Fatalf("%s: new reader: %v", format, err)
+		}
+		if _, err := r.Read(); err != nil {
+			t.Fatalf("%s: first record: %v", format, err)
+		}
+		if _, err := r.Read(); err == nil || !strings.Contains(err.Error(), "line 3") {
+			t.Fatalf("%s: bad record: %v", format, err)
+		}
+	}
+
+	if _, err := newRecordReader("binary", strings.NewReader("nope")); err == nil {
+		t.Fatal("binary file without its header accepted")
+	}
+	if _, err := newRecordReader("xml", strings.NewReader("")); err == nil {
+		t.Fatal("unknown format accepted")
+	}
+}
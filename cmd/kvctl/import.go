@@ -0,0 +1,121 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	desc "github.com/Na322Pr/kv-storage-service/pkg/api"
+)
+
+// importBatch is the number of records sent in one ImportRequest.
+const importBatch = 500
+
+// runImport reads the file twice: first to parse it in full, so a malformed
+// file imports nothing, then to stream it.
+func runImport(ctx context.Context, args []string) error {
+	var (
+		conn   connFlags
+		in     string
+		format string
+		prefix string
+		dryRun bool
+	)
+	fs := flag.NewFlagSet("import", flag.ContinueOnError)
+	conn.register(fs)
+	fs.StringVar(&in, "i", "", "file to import")
+	fs.StringVar(&format, "format", formatJSONL, "file format: jsonl, csv or binary")
+	fs.StringVar(&prefix, "prefix", "", "import only the keys with this prefix")
+	fs.BoolVar(&dryRun, "dry-run", false, "check the file and the namespaces without writing")
+	if err := parse(fs, args); err != nil {
+		return err
+	}
+	if in == "" {
+		return fmt.Errorf("-i is required")
+	}
+
+	if err := readRecords(in, format, func([]*desc.KeyValueRecord) error { return nil }); err != nil {
+		return fmt.Errorf("check %s: %w", in, err)
+	}
+
+	cc, ctx, err := conn.dial(ctx)
+	if err != nil {
+		return err
+	}
+	defer cc.Close()
+
+	stream, err := desc.NewKeyValueStorageClient(cc).Import(ctx)
+	if err != nil {
+		return err
+	}
+	req := &desc.ImportRequest{Options: &desc.ImportOptions{Prefix: prefix, DryRun: dryRun}}
+	err = readRecords(in, format, func(records []*desc.KeyValueRecord) error {
+		req.Records = records
+		err := stream.Send(req)
+		req = &desc.ImportRequest{}
+		return err
+	})
+	// The server reports why it stopped reading in the response.
+	if err != nil && !errors.Is(err, io.EOF) {
+		return err
+	}
+	// A file without records still sends the options.
+	if req.Options != nil {
+		if err := stream.Send(req); err != nil && !errors.Is(err, io.EOF) {
+			return err
+		}
+	}
+	resp, err := stream.CloseAndRecv()
+	if err != nil {
+		return err
+	}
+
+	verb := "imported"
+	if dryRun {
+		verb = "would import"
+	}
+	fmt.Printf("%s %d keys, skipped %d by prefix and %d expired", verb, resp.Imported, resp.Skipped, resp.Expired)
+	if !dryRun {
+		fmt.Printf(", data version %d", resp.Revision)
+	}
+	fmt.Println()
+	return nil
+}
+
+// readRecords calls fn with the records of the file in batches.
+func readRecords(path, format string, fn func([]*desc.KeyValueRecord) error) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	r, err := newRecordReader(format, f)
+	if err != nil {
+		return err
+	}
+	var batch []*desc.KeyValueRecord
+	for {
+		record, err := r.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		batch = append(batch, record)
+		if len(batch) == importBatch {
+			if err := fn(batch); err != nil {
+				return err
+			}
+			batch = nil
+		}
+	}
+	if len(batch) == 0 {
+		return nil
+	}
+	return fn(batch)
+}
@@ -0,0 +1,133 @@
+// Command kvctl runs administrative operations against a node:
+//
+//	kvctl backup  -o FILE   stream a snapshot of the leader to FILE
+//	kvctl restore -i FILE   load FILE into an empty leader and its replicas
+//	kvctl export  -o FILE   write the keys to FILE as JSONL, CSV or binary
+//	kvctl import  -i FILE   store the keys of FILE on the leader in batches
+//
+// The backup file format is described in package internal/backup, the
+// export formats in format.go. Every command takes the connection flags
+// -addr, -token, -ca, -cert and -key.
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+)
+
+type command struct {
+	usage string
+	run   func(ctx context.Context, args []string) error
+}
+
+var commands = map[string]command{
+	"backup":  {usage: "backup -o FILE", run: runBackup},
+	"restore": {usage: "restore -i FILE", run: runRestore},
+	"export":  {usage: "export -o FILE [-format jsonl|csv|binary] [-namespace NS] [-prefix PREFIX]", run: runExport},
+	"import":  {usage: "import -i FILE [-format jsonl|csv|binary] [-prefix PREFIX] [-dry-run]", run: runImport},
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+	}
+	cmd, ok := commands[os.Args[1]]
+	if !ok {
+		usage()
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if err := cmd.run(ctx, os.Args[2:]); err != nil {
+		fmt.Fprintf(os.Stderr, "kvctl %s: %v\n", os.Args[1], err)
+		os.Exit(1)
+	}
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage:")
+	for _, name := range []string{"backup", "restore", "export", "import"} {
+		fmt.Fprintf(os.Stderr, "  kvctl %s\n", commands[name].usage)
+	}
+	os.Exit(2)
+}
+
+// connFlags are the flags every command takes to reach the node.
+type connFlags struct {
+	addr  string
+	token string
+	ca    string
+	cert  string
+	key   string
+}
+
+func (c *connFlags) register(fs *flag.FlagSet) {
+	fs.StringVar(&c.addr, "addr", "localhost:8080", "address of the node")
+	fs.StringVar(&c.token, "token", os.Getenv("KVCTL_TOKEN"), "bearer token, KVCTL_TOKEN by default")
+	fs.StringVar(&c.ca, "ca", "", "CA file to verify the node with, enables TLS")
+	fs.StringVar(&c.cert, "cert", "", "client certificate file for mutual TLS")
+	fs.StringVar(&c.key, "key", "", "client key file for mutual TLS")
+}
+
+// dial connects to the node and returns the context carrying the token.
+func (c *connFlags) dial(ctx context.Context) (*grpc.ClientConn, context.Context, error) {
+	creds := insecure.NewCredentials()
+	if c.ca != "" || c.cert != "" {
+		config := &tls.Config{MinVersion: tls.VersionTLS12}
+		if c.ca != "" {
+			pem, err := os.ReadFile(c.ca)
+			if err != nil {
+				return nil, nil, err
+			}
+			pool := x509.NewCertPool()
+			if !pool.AppendCertsFromPEM(pem) {
+				return nil, nil, fmt.Errorf("no certificate in %s", c.ca)
+			}
+			config.RootCAs = pool
+		}
+		if c.cert != "" {
+			cert, err := tls.LoadX509KeyPair(c.cert, c.key)
+			if err != nil {
+				return nil, nil, err
+			}
+			config.Certificates = []tls.Certificate{cert}
+		}
+		creds = credentials.NewTLS(config)
+	}
+
+	conn, err := grpc.NewClient(c.addr, grpc.WithTransportCredentials(creds))
+	if err != nil {
+		return nil, nil, err
+	}
+	if c.token != "" {
+		ctx = metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer "+c.token)
+	}
+	return conn, ctx, nil
+}
+
+var errUsage = errors.New("invalid arguments")
+
+// parse parses the flags of a command, which takes no positional argument.
+func parse(fs *flag.FlagSet, args []string) error {
+	if err := fs.Parse(args); err != nil {
+		return errUsage
+	}
+	if fs.NArg() > 0 {
+		fs.Usage()
+		return errUsage
+	}
+	return nil
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/Na322Pr/kv-storage-service/internal/backup"
+	desc "github.com/Na322Pr/kv-storage-service/pkg/api"
+)
+
+// runRestore reads the file twice: first to check it in full, so a damaged
+// backup never reaches the node, then to stream it.
+func runRestore(ctx context.Context, args []string) error {
+	var (
+		conn connFlags
+		in   string
+	)
+	fs := flag.NewFlagSet("restore", flag.ContinueOnError)
+	conn.register(fs)
+	fs.StringVar(&in, "i", "", "backup file to restore")
+	if err := parse(fs, args); err != nil {
+		return err
+	}
+	if in == "" {
+		return fmt.Errorf("-i is required")
+	}
+
+	if err := readBackup(in, func(*desc.BackupRecord) error { return nil }); err != nil {
+		return fmt.Errorf("check %s: %w", in, err)
+	}
+
+	cc, ctx, err := conn.dial(ctx)
+	if err != nil {
+		return err
+	}
+	defer cc.Close()
+
+	stream, err := desc.NewKeyValueStorageClient(cc).Restore(ctx)
+	if err != nil {
+		return err
+	}
+	err = readBackup(in, func(record *desc.BackupRecord) error {
+		return stream.Send(record)
+	})
+	// The server reports why it stopped reading in the response.
+	if err != nil && !errors.Is(err, io.EOF) {
+		return err
+	}
+	resp, err := stream.CloseAndRecv()
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("restored %d namespaces and %d entries, data version %d\n",
+		resp.Namespaces, resp.Entries, resp.Revision)
+	return nil
+}
+
+// readBackup calls fn with every checked record of the file.
+func readBackup(path string, fn func(*desc.BackupRecord) error) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	r, err := backup.NewReader(f)
+	if err != nil {
+		return err
+	}
+	checker := backup.NewChecker()
+	for {
+		record, err := r.Read()
+		if errors.Is(err, io.EOF) {
+			return checker.Done()
+		}
+		if err != nil {
+			return err
+		}
+		if err := checker.Check(record); err != nil {
+			return err
+		}
+		if err := fn(record); err != nil {
+			return err
+		}
+	}
+}
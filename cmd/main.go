@@ -3,26 +3,38 @@ package main
 import (
 	"context"
 	"fmt"
+	"github.com/Na322Pr/kv-storage-service/internal/admission"
+	"github.com/Na322Pr/kv-storage-service/internal/app/gateway"
 	kv_storage_service "github.com/Na322Pr/kv-storage-service/internal/app/kv-storage-service"
+	"github.com/Na322Pr/kv-storage-service/internal/app/memcached"
+	"github.com/Na322Pr/kv-storage-service/internal/app/resp"
+	"github.com/Na322Pr/kv-storage-service/internal/auth"
 	"github.com/Na322Pr/kv-storage-service/internal/config"
+	kvhealth "github.com/Na322Pr/kv-storage-service/internal/health"
+	kvlogger "github.com/Na322Pr/kv-storage-service/internal/logger"
+	"github.com/Na322Pr/kv-storage-service/internal/metrics"
 	"github.com/Na322Pr/kv-storage-service/internal/model"
+	"github.com/Na322Pr/kv-storage-service/internal/mtls"
 	"github.com/Na322Pr/kv-storage-service/internal/service"
 	"github.com/Na322Pr/kv-storage-service/internal/storage"
+	"github.com/Na322Pr/kv-storage-service/internal/tracing"
 	desc "github.com/Na322Pr/kv-storage-service/pkg/api"
+	descv2 "github.com/Na322Pr/kv-storage-service/pkg/api/v2"
 	"github.com/Na322Pr/kv-storage-service/pkg/nodemodel"
 	"go.uber.org/zap"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
 	"google.golang.org/grpc/reflection"
 	"log"
 	"net"
 	"net/http"
-	"os"
 	"os/signal"
 	"strconv"
 	"strings"
 	"syscall"
 
 	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
 	//"github.com/prometheus/client_golang/prometheus/promauto"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 
@@ -38,13 +50,10 @@ func main() {
 	ctx, cancel := signal.NotifyContext(ctx, syscall.SIGINT, syscall.SIGTERM)
 	defer cancel()
 
-	stop := make(chan os.Signal, 1)
-	signal.Notify(stop, syscall.SIGINT, syscall.SIGTERM)
-
-	//logger := zap.NewProduction()
-	//defer logger.Sync()
-
-	logger, _ := zap.NewDevelopment()
+	logger, logLevel, err := kvlogger.New(cfg.Logger)
+	if err != nil {
+		log.Fatalf("failed to init logger: %v", err)
+	}
 	defer logger.Sync()
 
 	logger.Info("Service config info",
@@ -53,44 +62,166 @@ func main() {
 		zap.String("grpcAddress", grpcAddress),
 	)
 
+	shutdownTracing, err := tracing.Init(ctx, cfg.Tracing, strconv.Itoa(cfg.Node.ID))
+	if err != nil {
+		log.Fatalf("failed to init tracing: %v", err)
+	}
+	defer shutdownTracing(context.Background())
+
 	registry := prometheus.NewRegistry()
+	registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+	grpcMetrics := metrics.NewGRPCMetrics(registry)
 
 	// Expose the metrics endpoint
-	http.Handle("/metrics", promhttp.HandlerFor(registry, promhttp.HandlerOpts{}))
-	go http.ListenAndServe(":2112", nil)
+	metricsAddress := cfg.GetMetricsAddress()
+	http.Handle("/metrics", promhttp.HandlerFor(registry, promhttp.HandlerOpts{Registry: registry}))
+	go func() {
+		if err := http.ListenAndServe(metricsAddress, nil); err != nil {
+			logger.Error("metrics server stopped", zap.Error(err))
+		}
+	}()
 
 	oldNodeModel := nodemodel.NewNode(cfg.Node.ID, grpcAddress)
-	nodeModel := model.NewNode(strconv.Itoa(cfg.Node.ID), "", grpcAddress)
+	nodeModel := model.NewNode(strconv.Itoa(cfg.Node.ID), cfg.GetNomadID(), grpcAddress)
 
 	nodeService := service.NewNodeService(oldNodeModel, logger)
 	cmService := service.NewConnectionManagerService()
 
-	keyValueStorage := storage.NewKeyValueInMemoryStorage()
+	healthServer := health.NewServer()
+	healthChecker := kvhealth.NewChecker(healthServer, nodeModel, cmService, cfg.Health, logger)
+	http.Handle("/livez", healthChecker.Livez())
+	http.Handle("/readyz", healthChecker.Readyz())
+
+	// Storage is not served until it is restored, the lsm engine replays its
+	// write-ahead log on open.
+	healthChecker.SetRecovering(true)
+	storageEngine, err := storage.OpenEngine(cfg.Storage)
+	if err != nil {
+		log.Fatalf("failed to open storage engine: %v", err)
+	}
+	keyValueStorage, err := storage.NewStore(storageEngine, cfg.Storage)
+	if err != nil {
+		log.Fatalf("failed to init storage: %v", err)
+	}
+	healthChecker.SetRecovering(false)
 	storageService := service.NewStorageService(keyValueStorage, nodeModel, cmService)
+	go service.NewHistoryCompactor(storageService, cfg.Storage.History, logger).Run(ctx)
+	registry.MustRegister(metrics.NewNodeCollector(storageService, cmService))
+
+	serverOptions := []grpc.ServerOption{tracing.ServerOption()}
+	unaryInterceptors := []grpc.UnaryServerInterceptor{
+		kvlogger.UnaryServerInterceptor(logger),
+		grpcMetrics.UnaryServerInterceptor(),
+	}
+	streamInterceptors := []grpc.StreamServerInterceptor{
+		kvlogger.StreamServerInterceptor(logger),
+		grpcMetrics.StreamServerInterceptor(),
+	}
+	peerCredentials := insecure.NewCredentials()
+
+	var certs *mtls.Reloader
+	if cfg.TLS.Enabled {
+		certs, err = mtls.NewReloader(cfg.TLS, logger)
+		if err != nil {
+			log.Fatalf("failed to load TLS certificates: %v", err)
+		}
+		go certs.Run(ctx)
+
+		serverOptions = append(serverOptions, grpc.Creds(certs.ServerCredentials()))
+		unaryInterceptors = append(unaryInterceptors, certs.UnaryServerInterceptor())
+		streamInterceptors = append(streamInterceptors, certs.StreamServerInterceptor())
+		peerCredentials = certs.ClientCredentials()
+	}
+
+	var authorizer *auth.Authorizer
+	if cfg.Auth.Enabled {
+		authorizer, err = auth.NewAuthorizer(cfg.Auth)
+		if err != nil {
+			log.Fatalf("failed to init authentication: %v", err)
+		}
+
+		// Runs after the mtls interceptors, which verify the certificate it may rely on.
+		unaryInterceptors = append(unaryInterceptors, authorizer.UnaryServerInterceptor())
+		streamInterceptors = append(streamInterceptors, authorizer.StreamServerInterceptor())
+	}
 
-	leService := service.NewLeService(oldNodeModel, storageService, logger)
+	if cfg.Admin.Enabled {
+		adminHandler := http.Handler(logLevel)
+		if authorizer != nil {
+			adminHandler = authorizer.AdminHandler(adminHandler)
+		}
+		if certs != nil {
+			adminHandler = certs.Handler(adminHandler)
+		}
+		adminMux := http.NewServeMux()
+		// GET returns the current level, PUT {"level":"debug"} changes it
+		adminMux.Handle("/admin/log/level", adminHandler)
+		adminServer := &http.Server{Addr: cfg.GetAdminAddress(), Handler: adminMux}
+		if certs != nil {
+			adminServer.TLSConfig = certs.ServerConfig()
+		}
 
-	storeApp := kv_storage_service.NewImplementation(nodeService, storageService, leService, logger)
+		logger.Info(fmt.Sprintf("Starting admin server on %s...", adminServer.Addr))
+		go func() {
+			var err error
+			if certs != nil {
+				err = adminServer.ListenAndServeTLS("", "")
+			} else {
+				err = adminServer.ListenAndServe()
+			}
+			if err != nil {
+				logger.Error("admin server stopped", zap.Error(err))
+			}
+		}()
+	}
+
+	var admissionController *admission.Controller
+	if cfg.RateLimit.Enabled {
+		admissionController, err = admission.NewController(cfg.RateLimit, metrics.NewAdmissionMetrics(registry))
+		if err != nil {
+			log.Fatalf("failed to init rate limiting: %v", err)
+		}
+
+		// Runs after authentication to limit clients by their identity.
+		unaryInterceptors = append(unaryInterceptors, admissionController.UnaryServerInterceptor())
+		streamInterceptors = append(streamInterceptors, admissionController.StreamServerInterceptor())
+	}
+
+	dialOptions := []grpc.DialOption{
+		grpc.WithTransportCredentials(peerCredentials),
+		tracing.DialOption(),
+	}
+	leaseService, err := service.NewLeaseService(storageService, logger)
+	if err != nil {
+		log.Fatalf("failed to load leases: %v", err)
+	}
+	leService := service.NewLeService(nodeModel, storageService, cmService, leaseService, dialOptions, logger)
+	lockService := service.NewLockService(storageService)
+	go service.NewReplicaResyncer(storageService, dialOptions, logger).Run(ctx)
+
+	storeApp := kv_storage_service.NewImplementation(nodeService, storageService, leService, leaseService, lockService, logger)
+	storeAppV2 := kv_storage_service.NewImplementationV2(storageService, leaseService, logger)
 
 	lis, err := net.Listen("tcp", grpcAddress)
 	if err != nil {
 		log.Fatalf("failed to listen: %v", err)
 	}
 
-	grpcServer := grpc.NewServer()
+	serverOptions = append(serverOptions,
+		grpc.ChainUnaryInterceptor(unaryInterceptors...),
+		grpc.ChainStreamInterceptor(streamInterceptors...),
+	)
+	grpcServer := grpc.NewServer(serverOptions...)
 	reflection.Register(grpcServer)
 	desc.RegisterKeyValueStorageServer(grpcServer, storeApp)
+	descv2.RegisterKeyValueStorageServer(grpcServer, storeAppV2)
 
-	healthServer := health.NewServer()
 	grpc_health_v1.RegisterHealthServer(grpcServer, healthServer)
 
-	// Set initial status for your service
-	healthServer.SetServingStatus("kv_storage_service.KeyValueStorage",
-		grpc_health_v1.HealthCheckResponse_SERVING)
-
-	// Set overall server status
-	healthServer.SetServingStatus("",
-		grpc_health_v1.HealthCheckResponse_SERVING)
+	go healthChecker.Run(ctx)
 
 	logger.Info(fmt.Sprintf("Starting grpc server on %s...", grpcAddress))
 	go func() {
@@ -99,12 +230,105 @@ func main() {
 		}
 	}()
 
+	var gatewayServer *gateway.Server
+	if cfg.HTTP.Enabled {
+		httpAddress := cfg.GetHTTPAddress()
+		gatewayServer = gateway.NewServer(storeApp, httpAddress, certs, authorizer, admissionController, logger)
+
+		logger.Info(fmt.Sprintf("Starting http gateway on %s...", httpAddress))
+		go func() {
+			if err := gatewayServer.ListenAndServe(); err != nil {
+				log.Fatalf("failed to listen and serve http gateway: %v", err)
+			}
+		}()
+	}
+
+	var respServer *resp.Server
+	if cfg.RESP.Enabled {
+		respAddress := cfg.GetRESPAddress()
+		respServer = resp.NewServer(storageService, respAddress, admissionController, logger)
+
+		logger.Info(fmt.Sprintf("Starting resp server on %s...", respAddress))
+		go func() {
+			if err := respServer.ListenAndServe(); err != nil {
+				log.Fatalf("failed to listen and serve resp server: %v", err)
+			}
+		}()
+	}
+
+	var memcachedServer *memcached.Server
+	if cfg.Memcached.Enabled {
+		memcachedAddress := cfg.GetMemcachedAddress()
+		memcachedServer = memcached.NewServer(storageService, memcachedAddress, admissionController, logger)
+
+		logger.Info(fmt.Sprintf("Starting memcached server on %s...", memcachedAddress))
+		go func() {
+			if err := memcachedServer.ListenAndServe(); err != nil {
+				log.Fatalf("failed to listen and serve memcached server: %v", err)
+			}
+		}()
+	}
+
 	//logger.Info("Starting gossiping...")
 	//if err := nodeService.Run(ctx, cfg.SeedNodes); err != nil {
 	//	log.Fatalf("Failed to start node: %v", err)
 	//}
 
-	<-stop
-	fmt.Println("\nShutting down servers...")
-	os.Exit(0)
+	<-ctx.Done()
+	logger.Info("Shutting down servers...")
+
+	// Fail health checks first so clients and the cluster manager stop routing here.
+	healthChecker.Shutdown()
+
+	drainCtx, drainCancel := context.WithTimeout(context.Background(), cfg.Shutdown.DrainTimeout)
+	defer drainCancel()
+
+	if gatewayServer != nil {
+		if err := gatewayServer.Shutdown(drainCtx); err != nil {
+			logger.Warn("failed to drain http gateway", zap.Error(err))
+		}
+	}
+	if respServer != nil {
+		respServer.Close()
+	}
+	if memcachedServer != nil {
+		memcachedServer.Close()
+	}
+
+	stopped := make(chan struct{})
+	go func() {
+		grpcServer.GracefulStop()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-drainCtx.Done():
+		// Long-lived streams such as Watch never finish on their own.
+		logger.Warn("drain deadline exceeded, cancelling remaining calls")
+		grpcServer.Stop()
+	}
+
+	if nodeModel.IsLeader() {
+		catchUpCtx, catchUpCancel := context.WithTimeout(context.Background(), cfg.Shutdown.CatchUpTimeout)
+		if err := cmService.WaitCaughtUp(catchUpCtx); err != nil {
+			logger.Warn("replicas did not catch up before shutdown", zap.Error(err))
+		}
+		catchUpCancel()
+
+		if cfg.Shutdown.HandOffLeadership {
+			handOffCtx, handOffCancel := context.WithTimeout(context.Background(), cfg.Shutdown.HandOffTimeout)
+			address, err := leService.HandOff(handOffCtx)
+			handOffCancel()
+			if err != nil {
+				logger.Warn("failed to hand off leadership", zap.Error(err))
+			} else {
+				logger.Info("leadership handed off", zap.String("leader", address))
+			}
+		}
+	}
+
+	if err := storageService.Close(); err != nil {
+		logger.Error("failed to close storage", zap.Error(err))
+	}
+	logger.Info("Shutdown complete")
 }
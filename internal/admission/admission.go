@@ -0,0 +1,323 @@
+package admission
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/Na322Pr/kv-storage-service/internal/auth"
+	"github.com/Na322Pr/kv-storage-service/internal/config"
+	"github.com/Na322Pr/kv-storage-service/internal/metrics"
+	"github.com/Na322Pr/kv-storage-service/internal/mtls"
+	"github.com/Na322Pr/kv-storage-service/internal/ratelimit"
+	desc "github.com/Na322Pr/kv-storage-service/pkg/api"
+	descv2 "github.com/Na322Pr/kv-storage-service/pkg/api/v2"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// RetryAfterKey is the trailer carrying the number of seconds to wait before
+// retrying a rejected call.
+const RetryAfterKey = "retry-after"
+
+const (
+	reasonClientRate   = "client_rate"
+	reasonMethodRate   = "method_rate"
+	reasonShed         = "shed"
+	reasonQueueTimeout = "queue_timeout"
+)
+
+// writeMethods take a slot of the write concurrency cap. Lock is left out: it
+// waits for the lock holder, whose Unlock must not queue behind it.
+var writeMethods = map[string]bool{
+	desc.KeyValueStorage_Set_FullMethodName:             true,
+	desc.KeyValueStorage_Delete_FullMethodName:          true,
+	desc.KeyValueStorage_Increment_FullMethodName:       true,
+	desc.KeyValueStorage_Decrement_FullMethodName:       true,
+	desc.KeyValueStorage_Unlock_FullMethodName:          true,
+	desc.KeyValueStorage_LeaseGrant_FullMethodName:      true,
+	desc.KeyValueStorage_LeaseRevoke_FullMethodName:     true,
+	desc.KeyValueStorage_CreateNamespace_FullMethodName: true,
+	desc.KeyValueStorage_DropNamespace_FullMethodName:   true,
+	desc.KeyValueStorage_Restore_FullMethodName:         true,
+	desc.KeyValueStorage_Import_FullMethodName:          true,
+
+	descv2.KeyValueStorage_Set_FullMethodName:      true,
+	descv2.KeyValueStorage_Delete_FullMethodName:   true,
+	descv2.KeyValueStorage_PutLarge_FullMethodName: true,
+}
+
+// healthService is never limited so probes keep working under load.
+const healthService = "/grpc.health.v1.Health/"
+
+type client struct {
+	// bucket is nil when there is no per-client rate.
+	bucket  *ratelimit.Bucket
+	methods map[string]*ratelimit.Bucket
+	// lastSeen is the unix time of the last request in nanoseconds.
+	lastSeen atomic.Int64
+}
+
+// Controller admits client requests: it applies token bucket limits per
+// client and per client and method, and caps the writes handled at once,
+// queueing the ones over the cap for a while before shedding them. Calls
+// between nodes are never limited.
+type Controller struct {
+	cfg     config.RateLimit
+	metrics *metrics.AdmissionMetrics
+
+	mu        sync.Mutex
+	clients   map[string]*client
+	lastSweep time.Time
+
+	// writes holds a token per write in flight, nil when writes are not capped.
+	writes chan struct{}
+	queued atomic.Int64
+}
+
+func NewController(cfg config.RateLimit, m *metrics.AdmissionMetrics) (*Controller, error) {
+	for name := range cfg.Methods {
+		if _, ok := methodNames[name]; !ok {
+			return nil, fmt.Errorf("rate limit: unknown method %q", name)
+		}
+	}
+
+	c := &Controller{
+		cfg:       cfg,
+		metrics:   m,
+		clients:   make(map[string]*client),
+		lastSweep: time.Now(),
+	}
+	if cfg.MaxInflightWrites > 0 {
+		c.writes = make(chan struct{}, cfg.MaxInflightWrites)
+	}
+	return c, nil
+}
+
+// Admit checks the rate limits of the caller and waits for a write slot if
+// the method needs one. The returned function releases the slot.
+func (c *Controller) Admit(ctx context.Context, method string) (func(), error) {
+	if strings.HasPrefix(method, healthService) || mtls.IsInternal(method) {
+		return func() {}, nil
+	}
+
+	if err := c.limit(ctx, method); err != nil {
+		return nil, err
+	}
+	if !writeMethods[method] || c.writes == nil {
+		return func() {}, nil
+	}
+	return c.acquire(ctx, method)
+}
+
+func (c *Controller) limit(ctx context.Context, method string) error {
+	cl := c.client(clientID(ctx))
+	cl.lastSeen.Store(time.Now().UnixNano())
+
+	if cl.bucket != nil {
+		if ok, wait := cl.bucket.Take(); !ok {
+			return c.reject(ctx, method, reasonClientRate, wait)
+		}
+	}
+	if bucket, ok := cl.methods[methodName(method)]; ok {
+		if ok, wait := bucket.Take(); !ok {
+			return c.reject(ctx, method, reasonMethodRate, wait)
+		}
+	}
+	return nil
+}
+
+// client returns the buckets of the client, creating them on its first call.
+// Clients idle for longer than ClientIdleTimeout are forgotten.
+func (c *Controller) client(id string) *client {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	if now.Sub(c.lastSweep) > c.cfg.ClientIdleTimeout {
+		idle := now.Add(-c.cfg.ClientIdleTimeout).UnixNano()
+		for key, cl := range c.clients {
+			if cl.lastSeen.Load() < idle {
+				delete(c.clients, key)
+			}
+		}
+		c.lastSweep = now
+	}
+
+	if cl, ok := c.clients[id]; ok {
+		return cl
+	}
+
+	cl := &client{methods: make(map[string]*ratelimit.Bucket, len(c.cfg.Methods))}
+	if c.cfg.ClientRate > 0 {
+		cl.bucket = ratelimit.NewBucket(c.cfg.ClientRate, burst(c.cfg.ClientRate, c.cfg.ClientBurst))
+	}
+	for name, limit := range c.cfg.Methods {
+		if limit.Rate > 0 {
+			cl.methods[name] = ratelimit.NewBucket(limit.Rate, burst(limit.Rate, limit.Burst))
+		}
+	}
+	c.clients[id] = cl
+	return cl
+}
+
+// burst defaults to one second worth of requests.
+func burst(rate, burst float64) float64 {
+	if burst > 0 {
+		return burst
+	}
+	return rate
+}
+
+// acquire takes a write slot. When all slots are busy the call waits in the
+// queue up to WriteQueueTimeout, calls finding the queue full are shed.
+func (c *Controller) acquire(ctx context.Context, method string) (func(), error) {
+	release := func() {
+		<-c.writes
+		c.metrics.WriteFinished()
+	}
+
+	select {
+	case c.writes <- struct{}{}:
+		c.metrics.WriteStarted()
+		return release, nil
+	default:
+	}
+
+	if c.queued.Add(1) > int64(c.cfg.WriteQueueSize) {
+		c.queued.Add(-1)
+		return nil, c.reject(ctx, method, reasonShed, c.cfg.WriteQueueTimeout)
+	}
+	c.metrics.Queued(1)
+	defer func() {
+		c.queued.Add(-1)
+		c.metrics.Queued(-1)
+	}()
+
+	start := time.Now()
+	timer := time.NewTimer(c.cfg.WriteQueueTimeout)
+	defer timer.Stop()
+
+	select {
+	case c.writes <- struct{}{}:
+		c.metrics.Waited(time.Since(start))
+		c.metrics.WriteStarted()
+		return release, nil
+	case <-timer.C:
+		c.metrics.Waited(time.Since(start))
+		return nil, c.reject(ctx, method, reasonQueueTimeout, c.cfg.WriteQueueTimeout)
+	case <-ctx.Done():
+		c.metrics.Waited(time.Since(start))
+		return nil, status.FromContextError(ctx.Err()).Err()
+	}
+}
+
+// rejectedError is a ResourceExhausted status carrying the seconds to wait
+// for the transports without gRPC trailers.
+type rejectedError struct {
+	status     *status.Status
+	retryAfter int64
+}
+
+func (e *rejectedError) Error() string {
+	return e.status.Err().Error()
+}
+
+func (e *rejectedError) GRPCStatus() *status.Status {
+	return e.status
+}
+
+// RetryAfter returns the seconds to wait before retrying a call rejected by
+// Admit.
+func RetryAfter(err error) (int64, bool) {
+	var rejected *rejectedError
+	if !errors.As(err, &rejected) {
+		return 0, false
+	}
+	return rejected.retryAfter, true
+}
+
+// reject counts the rejection and tells the caller when to retry, in whole
+// seconds like the HTTP Retry-After header.
+func (c *Controller) reject(ctx context.Context, method, reason string, wait time.Duration) error {
+	c.metrics.Rejected(method, reason)
+
+	seconds := max(1, int64((wait+time.Second-1)/time.Second))
+	_ = grpc.SetTrailer(ctx, metadata.Pairs(RetryAfterKey, strconv.FormatInt(seconds, 10)))
+	return &rejectedError{
+		status:     status.Newf(codes.ResourceExhausted, "%s: too many requests (%s), retry after %ds", methodName(method), reason, seconds),
+		retryAfter: seconds,
+	}
+}
+
+func (c *Controller) UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		release, err := c.Admit(ctx, info.FullMethod)
+		if err != nil {
+			return nil, err
+		}
+		defer release()
+		return handler(ctx, req)
+	}
+}
+
+// StreamServerInterceptor admits streams when they open.
+func (c *Controller) StreamServerInterceptor() grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		release, err := c.Admit(ss.Context(), info.FullMethod)
+		if err != nil {
+			return err
+		}
+		defer release()
+		return handler(srv, ss)
+	}
+}
+
+// clientID identifies the caller by its authenticated subject, its client
+// certificate or, failing both, its address.
+func clientID(ctx context.Context) string {
+	if identity, ok := auth.FromContext(ctx); ok && identity.Subject != "" {
+		return "subject:" + identity.Subject
+	}
+	if cert, ok := mtls.PeerCertificate(ctx); ok {
+		return "certificate:" + cert.Subject.CommonName
+	}
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		host, _, err := net.SplitHostPort(p.Addr.String())
+		if err != nil {
+			host = p.Addr.String()
+		}
+		return "address:" + host
+	}
+	return ""
+}
+
+// methodName returns the short name of a full method, as used in the configuration.
+func methodName(method string) string {
+	return path.Base(method)
+}
+
+// methodNames are the short names of the methods that can be limited. The
+// methods of both API versions share them.
+var methodNames = func() map[string]struct{} {
+	names := make(map[string]struct{})
+	for _, sd := range []*grpc.ServiceDesc{&desc.KeyValueStorage_ServiceDesc, &descv2.KeyValueStorage_ServiceDesc} {
+		for _, method := range sd.Methods {
+			names[method.MethodName] = struct{}{}
+		}
+		for _, stream := range sd.Streams {
+			names[stream.StreamName] = struct{}{}
+		}
+	}
+	return names
+}()
@@ -0,0 +1,141 @@
+package admission_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Na322Pr/kv-storage-service/internal/admission"
+	"github.com/Na322Pr/kv-storage-service/internal/auth"
+	"github.com/Na322Pr/kv-storage-service/internal/config"
+	"github.com/Na322Pr/kv-storage-service/internal/metrics"
+	desc "github.com/Na322Pr/kv-storage-service/pkg/api"
+	"github.com/prometheus/client_golang/prometheus"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func newController(t *testing.T, cfg config.RateLimit) *admission.Controller {
+	t.Helper()
+	cfg.Enabled = true
+	if cfg.ClientIdleTimeout == 0 {
+		cfg.ClientIdleTimeout = time.Hour
+	}
+	c, err := admission.NewController(cfg, metrics.NewAdmissionMetrics(prometheus.NewRegistry()))
+	if err != nil {
+		t.Fatalf("new controller: %v", err)
+	}
+	return c
+}
+
+func as(subject string) context.Context {
+	return auth.WithIdentity(context.Background(), &auth.Identity{Subject: subject})
+}
+
+// rejected checks err is a ResourceExhausted rejection telling when to retry.
+func rejected(t *testing.T, err error) int64 {
+	t.Helper()
+	if status.Code(err) != codes.ResourceExhausted {
+		t.Fatalf("got %v, want a rejection", err)
+	}
+	seconds, ok := admission.RetryAfter(err)
+	if !ok || seconds < 1 {
+		t.Fatalf("retry after %d %v", seconds, ok)
+	}
+	return seconds
+}
+
+func TestClientRate(t *testing.T) {
+	c := newController(t, config.RateLimit{ClientRate: 0.1, ClientBurst: 2})
+
+	for i := 0; i < 2; i++ {
+		release, err := c.Admit(as("alice"), desc.KeyValueStorage_Get_FullMethodName)
+		if err != nil {
+			t.Fatalf("call %d: %v", i, err)
+		}
+		release()
+	}
+	// One token comes back every ten seconds.
+	_, err := c.Admit(as("alice"), desc.KeyValueStorage_Set_FullMethodName)
+	if seconds := rejected(t, err); seconds > 10 {
+		t.Fatalf("retry after %ds", seconds)
+	}
+
+	// Every client has its own bucket.
+	if _, err := c.Admit(as("bob"), desc.KeyValueStorage_Get_FullMethodName); err != nil {
+		t.Fatalf("other client: %v", err)
+	}
+	// Probes and calls between nodes are never limited.
+	for _, method := range []string{"/grpc.health.v1.Health/Check", desc.KeyValueStorage_SetStream_FullMethodName} {
+		if _, err := c.Admit(as("alice"), method); err != nil {
+			t.Fatalf("%s: %v", method, err)
+		}
+	}
+}
+
+func TestMethodRate(t *testing.T) {
+	c := newController(t, config.RateLimit{Methods: map[string]config.MethodRateLimit{"Set": {Rate: 0.1, Burst: 1}}})
+
+	if _, err := c.Admit(as("alice"), desc.KeyValueStorage_Set_FullMethodName); err != nil {
+		t.Fatalf("first set: %v", err)
+	}
+	_, err := c.Admit(as("alice"), desc.KeyValueStorage_Set_FullMethodName)
+	rejected(t, err)
+	if _, err := c.Admit(as("alice"), desc.KeyValueStorage_Get_FullMethodName); err != nil {
+		t.Fatalf("other method: %v", err)
+	}
+}
+
+func TestUnknownMethod(t *testing.T) {
+	_, err := admission.NewController(config.RateLimit{Methods: map[string]config.MethodRateLimit{"Sett": {Rate: 1}}},
+		metrics.NewAdmissionMetrics(prometheus.NewRegistry()))
+	if err == nil {
+		t.Fatal("unknown method accepted")
+	}
+}
+
+func TestWriteQueue(t *testing.T) {
+	c := newController(t, config.RateLimit{MaxInflightWrites: 1, WriteQueueSize: 1, WriteQueueTimeout: 100 * time.Millisecond})
+	set := desc.KeyValueStorage_Set_FullMethodName
+
+	release, err := c.Admit(as("alice"), set)
+	if err != nil {
+		t.Fatalf("first write: %v", err)
+	}
+	// Reads do not take a write slot.
+	if _, err := c.Admit(as("alice"), desc.KeyValueStorage_Get_FullMethodName); err != nil {
+		t.Fatalf("read: %v", err)
+	}
+
+	// The second write queues, the third finds the queue full.
+	queued := make(chan error, 1)
+	go func() {
+		release, err := c.Admit(as("bob"), set)
+		if err == nil {
+			release()
+		}
+		queued <- err
+	}()
+	time.Sleep(20 * time.Millisecond)
+	_, err = c.Admit(as("carol"), set)
+	rejected(t, err)
+
+	// A slot freed in time admits the queued write.
+	release()
+	if err := <-queued; err != nil {
+		t.Fatalf("queued write: %v", err)
+	}
+
+	// A write queued for longer than the timeout is rejected.
+	release, err = c.Admit(as("alice"), set)
+	if err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	defer release()
+	start := time.Now()
+	_, err = c.Admit(as("bob"), set)
+	rejected(t, err)
+	if waited := time.Since(start); waited < 100*time.Millisecond {
+		t.Fatalf("rejected after %v in the queue", waited)
+	}
+}
@@ -0,0 +1,278 @@
+package gateway
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Na322Pr/kv-storage-service/internal/service"
+	desc "github.com/Na322Pr/kv-storage-service/pkg/api"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type keyValue struct {
+	Key   string `json:"key"`
+	Value string `json:"value"`
+}
+
+type putRequest struct {
+	Value string `json:"value"`
+	// TTLMs is an optional time to live in milliseconds.
+	TTLMs int64 `json:"ttl_ms,omitempty"`
+}
+
+type scanResponse struct {
+	Items          []keyValue `json:"items"`
+	NextStartAfter string     `json:"next_start_after,omitempty"`
+}
+
+type deleteResponse struct {
+	Deleted bool `json:"deleted"`
+}
+
+type keyVersion struct {
+	Revision   int64  `json:"revision"`
+	Value      string `json:"value"`
+	Deleted    bool   `json:"deleted,omitempty"`
+	Expiration int64  `json:"expiration,omitempty"`
+	Time       int64  `json:"time"`
+}
+
+type historyResponse struct {
+	Versions          []keyVersion `json:"versions"`
+	NextStartRevision int64        `json:"next_start_revision,omitempty"`
+	CompactRevision   int64        `json:"compact_revision"`
+}
+
+// revisionParam parses the optional revision query parameter.
+func revisionParam(r *http.Request) (*int64, error) {
+	value := r.URL.Query().Get("revision")
+	if value == "" {
+		return nil, nil
+	}
+	revision, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid revision: %v", err)
+	}
+	return &revision, nil
+}
+
+func (s *Server) get(w http.ResponseWriter, r *http.Request) {
+	key := r.PathValue("key")
+	req := &desc.GetRequest{Key: key, Namespace: r.URL.Query().Get("namespace")}
+	revision, err := revisionParam(r)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	req.Revision = revision
+
+	ctx, release, err := s.admit(r, desc.KeyValueStorage_Get_FullMethodName, req)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	defer release()
+
+	resp, err := s.impl.Get(ctx, req)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if !resp.Found {
+		writeError(w, status.Errorf(codes.NotFound, "key %q not found", key))
+		return
+	}
+
+	writeJSON(w, http.StatusOK, keyValue{Key: key, Value: resp.Value})
+}
+
+func (s *Server) put(w http.ResponseWriter, r *http.Request) {
+	var body putRequest
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		writeError(w, status.Errorf(codes.InvalidArgument, "invalid request body: %v", err))
+		return
+	}
+	if body.TTLMs < 0 {
+		writeError(w, status.Error(codes.InvalidArgument, "ttl_ms must not be negative"))
+		return
+	}
+
+	operation := string(service.OperationSet)
+	req := &desc.SetRequest{
+		Namespace: r.URL.Query().Get("namespace"),
+		Key:       r.PathValue("key"),
+		Value:     body.Value,
+		Operation: &operation,
+	}
+	if body.TTLMs > 0 {
+		expiration := time.Now().Add(time.Duration(body.TTLMs) * time.Millisecond).UnixNano()
+		req.Expiration = &expiration
+	}
+
+	ctx, release, err := s.admit(r, desc.KeyValueStorage_Set_FullMethodName, req)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	defer release()
+
+	if _, err := s.impl.Set(ctx, req); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) delete(w http.ResponseWriter, r *http.Request) {
+	req := &desc.DeleteRequest{Key: r.PathValue("key"), Namespace: r.URL.Query().Get("namespace")}
+
+	ctx, release, err := s.admit(r, desc.KeyValueStorage_Delete_FullMethodName, req)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	defer release()
+
+	resp, err := s.impl.Delete(ctx, req)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, deleteResponse{Deleted: resp.Deleted})
+}
+
+func (s *Server) scan(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	req := &desc.ScanRequest{
+		Namespace:  query.Get("namespace"),
+		Prefix:     query.Get("prefix"),
+		StartAfter: query.Get("start_after"),
+	}
+	if limit := query.Get("limit"); limit != "" {
+		n, err := strconv.ParseInt(limit, 10, 32)
+		if err != nil {
+			writeError(w, status.Errorf(codes.InvalidArgument, "invalid limit: %v", err))
+			return
+		}
+		req.Limit = int32(n)
+	}
+	revision, err := revisionParam(r)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	req.Revision = revision
+
+	ctx, release, err := s.admit(r, desc.KeyValueStorage_Scan_FullMethodName, req)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	defer release()
+
+	resp, err := s.impl.Scan(ctx, req)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	out := scanResponse{
+		Items:          make([]keyValue, 0, len(resp.Items)),
+		NextStartAfter: resp.NextStartAfter,
+	}
+	for _, item := range resp.Items {
+		out.Items = append(out.Items, keyValue{Key: item.Key, Value: item.Value})
+	}
+
+	writeJSON(w, http.StatusOK, out)
+}
+
+func (s *Server) history(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	req := &desc.HistoryRequest{
+		Namespace: query.Get("namespace"),
+		Key:       r.PathValue("key"),
+	}
+	if start := query.Get("start_revision"); start != "" {
+		n, err := strconv.ParseInt(start, 10, 64)
+		if err != nil {
+			writeError(w, status.Errorf(codes.InvalidArgument, "invalid start_revision: %v", err))
+			return
+		}
+		req.StartRevision = n
+	}
+	if limit := query.Get("limit"); limit != "" {
+		n, err := strconv.ParseInt(limit, 10, 32)
+		if err != nil {
+			writeError(w, status.Errorf(codes.InvalidArgument, "invalid limit: %v", err))
+			return
+		}
+		req.Limit = int32(n)
+	}
+
+	ctx, release, err := s.admit(r, desc.KeyValueStorage_History_FullMethodName, req)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	defer release()
+
+	resp, err := s.impl.History(ctx, req)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	out := historyResponse{
+		Versions:          make([]keyVersion, 0, len(resp.Versions)),
+		NextStartRevision: resp.NextStartRevision,
+		CompactRevision:   resp.CompactRevision,
+	}
+	for _, v := range resp.Versions {
+		out.Versions = append(out.Versions, keyVersion{
+			Revision:   v.Revision,
+			Value:      v.Value,
+			Deleted:    v.Deleted,
+			Expiration: v.Expiration,
+			Time:       v.Time,
+		})
+	}
+
+	writeJSON(w, http.StatusOK, out)
+}
+
+func (s *Server) watch(w http.ResponseWriter, r *http.Request) {
+	req := &desc.WatchRequest{
+		Namespace: r.URL.Query().Get("namespace"),
+		Key:       r.PathValue("key"),
+		Prefix:    r.URL.Query().Get("prefix") == "true",
+	}
+	if req.Key == "" && !req.Prefix {
+		writeError(w, status.Error(codes.InvalidArgument, "key is required"))
+		return
+	}
+
+	_, release, err := s.admit(r, desc.KeyValueStorage_Watch_FullMethodName, req)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	defer release()
+
+	stream, err := newSSEStream(w, r)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	if err := s.impl.Watch(req, stream); err != nil && r.Context().Err() == nil {
+		stream.sendError(err)
+	}
+}
@@ -0,0 +1,109 @@
+package gateway
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/Na322Pr/kv-storage-service/internal/admission"
+	kv_storage_service "github.com/Na322Pr/kv-storage-service/internal/app/kv-storage-service"
+	"github.com/Na322Pr/kv-storage-service/internal/auth"
+	"github.com/Na322Pr/kv-storage-service/internal/mtls"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/metadata"
+)
+
+const readHeaderTimeout = 10 * time.Second
+
+// Server exposes the gRPC Implementation as an HTTP/JSON API for clients that
+// cannot speak gRPC.
+type Server struct {
+	impl *kv_storage_service.Implementation
+	// certs is nil when TLS is disabled.
+	certs *mtls.Reloader
+	// authorizer is nil when authentication is disabled.
+	authorizer *auth.Authorizer
+	// admission is nil when rate limiting is disabled.
+	admission *admission.Controller
+	server    *http.Server
+	logger    *zap.Logger
+}
+
+func NewServer(impl *kv_storage_service.Implementation, address string, certs *mtls.Reloader, authorizer *auth.Authorizer, admissionController *admission.Controller, logger *zap.Logger) *Server {
+	s := &Server{
+		impl:       impl,
+		certs:      certs,
+		authorizer: authorizer,
+		admission:  admissionController,
+		logger:     logger,
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /v1/kv", s.scan)
+	mux.HandleFunc("GET /v1/kv/{key...}", s.get)
+	mux.HandleFunc("PUT /v1/kv/{key...}", s.put)
+	mux.HandleFunc("DELETE /v1/kv/{key...}", s.delete)
+	mux.HandleFunc("GET /v1/watch/{key...}", s.watch)
+	mux.HandleFunc("GET /v1/history/{key...}", s.history)
+
+	s.server = &http.Server{
+		Addr:              address,
+		Handler:           mux,
+		ReadHeaderTimeout: readHeaderTimeout,
+	}
+	if certs != nil {
+		s.server.TLSConfig = certs.ServerConfig()
+	}
+
+	return s
+}
+
+// ListenAndServe serves HTTPS with the node certificate when TLS is enabled,
+// plain HTTP otherwise.
+func (s *Server) ListenAndServe() error {
+	var err error
+	if s.certs != nil {
+		err = s.server.ListenAndServeTLS("", "")
+	} else {
+		err = s.server.ListenAndServe()
+	}
+	if err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+func (s *Server) Shutdown(ctx context.Context) error {
+	return s.server.Shutdown(ctx)
+}
+
+// admit applies the gRPC access rules and rate limits to the request, reading
+// the bearer token from the Authorization header and verifying the client
+// certificate like the mtls interceptors do. The returned function releases
+// the write slot taken by the request.
+func (s *Server) admit(r *http.Request, method string, req any) (context.Context, func(), error) {
+	ctx := mtls.WithHTTPPeer(r.Context(), r)
+
+	if s.certs != nil {
+		var err error
+		if ctx, err = s.certs.Authorize(ctx, method); err != nil {
+			return nil, nil, err
+		}
+	}
+	if s.authorizer != nil {
+		var err error
+		ctx = metadata.NewIncomingContext(ctx, metadata.Pairs("authorization", r.Header.Get("Authorization")))
+		if ctx, err = s.authorizer.AuthorizeCall(ctx, method, req); err != nil {
+			return nil, nil, err
+		}
+	}
+	if s.admission == nil {
+		return ctx, func() {}, nil
+	}
+	release, err := s.admission.Admit(ctx, method)
+	if err != nil {
+		return nil, nil, err
+	}
+	return ctx, release, nil
+}
@@ -0,0 +1,330 @@
+package gateway
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
+	"io"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Na322Pr/kv-storage-service/internal/admission"
+	kv_storage_service "github.com/Na322Pr/kv-storage-service/internal/app/kv-storage-service"
+	"github.com/Na322Pr/kv-storage-service/internal/auth"
+	"github.com/Na322Pr/kv-storage-service/internal/config"
+	"github.com/Na322Pr/kv-storage-service/internal/metrics"
+	"github.com/Na322Pr/kv-storage-service/internal/model"
+	"github.com/Na322Pr/kv-storage-service/internal/mtls"
+	"github.com/Na322Pr/kv-storage-service/internal/service"
+	"github.com/Na322Pr/kv-storage-service/internal/storage"
+	"github.com/prometheus/client_golang/prometheus"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+)
+
+// newImplementation returns the gRPC implementation of a leader node.
+func newImplementation(t *testing.T) *kv_storage_service.Implementation {
+	t.Helper()
+	store, err := storage.NewStore(storage.NewMemoryEngine(), config.Storage{EvictionPolicy: "noeviction"})
+	if err != nil {
+		t.Fatalf("new store: %v", err)
+	}
+	node := model.NewNode("1", "1", "127.0.0.1:2110")
+	node.SetLeader(true)
+	ss := service.NewStorageService(store, node, service.NewConnectionManagerService())
+	leases, err := service.NewLeaseService(ss, zap.NewNop())
+	if err != nil {
+		t.Fatalf("new lease service: %v", err)
+	}
+	return kv_storage_service.NewImplementation(nil, ss, nil, leases, service.NewLockService(ss), zap.NewNop())
+}
+
+// start serves the gateway of a leader node over HTTP.
+func start(t *testing.T, authorizer *auth.Authorizer) *httptest.Server {
+	t.Helper()
+	s := NewServer(newImplementation(t), "", nil, authorizer, nil, zap.NewNop())
+	ts := httptest.NewServer(s.server.Handler)
+	t.Cleanup(ts.Close)
+	return ts
+}
+
+// call sends the request and decodes a JSON reply into out when given.
+func call(t *testing.T, ts *httptest.Server, method, path, token, body string, out any) int {
+	t.Helper()
+	req, err := http.NewRequest(method, ts.URL+path, strings.NewReader(body))
+	if err != nil {
+		t.Fatalf("new request: %v", err)
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp, err := ts.Client().Do(req)
+	if err != nil {
+		t.Fatalf("%s %s: %v", method, path, err)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("read body: %v", err)
+	}
+	if out != nil && len(data) > 0 {
+		if err := json.Unmarshal(data, out); err != nil {
+			t.Fatalf("%s %s: decode %q: %v", method, path, data, err)
+		}
+	}
+	return resp.StatusCode
+}
+
+func TestRoutes(t *testing.T) {
+	ts := start(t, nil)
+
+	if code := call(t, ts, "PUT", "/v1/kv/a/b", "", `{"value":"1"}`, nil); code != http.StatusNoContent {
+		t.Fatalf("put: %d", code)
+	}
+	var kv keyValue
+	if code := call(t, ts, "GET", "/v1/kv/a/b", "", "", &kv); code != http.StatusOK || kv != (keyValue{Key: "a/b", Value: "1"}) {
+		t.Fatalf("get: %d %+v", code, kv)
+	}
+	call(t, ts, "PUT", "/v1/kv/a/c", "", `{"value":"2","ttl_ms":60000}`, nil)
+	call(t, ts, "PUT", "/v1/kv/b", "", `{"value":"3"}`, nil)
+	call(t, ts, "PUT", "/v1/kv/a/b", "", `{"value":"4"}`, nil)
+
+	var scan scanResponse
+	if code := call(t, ts, "GET", "/v1/kv?prefix=a/&limit=1", "", "", &scan); code != http.StatusOK ||
+		len(scan.Items) != 1 || scan.Items[0].Key != "a/b" || scan.NextStartAfter != "a/b" {
+		t.Fatalf("scan: %d %+v", code, scan)
+	}
+	if code := call(t, ts, "GET", "/v1/kv?prefix=a/&start_after=a/b", "", "", &scan); code != http.StatusOK ||
+		len(scan.Items) != 1 || scan.Items[0].Key != "a/c" {
+		t.Fatalf("scan after: %d %+v", code, scan)
+	}
+
+	// The first write of a/b is still readable at its revision.
+	if code := call(t, ts, "GET", "/v1/kv/a/b?revision=1", "", "", &kv); code != http.StatusOK || kv.Value != "1" {
+		t.Fatalf("get at revision: %d %+v", code, kv)
+	}
+	var history historyResponse
+	if code := call(t, ts, "GET", "/v1/history/a/b", "", "", &history); code != http.StatusOK ||
+		len(history.Versions) != 2 || history.Versions[0].Value != "1" || history.Versions[1].Value != "4" {
+		t.Fatalf("history: %d %+v", code, history)
+	}
+
+	var deleted deleteResponse
+	if code := call(t, ts, "DELETE", "/v1/kv/a/b", "", "", &deleted); code != http.StatusOK || !deleted.Deleted {
+		t.Fatalf("delete: %d %+v", code, deleted)
+	}
+	if code := call(t, ts, "DELETE", "/v1/kv/a/b", "", "", &deleted); code != http.StatusOK || deleted.Deleted {
+		t.Fatalf("delete again: %d %+v", code, deleted)
+	}
+}
+
+func TestErrors(t *testing.T) {
+	ts := start(t, nil)
+
+	for _, tt := range []struct {
+		method, path, body string
+		code               int
+		grpcCode           codes.Code
+	}{
+		{"GET", "/v1/kv/missing", "", http.StatusNotFound, codes.NotFound},
+		{"GET", "/v1/kv/a?revision=x", "", http.StatusBadRequest, codes.InvalidArgument},
+		{"GET", "/v1/kv/a?revision=1000", "", http.StatusBadRequest, codes.OutOfRange},
+		{"GET", "/v1/kv?limit=x", "", http.StatusBadRequest, codes.InvalidArgument},
+		{"GET", "/v1/kv/a?namespace=missing", "", http.StatusNotFound, codes.NotFound},
+		{"PUT", "/v1/kv/a", "{", http.StatusBadRequest, codes.InvalidArgument},
+		{"PUT", "/v1/kv/a", `{"value":"1","ttl_ms":-1}`, http.StatusBadRequest, codes.InvalidArgument},
+		{"GET", "/v1/watch/", "", http.StatusBadRequest, codes.InvalidArgument},
+	} {
+		var got errorResponse
+		code := call(t, ts, tt.method, tt.path, "", tt.body, &got)
+		if code != tt.code || got.Code != tt.grpcCode.String() || got.Message == "" {
+			t.Errorf("%s %s: %d %+v, want %d %s", tt.method, tt.path, code, got, tt.code, tt.grpcCode)
+		}
+	}
+
+	if code := call(t, ts, "POST", "/v1/kv/a", "", "", nil); code != http.StatusMethodNotAllowed {
+		t.Errorf("post: %d", code)
+	}
+}
+
+func TestHTTPStatus(t *testing.T) {
+	for code, want := range map[codes.Code]int{
+		codes.OK:                 http.StatusOK,
+		codes.Canceled:           499,
+		codes.InvalidArgument:    http.StatusBadRequest,
+		codes.FailedPrecondition: http.StatusBadRequest,
+		codes.NotFound:           http.StatusNotFound,
+		codes.AlreadyExists:      http.StatusConflict,
+		codes.Aborted:            http.StatusConflict,
+		codes.PermissionDenied:   http.StatusForbidden,
+		codes.Unauthenticated:    http.StatusUnauthorized,
+		codes.ResourceExhausted:  http.StatusTooManyRequests,
+		codes.Unavailable:        http.StatusServiceUnavailable,
+		codes.Internal:           http.StatusInternalServerError,
+		codes.Unknown:            http.StatusInternalServerError,
+	} {
+		if got := httpStatus(code); got != want {
+			t.Errorf("%s: %d, want %d", code, got, want)
+		}
+	}
+}
+
+func TestAuthorization(t *testing.T) {
+	authorizer, err := auth.NewAuthorizer(config.Auth{
+		Enabled: true,
+		Tokens: []config.AuthToken{
+			{Token: "reader", Subject: "reader", Roles: []string{"reader"}},
+			{Token: "writer", Subject: "writer", Roles: []string{"writer"}},
+		},
+		Roles: map[string][]config.ACLRule{
+			"reader": {{Prefix: "app/", Permissions: []string{"read"}}},
+			"writer": {{Prefix: "app/", Permissions: []string{"read", "write"}}},
+		},
+	})
+	if err != nil {
+		t.Fatalf("new authorizer: %v", err)
+	}
+	ts := start(t, authorizer)
+
+	for _, tt := range []struct {
+		method, path, token string
+		code                int
+	}{
+		{"PUT", "/v1/kv/app/a", "", http.StatusUnauthorized},
+		{"PUT", "/v1/kv/app/a", "bogus", http.StatusUnauthorized},
+		{"PUT", "/v1/kv/app/a", "reader", http.StatusForbidden},
+		{"PUT", "/v1/kv/app/a", "writer", http.StatusNoContent},
+		{"PUT", "/v1/kv/other", "writer", http.StatusForbidden},
+		{"GET", "/v1/kv/app/a", "reader", http.StatusOK},
+		{"GET", "/v1/kv/other", "reader", http.StatusForbidden},
+		{"GET", "/v1/kv?prefix=app/", "reader", http.StatusOK},
+		{"GET", "/v1/kv", "reader", http.StatusForbidden},
+		{"DELETE", "/v1/kv/app/a", "reader", http.StatusForbidden},
+	} {
+		if code := call(t, ts, tt.method, tt.path, tt.token, `{"value":"1"}`, nil); code != tt.code {
+			t.Errorf("%s %s as %q: %d, want %d", tt.method, tt.path, tt.token, code, tt.code)
+		}
+	}
+}
+
+func TestAdmission(t *testing.T) {
+	controller, err := admission.NewController(config.RateLimit{Enabled: true, ClientRate: 0.01, ClientBurst: 1, ClientIdleTimeout: time.Hour},
+		metrics.NewAdmissionMetrics(prometheus.NewRegistry()))
+	if err != nil {
+		t.Fatalf("new controller: %v", err)
+	}
+	s := NewServer(newImplementation(t), "", nil, nil, controller, zap.NewNop())
+	ts := httptest.NewServer(s.server.Handler)
+	defer ts.Close()
+
+	if code := call(t, ts, "PUT", "/v1/kv/a", "", `{"value":"1"}`, nil); code != http.StatusNoContent {
+		t.Fatalf("put: %d", code)
+	}
+	resp, err := ts.Client().Get(ts.URL + "/v1/kv/a")
+	if err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusTooManyRequests || resp.Header.Get("Retry-After") != "100" {
+		t.Fatalf("rejected get: %d, retry after %q", resp.StatusCode, resp.Header.Get("Retry-After"))
+	}
+}
+
+func TestTLS(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "node1"},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("create certificate: %v", err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatalf("marshal key: %v", err)
+	}
+	dir := t.TempDir()
+	certFile, keyFile := filepath.Join(dir, "node.crt"), filepath.Join(dir, "node.key")
+	if err := os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600); err != nil {
+		t.Fatalf("write certificate: %v", err)
+	}
+	if err := os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600); err != nil {
+		t.Fatalf("write key: %v", err)
+	}
+	certs, err := mtls.NewReloader(config.TLS{
+		Enabled:           true,
+		CertFile:          certFile,
+		KeyFile:           keyFile,
+		PeerCAFile:        certFile,
+		RequireClientCert: true,
+		ReloadInterval:    time.Minute,
+	}, zap.NewNop())
+	if err != nil {
+		t.Fatalf("new reloader: %v", err)
+	}
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	addr := lis.Addr().String()
+	lis.Close()
+	s := NewServer(newImplementation(t), addr, certs, nil, nil, zap.NewNop())
+	go s.ListenAndServe()
+	t.Cleanup(func() { s.Shutdown(context.Background()) })
+
+	pair, err := tls.X509KeyPair(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}))
+	if err != nil {
+		t.Fatalf("key pair: %v", err)
+	}
+	roots := x509.NewCertPool()
+	roots.AppendCertsFromPEM(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}))
+	get := func(scheme string, cfg *tls.Config) int {
+		t.Helper()
+		client := &http.Client{Transport: &http.Transport{TLSClientConfig: cfg}}
+		for deadline := time.Now().Add(5 * time.Second); ; {
+			resp, err := client.Get(scheme + "://" + addr + "/v1/kv/a")
+			if err == nil {
+				resp.Body.Close()
+				return resp.StatusCode
+			}
+			if time.Now().After(deadline) {
+				t.Fatalf("get: %v", err)
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+
+	// Plain HTTP is answered with an error by the TLS server.
+	if code := get("http", nil); code != http.StatusBadRequest {
+		t.Fatalf("plain http: %d", code)
+	}
+	if code := get("https", &tls.Config{RootCAs: roots}); code != http.StatusUnauthorized {
+		t.Fatalf("without a client certificate: %d", code)
+	}
+	if code := get("https", &tls.Config{RootCAs: roots, Certificates: []tls.Certificate{pair}}); code != http.StatusNotFound {
+		t.Fatalf("with a client certificate: %d", code)
+	}
+}
@@ -0,0 +1,87 @@
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	desc "github.com/Na322Pr/kv-storage-service/pkg/api"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// sseStream adapts a server-sent events response to the gRPC server stream
+// expected by Implementation.Watch.
+type sseStream struct {
+	ctx     context.Context
+	w       http.ResponseWriter
+	flusher http.Flusher
+}
+
+func newSSEStream(w http.ResponseWriter, r *http.Request) (*sseStream, error) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		return nil, status.Error(codes.Unimplemented, "streaming is not supported")
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	return &sseStream{
+		ctx:     r.Context(),
+		w:       w,
+		flusher: flusher,
+	}, nil
+}
+
+func (s *sseStream) Send(resp *desc.WatchResponse) error {
+	return s.event(resp.Operation, keyValue{Key: resp.Key, Value: resp.Value})
+}
+
+func (s *sseStream) sendError(err error) {
+	st := status.Convert(err)
+	s.event("error", errorResponse{Code: st.Code().String(), Message: st.Message()})
+}
+
+func (s *sseStream) event(name string, v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	if _, err := fmt.Fprintf(s.w, "event: %s\ndata: %s\n\n", name, data); err != nil {
+		return err
+	}
+	s.flusher.Flush()
+	return nil
+}
+
+func (s *sseStream) Context() context.Context {
+	return s.ctx
+}
+
+func (s *sseStream) SetHeader(metadata.MD) error {
+	return nil
+}
+
+func (s *sseStream) SendHeader(metadata.MD) error {
+	return nil
+}
+
+func (s *sseStream) SetTrailer(metadata.MD) {}
+
+func (s *sseStream) SendMsg(m any) error {
+	resp, ok := m.(*desc.WatchResponse)
+	if !ok {
+		return status.Errorf(codes.Internal, "unexpected message %T", m)
+	}
+	return s.Send(resp)
+}
+
+func (s *sseStream) RecvMsg(any) error {
+	return status.Error(codes.Unimplemented, "server-sent events are one way")
+}
@@ -0,0 +1,33 @@
+package kv_storage_service
+
+import (
+	"github.com/Na322Pr/kv-storage-service/internal/backup"
+	"github.com/Na322Pr/kv-storage-service/internal/logger"
+	desc "github.com/Na322Pr/kv-storage-service/pkg/api"
+	"go.uber.org/zap"
+)
+
+// Backup streams a consistent snapshot in the order of the backup file
+// format, see package backup. Writes go on while it runs, the snapshot
+// holds the data as of the revision of the header.
+func (s *Implementation) Backup(req *desc.BackupRequest, stream desc.KeyValueStorage_BackupServer) error {
+	ctx := stream.Context()
+
+	dump, err := s.storageService.Dump()
+	if err != nil {
+		return storageStatus(err)
+	}
+	defer dump.Release()
+
+	trailer, err := backup.Stream(dump, s.storageService.NodeID(), stream.Send)
+	if err != nil {
+		return storageStatus(err)
+	}
+
+	logger.FromContext(ctx, s.logger).Info("Backup streamed",
+		zap.Int64("revision", dump.Revision),
+		zap.Int64("namespaces", trailer.Namespaces),
+		zap.Int64("entries", trailer.Entries),
+	)
+	return nil
+}
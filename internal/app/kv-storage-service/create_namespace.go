@@ -0,0 +1,41 @@
+package kv_storage_service
+
+import (
+	"context"
+
+	"github.com/Na322Pr/kv-storage-service/internal/storage"
+	desc "github.com/Na322Pr/kv-storage-service/pkg/api"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func (s *Implementation) CreateNamespace(ctx context.Context, req *desc.CreateNamespaceRequest) (*desc.CreateNamespaceResponse, error) {
+	if req.Name == "" {
+		return nil, status.Error(codes.InvalidArgument, "name is required")
+	}
+	if !s.storageService.IsLeader() {
+		return nil, status.Error(codes.FailedPrecondition, "namespaces are created on the leader")
+	}
+
+	quota := storage.Quota{
+		MaxKeys:           req.GetQuota().GetMaxKeys(),
+		MaxBytes:          req.GetQuota().GetMaxBytes(),
+		RequestsPerSecond: req.GetQuota().GetRequestsPerSecond(),
+		Compression:       req.GetQuota().GetCompression(),
+	}
+	if quota.MaxKeys < 0 || quota.MaxBytes < 0 || quota.RequestsPerSecond < 0 {
+		return nil, status.Error(codes.InvalidArgument, "quota must not be negative")
+	}
+	if quota.Compression != "" {
+		if _, err := storage.ParseCodec(quota.Compression); err != nil {
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		}
+	}
+
+	revision, err := s.storageService.CreateNamespace(ctx, req.Name, quota)
+	if err != nil {
+		return nil, storageStatus(err)
+	}
+
+	return &desc.CreateNamespaceResponse{Revision: revision}, nil
+}
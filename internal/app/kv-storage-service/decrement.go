@@ -0,0 +1,10 @@
+package kv_storage_service
+
+import (
+	"context"
+	desc "github.com/Na322Pr/kv-storage-service/pkg/api"
+)
+
+func (s *Implementation) Decrement(ctx context.Context, req *desc.CounterRequest) (*desc.CounterResponse, error) {
+	return s.applyCounter(ctx, req, -1)
+}
@@ -0,0 +1,22 @@
+package kv_storage_service
+
+import (
+	"context"
+
+	desc "github.com/Na322Pr/kv-storage-service/pkg/api"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func (s *Implementation) DropNamespace(ctx context.Context, req *desc.DropNamespaceRequest) (*desc.DropNamespaceResponse, error) {
+	if !s.storageService.IsLeader() {
+		return nil, status.Error(codes.FailedPrecondition, "namespaces are dropped on the leader")
+	}
+
+	deleted, revision, err := s.storageService.DropNamespace(ctx, req.Name)
+	if err != nil {
+		return nil, storageStatus(err)
+	}
+
+	return &desc.DropNamespaceResponse{DeletedKeys: int64(deleted), Revision: revision}, nil
+}
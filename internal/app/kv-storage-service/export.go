@@ -0,0 +1,96 @@
+package kv_storage_service
+
+import (
+	"slices"
+
+	"github.com/Na322Pr/kv-storage-service/internal/logger"
+	"github.com/Na322Pr/kv-storage-service/internal/service"
+	"github.com/Na322Pr/kv-storage-service/internal/storage"
+	desc "github.com/Na322Pr/kv-storage-service/pkg/api"
+	"go.uber.org/zap"
+)
+
+// exportBatchKeys and exportBatchBytes bound the records of one ExportResponse.
+const (
+	exportBatchKeys  = 500
+	exportBatchBytes = 1 << 20
+)
+
+// Export streams the keys of a consistent snapshot in batches, namespace by
+// namespace in key order. Large values are skipped and counted, Backup
+// carries them. Lock keys are left out, they live with their lease.
+func (s *Implementation) Export(req *desc.ExportRequest, stream desc.KeyValueStorage_ExportServer) error {
+	ctx := stream.Context()
+
+	dump, err := s.storageService.Dump()
+	if err != nil {
+		return storageStatus(err)
+	}
+	defer dump.Release()
+
+	namespaces, err := dump.Namespaces()
+	if err != nil {
+		return storageStatus(err)
+	}
+
+	var (
+		resp     = &desc.ExportResponse{}
+		size     int
+		exported int64
+	)
+	flush := func() error {
+		if len(resp.Records) == 0 {
+			return nil
+		}
+		if err := stream.Send(resp); err != nil {
+			return err
+		}
+		resp, size = &desc.ExportResponse{}, 0
+		return nil
+	}
+
+	var skipped int64
+	for _, ns := range namespaces {
+		if len(req.Namespaces) > 0 && !slices.Contains(req.Namespaces, ns.Name) {
+			continue
+		}
+		err := dump.Items(ns.Name, req.Prefix, func(key string, item storage.Item) error {
+			if service.IsLockKey(key) {
+				return nil
+			}
+			if item.Large() {
+				skipped++
+				return nil
+			}
+			exported++
+			resp.Records = append(resp.Records, &desc.KeyValueRecord{
+				Namespace:   ns.Name,
+				Key:         key,
+				Value:       []byte(item.Value),
+				ContentType: item.ContentType,
+				Expiration:  item.Expiration,
+				Revision:    item.Revision,
+			})
+			size += len(key) + len(item.Value)
+			if len(resp.Records) < exportBatchKeys && size < exportBatchBytes {
+				return nil
+			}
+			return flush()
+		})
+		if err != nil {
+			return storageStatus(err)
+		}
+	}
+
+	resp.SkippedLarge = skipped
+	if err := stream.Send(resp); err != nil {
+		return err
+	}
+
+	logger.FromContext(ctx, s.logger).Info("Keys exported",
+		zap.Int64("revision", dump.Revision),
+		zap.Int64("keys", exported),
+		zap.Int64("skipped_large", skipped),
+	)
+	return nil
+}
@@ -2,15 +2,44 @@ package kv_storage_service
 
 import (
 	"context"
+	"github.com/Na322Pr/kv-storage-service/internal/storage"
 	desc "github.com/Na322Pr/kv-storage-service/pkg/api"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 func (s *Implementation) Get(ctx context.Context, req *desc.GetRequest) (*desc.GetResponse, error) {
+	if req.Key == "" {
+		return nil, status.Error(codes.InvalidArgument, "key is required")
+	}
+
+	if err := s.admit(req.Namespace); err != nil {
+		return nil, err
+	}
 
-	value, ok := s.storageService.Get(ctx, req.Key)
+	var (
+		item storage.Item
+		ok   bool
+	)
+	if req.Revision != nil {
+		if *req.Revision <= 0 {
+			return nil, status.Error(codes.InvalidArgument, "revision must be positive")
+		}
+		var err error
+		if item, ok, err = s.storageService.GetAt(ctx, req.Namespace, req.Key, *req.Revision); err != nil {
+			return nil, revisionError(err)
+		}
+	} else {
+		item, ok = s.storageService.GetItem(ctx, req.Namespace, req.Key)
+	}
+	if ok {
+		if err := textValue(req.Key, item.Value, item.Large()); err != nil {
+			return nil, err
+		}
+	}
 
 	resp := &desc.GetResponse{
-		Value: value,
+		Value: item.Value,
 		Found: ok,
 	}
 
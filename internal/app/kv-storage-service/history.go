@@ -0,0 +1,68 @@
+package kv_storage_service
+
+import (
+	"context"
+	"errors"
+
+	"github.com/Na322Pr/kv-storage-service/internal/storage"
+	desc "github.com/Na322Pr/kv-storage-service/pkg/api"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	defaultHistoryLimit = 100
+	maxHistoryLimit     = 1000
+)
+
+func (s *Implementation) History(ctx context.Context, req *desc.HistoryRequest) (*desc.HistoryResponse, error) {
+	if req.Key == "" {
+		return nil, status.Error(codes.InvalidArgument, "key is required")
+	}
+
+	limit := int(req.Limit)
+	switch {
+	case limit < 0 || limit > maxHistoryLimit:
+		return nil, status.Errorf(codes.InvalidArgument, "limit must be between 0 and %d", maxHistoryLimit)
+	case limit == 0:
+		limit = defaultHistoryLimit
+	}
+
+	if err := s.admit(req.Namespace); err != nil {
+		return nil, err
+	}
+
+	versions, more, err := s.storageService.History(ctx, req.Namespace, req.Key, req.StartRevision, limit)
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	resp := &desc.HistoryResponse{
+		Versions:        make([]*desc.KeyVersion, 0, len(versions)),
+		CompactRevision: s.storageService.CompactedRevision(),
+	}
+	for _, v := range versions {
+		resp.Versions = append(resp.Versions, &desc.KeyVersion{
+			Revision:   v.Revision,
+			Value:      v.Value,
+			Deleted:    v.Deleted,
+			Expiration: v.Expiration,
+			Time:       v.Time,
+		})
+	}
+	if more {
+		resp.NextStartRevision = versions[len(versions)-1].Revision + 1
+	}
+
+	return resp, nil
+}
+
+// revisionError maps the errors of reads at a revision to gRPC statuses.
+func revisionError(err error) error {
+	switch {
+	case errors.Is(err, storage.ErrCompacted), errors.Is(err, storage.ErrFutureRevision):
+		return status.Error(codes.OutOfRange, err.Error())
+	default:
+		return status.Error(codes.Internal, err.Error())
+	}
+}
@@ -0,0 +1,119 @@
+package kv_storage_service
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/Na322Pr/kv-storage-service/internal/logger"
+	"github.com/Na322Pr/kv-storage-service/internal/storage"
+	desc "github.com/Na322Pr/kv-storage-service/pkg/api"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// importBatchKeys and importBatchBytes bound the keys imported with one write.
+const (
+	importBatchKeys  = 500
+	importBatchBytes = 1 << 20
+)
+
+// Import stores the streamed keys in batches, each written and replicated
+// at once. Keys keep their content type and their deadline, keys already
+// expired are skipped. The batches written before an error stay. A dry run
+// checks the records and the namespaces without writing anything.
+func (s *Implementation) Import(stream desc.KeyValueStorage_ImportServer) error {
+	ctx := stream.Context()
+
+	if !s.storageService.IsLeader() {
+		return status.Error(codes.FailedPrecondition, "keys are imported on the leader")
+	}
+
+	var (
+		options  *desc.ImportOptions
+		resp     = &desc.ImportResponse{}
+		admitted = map[string]bool{}
+		batch    []storage.BatchItem
+		batchNS  string
+		size     int
+	)
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		if !options.GetDryRun() {
+			revision, err := s.storageService.SetBatch(ctx, batchNS, batch)
+			if err != nil {
+				return storageStatus(err)
+			}
+			resp.Revision = revision
+		}
+		resp.Imported += int64(len(batch))
+		batch, size = nil, 0
+		return nil
+	}
+
+	for {
+		req, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		if options == nil {
+			options = req.GetOptions()
+			if options == nil {
+				options = &desc.ImportOptions{}
+			}
+		}
+
+		now := time.Now().UnixNano()
+		for _, record := range req.Records {
+			if record.Key == "" {
+				return status.Error(codes.InvalidArgument, "key is required")
+			}
+			if !strings.HasPrefix(record.Key, options.Prefix) {
+				resp.Skipped++
+				continue
+			}
+			if record.Expiration > 0 && record.Expiration <= now {
+				resp.Expired++
+				continue
+			}
+			if !admitted[record.Namespace] {
+				if err := s.admit(record.Namespace); err != nil {
+					return err
+				}
+				admitted[record.Namespace] = true
+			}
+
+			if record.Namespace != batchNS || len(batch) >= importBatchKeys || size >= importBatchBytes {
+				if err := flush(); err != nil {
+					return err
+				}
+				batchNS = record.Namespace
+			}
+			batch = append(batch, storage.BatchItem{Key: record.Key, Item: storage.Item{
+				Value:       string(record.Value),
+				ContentType: record.ContentType,
+				Expiration:  record.Expiration,
+			}})
+			size += len(record.Key) + len(record.Value)
+		}
+	}
+	if err := flush(); err != nil {
+		return err
+	}
+
+	logger.FromContext(ctx, s.logger).Info("Keys imported",
+		zap.Bool("dry_run", options.GetDryRun()),
+		zap.Int64("imported", resp.Imported),
+		zap.Int64("skipped", resp.Skipped),
+		zap.Int64("expired", resp.Expired),
+		zap.Int64("revision", resp.Revision),
+	)
+	return stream.SendAndClose(resp)
+}
@@ -0,0 +1,200 @@
+package kv_storage_service_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	kv_storage_service "github.com/Na322Pr/kv-storage-service/internal/app/kv-storage-service"
+	"github.com/Na322Pr/kv-storage-service/internal/config"
+	"github.com/Na322Pr/kv-storage-service/internal/model"
+	"github.com/Na322Pr/kv-storage-service/internal/service"
+	"github.com/Na322Pr/kv-storage-service/internal/storage"
+	desc "github.com/Na322Pr/kv-storage-service/pkg/api"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// start serves the API of a leader node in memory.
+func start(t *testing.T) (*storage.Store, desc.KeyValueStorageClient) {
+	t.Helper()
+	store, err := storage.NewStore(storage.NewMemoryEngine(), config.Storage{EvictionPolicy: "noeviction"})
+	if err != nil {
+		t.Fatalf("new store: %v", err)
+	}
+	node := model.NewNode("1", "1", "127.0.0.1:2110")
+	node.SetLeader(true)
+	ss := service.NewStorageService(store, node, service.NewConnectionManagerService())
+	leases, err := service.NewLeaseService(ss, zap.NewNop())
+	if err != nil {
+		t.Fatalf("new lease service: %v", err)
+	}
+	t.Cleanup(leases.Suspend)
+
+	srv := grpc.NewServer()
+	desc.RegisterKeyValueStorageServer(srv, kv_storage_service.NewImplementation(nil, ss, nil, leases, service.NewLockService(ss), zap.NewNop()))
+	lis := bufconn.Listen(1 << 20)
+	go srv.Serve(lis)
+	t.Cleanup(srv.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufconn",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatalf("new client: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return store, desc.NewKeyValueStorageClient(conn)
+}
+
+func set(t *testing.T, store *storage.Store, ns, key string, item storage.Item) {
+	t.Helper()
+	if _, err := store.SetUntil(ns, key, item); err != nil {
+		t.Fatalf("set %s: %v", key, err)
+	}
+}
+
+func export(t *testing.T, client desc.KeyValueStorageClient, req *desc.ExportRequest) []*desc.KeyValueRecord {
+	t.Helper()
+	stream, err := client.Export(context.Background(), req)
+	if err != nil {
+		t.Fatalf("export: %v", err)
+	}
+	var records []*desc.KeyValueRecord
+	for {
+		resp, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			return records
+		}
+		if err != nil {
+			t.Fatalf("export: %v", err)
+		}
+		records = append(records, resp.Records...)
+	}
+}
+
+// importRecords sends the records in several requests, the options with the
+// first one.
+func importRecords(t *testing.T, client desc.KeyValueStorageClient, options *desc.ImportOptions, records []*desc.KeyValueRecord) (*desc.ImportResponse, error) {
+	t.Helper()
+	stream, err := client.Import(context.Background())
+	if err != nil {
+		t.Fatalf("import: %v", err)
+	}
+	req := &desc.ImportRequest{Options: options}
+	for len(records) > 0 {
+		n := min(len(records), 100)
+		req.Records = records[:n]
+		if err := stream.Send(req); err != nil {
+			break
+		}
+		req, records = &desc.ImportRequest{}, records[n:]
+	}
+	return stream.CloseAndRecv()
+}
+
+func TestExportImport(t *testing.T) {
+	src, srcClient := start(t)
+	if _, err := src.CreateNamespace("team", storage.Quota{}); err != nil {
+		t.Fatalf("create namespace: %v", err)
+	}
+	for i := 0; i < 1200; i++ {
+		set(t, src, "", fmt.Sprintf("user/%04d", i), storage.Item{Value: "v"})
+	}
+	deadline := time.Now().Add(time.Hour).UnixNano()
+	set(t, src, "", "other", storage.Item{Value: "\xff\x00", ContentType: "application/octet-stream", Expiration: deadline})
+	set(t, src, "team", "user/t", storage.Item{Value: "t"})
+
+	if records := export(t, srcClient, &desc.ExportRequest{Namespaces: []string{"team"}}); len(records) != 1 || records[0].Key != "user/t" {
+		t.Fatalf("export of a namespace: %v", records)
+	}
+	if records := export(t, srcClient, &desc.ExportRequest{Prefix: "user/"}); len(records) != 1201 {
+		t.Fatalf("export of a prefix: %d records", len(records))
+	}
+	records := export(t, srcClient, &desc.ExportRequest{})
+	if len(records) != 1202 {
+		t.Fatalf("export: %d records", len(records))
+	}
+
+	dst, dstClient := start(t)
+	// The namespaces are not created by the import.
+	if _, err := importRecords(t, dstClient, nil, records); status.Code(err) != codes.NotFound {
+		t.Fatalf("import into a missing namespace: %v", err)
+	}
+	if _, err := dst.CreateNamespace("team", storage.Quota{}); err != nil {
+		t.Fatalf("create namespace: %v", err)
+	}
+	resp, err := importRecords(t, dstClient, nil, records)
+	if err != nil {
+		t.Fatalf("import: %v", err)
+	}
+	if resp.Imported != 1202 || resp.Skipped != 0 || resp.Expired != 0 || resp.Revision != dst.GetDataVersion() {
+		t.Fatalf("import: %+v", resp)
+	}
+	item, ok := dst.Get("", "other")
+	if !ok || item.Value != "\xff\x00" || item.ContentType != "application/octet-stream" || item.Expiration != deadline {
+		t.Fatalf("imported key: %+v %v", item, ok)
+	}
+	if item, ok := dst.Get("team", "user/t"); !ok || item.Value != "t" {
+		t.Fatalf("imported key in a namespace: %+v %v", item, ok)
+	}
+}
+
+func TestImportDryRun(t *testing.T) {
+	store, client := start(t)
+	before := store.GetDataVersion()
+
+	records := []*desc.KeyValueRecord{{Key: "a", Value: []byte("1")}, {Key: "b", Value: []byte("2")}}
+	resp, err := importRecords(t, client, &desc.ImportOptions{DryRun: true}, records)
+	if err != nil {
+		t.Fatalf("dry run: %v", err)
+	}
+	if resp.Imported != 2 || resp.Revision != 0 {
+		t.Fatalf("dry run: %+v", resp)
+	}
+	if _, ok := store.Get("", "a"); ok || store.GetDataVersion() != before {
+		t.Fatal("dry run wrote keys")
+	}
+
+	// The records and namespaces are still checked.
+	if _, err := importRecords(t, client, &desc.ImportOptions{DryRun: true}, []*desc.KeyValueRecord{{Key: ""}}); status.Code(err) != codes.InvalidArgument {
+		t.Fatalf("dry run of an empty key: %v", err)
+	}
+	missing := []*desc.KeyValueRecord{{Namespace: "missing", Key: "a"}}
+	if _, err := importRecords(t, client, &desc.ImportOptions{DryRun: true}, missing); status.Code(err) != codes.NotFound {
+		t.Fatalf("dry run into a missing namespace: %v", err)
+	}
+}
+
+func TestImportSkips(t *testing.T) {
+	store, client := start(t)
+	now := time.Now()
+
+	resp, err := importRecords(t, client, &desc.ImportOptions{Prefix: "app/"}, []*desc.KeyValueRecord{
+		{Key: "app/a", Value: []byte("1")},
+		{Key: "app/b", Value: []byte("2"), Expiration: now.Add(time.Hour).UnixNano()},
+		{Key: "app/gone", Value: []byte("3"), Expiration: now.Add(-time.Second).UnixNano()},
+		{Key: "other", Value: []byte("4")},
+		// The prefix is checked before the deadline.
+		{Key: "other/gone", Value: []byte("5"), Expiration: now.Add(-time.Second).UnixNano()},
+	})
+	if err != nil {
+		t.Fatalf("import: %v", err)
+	}
+	if resp.Imported != 2 || resp.Skipped != 2 || resp.Expired != 1 {
+		t.Fatalf("import: %+v", resp)
+	}
+	for key, want := range map[string]bool{"app/a": true, "app/b": true, "app/gone": false, "other": false, "other/gone": false} {
+		if _, ok := store.Get("", key); ok != want {
+			t.Errorf("%s stored: %v, want %v", key, ok, want)
+		}
+	}
+}
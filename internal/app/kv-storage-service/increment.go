@@ -0,0 +1,66 @@
+package kv_storage_service
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/Na322Pr/kv-storage-service/internal/service"
+	"github.com/Na322Pr/kv-storage-service/internal/storage"
+	desc "github.com/Na322Pr/kv-storage-service/pkg/api"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func (s *Implementation) Increment(ctx context.Context, req *desc.CounterRequest) (*desc.CounterResponse, error) {
+	return s.applyCounter(ctx, req, 1)
+}
+
+// applyCounter validates the request and applies its delta with the given sign.
+func (s *Implementation) applyCounter(ctx context.Context, req *desc.CounterRequest, sign int64) (*desc.CounterResponse, error) {
+	if req.Key == "" {
+		return nil, status.Error(codes.InvalidArgument, "key is required")
+	}
+	if req.Delta != nil && *req.Delta < 0 {
+		return nil, status.Error(codes.InvalidArgument, "delta must not be negative")
+	}
+	if req.TtlMs != nil && *req.TtlMs <= 0 {
+		return nil, status.Error(codes.InvalidArgument, "ttl_ms must be positive")
+	}
+	if req.Min != nil && req.Max != nil && *req.Min > *req.Max {
+		return nil, status.Error(codes.InvalidArgument, "min must not exceed max")
+	}
+
+	if err := s.admit(req.Namespace); err != nil {
+		return nil, err
+	}
+
+	msg := service.IncrementMessage{
+		Namespace: req.Namespace,
+		Key:       req.Key,
+		Delta:     sign,
+		Min:       req.Min,
+		Max:       req.Max,
+	}
+	if req.Delta != nil {
+		msg.Delta = sign * *req.Delta
+	}
+	if req.TtlMs != nil {
+		msg.Expiration = time.Now().Add(time.Duration(*req.TtlMs) * time.Millisecond).UnixNano()
+	}
+
+	value, revision, err := s.storageService.Increment(ctx, msg)
+	switch {
+	case errors.Is(err, storage.ErrNotInteger):
+		return nil, status.Error(codes.FailedPrecondition, err.Error())
+	case errors.Is(err, storage.ErrOverflow), errors.Is(err, storage.ErrOutOfBounds):
+		return nil, status.Error(codes.OutOfRange, err.Error())
+	case errors.Is(err, storage.ErrNamespaceNotFound), errors.Is(err, storage.ErrQuotaExceeded),
+		errors.Is(err, storage.ErrOutOfMemory), errors.Is(err, service.ErrRestoring), errors.Is(err, service.ErrReservedKey):
+		return nil, storageStatus(err)
+	case err != nil:
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	return &desc.CounterResponse{Value: value, Revision: revision}, nil
+}
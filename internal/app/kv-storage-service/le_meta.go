@@ -8,7 +8,8 @@ import (
 func (s *Implementation) LeMeta(ctx context.Context, req *desc.LeMetaRequest) (*desc.LeMetaResponse, error) {
 	meta := s.leService.Meta()
 	return &desc.LeMetaResponse{
-		Node:        int64(meta.NodeID),
+		NomadId:     meta.NomadID,
 		DataVersion: meta.DataVersion,
+		LeaderEpoch: meta.LeaderEpoch,
 	}, nil
 }
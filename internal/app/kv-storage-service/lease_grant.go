@@ -0,0 +1,41 @@
+package kv_storage_service
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/Na322Pr/kv-storage-service/internal/auth"
+	"github.com/Na322Pr/kv-storage-service/internal/service"
+	desc "github.com/Na322Pr/kv-storage-service/pkg/api"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func (s *Implementation) LeaseGrant(ctx context.Context, req *desc.LeaseGrantRequest) (*desc.LeaseGrantResponse, error) {
+	if !s.storageService.IsLeader() {
+		return nil, status.Error(codes.FailedPrecondition, "leases are granted by the leader")
+	}
+	if req.TtlMs <= 0 {
+		return nil, status.Error(codes.InvalidArgument, "ttl_ms must be positive")
+	}
+
+	id, err := s.leaseService.Grant(ctx, time.Duration(req.TtlMs)*time.Millisecond, leaseOwner(ctx))
+	if errors.Is(err, service.ErrRestoring) {
+		return nil, storageStatus(err)
+	}
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	return &desc.LeaseGrantResponse{Id: id, TtlMs: req.TtlMs}, nil
+}
+
+// leaseOwner returns the subject of the authenticated caller, empty when
+// authentication is off.
+func leaseOwner(ctx context.Context) string {
+	if identity, ok := auth.FromContext(ctx); ok {
+		return identity.Subject
+	}
+	return ""
+}
@@ -0,0 +1,40 @@
+package kv_storage_service
+
+import (
+	"errors"
+
+	"github.com/Na322Pr/kv-storage-service/internal/service"
+	desc "github.com/Na322Pr/kv-storage-service/pkg/api"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func (s *Implementation) LeaseKeepAlive(stream desc.KeyValueStorage_LeaseKeepAliveServer) error {
+	if !s.storageService.IsLeader() {
+		return status.Error(codes.FailedPrecondition, "leases are kept alive on the leader")
+	}
+
+	for {
+		req, err := stream.Recv()
+		if err != nil {
+			return err
+		}
+
+		resp := &desc.LeaseKeepAliveResponse{Id: req.Id}
+
+		ttl, err := s.leaseService.KeepAlive(req.Id, leaseOwner(stream.Context()))
+		switch {
+		case errors.Is(err, service.ErrLeaseNotFound):
+		case errors.Is(err, service.ErrLeaseNotOwned):
+			return status.Errorf(codes.PermissionDenied, "lease %d is granted to another caller", req.Id)
+		case err != nil:
+			return status.Error(codes.Internal, err.Error())
+		default:
+			resp.TtlMs = ttl.Milliseconds()
+		}
+
+		if err := stream.Send(resp); err != nil {
+			return err
+		}
+	}
+}
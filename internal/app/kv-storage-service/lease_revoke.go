@@ -0,0 +1,30 @@
+package kv_storage_service
+
+import (
+	"context"
+	"errors"
+
+	"github.com/Na322Pr/kv-storage-service/internal/service"
+	desc "github.com/Na322Pr/kv-storage-service/pkg/api"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func (s *Implementation) LeaseRevoke(ctx context.Context, req *desc.LeaseRevokeRequest) (*desc.LeaseRevokeResponse, error) {
+	if !s.storageService.IsLeader() {
+		return nil, status.Error(codes.FailedPrecondition, "leases are revoked on the leader")
+	}
+
+	revision, err := s.leaseService.Revoke(ctx, req.Id, leaseOwner(ctx))
+	if errors.Is(err, service.ErrLeaseNotFound) {
+		return nil, status.Errorf(codes.NotFound, "lease %d not found", req.Id)
+	}
+	if errors.Is(err, service.ErrLeaseNotOwned) {
+		return nil, status.Errorf(codes.PermissionDenied, "lease %d is granted to another caller", req.Id)
+	}
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	return &desc.LeaseRevokeResponse{Revision: revision}, nil
+}
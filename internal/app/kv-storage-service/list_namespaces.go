@@ -0,0 +1,30 @@
+package kv_storage_service
+
+import (
+	"context"
+
+	desc "github.com/Na322Pr/kv-storage-service/pkg/api"
+)
+
+func (s *Implementation) ListNamespaces(ctx context.Context, req *desc.ListNamespacesRequest) (*desc.ListNamespacesResponse, error) {
+	namespaces := s.storageService.Namespaces()
+
+	resp := &desc.ListNamespacesResponse{
+		Namespaces: make([]*desc.Namespace, 0, len(namespaces)),
+	}
+	for _, ns := range namespaces {
+		resp.Namespaces = append(resp.Namespaces, &desc.Namespace{
+			Name: ns.Name,
+			Quota: &desc.NamespaceQuota{
+				MaxKeys:           ns.Quota.MaxKeys,
+				MaxBytes:          ns.Quota.MaxBytes,
+				RequestsPerSecond: ns.Quota.RequestsPerSecond,
+				Compression:       ns.Quota.Compression,
+			},
+			Keys:  ns.Keys,
+			Bytes: ns.Bytes,
+		})
+	}
+
+	return resp, nil
+}
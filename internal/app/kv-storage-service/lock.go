@@ -0,0 +1,40 @@
+package kv_storage_service
+
+import (
+	"context"
+	"errors"
+
+	"github.com/Na322Pr/kv-storage-service/internal/service"
+	"github.com/Na322Pr/kv-storage-service/internal/storage"
+	desc "github.com/Na322Pr/kv-storage-service/pkg/api"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func (s *Implementation) Lock(ctx context.Context, req *desc.LockRequest) (*desc.LockResponse, error) {
+	if req.Name == "" {
+		return nil, status.Error(codes.InvalidArgument, "name is required")
+	}
+	if !s.storageService.IsLeader() {
+		return nil, status.Error(codes.FailedPrecondition, "locks are held on the leader")
+	}
+
+	if err := s.admit(req.Namespace); err != nil {
+		return nil, err
+	}
+
+	key, token, err := s.lockService.Lock(ctx, req.Namespace, req.Name, req.Lease)
+	switch {
+	case errors.Is(err, service.ErrLeaseNotFound):
+		return nil, status.Errorf(codes.NotFound, "lease %d not found", req.Lease)
+	case errors.Is(err, storage.ErrNamespaceNotFound), errors.Is(err, storage.ErrQuotaExceeded),
+		errors.Is(err, storage.ErrOutOfMemory):
+		return nil, storageStatus(err)
+	case ctx.Err() != nil:
+		return nil, status.FromContextError(ctx.Err()).Err()
+	case err != nil:
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	return &desc.LockResponse{Key: key, FencingToken: token}, nil
+}
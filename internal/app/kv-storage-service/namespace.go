@@ -0,0 +1,41 @@
+package kv_storage_service
+
+import (
+	"errors"
+
+	"github.com/Na322Pr/kv-storage-service/internal/backup"
+	"github.com/Na322Pr/kv-storage-service/internal/service"
+	"github.com/Na322Pr/kv-storage-service/internal/storage"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// admit rejects requests to unknown namespaces and over the namespace request rate.
+func (s *Implementation) admit(namespace string) error {
+	return storageStatus(s.storageService.Admit(namespace))
+}
+
+// storageStatus maps namespace, memory and value errors to gRPC statuses and
+// returns other errors as is.
+func storageStatus(err error) error {
+	switch {
+	case errors.Is(err, storage.ErrNamespaceNotFound), errors.Is(err, service.ErrLeaseNotFound):
+		return status.Error(codes.NotFound, err.Error())
+	case errors.Is(err, storage.ErrNamespaceExists):
+		return status.Error(codes.AlreadyExists, err.Error())
+	case errors.Is(err, storage.ErrDefaultNamespace), errors.Is(err, storage.ErrInvalidNamespace),
+		errors.Is(err, storage.ErrInvalidCodec), errors.Is(err, service.ErrReservedKey):
+		return status.Error(codes.InvalidArgument, err.Error())
+	case errors.Is(err, storage.ErrQuotaExceeded), errors.Is(err, service.ErrRateLimited),
+		errors.Is(err, storage.ErrOutOfMemory), errors.Is(err, storage.ErrValueTooLarge):
+		return status.Error(codes.ResourceExhausted, err.Error())
+	case errors.Is(err, storage.ErrBlobIncomplete), errors.Is(err, backup.ErrCorrupt),
+		errors.Is(err, backup.ErrChecksum), errors.Is(err, backup.ErrIncomplete):
+		return status.Error(codes.DataLoss, err.Error())
+	case errors.Is(err, backup.ErrVersion):
+		return status.Error(codes.InvalidArgument, err.Error())
+	case errors.Is(err, service.ErrNotEmpty), errors.Is(err, service.ErrRestoring):
+		return status.Error(codes.FailedPrecondition, err.Error())
+	}
+	return err
+}
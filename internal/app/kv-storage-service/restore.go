@@ -0,0 +1,132 @@
+package kv_storage_service
+
+import (
+	"errors"
+	"io"
+
+	"github.com/Na322Pr/kv-storage-service/internal/backup"
+	"github.com/Na322Pr/kv-storage-service/internal/logger"
+	"github.com/Na322Pr/kv-storage-service/internal/service"
+	"github.com/Na322Pr/kv-storage-service/internal/storage"
+	desc "github.com/Na322Pr/kv-storage-service/pkg/api"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Restore loads a backup streamed in the order of the backup file format
+// into an empty leader, the replicas are reset and seeded as it goes. The
+// records are checked as they arrive: a broken or incomplete backup leaves
+// the leader and the replicas empty.
+func (s *Implementation) Restore(stream desc.KeyValueStorage_RestoreServer) error {
+	ctx := stream.Context()
+
+	if !s.storageService.IsLeader() {
+		return status.Error(codes.FailedPrecondition, "backups are restored on the leader")
+	}
+
+	restorer, err := s.storageService.BeginRestore(ctx)
+	if err != nil {
+		return storageStatus(err)
+	}
+
+	var (
+		checker  = backup.NewChecker()
+		upload   *service.LargeUpload
+		restored bool
+	)
+	defer func() {
+		if restored {
+			return
+		}
+		if upload != nil {
+			_ = upload.Abort(ctx)
+		}
+		if err := restorer.Abort(ctx); err != nil {
+			logger.FromContext(ctx, s.logger).Error("Failed to abort restore", zap.Error(err))
+		}
+	}()
+
+	for {
+		record, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		if err := checker.Check(record); err != nil {
+			return storageStatus(err)
+		}
+
+		switch r := record.Record.(type) {
+		case *desc.BackupRecord_Namespace:
+			quota := storage.Quota{
+				MaxKeys:           r.Namespace.GetQuota().GetMaxKeys(),
+				MaxBytes:          r.Namespace.GetQuota().GetMaxBytes(),
+				RequestsPerSecond: r.Namespace.GetQuota().GetRequestsPerSecond(),
+				Compression:       r.Namespace.GetQuota().GetCompression(),
+			}
+			// The default namespace always exists, it only needs a quota.
+			if r.Namespace.Name == storage.DefaultNamespace && quota == (storage.Quota{}) {
+				continue
+			}
+			if err := restorer.Namespace(ctx, r.Namespace.Name, quota); err != nil {
+				return storageStatus(err)
+			}
+		case *desc.BackupRecord_Entry:
+			msg := service.SetMessage{
+				Namespace:   r.Entry.Namespace,
+				Key:         r.Entry.Key,
+				ContentType: r.Entry.ContentType,
+				Expiration:  r.Entry.Expiration,
+				Revision:    r.Entry.Revision,
+			}
+			if !r.Entry.Large {
+				msg.Value = string(r.Entry.Value)
+				if err := restorer.Item(ctx, msg); err != nil {
+					return storageStatus(err)
+				}
+				continue
+			}
+			if upload, err = restorer.Large(msg); err != nil {
+				return storageStatus(err)
+			}
+		case *desc.BackupRecord_Chunk:
+			if err := upload.Write(ctx, r.Chunk); err != nil {
+				return storageStatus(err)
+			}
+		}
+
+		// The large value is stored once its last chunk is in.
+		if upload != nil && checker.Pending() == 0 {
+			if _, err := upload.Commit(ctx); err != nil {
+				return storageStatus(err)
+			}
+			upload = nil
+		}
+	}
+	if err := checker.Done(); err != nil {
+		return storageStatus(err)
+	}
+
+	header := checker.Header()
+	revision, err := restorer.Commit(ctx, header.Revision)
+	if err != nil {
+		return storageStatus(err)
+	}
+	restored = true
+
+	logger.FromContext(ctx, s.logger).Info("Backup restored",
+		zap.Int64("revision", revision),
+		zap.String("node", header.NodeId),
+		zap.Int64("namespaces", checker.Namespaces()),
+		zap.Int64("entries", checker.Entries()),
+	)
+
+	return stream.SendAndClose(&desc.RestoreResponse{
+		Revision:   revision,
+		Namespaces: checker.Namespaces(),
+		Entries:    checker.Entries(),
+	})
+}
@@ -0,0 +1,51 @@
+package kv_storage_service
+
+import (
+	"context"
+	desc "github.com/Na322Pr/kv-storage-service/pkg/api"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	defaultScanLimit = 100
+	maxScanLimit     = 1000
+)
+
+func (s *Implementation) Scan(ctx context.Context, req *desc.ScanRequest) (*desc.ScanResponse, error) {
+	limit := int(req.Limit)
+	switch {
+	case limit < 0 || limit > maxScanLimit:
+		return nil, status.Errorf(codes.InvalidArgument, "limit must be between 0 and %d", maxScanLimit)
+	case limit == 0:
+		limit = defaultScanLimit
+	}
+
+	if req.Revision != nil && *req.Revision <= 0 {
+		return nil, status.Error(codes.InvalidArgument, "revision must be positive")
+	}
+
+	if err := s.admit(req.Namespace); err != nil {
+		return nil, err
+	}
+
+	items, more, err := s.storageService.Scan(ctx, req.Namespace, req.Prefix, req.StartAfter, limit, req.GetRevision())
+	if err != nil {
+		return nil, revisionError(err)
+	}
+
+	resp := &desc.ScanResponse{
+		Items: make([]*desc.KeyValue, 0, len(items)),
+	}
+	for _, item := range items {
+		if err := textValue(item.Key, item.Value, item.Large); err != nil {
+			return nil, err
+		}
+		resp.Items = append(resp.Items, &desc.KeyValue{Key: item.Key, Value: item.Value})
+	}
+	if more {
+		resp.NextStartAfter = items[len(items)-1].Key
+	}
+
+	return resp, nil
+}
@@ -3,15 +3,20 @@ package kv_storage_service
 import (
 	"github.com/Na322Pr/kv-storage-service/internal/service"
 	desc "github.com/Na322Pr/kv-storage-service/pkg/api"
+	"go.opentelemetry.io/otel"
 	"go.uber.org/zap"
 )
 
+var tracer = otel.Tracer("github.com/Na322Pr/kv-storage-service/internal/app/kv-storage-service")
+
 type Implementation struct {
 	desc.UnimplementedKeyValueStorageServer
 
 	nodeService    *service.NodeService
 	storageService *service.StorageService
 	leService      *service.LeService
+	leaseService   *service.LeaseService
+	lockService    *service.LockService
 
 	logger *zap.Logger
 }
@@ -20,12 +25,16 @@ func NewImplementation(
 	nodeService *service.NodeService,
 	storeService *service.StorageService,
 	leService *service.LeService,
+	leaseService *service.LeaseService,
+	lockService *service.LockService,
 	logger *zap.Logger,
 ) *Implementation {
 	return &Implementation{
 		nodeService:    nodeService,
 		storageService: storeService,
 		leService:      leService,
+		leaseService:   leaseService,
+		lockService:    lockService,
 		logger:         logger,
 	}
 }
@@ -2,28 +2,58 @@ package kv_storage_service
 
 import (
 	"context"
-	"fmt"
+	"github.com/Na322Pr/kv-storage-service/internal/logger"
 	"github.com/Na322Pr/kv-storage-service/internal/service"
 	desc "github.com/Na322Pr/kv-storage-service/pkg/api"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 func (s *Implementation) Set(ctx context.Context, req *desc.SetRequest) (*desc.SetResponse, error) {
+	if req.Key == "" {
+		return nil, status.Error(codes.InvalidArgument, "key is required")
+	}
+	if err := s.admit(req.Namespace); err != nil {
+		return nil, err
+	}
+	if req.Lease != nil && !s.leaseService.Exists(*req.Lease) {
+		return nil, status.Errorf(codes.NotFound, "lease %d not found", *req.Lease)
+	}
+
 	operation := service.Operation("empty")
 	if req.Operation != nil {
 		operation = service.Operation(*req.Operation)
 	}
+	switch operation {
+	case service.OperationCreateNamespace, service.OperationDropNamespace,
+		service.OperationBlobChunk, service.OperationBlobAbort,
+		service.OperationRestore, service.OperationReset, service.OperationRestoreDone,
+		service.OperationBatch,
+		service.OperationLeaseGrant, service.OperationLeaseRevoke:
+		return nil, status.Errorf(codes.InvalidArgument, "operation %q is reserved for replication", operation)
+	}
 
 	msg := service.SetMessage{
-		Key:       req.Key,
-		Value:     req.Value,
-		Operation: operation,
+		Namespace:  req.Namespace,
+		Key:        req.Key,
+		Value:      req.Value,
+		Operation:  operation,
+		Expiration: req.GetExpiration(),
+		Lease:      req.GetLease(),
 	}
 
-	s.logger.Debug(fmt.Sprintf("Received request: key=%s, value=%s, operation=%s", msg.Key, msg.Value, msg.Operation))
+	logger.FromContext(ctx, s.logger).Debug("Received set request",
+		zap.String("namespace", msg.Namespace),
+		zap.String("key", msg.Key),
+		logger.Value(msg.Value),
+		zap.String("operation", string(msg.Operation)),
+	)
 
-	if err := s.storageService.Set(ctx, msg); err != nil {
-		return &desc.SetResponse{}, err
+	revision, err := s.storageService.Set(ctx, msg)
+	if err != nil {
+		return &desc.SetResponse{}, storageStatus(err)
 	}
 
-	return &desc.SetResponse{}, nil
+	return &desc.SetResponse{Revision: revision}, nil
 }
@@ -2,12 +2,22 @@ package kv_storage_service
 
 import (
 	"context"
-	"fmt"
+	"github.com/Na322Pr/kv-storage-service/internal/logger"
 	"github.com/Na322Pr/kv-storage-service/internal/service"
+	"github.com/Na322Pr/kv-storage-service/internal/storage"
 	desc "github.com/Na322Pr/kv-storage-service/pkg/api"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
 )
 
 func (s *Implementation) SetStream(stream desc.KeyValueStorage_SetStreamServer) error {
+	streamLogger := logger.FromContext(stream.Context(), s.logger)
+
+	release := s.storageService.Follow()
+	defer release()
+
 	for {
 		req, err := stream.Recv()
 		if err != nil {
@@ -20,17 +30,55 @@ func (s *Implementation) SetStream(stream desc.KeyValueStorage_SetStreamServer)
 		}
 
 		msg := service.SetMessage{
-			Key:       req.Key,
-			Value:     req.Value,
-			Operation: operation,
+			Namespace:   req.Namespace,
+			Key:         req.Key,
+			Value:       req.Value,
+			ContentType: req.ContentType,
+			Blob:        req.Blob,
+			Size:        req.Size,
+			Codec:       storage.Codec(req.Codec),
+			Operation:   operation,
+			Expiration:  req.GetExpiration(),
+			Revision:    req.Revision,
+			Lease:       req.GetLease(),
+			Replicated:  true,
+		}
+		if req.RawValue != nil {
+			msg.Value = string(req.RawValue)
 		}
-		s.logger.Debug(fmt.Sprintf("Received stream request: key=%s, value=%s, operation=%s", msg.Key, msg.Value, msg.Operation))
+		for _, entry := range req.Entries {
+			msg.Batch = append(msg.Batch, storage.BatchItem{Key: entry.Key, Item: storage.Item{
+				Value:       string(entry.Value),
+				ContentType: entry.ContentType,
+				Expiration:  entry.Expiration,
+				Codec:       storage.Codec(entry.Codec),
+			}})
+		}
+		streamLogger.Debug("Received stream request",
+			zap.String("namespace", msg.Namespace),
+			zap.String("key", msg.Key),
+			logger.Value(msg.Value),
+			zap.String("operation", string(msg.Operation)),
+		)
 
-		if err := s.storageService.Set(context.Background(), msg); err != nil {
+		// Continue the leader's trace carried inside the replicated request.
+		ctx := otel.GetTextMapPropagator().Extract(context.Background(), propagation.MapCarrier(req.TraceContext))
+		ctx, span := tracer.Start(ctx, "replica.apply", trace.WithSpanKind(trace.SpanKindConsumer))
+		revision, err := s.storageService.Set(ctx, msg)
+		span.End()
+		if err != nil {
+			// The replica diverged from the leader: the stream fails without
+			// an ack so the leader resyncs the replica from a snapshot.
+			streamLogger.Warn("Failed to apply replicated request",
+				zap.String("namespace", msg.Namespace),
+				zap.String("key", msg.Key),
+				zap.String("operation", string(msg.Operation)),
+				zap.Error(err),
+			)
 			return err
 		}
 
-		if err := stream.Send(&desc.SetResponse{}); err != nil {
+		if err := stream.Send(&desc.SetResponse{Revision: revision}); err != nil {
 			return err
 		}
 	}
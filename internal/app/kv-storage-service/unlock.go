@@ -0,0 +1,33 @@
+package kv_storage_service
+
+import (
+	"context"
+	"errors"
+
+	"github.com/Na322Pr/kv-storage-service/internal/service"
+	desc "github.com/Na322Pr/kv-storage-service/pkg/api"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func (s *Implementation) Unlock(ctx context.Context, req *desc.UnlockRequest) (*desc.UnlockResponse, error) {
+	if req.Name == "" {
+		return nil, status.Error(codes.InvalidArgument, "name is required")
+	}
+
+	if err := s.admit(req.Namespace); err != nil {
+		return nil, err
+	}
+
+	revision, err := s.lockService.Unlock(ctx, req.Namespace, req.Name, req.FencingToken)
+	switch {
+	case errors.Is(err, service.ErrNotFound):
+		return nil, status.Errorf(codes.NotFound, "lock %q is not held", req.Name)
+	case errors.Is(err, service.ErrConditionNotMet):
+		return nil, status.Error(codes.FailedPrecondition, "lock is held with a different fencing token")
+	case err != nil:
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	return &desc.UnlockResponse{Revision: revision}, nil
+}
@@ -2,10 +2,23 @@ package kv_storage_service
 
 import (
 	"context"
+	"errors"
+
+	"github.com/Na322Pr/kv-storage-service/internal/service"
 	desc "github.com/Na322Pr/kv-storage-service/pkg/api"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 func (s *Implementation) UpdateLeader(ctx context.Context, req *desc.UpdateLeaderRequest) (*desc.UpdateLeaderResponse, error) {
-	s.leService.SetLeader(req.NomadId)
+	err := s.leService.SetLeader(req.NomadId, req.Address, req.Epoch)
+	switch {
+	case errors.Is(err, service.ErrNoLeaderID):
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	case errors.Is(err, service.ErrStaleEpoch):
+		return nil, status.Error(codes.FailedPrecondition, err.Error())
+	case err != nil:
+		return nil, status.Error(codes.Internal, err.Error())
+	}
 	return &desc.UpdateLeaderResponse{}, nil
 }
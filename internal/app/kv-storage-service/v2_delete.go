@@ -0,0 +1,31 @@
+package kv_storage_service
+
+import (
+	"context"
+	"errors"
+
+	"github.com/Na322Pr/kv-storage-service/internal/service"
+	descv2 "github.com/Na322Pr/kv-storage-service/pkg/api/v2"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func (s *ImplementationV2) Delete(ctx context.Context, req *descv2.DeleteRequest) (*descv2.DeleteResponse, error) {
+	if req.Key == "" {
+		return nil, status.Error(codes.InvalidArgument, "key is required")
+	}
+
+	if err := s.admit(req.Namespace); err != nil {
+		return nil, err
+	}
+
+	deleted, revision, err := s.storageService.Delete(ctx, req.Namespace, req.Key)
+	if errors.Is(err, service.ErrRestoring) || errors.Is(err, service.ErrReservedKey) {
+		return nil, storageStatus(err)
+	}
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	return &descv2.DeleteResponse{Deleted: deleted, Revision: revision}, nil
+}
@@ -0,0 +1,55 @@
+package kv_storage_service
+
+import (
+	"context"
+
+	"github.com/Na322Pr/kv-storage-service/internal/storage"
+	descv2 "github.com/Na322Pr/kv-storage-service/pkg/api/v2"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func (s *ImplementationV2) Get(ctx context.Context, req *descv2.GetRequest) (*descv2.GetResponse, error) {
+	if req.Key == "" {
+		return nil, status.Error(codes.InvalidArgument, "key is required")
+	}
+
+	if err := s.admit(req.Namespace); err != nil {
+		return nil, err
+	}
+
+	item, ok, err := s.getItem(ctx, req.Namespace, req.Key, req.Revision)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return &descv2.GetResponse{}, nil
+	}
+	if item.Large() {
+		return nil, status.Errorf(codes.FailedPrecondition, "value of %q is large, read it with GetLarge", req.Key)
+	}
+
+	return &descv2.GetResponse{
+		Value:       []byte(item.Value),
+		Found:       true,
+		ContentType: item.ContentType,
+		Revision:    item.Revision,
+	}, nil
+}
+
+// getItem reads the current item of the key, or the one it held at the
+// revision when set.
+func (s *ImplementationV2) getItem(ctx context.Context, namespace, key string, revision *int64) (storage.Item, bool, error) {
+	if revision == nil {
+		item, ok := s.storageService.GetItem(ctx, namespace, key)
+		return item, ok, nil
+	}
+	if *revision <= 0 {
+		return storage.Item{}, false, status.Error(codes.InvalidArgument, "revision must be positive")
+	}
+	item, ok, err := s.storageService.GetAt(ctx, namespace, key, *revision)
+	if err != nil {
+		return storage.Item{}, false, revisionError(err)
+	}
+	return item, ok, nil
+}
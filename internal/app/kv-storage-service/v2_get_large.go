@@ -0,0 +1,54 @@
+package kv_storage_service
+
+import (
+	descv2 "github.com/Na322Pr/kv-storage-service/pkg/api/v2"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// GetLarge streams the value chunk by chunk after a header, inline values
+// included. Only one chunk is held in memory at a time.
+func (s *ImplementationV2) GetLarge(req *descv2.GetLargeRequest, stream descv2.KeyValueStorage_GetLargeServer) error {
+	if req.Key == "" {
+		return status.Error(codes.InvalidArgument, "key is required")
+	}
+
+	if err := s.admit(req.Namespace); err != nil {
+		return err
+	}
+
+	ctx := stream.Context()
+	item, ok, err := s.getItem(ctx, req.Namespace, req.Key, req.Revision)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return status.Errorf(codes.NotFound, "key %q not found", req.Key)
+	}
+
+	err = stream.Send(&descv2.GetLargeResponse{
+		Message: &descv2.GetLargeResponse_Header{Header: &descv2.GetLargeHeader{
+			ContentType: item.ContentType,
+			Size:        item.Len(),
+			Revision:    item.Revision,
+		}},
+	})
+	if err != nil {
+		return err
+	}
+
+	err = s.storageService.ReadLarge(ctx, req.Namespace, item, func(chunk []byte) error {
+		for len(chunk) > 0 {
+			n := min(len(chunk), largeChunkSize)
+			err := stream.Send(&descv2.GetLargeResponse{
+				Message: &descv2.GetLargeResponse_Chunk{Chunk: chunk[:n]},
+			})
+			if err != nil {
+				return err
+			}
+			chunk = chunk[n:]
+		}
+		return nil
+	})
+	return storageStatus(err)
+}
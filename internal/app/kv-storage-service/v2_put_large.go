@@ -0,0 +1,95 @@
+package kv_storage_service
+
+import (
+	"errors"
+	"io"
+
+	"github.com/Na322Pr/kv-storage-service/internal/logger"
+	"github.com/Na322Pr/kv-storage-service/internal/service"
+	descv2 "github.com/Na322Pr/kv-storage-service/pkg/api/v2"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// PutLarge stores every chunk as it arrives and makes the value visible once
+// the client closes the stream. A failed or cancelled stream leaves the key
+// as it was.
+func (s *ImplementationV2) PutLarge(stream descv2.KeyValueStorage_PutLargeServer) error {
+	ctx := stream.Context()
+
+	req, err := stream.Recv()
+	if errors.Is(err, io.EOF) {
+		return status.Error(codes.InvalidArgument, "header is required")
+	}
+	if err != nil {
+		return err
+	}
+	header := req.GetHeader()
+	if header == nil {
+		return status.Error(codes.InvalidArgument, "the first message must be the header")
+	}
+	if header.Key == "" {
+		return status.Error(codes.InvalidArgument, "key is required")
+	}
+	if header.Size != nil {
+		if *header.Size < 0 {
+			return status.Error(codes.InvalidArgument, "size must not be negative")
+		}
+		if limit := s.storageService.MaxValueSize(); limit > 0 && *header.Size > limit {
+			return status.Errorf(codes.ResourceExhausted, "value of %d bytes exceeds the limit of %d", *header.Size, limit)
+		}
+	}
+	if err := s.admit(header.Namespace); err != nil {
+		return err
+	}
+	if header.Lease != nil && !s.leaseService.Exists(*header.Lease) {
+		return status.Errorf(codes.NotFound, "lease %d not found", *header.Lease)
+	}
+
+	logger.FromContext(ctx, s.logger).Debug("Received large set request",
+		zap.String("namespace", header.Namespace),
+		zap.String("key", header.Key),
+		zap.String("contentType", header.ContentType),
+	)
+
+	upload, err := s.storageService.BeginLarge(service.SetMessage{
+		Namespace:   header.Namespace,
+		Key:         header.Key,
+		ContentType: header.ContentType,
+		Expiration:  header.GetExpiration(),
+		Lease:       header.GetLease(),
+	})
+	if err != nil {
+		return storageStatus(err)
+	}
+	// Abort does nothing once the value is committed.
+	defer upload.Abort(ctx)
+
+	for {
+		req, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		if req.GetHeader() != nil {
+			return status.Error(codes.InvalidArgument, "header must be sent once")
+		}
+		if err := upload.Write(ctx, req.GetChunk()); err != nil {
+			return storageStatus(err)
+		}
+	}
+
+	if header.Size != nil && *header.Size != upload.Size() {
+		return status.Errorf(codes.InvalidArgument, "received %d bytes, the header announced %d", upload.Size(), *header.Size)
+	}
+
+	revision, err := upload.Commit(ctx)
+	if err != nil {
+		return storageStatus(err)
+	}
+
+	return stream.SendAndClose(&descv2.PutLargeResponse{Revision: revision, Size: upload.Size()})
+}
@@ -0,0 +1,51 @@
+package kv_storage_service
+
+import (
+	"context"
+
+	descv2 "github.com/Na322Pr/kv-storage-service/pkg/api/v2"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func (s *ImplementationV2) Scan(ctx context.Context, req *descv2.ScanRequest) (*descv2.ScanResponse, error) {
+	limit := int(req.Limit)
+	switch {
+	case limit < 0 || limit > maxScanLimit:
+		return nil, status.Errorf(codes.InvalidArgument, "limit must be between 0 and %d", maxScanLimit)
+	case limit == 0:
+		limit = defaultScanLimit
+	}
+
+	if req.Revision != nil && *req.Revision <= 0 {
+		return nil, status.Error(codes.InvalidArgument, "revision must be positive")
+	}
+
+	if err := s.admit(req.Namespace); err != nil {
+		return nil, err
+	}
+
+	items, more, err := s.storageService.Scan(ctx, req.Namespace, req.Prefix, req.StartAfter, limit, req.GetRevision())
+	if err != nil {
+		return nil, revisionError(err)
+	}
+
+	resp := &descv2.ScanResponse{
+		Items: make([]*descv2.KeyValue, 0, len(items)),
+	}
+	for _, item := range items {
+		resp.Items = append(resp.Items, &descv2.KeyValue{
+			Key:         item.Key,
+			Value:       []byte(item.Value),
+			ContentType: item.ContentType,
+			Revision:    item.Revision,
+			Large:       item.Large,
+			Size:        item.Size,
+		})
+	}
+	if more {
+		resp.NextStartAfter = items[len(items)-1].Key
+	}
+
+	return resp, nil
+}
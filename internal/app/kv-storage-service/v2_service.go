@@ -0,0 +1,39 @@
+package kv_storage_service
+
+import (
+	"github.com/Na322Pr/kv-storage-service/internal/service"
+	descv2 "github.com/Na322Pr/kv-storage-service/pkg/api/v2"
+	"go.uber.org/zap"
+)
+
+// largeChunkSize bounds the chunks GetLarge sends, whatever the size of the
+// chunks the value was written with.
+const largeChunkSize = 1 << 20
+
+// ImplementationV2 serves API v2, where values are bytes with an optional
+// content type and large values are streamed in chunks.
+type ImplementationV2 struct {
+	descv2.UnimplementedKeyValueStorageServer
+
+	storageService *service.StorageService
+	leaseService   *service.LeaseService
+
+	logger *zap.Logger
+}
+
+func NewImplementationV2(
+	storeService *service.StorageService,
+	leaseService *service.LeaseService,
+	logger *zap.Logger,
+) *ImplementationV2 {
+	return &ImplementationV2{
+		storageService: storeService,
+		leaseService:   leaseService,
+		logger:         logger,
+	}
+}
+
+// admit rejects requests to unknown namespaces and over the namespace request rate.
+func (s *ImplementationV2) admit(namespace string) error {
+	return storageStatus(s.storageService.Admit(namespace))
+}
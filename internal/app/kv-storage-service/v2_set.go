@@ -0,0 +1,48 @@
+package kv_storage_service
+
+import (
+	"context"
+
+	"github.com/Na322Pr/kv-storage-service/internal/logger"
+	"github.com/Na322Pr/kv-storage-service/internal/service"
+	descv2 "github.com/Na322Pr/kv-storage-service/pkg/api/v2"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func (s *ImplementationV2) Set(ctx context.Context, req *descv2.SetRequest) (*descv2.SetResponse, error) {
+	if req.Key == "" {
+		return nil, status.Error(codes.InvalidArgument, "key is required")
+	}
+	if err := s.admit(req.Namespace); err != nil {
+		return nil, err
+	}
+	if req.Lease != nil && !s.leaseService.Exists(*req.Lease) {
+		return nil, status.Errorf(codes.NotFound, "lease %d not found", *req.Lease)
+	}
+
+	msg := service.SetMessage{
+		Namespace:   req.Namespace,
+		Key:         req.Key,
+		Value:       string(req.Value),
+		ContentType: req.ContentType,
+		Operation:   service.OperationSet,
+		Expiration:  req.GetExpiration(),
+		Lease:       req.GetLease(),
+	}
+
+	logger.FromContext(ctx, s.logger).Debug("Received set request",
+		zap.String("namespace", msg.Namespace),
+		zap.String("key", msg.Key),
+		zap.Int("size", len(req.Value)),
+		zap.String("contentType", msg.ContentType),
+	)
+
+	revision, err := s.storageService.Set(ctx, msg)
+	if err != nil {
+		return nil, storageStatus(err)
+	}
+
+	return &descv2.SetResponse{Revision: revision}, nil
+}
@@ -0,0 +1,17 @@
+package kv_storage_service
+
+import (
+	"unicode/utf8"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// textValue rejects the values API v1 cannot return: proto strings must be
+// UTF-8 and large values are only streamed by API v2.
+func textValue(key, value string, large bool) error {
+	if large || !utf8.ValidString(value) {
+		return status.Errorf(codes.FailedPrecondition, "value of %q is binary or large, read it with API v2", key)
+	}
+	return nil
+}
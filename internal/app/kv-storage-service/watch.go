@@ -0,0 +1,48 @@
+package kv_storage_service
+
+import (
+	"unicode/utf8"
+
+	desc "github.com/Na322Pr/kv-storage-service/pkg/api"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func (s *Implementation) Watch(req *desc.WatchRequest, stream desc.KeyValueStorage_WatchServer) error {
+	if req.Key == "" && !req.Prefix {
+		return status.Error(codes.InvalidArgument, "key is required")
+	}
+
+	if err := s.admit(req.Namespace); err != nil {
+		return err
+	}
+
+	ctx := stream.Context()
+	events := s.storageService.Watch(ctx, req.Namespace, req.Key, req.Prefix)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return status.FromContextError(ctx.Err()).Err()
+		case event, ok := <-events:
+			if !ok {
+				if ctx.Err() != nil {
+					return status.FromContextError(ctx.Err()).Err()
+				}
+				return status.Error(codes.Aborted, "watcher is too slow, resubscribe")
+			}
+
+			resp := &desc.WatchResponse{
+				Key:       event.Key,
+				Operation: string(event.Operation),
+			}
+			// Binary values are left out, API v1 carries text only.
+			if utf8.ValidString(event.Value) {
+				resp.Value = event.Value
+			}
+			if err := stream.Send(resp); err != nil {
+				return err
+			}
+		}
+	}
+}
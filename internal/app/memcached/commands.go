@@ -0,0 +1,433 @@
+package memcached
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Na322Pr/kv-storage-service/internal/service"
+	"github.com/Na322Pr/kv-storage-service/internal/storage"
+	desc "github.com/Na322Pr/kv-storage-service/pkg/api"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	maxKeyLength = 250
+	maxValueSize = 1024 * 1024
+	// Exptime values above thirty days are absolute unix timestamps.
+	maxRelativeExptime = 60 * 60 * 24 * 30
+	// flagsContentType is the content type keeping the client flags of the
+	// items stored with non-zero flags.
+	flagsContentType = "application/x-memcached; flags="
+)
+
+const (
+	replyError         = "ERROR\r\n"
+	replyStored        = "STORED\r\n"
+	replyNotStored     = "NOT_STORED\r\n"
+	replyExists        = "EXISTS\r\n"
+	replyNotFound      = "NOT_FOUND\r\n"
+	replyDeleted       = "DELETED\r\n"
+	replyTouched       = "TOUCHED\r\n"
+	replyEnd           = "END\r\n"
+	replyBadFormat     = "CLIENT_ERROR bad command line format\r\n"
+	replyBadChunk      = "CLIENT_ERROR bad data chunk\r\n"
+	replyNonNumeric    = "CLIENT_ERROR cannot increment or decrement non-numeric value\r\n"
+	replyInvalidDelta  = "CLIENT_ERROR invalid numeric delta argument\r\n"
+	replyReadOnly      = "SERVER_ERROR read only replica\r\n"
+	replyObjectTooLong = "SERVER_ERROR object too large for cache\r\n"
+	replyOutOfMemory   = "SERVER_ERROR out of memory storing object\r\n"
+)
+
+// methods are the gRPC methods whose rate limits apply to the commands.
+var methods = map[string]string{
+	"get":     desc.KeyValueStorage_Get_FullMethodName,
+	"gets":    desc.KeyValueStorage_Get_FullMethodName,
+	"set":     desc.KeyValueStorage_Set_FullMethodName,
+	"add":     desc.KeyValueStorage_Set_FullMethodName,
+	"replace": desc.KeyValueStorage_Set_FullMethodName,
+	"cas":     desc.KeyValueStorage_Set_FullMethodName,
+	"touch":   desc.KeyValueStorage_Set_FullMethodName,
+	"delete":  desc.KeyValueStorage_Delete_FullMethodName,
+	"incr":    desc.KeyValueStorage_Increment_FullMethodName,
+	"decr":    desc.KeyValueStorage_Decrement_FullMethodName,
+}
+
+func (s *Server) dispatch(ctx context.Context, r *bufio.Reader, w *bufio.Writer, args []string) (bool, error) {
+	switch args[0] {
+	case "set", "add", "replace", "cas":
+		return false, s.store(ctx, r, w, args)
+	case "version":
+		w.WriteString("VERSION kv-storage-service\r\n")
+		return false, nil
+	case "quit":
+		return true, nil
+	}
+
+	method, ok := methods[args[0]]
+	if !ok {
+		w.WriteString(replyError)
+		return false, nil
+	}
+	release, err := s.admit(ctx, method)
+	if err != nil {
+		if args[len(args)-1] != "noreply" {
+			w.WriteString(rejectedReply(err))
+		}
+		return false, nil
+	}
+	defer release()
+
+	switch args[0] {
+	case "get":
+		return false, s.get(w, args, false)
+	case "gets":
+		return false, s.get(w, args, true)
+	case "delete":
+		s.delete(w, args)
+	case "incr", "decr":
+		s.incr(w, args)
+	case "touch":
+		s.touch(w, args)
+	}
+	return false, nil
+}
+
+// admit applies the rate limits of the method to the client. The returned
+// function releases the write slot taken by the command.
+func (s *Server) admit(ctx context.Context, method string) (func(), error) {
+	if s.admission == nil {
+		return func() {}, nil
+	}
+	return s.admission.Admit(ctx, method)
+}
+
+func rejectedReply(err error) string {
+	return "SERVER_ERROR " + status.Convert(err).Message() + "\r\n"
+}
+
+// get answers get and gets. The CAS token is the revision at which the key
+// was last modified. Large values are streamed chunk by chunk, a failure
+// midway leaves the reply broken and is returned to close the connection.
+func (s *Server) get(w *bufio.Writer, args []string, withCAS bool) error {
+	if len(args) < 2 {
+		w.WriteString(replyError)
+		return nil
+	}
+
+	ctx := context.Background()
+	for _, key := range args[1:] {
+		item, ok := s.storageService.GetItem(ctx, storage.DefaultNamespace, key)
+		if !ok {
+			continue
+		}
+
+		w.WriteString("VALUE " + key + " " + strconv.FormatUint(uint64(flags(item.ContentType)), 10) + " " + strconv.FormatInt(item.Len(), 10))
+		if withCAS {
+			w.WriteString(" " + strconv.FormatInt(item.Revision, 10))
+		}
+		w.WriteString("\r\n")
+		err := s.storageService.ReadLarge(ctx, storage.DefaultNamespace, item, func(chunk []byte) error {
+			_, err := w.Write(chunk)
+			return err
+		})
+		if err != nil {
+			return err
+		}
+		w.WriteString("\r\n")
+	}
+	w.WriteString(replyEnd)
+	return nil
+}
+
+// flagsType returns the content type keeping the client flags.
+func flagsType(flags uint32) string {
+	if flags == 0 {
+		return ""
+	}
+	return flagsContentType + strconv.FormatUint(uint64(flags), 10)
+}
+
+// flags returns the client flags kept in the content type, zero for items
+// stored without flags or through another API.
+func flags(contentType string) uint32 {
+	value, ok := strings.CutPrefix(contentType, flagsContentType)
+	if !ok {
+		return 0
+	}
+	flags, err := strconv.ParseUint(value, 10, 32)
+	if err != nil {
+		return 0
+	}
+	return uint32(flags)
+}
+
+// store handles set, add, replace and cas:
+// <command> <key> <flags> <exptime> <bytes> [<cas unique>] [noreply]
+// The data block is read before the command is admitted.
+func (s *Server) store(ctx context.Context, r *bufio.Reader, w *bufio.Writer, args []string) error {
+	isCAS := args[0] == "cas"
+
+	fields := 5
+	if isCAS {
+		fields = 6
+	}
+	if len(args) < fields || len(args) > fields+1 {
+		w.WriteString(replyError)
+		return nil
+	}
+	noreply := len(args) == fields+1 && args[fields] == "noreply"
+
+	clientFlags, flagsErr := strconv.ParseUint(args[2], 10, 32)
+	exptime, exptimeErr := strconv.ParseInt(args[3], 10, 64)
+	size, sizeErr := strconv.Atoi(args[4])
+	if flagsErr != nil || exptimeErr != nil || sizeErr != nil || size < 0 || !validKey(args[1]) {
+		w.WriteString(replyBadFormat)
+		return nil
+	}
+
+	msg := service.SetMessage{
+		Key:         args[1],
+		Operation:   service.OperationSet,
+		Expiration:  expiration(exptime),
+		ContentType: flagsType(uint32(clientFlags)),
+	}
+
+	if isCAS {
+		revision, err := strconv.ParseInt(args[5], 10, 64)
+		if err != nil {
+			w.WriteString(replyBadFormat)
+			return nil
+		}
+		msg.Condition = service.ConditionRevision
+		msg.Revision = revision
+	}
+
+	switch args[0] {
+	case "add":
+		msg.Condition = service.ConditionNotExists
+	case "replace":
+		msg.Condition = service.ConditionExists
+	}
+
+	if size > maxValueSize {
+		if _, err := r.Discard(size + 2); err != nil {
+			return err
+		}
+		w.WriteString(replyObjectTooLong)
+		return nil
+	}
+
+	data := make([]byte, size+2)
+	if _, err := io.ReadFull(r, data); err != nil {
+		return err
+	}
+	if data[size] != '\r' || data[size+1] != '\n' {
+		w.WriteString(replyBadChunk)
+		return nil
+	}
+	msg.Value = string(data[:size])
+
+	release, err := s.admit(ctx, methods[args[0]])
+	if err != nil {
+		if !noreply {
+			w.WriteString(rejectedReply(err))
+		}
+		return nil
+	}
+	defer release()
+
+	reply := replyStored
+	if !s.storageService.IsLeader() {
+		reply = replyReadOnly
+	} else {
+		_, err := s.storageService.Set(context.Background(), msg)
+		switch {
+		case errors.Is(err, service.ErrNotFound):
+			reply = replyNotFound
+		case errors.Is(err, service.ErrConditionNotMet) && isCAS:
+			reply = replyExists
+		case errors.Is(err, service.ErrConditionNotMet):
+			reply = replyNotStored
+		case errors.Is(err, storage.ErrOutOfMemory):
+			reply = replyOutOfMemory
+		case err != nil:
+			reply = "SERVER_ERROR " + err.Error() + "\r\n"
+		}
+	}
+
+	if !noreply {
+		w.WriteString(reply)
+	}
+	return nil
+}
+
+// delete <key> [noreply]
+func (s *Server) delete(w *bufio.Writer, args []string) {
+	if len(args) < 2 || len(args) > 3 {
+		w.WriteString(replyError)
+		return
+	}
+	noreply := len(args) == 3 && args[2] == "noreply"
+	if !validKey(args[1]) {
+		w.WriteString(replyBadFormat)
+		return
+	}
+
+	reply := replyNotFound
+	if !s.storageService.IsLeader() {
+		reply = replyReadOnly
+	} else if deleted, _, err := s.storageService.Delete(context.Background(), storage.DefaultNamespace, args[1]); err != nil {
+		reply = "SERVER_ERROR " + err.Error() + "\r\n"
+	} else if deleted {
+		reply = replyDeleted
+	}
+
+	if !noreply {
+		w.WriteString(reply)
+	}
+}
+
+// incr|decr <key> <value> [noreply]
+// Values are unsigned 64-bit integers: incr wraps around and decr stops at zero.
+func (s *Server) incr(w *bufio.Writer, args []string) {
+	if len(args) < 3 || len(args) > 4 {
+		w.WriteString(replyError)
+		return
+	}
+	noreply := len(args) == 4 && args[3] == "noreply"
+
+	if !validKey(args[1]) {
+		w.WriteString(replyBadFormat)
+		return
+	}
+	delta, err := strconv.ParseUint(args[2], 10, 64)
+	if err != nil {
+		w.WriteString(replyInvalidDelta)
+		return
+	}
+
+	reply := s.applyDelta(args[1], delta, args[0] == "decr")
+	if !noreply {
+		w.WriteString(reply)
+	}
+}
+
+// applyDelta is a compare-and-swap loop over the key revision, so concurrent
+// updates through any protocol are never lost.
+func (s *Server) applyDelta(key string, delta uint64, decr bool) string {
+	if !s.storageService.IsLeader() {
+		return replyReadOnly
+	}
+
+	ctx := context.Background()
+	for {
+		item, ok := s.storageService.GetItem(ctx, storage.DefaultNamespace, key)
+		if !ok {
+			return replyNotFound
+		}
+
+		current, err := strconv.ParseUint(item.Value, 10, 64)
+		if err != nil {
+			return replyNonNumeric
+		}
+
+		switch {
+		case !decr:
+			current += delta
+		case delta > current:
+			current = 0
+		default:
+			current -= delta
+		}
+
+		value := strconv.FormatUint(current, 10)
+		_, err = s.storageService.Set(ctx, service.SetMessage{
+			Key:         key,
+			Value:       value,
+			ContentType: item.ContentType,
+			Operation:   service.OperationSet,
+			Expiration:  item.Expiration,
+			Condition:   service.ConditionRevision,
+			Revision:    item.Revision,
+		})
+		switch {
+		case err == nil:
+			return value + "\r\n"
+		case errors.Is(err, service.ErrNotFound):
+			return replyNotFound
+		case errors.Is(err, storage.ErrOutOfMemory):
+			return replyOutOfMemory
+		case !errors.Is(err, service.ErrConditionNotMet):
+			return "SERVER_ERROR " + err.Error() + "\r\n"
+		}
+	}
+}
+
+// touch <key> <exptime> [noreply]
+func (s *Server) touch(w *bufio.Writer, args []string) {
+	if len(args) < 3 || len(args) > 4 {
+		w.WriteString(replyError)
+		return
+	}
+	noreply := len(args) == 4 && args[3] == "noreply"
+
+	exptime, err := strconv.ParseInt(args[2], 10, 64)
+	if err != nil || !validKey(args[1]) {
+		w.WriteString(replyBadFormat)
+		return
+	}
+
+	reply := replyTouched
+	if !s.storageService.IsLeader() {
+		reply = replyReadOnly
+	} else {
+		_, err := s.storageService.Set(context.Background(), service.SetMessage{
+			Key:        args[1],
+			Operation:  service.OperationExpire,
+			Expiration: expiration(exptime),
+		})
+		switch {
+		case errors.Is(err, service.ErrNotFound):
+			reply = replyNotFound
+		case err != nil:
+			reply = "SERVER_ERROR " + err.Error() + "\r\n"
+		}
+	}
+
+	if !noreply {
+		w.WriteString(reply)
+	}
+}
+
+// expiration converts a memcached exptime into an absolute deadline in unix
+// nanoseconds as stored in storage.Item.
+func expiration(exptime int64) int64 {
+	now := time.Now()
+	switch {
+	case exptime == 0:
+		return 0
+	case exptime < 0:
+		return now.Add(-time.Second).UnixNano()
+	case exptime <= maxRelativeExptime:
+		return now.Add(time.Duration(exptime) * time.Second).UnixNano()
+	default:
+		return time.Unix(exptime, 0).UnixNano()
+	}
+}
+
+func validKey(key string) bool {
+	if len(key) == 0 || len(key) > maxKeyLength {
+		return false
+	}
+	for i := 0; i < len(key); i++ {
+		if key[i] <= ' ' || key[i] == 0x7f {
+			return false
+		}
+	}
+	return true
+}
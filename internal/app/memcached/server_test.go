@@ -0,0 +1,285 @@
+package memcached_test
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Na322Pr/kv-storage-service/internal/admission"
+	"github.com/Na322Pr/kv-storage-service/internal/app/memcached"
+	"github.com/Na322Pr/kv-storage-service/internal/config"
+	"github.com/Na322Pr/kv-storage-service/internal/metrics"
+	"github.com/Na322Pr/kv-storage-service/internal/model"
+	"github.com/Na322Pr/kv-storage-service/internal/service"
+	"github.com/Na322Pr/kv-storage-service/internal/storage"
+	"github.com/prometheus/client_golang/prometheus"
+	"go.uber.org/zap"
+)
+
+// client speaks the text protocol to a server listening on a real socket.
+type client struct {
+	t    *testing.T
+	conn net.Conn
+	r    *bufio.Reader
+}
+
+func start(t *testing.T, leader bool) (*service.StorageService, *client) {
+	t.Helper()
+	return startAdmitted(t, leader, nil)
+}
+
+// startAdmitted starts a server admitting its commands through the controller.
+func startAdmitted(t *testing.T, leader bool, controller *admission.Controller) (*service.StorageService, *client) {
+	t.Helper()
+	store, err := storage.NewStore(storage.NewMemoryEngine(), config.Storage{EvictionPolicy: "noeviction", MaxValueSize: 64 << 20})
+	if err != nil {
+		t.Fatalf("new store: %v", err)
+	}
+	node := model.NewNode("1", "1", "127.0.0.1:2110")
+	node.SetLeader(leader)
+	ss := service.NewStorageService(store, node, service.NewConnectionManagerService())
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	srv := memcached.NewServer(ss, lis.Addr().String(), controller, zap.NewNop())
+	go srv.Serve(lis)
+	t.Cleanup(func() { srv.Close() })
+
+	conn, err := net.Dial("tcp", lis.Addr().String())
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return ss, &client{t: t, conn: conn, r: bufio.NewReader(conn)}
+}
+
+func (c *client) send(s string) {
+	c.t.Helper()
+	if _, err := io.WriteString(c.conn, s); err != nil {
+		c.t.Fatalf("write: %v", err)
+	}
+}
+
+// expect reads exactly the bytes of want.
+func (c *client) expect(want string) {
+	c.t.Helper()
+	got := make([]byte, len(want))
+	if _, err := io.ReadFull(c.r, got); err != nil {
+		c.t.Fatalf("read %q: %v", want, err)
+	}
+	if string(got) != want {
+		c.t.Fatalf("got %q, want %q", got, want)
+	}
+}
+
+func (c *client) line() string {
+	c.t.Helper()
+	line, err := c.r.ReadString('\n')
+	if err != nil {
+		c.t.Fatalf("read: %v", err)
+	}
+	return line
+}
+
+func TestStorageCommands(t *testing.T) {
+	_, c := start(t, true)
+
+	c.send("get a\r\n")
+	c.expect("END\r\n")
+	c.send("add a 0 0 1\r\n1\r\n")
+	c.expect("STORED\r\n")
+	c.send("add a 0 0 1\r\n2\r\n")
+	c.expect("NOT_STORED\r\n")
+	c.send("replace b 0 0 1\r\n2\r\n")
+	c.expect("NOT_STORED\r\n")
+	c.send("set b 42 0 2\r\nhi\r\n")
+	c.expect("STORED\r\n")
+	c.send("get a b missing\r\n")
+	c.expect("VALUE a 0 1\r\n1\r\nVALUE b 42 2\r\nhi\r\nEND\r\n")
+
+	c.send("delete b\r\n")
+	c.expect("DELETED\r\n")
+	c.send("delete b\r\n")
+	c.expect("NOT_FOUND\r\n")
+
+	// The rest of a bad block is read as a command, as memcached does.
+	c.send("set a 0 0 1\r\nxyz\r\n")
+	c.expect("CLIENT_ERROR bad data chunk\r\nERROR\r\n")
+	c.send("bogus\r\n")
+	c.expect("ERROR\r\n")
+	c.send("version\r\n")
+	if line := c.line(); !strings.HasPrefix(line, "VERSION ") {
+		t.Fatalf("version: %q", line)
+	}
+}
+
+func TestCAS(t *testing.T) {
+	_, c := start(t, true)
+
+	c.send("cas a 0 0 1 1\r\n1\r\n")
+	c.expect("NOT_FOUND\r\n")
+	c.send("set a 0 0 1\r\n1\r\n")
+	c.expect("STORED\r\n")
+
+	c.send("gets a\r\n")
+	var flags, size int
+	var unique int64
+	if _, err := fmt.Sscanf(c.line(), "VALUE a %d %d %d\r\n", &flags, &size, &unique); err != nil {
+		t.Fatalf("gets: %v", err)
+	}
+	c.expect("1\r\nEND\r\n")
+
+	c.send(fmt.Sprintf("cas a 0 0 1 %d\r\n2\r\n", unique+1))
+	c.expect("EXISTS\r\n")
+	c.send(fmt.Sprintf("cas a 0 0 1 %d\r\n2\r\n", unique))
+	c.expect("STORED\r\n")
+	c.send(fmt.Sprintf("cas a 0 0 1 %d\r\n3\r\n", unique))
+	c.expect("EXISTS\r\n")
+	c.send("get a\r\n")
+	c.expect("VALUE a 0 1\r\n2\r\nEND\r\n")
+}
+
+func TestNoreply(t *testing.T) {
+	_, c := start(t, true)
+
+	// Only the reply of the last command comes back.
+	c.send("set a 0 0 1 noreply\r\n5\r\n" +
+		"add a 0 0 1 noreply\r\n6\r\n" +
+		"incr a 2 noreply\r\n" +
+		"touch a 100 noreply\r\n" +
+		"delete missing noreply\r\n" +
+		"get a\r\n")
+	c.expect("VALUE a 0 1\r\n7\r\nEND\r\n")
+}
+
+func TestIncrDecr(t *testing.T) {
+	_, c := start(t, true)
+
+	c.send("incr n 1\r\n")
+	c.expect("NOT_FOUND\r\n")
+	c.send("set n 7 0 20\r\n18446744073709551615\r\n")
+	c.expect("STORED\r\n")
+	c.send("incr n 2\r\n")
+	c.expect("1\r\n")
+	c.send("decr n 5\r\n")
+	c.expect("0\r\n")
+	c.send("incr n x\r\n")
+	c.expect("CLIENT_ERROR invalid numeric delta argument\r\n")
+	c.send("set s 0 0 1\r\nx\r\n")
+	c.expect("STORED\r\n")
+	c.send("incr s 1\r\n")
+	c.expect("CLIENT_ERROR cannot increment or decrement non-numeric value\r\n")
+	c.send("get n\r\n")
+	c.expect("VALUE n 7 1\r\n0\r\nEND\r\n")
+}
+
+func TestTouch(t *testing.T) {
+	_, c := start(t, true)
+
+	c.send("touch a 10\r\n")
+	c.expect("NOT_FOUND\r\n")
+	c.send("set a 0 0 1\r\n1\r\n")
+	c.expect("STORED\r\n")
+	c.send("touch a 10\r\n")
+	c.expect("TOUCHED\r\n")
+	c.send("touch a -1\r\n")
+	c.expect("TOUCHED\r\n")
+	c.send("get a\r\n")
+	c.expect("END\r\n")
+
+	// Reserved keys are refused, not reported missing.
+	c.send("touch __lock/a 10\r\n")
+	if line := c.line(); !strings.HasPrefix(line, "SERVER_ERROR ") {
+		t.Fatalf("touch reserved key: %q", line)
+	}
+}
+
+func TestInvalidKeys(t *testing.T) {
+	_, c := start(t, true)
+
+	long := strings.Repeat("k", 251)
+	c.send("set " + long + " 0 0 1\r\n")
+	c.expect("CLIENT_ERROR bad command line format\r\n")
+	for _, command := range []string{
+		"touch " + long + " 10\r\n",
+		"delete " + long + "\r\n",
+		"incr " + long + " 1\r\n",
+		"decr " + long + " 1\r\n",
+	} {
+		c.send(command)
+		c.expect("CLIENT_ERROR bad command line format\r\n")
+	}
+}
+
+func TestLargeValues(t *testing.T) {
+	ss, c := start(t, true)
+
+	// A value over the limit is skipped without losing the stream.
+	c.send(fmt.Sprintf("set a 0 0 %d\r\n%s\r\nversion\r\n", 1<<20+1, strings.Repeat("x", 1<<20+1)))
+	c.expect("SERVER_ERROR object too large for cache\r\n")
+	if line := c.line(); !strings.HasPrefix(line, "VERSION ") {
+		t.Fatalf("stream out of sync: %q", line)
+	}
+
+	value := strings.Repeat("0123456789", 100_000)
+	c.send(fmt.Sprintf("set a 0 0 %d\r\n%s\r\n", len(value), value))
+	c.expect("STORED\r\n")
+	c.send("get a\r\n")
+	c.expect(fmt.Sprintf("VALUE a 0 %d\r\n%s\r\nEND\r\n", len(value), value))
+
+	// Values streamed in chunks through the other protocols are read whole.
+	large := bytes.Repeat([]byte("0123456789"), 300_000)
+	upload, err := ss.BeginLarge(service.SetMessage{Key: "big", Operation: service.OperationSet})
+	if err != nil {
+		t.Fatalf("begin large: %v", err)
+	}
+	for off := 0; off < len(large); off += 100_000 {
+		if err := upload.Write(context.Background(), large[off:off+100_000]); err != nil {
+			t.Fatalf("write chunk: %v", err)
+		}
+	}
+	if _, err := upload.Commit(context.Background()); err != nil {
+		t.Fatalf("commit: %v", err)
+	}
+	c.send("get big\r\n")
+	c.expect(fmt.Sprintf("VALUE big 0 %d\r\n%s\r\nEND\r\n", len(large), large))
+}
+
+func TestReplicaIsReadOnly(t *testing.T) {
+	_, c := start(t, false)
+
+	c.send("set a 0 0 1\r\n1\r\n")
+	c.expect("SERVER_ERROR read only replica\r\n")
+	c.send("delete a\r\n")
+	c.expect("SERVER_ERROR read only replica\r\n")
+	c.send("get a\r\n")
+	c.expect("END\r\n")
+}
+
+func TestAdmissionRejects(t *testing.T) {
+	controller, err := admission.NewController(config.RateLimit{Enabled: true, ClientRate: 0.01, ClientBurst: 1, ClientIdleTimeout: time.Hour},
+		metrics.NewAdmissionMetrics(prometheus.NewRegistry()))
+	if err != nil {
+		t.Fatalf("new controller: %v", err)
+	}
+	_, c := startAdmitted(t, true, controller)
+
+	c.send("set a 0 0 1\r\n1\r\n")
+	c.expect("STORED\r\n")
+	// The data block of a rejected command is skipped.
+	c.send("set a 0 0 1\r\n2\r\nversion\r\n")
+	if line := c.line(); !strings.HasPrefix(line, "SERVER_ERROR ") {
+		t.Fatalf("rejected set: %q", line)
+	}
+	if line := c.line(); !strings.HasPrefix(line, "VERSION ") {
+		t.Fatalf("stream out of sync: %q", line)
+	}
+}
@@ -0,0 +1,366 @@
+package resp
+
+import (
+	"context"
+	"errors"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Na322Pr/kv-storage-service/internal/service"
+	"github.com/Na322Pr/kv-storage-service/internal/storage"
+	desc "github.com/Na322Pr/kv-storage-service/pkg/api"
+)
+
+type command struct {
+	// arity follows redis conventions: positive is exact, negative is minimum.
+	arity int
+	write bool
+	// method is the gRPC method whose rate limits apply, empty for commands
+	// never limited.
+	method  string
+	handler func(s *Server, w *writer, args []string)
+}
+
+var commands = map[string]command{
+	"PING":    {arity: -1, handler: (*Server).ping},
+	"HELLO":   {arity: -1, handler: (*Server).hello},
+	"QUIT":    {arity: 1, handler: (*Server).quit},
+	"COMMAND": {arity: -1, handler: (*Server).command},
+	"GET":     {arity: 2, method: desc.KeyValueStorage_Get_FullMethodName, handler: (*Server).get},
+	"SET":     {arity: -3, write: true, method: desc.KeyValueStorage_Set_FullMethodName, handler: (*Server).set},
+	"DEL":     {arity: -2, write: true, method: desc.KeyValueStorage_Delete_FullMethodName, handler: (*Server).del},
+	"EXISTS":  {arity: -2, method: desc.KeyValueStorage_Get_FullMethodName, handler: (*Server).exists},
+	"MGET":    {arity: -2, method: desc.KeyValueStorage_Get_FullMethodName, handler: (*Server).mget},
+	"MSET":    {arity: -3, write: true, method: desc.KeyValueStorage_Set_FullMethodName, handler: (*Server).mset},
+	"EXPIRE":  {arity: 3, write: true, method: desc.KeyValueStorage_Set_FullMethodName, handler: (*Server).expire},
+	"TTL":     {arity: 2, method: desc.KeyValueStorage_Get_FullMethodName, handler: (*Server).ttl},
+	"INCR":    {arity: 2, write: true, method: desc.KeyValueStorage_Increment_FullMethodName, handler: (*Server).incr},
+	"SCAN":    {arity: -2, method: desc.KeyValueStorage_Scan_FullMethodName, handler: (*Server).scan},
+}
+
+const (
+	errSyntax     = "ERR syntax error"
+	errNotInteger = "ERR value is not an integer or out of range"
+	errOOM        = "OOM command not allowed when used memory > 'maxmemory'"
+)
+
+// errorReply formats a storage error the way Redis reports it.
+func errorReply(err error) string {
+	if errors.Is(err, storage.ErrOutOfMemory) {
+		return errOOM
+	}
+	return "ERR " + err.Error()
+}
+
+func (s *Server) ping(w *writer, args []string) {
+	if len(args) > 1 {
+		w.bulk(args[1])
+		return
+	}
+	w.simple("PONG")
+}
+
+func (s *Server) hello(w *writer, args []string) {
+	if len(args) > 1 {
+		version, err := strconv.Atoi(args[1])
+		if err != nil || version < 2 || version > 3 {
+			w.error("NOPROTO unsupported protocol version")
+			return
+		}
+		w.proto = version
+	}
+
+	role := "replica"
+	if s.storageService.IsLeader() {
+		role = "master"
+	}
+
+	w.mapHeader(4)
+	w.bulk("server")
+	w.bulk("kv-storage-service")
+	w.bulk("proto")
+	w.integer(int64(w.proto))
+	w.bulk("mode")
+	w.bulk("standalone")
+	w.bulk("role")
+	w.bulk(role)
+}
+
+func (s *Server) quit(w *writer, _ []string) {
+	w.simple("OK")
+}
+
+// command answers the introspection call redis-cli issues on connect.
+func (s *Server) command(w *writer, _ []string) {
+	w.array(0)
+}
+
+func (s *Server) get(w *writer, args []string) {
+	value, ok := s.storageService.Get(context.Background(), storage.DefaultNamespace, args[1])
+	if !ok {
+		w.null()
+		return
+	}
+	w.bulk(value)
+}
+
+func (s *Server) set(w *writer, args []string) {
+	msg := service.SetMessage{
+		Key:       args[1],
+		Value:     args[2],
+		Operation: service.OperationSet,
+	}
+
+	var ok bool
+	for i := 3; i < len(args); i++ {
+		switch strings.ToUpper(args[i]) {
+		case "NX":
+			if msg.Condition != service.ConditionNone {
+				w.error(errSyntax)
+				return
+			}
+			msg.Condition = service.ConditionNotExists
+		case "XX":
+			if msg.Condition != service.ConditionNone {
+				w.error(errSyntax)
+				return
+			}
+			msg.Condition = service.ConditionExists
+		case "EX", "PX":
+			if msg.Expiration != 0 || i+1 == len(args) {
+				w.error(errSyntax)
+				return
+			}
+			n, err := strconv.ParseInt(args[i+1], 10, 64)
+			if err != nil || n <= 0 {
+				w.error("ERR invalid expire time in 'set' command")
+				return
+			}
+			unit := time.Second
+			if strings.EqualFold(args[i], "PX") {
+				unit = time.Millisecond
+			}
+			if msg.Expiration, ok = deadline(n, unit); !ok {
+				w.error("ERR invalid expire time in 'set' command")
+				return
+			}
+			i++
+		default:
+			w.error(errSyntax)
+			return
+		}
+	}
+
+	_, err := s.storageService.Set(context.Background(), msg)
+	if errors.Is(err, service.ErrConditionNotMet) {
+		w.null()
+		return
+	}
+	if err != nil {
+		w.error(errorReply(err))
+		return
+	}
+	w.simple("OK")
+}
+
+func (s *Server) del(w *writer, args []string) {
+	var deleted int64
+	for _, key := range args[1:] {
+		ok, _, err := s.storageService.Delete(context.Background(), storage.DefaultNamespace, key)
+		if err != nil {
+			w.error(errorReply(err))
+			return
+		}
+		if ok {
+			deleted++
+		}
+	}
+	w.integer(deleted)
+}
+
+func (s *Server) exists(w *writer, args []string) {
+	var found int64
+	for _, key := range args[1:] {
+		if _, ok := s.storageService.Get(context.Background(), storage.DefaultNamespace, key); ok {
+			found++
+		}
+	}
+	w.integer(found)
+}
+
+func (s *Server) mget(w *writer, args []string) {
+	w.array(len(args) - 1)
+	for _, key := range args[1:] {
+		value, ok := s.storageService.Get(context.Background(), storage.DefaultNamespace, key)
+		if !ok {
+			w.null()
+			continue
+		}
+		w.bulk(value)
+	}
+}
+
+func (s *Server) mset(w *writer, args []string) {
+	if len(args)%2 != 1 {
+		w.error("ERR wrong number of arguments for 'mset' command")
+		return
+	}
+
+	// The keys are written at once, like redis MSET never sets only some.
+	items := make([]storage.BatchItem, 0, len(args)/2)
+	for i := 1; i < len(args); i += 2 {
+		items = append(items, storage.BatchItem{Key: args[i], Item: storage.Item{Value: args[i+1]}})
+	}
+	if _, err := s.storageService.SetBatch(context.Background(), storage.DefaultNamespace, items); err != nil {
+		w.error(errorReply(err))
+		return
+	}
+	w.simple("OK")
+}
+
+func (s *Server) expire(w *writer, args []string) {
+	seconds, err := strconv.ParseInt(args[2], 10, 64)
+	if err != nil {
+		w.error(errNotInteger)
+		return
+	}
+
+	// A non-positive timeout deletes the key right away, as redis does.
+	if seconds <= 0 {
+		deleted, _, err := s.storageService.Delete(context.Background(), storage.DefaultNamespace, args[1])
+		switch {
+		case err != nil:
+			w.error(errorReply(err))
+		case deleted:
+			w.integer(1)
+		default:
+			w.integer(0)
+		}
+		return
+	}
+
+	expiration, ok := deadline(seconds, time.Second)
+	if !ok {
+		w.error(errNotInteger)
+		return
+	}
+	msg := service.SetMessage{
+		Key:        args[1],
+		Operation:  service.OperationExpire,
+		Expiration: expiration,
+	}
+	_, err = s.storageService.Set(context.Background(), msg)
+	switch {
+	case errors.Is(err, service.ErrNotFound):
+		w.integer(0)
+	case err != nil:
+		w.error(errorReply(err))
+	default:
+		w.integer(1)
+	}
+}
+
+// deadline returns the unix time in nanoseconds n units from now, false
+// when it is past the largest one.
+func deadline(n int64, unit time.Duration) (int64, bool) {
+	now := time.Now().UnixNano()
+	if n > (math.MaxInt64-now)/int64(unit) {
+		return 0, false
+	}
+	return now + n*int64(unit), true
+}
+
+func (s *Server) ttl(w *writer, args []string) {
+	item, ok := s.storageService.GetItem(context.Background(), storage.DefaultNamespace, args[1])
+	switch {
+	case !ok:
+		w.integer(-2)
+	case item.Expiration == 0:
+		w.integer(-1)
+	default:
+		remaining := time.Until(time.Unix(0, item.Expiration))
+		w.integer(int64((remaining + time.Second - 1) / time.Second))
+	}
+}
+
+func (s *Server) incr(w *writer, args []string) {
+	value, _, err := s.storageService.Increment(context.Background(), service.IncrementMessage{
+		Key:   args[1],
+		Delta: 1,
+	})
+	switch {
+	case errors.Is(err, storage.ErrNotInteger):
+		w.error(errNotInteger)
+	case errors.Is(err, storage.ErrOverflow):
+		w.error("ERR increment or decrement would overflow")
+	case err != nil:
+		w.error(errorReply(err))
+	default:
+		w.integer(value)
+	}
+}
+
+// scan iterates over the sorted key space, the cursor stands for the last
+// key returned and 0 marks the start and the end of the iteration.
+func (s *Server) scan(w *writer, args []string) {
+	cursor, err := strconv.ParseUint(args[1], 10, 64)
+	if err != nil {
+		w.error("ERR invalid cursor")
+		return
+	}
+	var startAfter string
+	if cursor != 0 {
+		var ok bool
+		if startAfter, ok = s.cursors.get(cursor); !ok {
+			w.error("ERR invalid cursor")
+			return
+		}
+	}
+
+	pattern, count := "*", 10
+	for i := 2; i < len(args); i += 2 {
+		if i+1 == len(args) {
+			w.error(errSyntax)
+			return
+		}
+		switch strings.ToUpper(args[i]) {
+		case "MATCH":
+			pattern = args[i+1]
+		case "COUNT":
+			count, err = strconv.Atoi(args[i+1])
+			if err != nil || count < 1 {
+				w.error(errSyntax)
+				return
+			}
+		default:
+			w.error(errSyntax)
+			return
+		}
+	}
+
+	items, more, err := s.storageService.Scan(context.Background(), storage.DefaultNamespace, literalPrefix(pattern), startAfter, count, 0)
+	if err != nil {
+		w.error(errorReply(err))
+		return
+	}
+
+	var batch []string
+	for _, item := range items {
+		if matchGlob(pattern, item.Key) {
+			batch = append(batch, item.Key)
+		}
+	}
+	var next uint64
+	if more {
+		next = s.cursors.add(items[len(items)-1].Key)
+	}
+
+	w.array(2)
+	w.bulk(strconv.FormatUint(next, 10))
+	w.array(len(batch))
+	for _, key := range batch {
+		w.bulk(key)
+	}
+}
@@ -0,0 +1,43 @@
+package resp
+
+import "sync"
+
+// maxCursors bounds the SCAN cursors kept, the oldest are forgotten first.
+const maxCursors = 4096
+
+// cursors maps the numeric SCAN cursors handed to clients, which expect
+// 64-bit numbers, to the last key returned. Iterations resume after that key
+// and never skip or repeat keys when others are added or removed meanwhile.
+type cursors struct {
+	mu    sync.Mutex
+	next  uint64
+	keys  map[uint64]string
+	order []uint64
+}
+
+func newCursors() *cursors {
+	return &cursors{keys: make(map[uint64]string)}
+}
+
+// add returns a new cursor resuming after the key.
+func (c *cursors) add(key string) uint64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if len(c.order) == maxCursors {
+		delete(c.keys, c.order[0])
+		c.order = c.order[1:]
+	}
+	c.next++
+	c.keys[c.next] = key
+	c.order = append(c.order, c.next)
+	return c.next
+}
+
+// get returns the key the cursor resumes after.
+func (c *cursors) get(cursor uint64) (string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	key, ok := c.keys[cursor]
+	return key, ok
+}
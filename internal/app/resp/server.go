@@ -0,0 +1,187 @@
+package resp
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/Na322Pr/kv-storage-service/internal/admission"
+	"github.com/Na322Pr/kv-storage-service/internal/service"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// Server exposes StorageService over the Redis serialization protocol.
+type Server struct {
+	storageService *service.StorageService
+	address        string
+	// admission is nil when rate limiting is disabled.
+	admission *admission.Controller
+	cursors   *cursors
+	logger    *zap.Logger
+
+	mu       sync.Mutex
+	listener net.Listener
+	conns    map[net.Conn]struct{}
+	closed   bool
+}
+
+func NewServer(storageService *service.StorageService, address string, admissionController *admission.Controller, logger *zap.Logger) *Server {
+	return &Server{
+		storageService: storageService,
+		address:        address,
+		admission:      admissionController,
+		cursors:        newCursors(),
+		logger:         logger,
+		conns:          make(map[net.Conn]struct{}),
+	}
+}
+
+func (s *Server) ListenAndServe() error {
+	lis, err := net.Listen("tcp", s.address)
+	if err != nil {
+		return err
+	}
+	return s.Serve(lis)
+}
+
+func (s *Server) Serve(lis net.Listener) error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		lis.Close()
+		return net.ErrClosed
+	}
+	s.listener = lis
+	s.mu.Unlock()
+
+	for {
+		conn, err := lis.Accept()
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return nil
+			}
+			return err
+		}
+
+		if !s.track(conn) {
+			conn.Close()
+			return nil
+		}
+		go s.handle(conn)
+	}
+}
+
+// Close stops accepting connections and closes the active ones.
+func (s *Server) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.closed = true
+	for conn := range s.conns {
+		conn.Close()
+	}
+	if s.listener != nil {
+		return s.listener.Close()
+	}
+	return nil
+}
+
+func (s *Server) track(conn net.Conn) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return false
+	}
+	s.conns[conn] = struct{}{}
+	return true
+}
+
+func (s *Server) untrack(conn net.Conn) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.conns, conn)
+}
+
+func (s *Server) handle(conn net.Conn) {
+	defer s.untrack(conn)
+	defer conn.Close()
+
+	r := bufio.NewReader(conn)
+	w := newWriter(conn)
+	// Clients are told apart by address, as unauthenticated gRPC callers.
+	ctx := peer.NewContext(context.Background(), &peer.Peer{Addr: conn.RemoteAddr()})
+
+	for {
+		args, err := readCommand(r)
+		if err != nil {
+			if errors.Is(err, errProtocol) {
+				w.error("ERR " + err.Error())
+				w.flush()
+			} else if !errors.Is(err, io.EOF) && !errors.Is(err, net.ErrClosed) {
+				s.logger.Debug("resp connection error", zap.String("remote", conn.RemoteAddr().String()), zap.Error(err))
+			}
+			return
+		}
+		if len(args) == 0 {
+			continue
+		}
+
+		quit := s.dispatch(ctx, w, args)
+
+		if err := w.flush(); err != nil || quit {
+			return
+		}
+	}
+}
+
+func (s *Server) dispatch(ctx context.Context, w *writer, args []string) bool {
+	name := strings.ToUpper(args[0])
+
+	cmd, ok := commands[name]
+	if !ok {
+		w.error("ERR unknown command '" + args[0] + "'")
+		return false
+	}
+
+	if cmd.arity > 0 && len(args) != cmd.arity || cmd.arity < 0 && len(args) < -cmd.arity {
+		w.error("ERR wrong number of arguments for '" + strings.ToLower(name) + "' command")
+		return false
+	}
+
+	if cmd.write && !s.storageService.IsLeader() {
+		w.error(s.redirect(args))
+		return false
+	}
+
+	if cmd.method != "" && s.admission != nil {
+		release, err := s.admission.Admit(ctx, cmd.method)
+		if err != nil {
+			w.error("ERR " + status.Convert(err).Message())
+			return false
+		}
+		defer release()
+	}
+
+	cmd.handler(s, w, args)
+	return name == "QUIT"
+}
+
+// redirect builds the error returned for writes sent to a replica: MOVED when
+// the leader is known so cluster-aware clients can follow it, READONLY otherwise.
+// Nodes are expected to share the RESP port, only the leader host is taken
+// from its gRPC address.
+func (s *Server) redirect(args []string) string {
+	leaderHost, _, err := net.SplitHostPort(s.storageService.LeaderAddress())
+	_, port, portErr := net.SplitHostPort(s.address)
+	if err != nil || portErr != nil || len(args) < 2 {
+		return "READONLY You can't write against a read only replica."
+	}
+	return "MOVED " + strconv.Itoa(keySlot(args[1])) + " " + net.JoinHostPort(leaderHost, port)
+}
@@ -0,0 +1,257 @@
+package resp_test
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Na322Pr/kv-storage-service/internal/admission"
+	"github.com/Na322Pr/kv-storage-service/internal/app/resp"
+	"github.com/Na322Pr/kv-storage-service/internal/config"
+	"github.com/Na322Pr/kv-storage-service/internal/metrics"
+	"github.com/Na322Pr/kv-storage-service/internal/model"
+	"github.com/Na322Pr/kv-storage-service/internal/service"
+	"github.com/Na322Pr/kv-storage-service/internal/storage"
+	"github.com/prometheus/client_golang/prometheus"
+	"go.uber.org/zap"
+)
+
+// client speaks RESP2 to a server listening on a real socket.
+type client struct {
+	t    *testing.T
+	conn net.Conn
+	r    *bufio.Reader
+}
+
+func start(t *testing.T, leader bool) (*service.StorageService, *client) {
+	t.Helper()
+	return startAdmitted(t, leader, nil)
+}
+
+// startAdmitted starts a server admitting its commands through the controller.
+func startAdmitted(t *testing.T, leader bool, controller *admission.Controller) (*service.StorageService, *client) {
+	t.Helper()
+	store, err := storage.NewStore(storage.NewMemoryEngine(), config.Storage{EvictionPolicy: "noeviction"})
+	if err != nil {
+		t.Fatalf("new store: %v", err)
+	}
+	node := model.NewNode("1", "1", "127.0.0.1:2110")
+	node.SetLeader(leader)
+	ss := service.NewStorageService(store, node, service.NewConnectionManagerService())
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	srv := resp.NewServer(ss, lis.Addr().String(), controller, zap.NewNop())
+	go srv.Serve(lis)
+	t.Cleanup(func() { srv.Close() })
+
+	conn, err := net.Dial("tcp", lis.Addr().String())
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return ss, &client{t: t, conn: conn, r: bufio.NewReader(conn)}
+}
+
+// do sends the command and returns its reply: a string for simple and bulk
+// strings, an int64, nil, a []any or a replyError.
+func (c *client) do(args ...string) any {
+	c.t.Helper()
+	var b strings.Builder
+	fmt.Fprintf(&b, "*%d\r\n", len(args))
+	for _, arg := range args {
+		fmt.Fprintf(&b, "$%d\r\n%s\r\n", len(arg), arg)
+	}
+	if _, err := io.WriteString(c.conn, b.String()); err != nil {
+		c.t.Fatalf("write: %v", err)
+	}
+	reply, err := c.read()
+	if err != nil {
+		c.t.Fatalf("read reply to %s: %v", args[0], err)
+	}
+	return reply
+}
+
+type replyError string
+
+func (c *client) read() (any, error) {
+	line, err := c.r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	line = strings.TrimSuffix(line, "\r\n")
+	if line == "" {
+		return nil, errors.New("empty reply")
+	}
+	switch line[0] {
+	case '+':
+		return line[1:], nil
+	case '-':
+		return replyError(line[1:]), nil
+	case ':':
+		return strconv.ParseInt(line[1:], 10, 64)
+	case '$':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil || n < 0 {
+			return nil, err
+		}
+		buf := make([]byte, n+2)
+		if _, err := io.ReadFull(c.r, buf); err != nil {
+			return nil, err
+		}
+		return string(buf[:n]), nil
+	case '*':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil || n < 0 {
+			return nil, err
+		}
+		items := make([]any, n)
+		for i := range items {
+			if items[i], err = c.read(); err != nil {
+				return nil, err
+			}
+		}
+		return items, nil
+	}
+	return nil, fmt.Errorf("unexpected reply %q", line)
+}
+
+func expect(t *testing.T, got, want any) {
+	t.Helper()
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Fatalf("got %#v, want %#v", got, want)
+	}
+}
+
+func expectError(t *testing.T, got any, prefix string) {
+	t.Helper()
+	if e, ok := got.(replyError); !ok || !strings.HasPrefix(string(e), prefix) {
+		t.Fatalf("got %#v, want an error starting with %q", got, prefix)
+	}
+}
+
+func TestStrings(t *testing.T) {
+	_, c := start(t, true)
+
+	expect(t, c.do("PING"), "PONG")
+	expect(t, c.do("GET", "a"), nil)
+	expect(t, c.do("SET", "a", "1"), "OK")
+	expect(t, c.do("GET", "a"), "1")
+	expect(t, c.do("SET", "a", "2", "NX"), nil)
+	expect(t, c.do("SET", "b", "2", "XX"), nil)
+	expect(t, c.do("SET", "a", "2", "XX"), "OK")
+	expect(t, c.do("EXISTS", "a", "b", "a"), int64(2))
+	expect(t, c.do("INCR", "n"), int64(1))
+	expect(t, c.do("INCR", "a"), int64(3))
+	expectError(t, c.do("INCR", "n", "x"), "ERR wrong number of arguments")
+	expect(t, c.do("DEL", "a", "b", "n"), int64(2))
+	expectError(t, c.do("NOPE"), "ERR unknown command")
+
+	// A value larger than the read buffers goes through whole.
+	large := strings.Repeat("0123456789", 100_000)
+	expect(t, c.do("SET", "large", large), "OK")
+	if got := c.do("GET", "large"); got != large {
+		t.Fatalf("large value came back with %d bytes", len(got.(string)))
+	}
+}
+
+func TestExpire(t *testing.T) {
+	_, c := start(t, true)
+
+	expect(t, c.do("SET", "a", "1", "EX", "100"), "OK")
+	if ttl := c.do("TTL", "a").(int64); ttl < 99 || ttl > 100 {
+		t.Fatalf("ttl %d, want 100", ttl)
+	}
+	expectError(t, c.do("SET", "a", "1", "EX", "9223372036854775807"), "ERR invalid expire time")
+	expect(t, c.do("EXPIRE", "missing", "10"), int64(0))
+	expectError(t, c.do("EXPIRE", "a", "9223372036854775807"), "ERR value is not an integer")
+	expect(t, c.do("EXPIRE", "a", "10"), int64(1))
+	if ttl := c.do("TTL", "a").(int64); ttl < 9 || ttl > 10 {
+		t.Fatalf("ttl %d, want 10", ttl)
+	}
+	expect(t, c.do("EXPIRE", "a", "0"), int64(1))
+	expect(t, c.do("GET", "a"), nil)
+
+	// Reserved keys are refused, not reported missing.
+	expectError(t, c.do("EXPIRE", "__lock/b", "10"), "ERR")
+}
+
+func TestMSetIsAtomic(t *testing.T) {
+	_, c := start(t, true)
+
+	expectError(t, c.do("MSET", "a", "1", "__lock/b", "2"), "ERR")
+	expect(t, c.do("GET", "a"), nil)
+
+	expect(t, c.do("MSET", "a", "1", "b", "2", "a", "3"), "OK")
+	expect(t, c.do("MGET", "a", "b", "c"), []any{"3", "2", nil})
+}
+
+func TestScan(t *testing.T) {
+	ss, c := start(t, true)
+	ctx := context.Background()
+	for i := 0; i < 25; i++ {
+		if _, err := ss.Set(ctx, service.SetMessage{Key: fmt.Sprintf("k%02d", i), Value: "v", Operation: service.OperationSet}); err != nil {
+			t.Fatalf("set: %v", err)
+		}
+	}
+
+	seen := make(map[string]int)
+	cursor := "0"
+	for round := 0; ; round++ {
+		reply := c.do("SCAN", cursor, "MATCH", "k*", "COUNT", "10").([]any)
+		cursor = reply[0].(string)
+		for _, key := range reply[1].([]any) {
+			seen[key.(string)]++
+		}
+		if round == 0 {
+			// Removing keys already returned must not make the scan skip any.
+			for i := 0; i < 5; i++ {
+				if _, _, err := ss.Delete(ctx, "", fmt.Sprintf("k%02d", i)); err != nil {
+					t.Fatalf("delete: %v", err)
+				}
+			}
+		}
+		if cursor == "0" {
+			break
+		}
+	}
+	if len(seen) != 25 {
+		t.Fatalf("scan returned %d keys, want 25", len(seen))
+	}
+	for key, n := range seen {
+		if n != 1 {
+			t.Fatalf("scan returned %s %d times", key, n)
+		}
+	}
+	expectError(t, c.do("SCAN", "999999"), "ERR")
+}
+
+func TestReplicaRedirectsWrites(t *testing.T) {
+	_, c := start(t, false)
+
+	expect(t, c.do("GET", "a"), nil)
+	expectError(t, c.do("SET", "a", "1"), "MOVED ")
+}
+
+func TestAdmissionRejects(t *testing.T) {
+	controller, err := admission.NewController(config.RateLimit{Enabled: true, ClientRate: 0.01, ClientBurst: 1, ClientIdleTimeout: time.Hour},
+		metrics.NewAdmissionMetrics(prometheus.NewRegistry()))
+	if err != nil {
+		t.Fatalf("new controller: %v", err)
+	}
+	_, c := startAdmitted(t, true, controller)
+
+	expect(t, c.do("GET", "a"), nil)
+	expectError(t, c.do("SET", "a", "1"), "ERR ")
+	// Commands outside of the API are not limited.
+	expect(t, c.do("PING"), "PONG")
+}
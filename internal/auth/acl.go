@@ -0,0 +1,87 @@
+package auth
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/Na322Pr/kv-storage-service/internal/config"
+)
+
+type Permission string
+
+const (
+	PermissionRead  Permission = "read"
+	PermissionWrite Permission = "write"
+	PermissionWatch Permission = "watch"
+	// PermissionAdmin implies every other permission on the prefix.
+	PermissionAdmin Permission = "admin"
+)
+
+// AnyNamespace makes a rule apply to every namespace.
+const AnyNamespace = "*"
+
+type rule struct {
+	namespace   string
+	prefix      string
+	permissions []Permission
+}
+
+func (r rule) inNamespace(namespace string) bool {
+	return r.namespace == AnyNamespace || r.namespace == namespace
+}
+
+func (r rule) grants(permission Permission) bool {
+	return slices.Contains(r.permissions, permission) || slices.Contains(r.permissions, PermissionAdmin)
+}
+
+// ACL grants roles permissions on key prefixes of namespaces.
+type ACL struct {
+	roles map[string][]rule
+}
+
+func NewACL(roles map[string][]config.ACLRule) (*ACL, error) {
+	acl := &ACL{roles: make(map[string][]rule, len(roles))}
+	for role, rules := range roles {
+		for _, r := range rules {
+			parsed := rule{namespace: r.Namespace, prefix: r.Prefix}
+			for _, p := range r.Permissions {
+				permission := Permission(p)
+				switch permission {
+				case PermissionRead, PermissionWrite, PermissionWatch, PermissionAdmin:
+				default:
+					return nil, fmt.Errorf("role %q: unknown permission %q", role, p)
+				}
+				parsed.permissions = append(parsed.permissions, permission)
+			}
+			acl.roles[role] = append(acl.roles[role], parsed)
+		}
+	}
+	return acl, nil
+}
+
+// Allowed reports whether the identity holds the permission on the key of the namespace.
+func (a *ACL) Allowed(identity *Identity, permission Permission, namespace, key string) bool {
+	return a.match(identity, permission, func(r rule) bool {
+		return r.inNamespace(namespace) && strings.HasPrefix(key, r.prefix)
+	})
+}
+
+// AllowedPrefix reports whether the identity holds the permission on every
+// key of the namespace starting with prefix.
+func (a *ACL) AllowedPrefix(identity *Identity, permission Permission, namespace, prefix string) bool {
+	return a.match(identity, permission, func(r rule) bool {
+		return r.inNamespace(namespace) && strings.HasPrefix(prefix, r.prefix)
+	})
+}
+
+func (a *ACL) match(identity *Identity, permission Permission, covers func(rule) bool) bool {
+	for _, role := range identity.Roles {
+		for _, r := range a.roles[role] {
+			if covers(r) && r.grants(permission) {
+				return true
+			}
+		}
+	}
+	return false
+}
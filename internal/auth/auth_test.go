@@ -0,0 +1,265 @@
+package auth_test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Na322Pr/kv-storage-service/internal/auth"
+	"github.com/Na322Pr/kv-storage-service/internal/config"
+	"github.com/Na322Pr/kv-storage-service/internal/service"
+	desc "github.com/Na322Pr/kv-storage-service/pkg/api"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+var roles = map[string][]config.ACLRule{
+	"reader": {{Namespace: "", Prefix: "app/", Permissions: []string{"read"}}},
+	"writer": {
+		{Namespace: "", Prefix: "app/", Permissions: []string{"read", "write", "watch"}},
+		{Namespace: "team", Prefix: "", Permissions: []string{"write"}},
+	},
+	"ops":   {{Namespace: auth.AnyNamespace, Prefix: "", Permissions: []string{"read"}}},
+	"admin": {{Namespace: auth.AnyNamespace, Prefix: "", Permissions: []string{"admin"}}},
+}
+
+func TestACL(t *testing.T) {
+	acl, err := auth.NewACL(roles)
+	if err != nil {
+		t.Fatalf("new acl: %v", err)
+	}
+
+	for _, tt := range []struct {
+		role       string
+		permission auth.Permission
+		namespace  string
+		key        string
+		allowed    bool
+	}{
+		{"reader", auth.PermissionRead, "", "app/a", true},
+		{"reader", auth.PermissionWrite, "", "app/a", false},
+		{"reader", auth.PermissionRead, "", "other", false},
+		{"reader", auth.PermissionRead, "team", "app/a", false},
+		{"writer", auth.PermissionWatch, "", "app/a", true},
+		{"writer", auth.PermissionWrite, "team", "anything", true},
+		{"writer", auth.PermissionRead, "team", "anything", false},
+		{"ops", auth.PermissionRead, "any", "key", true},
+		{"ops", auth.PermissionWrite, "any", "key", false},
+		{"admin", auth.PermissionWrite, "any", "key", true},
+		{"unknown", auth.PermissionRead, "", "app/a", false},
+	} {
+		identity := &auth.Identity{Subject: "s", Roles: []string{tt.role}}
+		if got := acl.Allowed(identity, tt.permission, tt.namespace, tt.key); got != tt.allowed {
+			t.Errorf("%s %s %q/%q: %v, want %v", tt.role, tt.permission, tt.namespace, tt.key, got, tt.allowed)
+		}
+	}
+
+	// A prefix is covered only by rules on a prefix of it.
+	reader := &auth.Identity{Roles: []string{"reader"}}
+	if !acl.AllowedPrefix(reader, auth.PermissionRead, "", "app/x") {
+		t.Error("prefix inside the rule refused")
+	}
+	if acl.AllowedPrefix(reader, auth.PermissionRead, "", "ap") {
+		t.Error("prefix wider than the rule allowed")
+	}
+	// Roles add up.
+	both := &auth.Identity{Roles: []string{"reader", "ops"}}
+	if !acl.Allowed(both, auth.PermissionRead, "team", "x") {
+		t.Error("permission of the second role refused")
+	}
+}
+
+func TestNewACLRejectsUnknownPermissions(t *testing.T) {
+	_, err := auth.NewACL(map[string][]config.ACLRule{"r": {{Permissions: []string{"delete"}}}})
+	if err == nil {
+		t.Fatal("unknown permission accepted")
+	}
+}
+
+func withToken(token string) context.Context {
+	return metadata.NewIncomingContext(context.Background(), metadata.Pairs("authorization", "Bearer "+token))
+}
+
+func newAuthorizer(t *testing.T, jwt config.JWT) *auth.Authorizer {
+	t.Helper()
+	a, err := auth.NewAuthorizer(config.Auth{
+		Enabled: true,
+		Tokens: []config.AuthToken{
+			{Token: "reader-token", Subject: "reader", Roles: []string{"reader"}},
+			{Token: "writer-token", Subject: "writer", Roles: []string{"writer"}},
+			{Token: "admin-token", Subject: "admin", Roles: []string{"admin"}},
+			{Token: "cm-token", Subject: "cm", Roles: []string{auth.RoleClusterManager}},
+		},
+		JWT:   jwt,
+		Roles: roles,
+	})
+	if err != nil {
+		t.Fatalf("new authorizer: %v", err)
+	}
+	return a
+}
+
+func TestAuthorizeCall(t *testing.T) {
+	a := newAuthorizer(t, config.JWT{})
+
+	for _, tt := range []struct {
+		name   string
+		token  string
+		method string
+		req    any
+		code   codes.Code
+	}{
+		{"no token", "", desc.KeyValueStorage_Get_FullMethodName, &desc.GetRequest{Key: "app/a"}, codes.Unauthenticated},
+		{"unknown token", "bogus", desc.KeyValueStorage_Get_FullMethodName, &desc.GetRequest{Key: "app/a"}, codes.Unauthenticated},
+		{"read", "reader-token", desc.KeyValueStorage_Get_FullMethodName, &desc.GetRequest{Key: "app/a"}, codes.OK},
+		{"read outside the prefix", "reader-token", desc.KeyValueStorage_Get_FullMethodName, &desc.GetRequest{Key: "b"}, codes.PermissionDenied},
+		{"write without permission", "reader-token", desc.KeyValueStorage_Set_FullMethodName, &desc.SetRequest{Key: "app/a"}, codes.PermissionDenied},
+		{"write", "writer-token", desc.KeyValueStorage_Set_FullMethodName, &desc.SetRequest{Key: "app/a"}, codes.OK},
+		{"write in a namespace", "writer-token", desc.KeyValueStorage_Delete_FullMethodName, &desc.DeleteRequest{Namespace: "team", Key: "x"}, codes.OK},
+		{"scan the prefix", "reader-token", desc.KeyValueStorage_Scan_FullMethodName, &desc.ScanRequest{Prefix: "app/"}, codes.OK},
+		{"scan everything", "reader-token", desc.KeyValueStorage_Scan_FullMethodName, &desc.ScanRequest{}, codes.PermissionDenied},
+		{"watch a prefix", "writer-token", desc.KeyValueStorage_Watch_FullMethodName, &desc.WatchRequest{Key: "app/", Prefix: true}, codes.OK},
+		{"watch without permission", "reader-token", desc.KeyValueStorage_Watch_FullMethodName, &desc.WatchRequest{Key: "app/a"}, codes.PermissionDenied},
+		// Locks are checked on their key, outside of the user prefixes.
+		{"lock", "writer-token", desc.KeyValueStorage_Lock_FullMethodName, &desc.LockRequest{Name: "app/job"}, codes.PermissionDenied},
+		{"lock as admin", "admin-token", desc.KeyValueStorage_Lock_FullMethodName, &desc.LockRequest{Name: "app/job"}, codes.OK},
+		{"lease", "reader-token", desc.KeyValueStorage_LeaseGrant_FullMethodName, &desc.LeaseGrantRequest{}, codes.OK},
+		{"cluster method", "admin-token", desc.KeyValueStorage_UpdateLeader_FullMethodName, &desc.UpdateLeaderRequest{}, codes.PermissionDenied},
+		{"cluster method by its role", "cm-token", desc.KeyValueStorage_UpdateLeader_FullMethodName, &desc.UpdateLeaderRequest{}, codes.OK},
+		{"admin method", "writer-token", desc.KeyValueStorage_CreateNamespace_FullMethodName, &desc.CreateNamespaceRequest{}, codes.PermissionDenied},
+		{"admin method as admin", "admin-token", desc.KeyValueStorage_CreateNamespace_FullMethodName, &desc.CreateNamespaceRequest{}, codes.OK},
+		{"health", "", "/grpc.health.v1.Health/Check", nil, codes.OK},
+	} {
+		ctx := context.Background()
+		if tt.token != "" {
+			ctx = withToken(tt.token)
+		}
+		_, err := a.AuthorizeCall(ctx, tt.method, tt.req)
+		if code := status.Code(err); code != tt.code {
+			t.Errorf("%s: %s (%v), want %s", tt.name, code, err, tt.code)
+		}
+	}
+
+	if service.LockKey("app/job") == "app/job" {
+		t.Fatal("lock keys share the user key space")
+	}
+}
+
+func TestAdminHandler(t *testing.T) {
+	a := newAuthorizer(t, config.JWT{})
+	h := a.AdminHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	for token, want := range map[string]int{
+		"":             http.StatusUnauthorized,
+		"writer-token": http.StatusForbidden,
+		"admin-token":  http.StatusNoContent,
+	} {
+		req := httptest.NewRequest(http.MethodPut, "/admin/log/level", nil)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		if rec.Code != want {
+			t.Errorf("token %q: %d, want %d", token, rec.Code, want)
+		}
+	}
+}
+
+// signer issues ES256 JWTs verified through a JWKS file.
+type signer struct {
+	key  *ecdsa.PrivateKey
+	jwks string
+}
+
+func newSigner(t *testing.T) signer {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	encode := func(n *big.Int) string {
+		return base64.RawURLEncoding.EncodeToString(n.FillBytes(make([]byte, 32)))
+	}
+	data, err := json.Marshal(map[string]any{"keys": []map[string]string{{
+		"kty": "EC", "kid": "k1", "crv": "P-256", "x": encode(key.X), "y": encode(key.Y),
+	}}})
+	if err != nil {
+		t.Fatalf("marshal jwks: %v", err)
+	}
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatalf("write jwks: %v", err)
+	}
+	return signer{key: key, jwks: path}
+}
+
+func (s signer) sign(t *testing.T, claims map[string]any) string {
+	t.Helper()
+	segment := func(v any) string {
+		data, err := json.Marshal(v)
+		if err != nil {
+			t.Fatalf("marshal: %v", err)
+		}
+		return base64.RawURLEncoding.EncodeToString(data)
+	}
+	signed := segment(map[string]string{"alg": "ES256", "kid": "k1"}) + "." + segment(claims)
+	digest := sha256.Sum256([]byte(signed))
+	r, sig, err := ecdsa.Sign(rand.Reader, s.key, digest[:])
+	if err != nil {
+		t.Fatalf("sign: %v", err)
+	}
+	signature := append(r.FillBytes(make([]byte, 32)), sig.FillBytes(make([]byte, 32))...)
+	return signed + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+func TestJWT(t *testing.T) {
+	s := newSigner(t)
+	a := newAuthorizer(t, config.JWT{JWKSFile: s.jwks, Issuer: "issuer", Audience: "kv", RolesClaim: "roles"})
+	get := &desc.GetRequest{Key: "app/a"}
+	exp := time.Now().Add(time.Hour).Unix()
+
+	for _, tt := range []struct {
+		name   string
+		claims map[string]any
+		code   codes.Code
+	}{
+		{"valid", map[string]any{"sub": "alice", "iss": "issuer", "aud": "kv", "exp": exp, "roles": []string{"reader"}}, codes.OK},
+		{"roles as a string", map[string]any{"sub": "alice", "iss": "issuer", "aud": []string{"x", "kv"}, "exp": exp, "roles": "ops reader"}, codes.OK},
+		{"no role", map[string]any{"sub": "alice", "iss": "issuer", "aud": "kv", "exp": exp}, codes.PermissionDenied},
+		{"expired", map[string]any{"sub": "alice", "iss": "issuer", "aud": "kv", "exp": time.Now().Add(-time.Hour).Unix(), "roles": []string{"reader"}}, codes.Unauthenticated},
+		{"not valid yet", map[string]any{"sub": "alice", "iss": "issuer", "aud": "kv", "exp": exp, "nbf": exp, "roles": []string{"reader"}}, codes.Unauthenticated},
+		{"other issuer", map[string]any{"sub": "alice", "iss": "other", "aud": "kv", "exp": exp, "roles": []string{"reader"}}, codes.Unauthenticated},
+		{"other audience", map[string]any{"sub": "alice", "iss": "issuer", "aud": "other", "exp": exp, "roles": []string{"reader"}}, codes.Unauthenticated},
+		{"no expiry", map[string]any{"sub": "alice", "iss": "issuer", "aud": "kv", "roles": []string{"reader"}}, codes.Unauthenticated},
+	} {
+		_, err := a.AuthorizeCall(withToken(s.sign(t, tt.claims)), desc.KeyValueStorage_Get_FullMethodName, get)
+		if code := status.Code(err); code != tt.code {
+			t.Errorf("%s: %s (%v), want %s", tt.name, code, err, tt.code)
+		}
+	}
+
+	// A token signed by another key is rejected, not skipped.
+	forged := newSigner(t).sign(t, map[string]any{"sub": "eve", "iss": "issuer", "aud": "kv", "exp": exp, "roles": []string{"admin"}})
+	if _, err := a.AuthorizeCall(withToken(forged), desc.KeyValueStorage_Get_FullMethodName, get); status.Code(err) != codes.Unauthenticated {
+		t.Fatalf("forged token: %v", err)
+	}
+	// Static tokens still work next to JWTs.
+	if _, err := a.AuthorizeCall(withToken("reader-token"), desc.KeyValueStorage_Get_FullMethodName, get); err != nil {
+		t.Fatalf("static token: %v", err)
+	}
+}
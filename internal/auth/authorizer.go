@@ -0,0 +1,258 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/Na322Pr/kv-storage-service/internal/config"
+	"github.com/Na322Pr/kv-storage-service/internal/service"
+	"github.com/Na322Pr/kv-storage-service/internal/storage"
+	desc "github.com/Na322Pr/kv-storage-service/pkg/api"
+	descv2 "github.com/Na322Pr/kv-storage-service/pkg/api/v2"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// methodRoles restricts cluster RPCs to the roles allowed to call them.
+var methodRoles = map[string][]string{
+	desc.KeyValueStorage_SetStream_FullMethodName:       {RoleNode},
+	desc.KeyValueStorage_LeMeta_FullMethodName:          {RoleNode, RoleClusterManager},
+	desc.KeyValueStorage_UpdateLeader_FullMethodName:    {RoleClusterManager},
+	desc.KeyValueStorage_UpdateAddresses_FullMethodName: {RoleClusterManager},
+}
+
+// keyedMethods are checked against the ACL for every request message.
+var keyedMethods = map[string]bool{
+	desc.KeyValueStorage_Get_FullMethodName:       true,
+	desc.KeyValueStorage_Set_FullMethodName:       true,
+	desc.KeyValueStorage_Delete_FullMethodName:    true,
+	desc.KeyValueStorage_Scan_FullMethodName:      true,
+	desc.KeyValueStorage_Watch_FullMethodName:     true,
+	desc.KeyValueStorage_Increment_FullMethodName: true,
+	desc.KeyValueStorage_Decrement_FullMethodName: true,
+	desc.KeyValueStorage_Lock_FullMethodName:      true,
+	desc.KeyValueStorage_Unlock_FullMethodName:    true,
+	desc.KeyValueStorage_History_FullMethodName:   true,
+
+	descv2.KeyValueStorage_Get_FullMethodName:      true,
+	descv2.KeyValueStorage_Set_FullMethodName:      true,
+	descv2.KeyValueStorage_Delete_FullMethodName:   true,
+	descv2.KeyValueStorage_Scan_FullMethodName:     true,
+	descv2.KeyValueStorage_PutLarge_FullMethodName: true,
+	descv2.KeyValueStorage_GetLarge_FullMethodName: true,
+}
+
+// authenticatedMethods only need a known caller.
+var authenticatedMethods = map[string]bool{
+	desc.KeyValueStorage_LeaseGrant_FullMethodName:     true,
+	desc.KeyValueStorage_LeaseKeepAlive_FullMethodName: true,
+	desc.KeyValueStorage_LeaseRevoke_FullMethodName:    true,
+}
+
+// healthService stays reachable without credentials for probes.
+const healthService = "/grpc.health.v1.Health/"
+
+// Authorizer authenticates callers with the configured authenticators and
+// checks them against the ACL. Methods not listed above require the admin
+// permission on the whole key space.
+type Authorizer struct {
+	authenticators []Authenticator
+	acl            *ACL
+}
+
+func NewAuthorizer(cfg config.Auth) (*Authorizer, error) {
+	acl, err := NewACL(cfg.Roles)
+	if err != nil {
+		return nil, err
+	}
+
+	a := &Authorizer{acl: acl}
+	if len(cfg.Tokens) > 0 {
+		a.authenticators = append(a.authenticators, NewTokenAuthenticator(cfg.Tokens))
+	}
+	if cfg.JWT.JWKSFile != "" {
+		jwt, err := NewJWTAuthenticator(cfg.JWT)
+		if err != nil {
+			return nil, err
+		}
+		a.authenticators = append(a.authenticators, jwt)
+	}
+	if len(cfg.CertificateRoles) > 0 {
+		a.authenticators = append(a.authenticators, NewCertificateAuthenticator(cfg.CertificateRoles))
+	}
+	return a, nil
+}
+
+func (a *Authorizer) authenticate(ctx context.Context) (*Identity, error) {
+	for _, authenticator := range a.authenticators {
+		identity, err := authenticator.Authenticate(ctx)
+		if err == nil {
+			return identity, nil
+		}
+		if !errors.Is(err, ErrNoCredentials) {
+			return nil, status.Error(codes.Unauthenticated, err.Error())
+		}
+	}
+	return nil, status.Error(codes.Unauthenticated, "credentials required")
+}
+
+// Authorize authenticates the caller of the method and returns the context
+// carrying its identity. Key permissions are checked by AuthorizeRequest.
+func (a *Authorizer) Authorize(ctx context.Context, method string) (context.Context, error) {
+	if strings.HasPrefix(method, healthService) {
+		return ctx, nil
+	}
+
+	identity, err := a.authenticate(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if roles, ok := methodRoles[method]; ok {
+		if !slices.ContainsFunc(roles, identity.HasRole) {
+			return nil, status.Errorf(codes.PermissionDenied, "%s requires one of the roles %v", method, roles)
+		}
+	} else if !keyedMethods[method] && !authenticatedMethods[method] {
+		if !a.acl.AllowedPrefix(identity, PermissionAdmin, storage.DefaultNamespace, "") {
+			return nil, status.Errorf(codes.PermissionDenied, "%s requires admin permission", method)
+		}
+	}
+
+	return WithIdentity(ctx, identity), nil
+}
+
+// AuthorizeRequest checks the permission the request needs on its key or prefix.
+func (a *Authorizer) AuthorizeRequest(ctx context.Context, method string, req any) error {
+	if !keyedMethods[method] {
+		return nil
+	}
+	identity, ok := FromContext(ctx)
+	if !ok {
+		return status.Error(codes.Unauthenticated, "credentials required")
+	}
+
+	var allowed bool
+	switch r := req.(type) {
+	case *desc.GetRequest:
+		allowed = a.acl.Allowed(identity, PermissionRead, r.Namespace, r.Key)
+	case *desc.SetRequest:
+		allowed = a.acl.Allowed(identity, PermissionWrite, r.Namespace, r.Key)
+	case *desc.DeleteRequest:
+		allowed = a.acl.Allowed(identity, PermissionWrite, r.Namespace, r.Key)
+	case *desc.CounterRequest:
+		allowed = a.acl.Allowed(identity, PermissionWrite, r.Namespace, r.Key)
+	case *desc.HistoryRequest:
+		allowed = a.acl.Allowed(identity, PermissionRead, r.Namespace, r.Key)
+	case *desc.ScanRequest:
+		allowed = a.acl.AllowedPrefix(identity, PermissionRead, r.Namespace, r.Prefix)
+	case *desc.WatchRequest:
+		if r.Prefix {
+			allowed = a.acl.AllowedPrefix(identity, PermissionWatch, r.Namespace, r.Key)
+		} else {
+			allowed = a.acl.Allowed(identity, PermissionWatch, r.Namespace, r.Key)
+		}
+	case *desc.LockRequest:
+		allowed = a.acl.Allowed(identity, PermissionWrite, r.Namespace, service.LockKey(r.Name))
+	case *desc.UnlockRequest:
+		allowed = a.acl.Allowed(identity, PermissionWrite, r.Namespace, service.LockKey(r.Name))
+	case *descv2.GetRequest:
+		allowed = a.acl.Allowed(identity, PermissionRead, r.Namespace, r.Key)
+	case *descv2.SetRequest:
+		allowed = a.acl.Allowed(identity, PermissionWrite, r.Namespace, r.Key)
+	case *descv2.DeleteRequest:
+		allowed = a.acl.Allowed(identity, PermissionWrite, r.Namespace, r.Key)
+	case *descv2.ScanRequest:
+		allowed = a.acl.AllowedPrefix(identity, PermissionRead, r.Namespace, r.Prefix)
+	case *descv2.GetLargeRequest:
+		allowed = a.acl.Allowed(identity, PermissionRead, r.Namespace, r.Key)
+	case *descv2.PutLargeRequest:
+		// Only the header names the key, the handler takes it first.
+		if header := r.GetHeader(); header != nil {
+			allowed = a.acl.Allowed(identity, PermissionWrite, header.Namespace, header.Key)
+		} else {
+			allowed = true
+		}
+	}
+
+	if !allowed {
+		return status.Errorf(codes.PermissionDenied, "%s is not allowed for %q", method, identity.Subject)
+	}
+	return nil
+}
+
+// AuthorizeCall runs both checks for a single request, as unary calls and
+// the HTTP gateway do.
+func (a *Authorizer) AuthorizeCall(ctx context.Context, method string, req any) (context.Context, error) {
+	ctx, err := a.Authorize(ctx, method)
+	if err != nil {
+		return nil, err
+	}
+	if err := a.AuthorizeRequest(ctx, method, req); err != nil {
+		return nil, err
+	}
+	return ctx, nil
+}
+
+// AdminHandler serves h to the callers with the admin permission on the
+// whole key space, read from the Authorization header or from a client
+// certificate verified beforehand.
+func (a *Authorizer) AdminHandler(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := metadata.NewIncomingContext(r.Context(), metadata.Pairs("authorization", r.Header.Get("Authorization")))
+		// Paths are not methods of the ACL, they need the admin permission.
+		if _, err := a.Authorize(ctx, r.URL.Path); err != nil {
+			code := http.StatusForbidden
+			if status.Code(err) == codes.Unauthenticated {
+				code = http.StatusUnauthorized
+			}
+			http.Error(w, status.Convert(err).Message(), code)
+			return
+		}
+		h.ServeHTTP(w, r)
+	})
+}
+
+func (a *Authorizer) UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		ctx, err := a.AuthorizeCall(ctx, info.FullMethod, req)
+		if err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+func (a *Authorizer) StreamServerInterceptor() grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, err := a.Authorize(ss.Context(), info.FullMethod)
+		if err != nil {
+			return err
+		}
+		return handler(srv, &serverStream{ServerStream: ss, ctx: ctx, authorizer: a, method: info.FullMethod})
+	}
+}
+
+// serverStream checks every received message, as stream requests are not
+// known when the stream opens.
+type serverStream struct {
+	grpc.ServerStream
+	ctx        context.Context
+	authorizer *Authorizer
+	method     string
+}
+
+func (s *serverStream) Context() context.Context {
+	return s.ctx
+}
+
+func (s *serverStream) RecvMsg(m any) error {
+	if err := s.ServerStream.RecvMsg(m); err != nil {
+		return err
+	}
+	return s.authorizer.AuthorizeRequest(s.ctx, s.method, m)
+}
@@ -0,0 +1,50 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"slices"
+)
+
+var (
+	// ErrNoCredentials is returned by an authenticator that found nothing it can check.
+	ErrNoCredentials      = errors.New("no credentials")
+	ErrInvalidCredentials = errors.New("invalid credentials")
+)
+
+// Roles with a meaning of their own, besides the ones granted by ACL rules.
+const (
+	// RoleClusterManager may move leadership and change the replica set.
+	RoleClusterManager = "cluster-manager"
+	// RoleNode is held by cluster members replicating to each other.
+	RoleNode = "node"
+)
+
+// Identity is the authenticated caller.
+type Identity struct {
+	Subject string
+	Roles   []string
+	// Method names the authenticator that accepted the caller: token, jwt or certificate.
+	Method string
+}
+
+func (i *Identity) HasRole(role string) bool {
+	return slices.Contains(i.Roles, role)
+}
+
+// Authenticator extracts and checks the caller credentials from the incoming
+// request context.
+type Authenticator interface {
+	Authenticate(ctx context.Context) (*Identity, error)
+}
+
+type identityKey struct{}
+
+func WithIdentity(ctx context.Context, identity *Identity) context.Context {
+	return context.WithValue(ctx, identityKey{}, identity)
+}
+
+func FromContext(ctx context.Context) (*Identity, bool) {
+	identity, ok := ctx.Value(identityKey{}).(*Identity)
+	return identity, ok
+}
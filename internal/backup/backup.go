@@ -0,0 +1,305 @@
+// Package backup reads and writes backup files.
+//
+// A backup file starts with the 8 bytes "KVBACKUP" and a format version
+// byte, followed by records:
+//
+//	uvarint length | kv_storage_service.BackupRecord protobuf | crc32c
+//
+// The crc32c (Castagnoli) of the protobuf bytes is big-endian. The records
+// come in the order the Backup RPC streams them:
+//
+//   - a header with the format version, the revision the snapshot was taken
+//     at, the leader epoch, the creation time and the node,
+//   - every namespace with its quota,
+//   - the entries of the live keys by namespace and key, with the value
+//     uncompressed and the revision of the last change. A large value is
+//     not in its entry, chunk records with its bytes follow the entry,
+//   - a trailer with the number of namespaces and entries and a SHA-256
+//     digest of them, see Digest.
+//
+// A file without the trailer or with a wrong digest is incomplete and is
+// not restored.
+package backup
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash"
+	"hash/crc32"
+	"io"
+	"math"
+
+	desc "github.com/Na322Pr/kv-storage-service/pkg/api"
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	magic = "KVBACKUP"
+	// Version is the format version written to the file and the header.
+	Version = 1
+	// maxRecordSize bounds a record, chunks of large values are far smaller.
+	maxRecordSize = 64 << 20
+)
+
+var (
+	ErrNotBackup  = errors.New("not a backup file")
+	ErrVersion    = errors.New("unsupported backup format version")
+	ErrCorrupt    = errors.New("backup record is corrupt")
+	ErrIncomplete = errors.New("backup is incomplete")
+	ErrChecksum   = errors.New("backup checksum mismatch")
+)
+
+var castagnoli = crc32.MakeTable(crc32.Castagnoli)
+
+// Writer writes the records of a backup file.
+type Writer struct {
+	w   *bufio.Writer
+	buf []byte
+}
+
+// NewWriter writes the file header to w.
+func NewWriter(w io.Writer) (*Writer, error) {
+	bw := bufio.NewWriter(w)
+	if _, err := bw.WriteString(magic); err != nil {
+		return nil, err
+	}
+	if err := bw.WriteByte(Version); err != nil {
+		return nil, err
+	}
+	return &Writer{w: bw}, nil
+}
+
+func (w *Writer) Write(record *desc.BackupRecord) error {
+	data, err := proto.Marshal(record)
+	if err != nil {
+		return err
+	}
+	w.buf = binary.AppendUvarint(w.buf[:0], uint64(len(data)))
+	w.buf = append(w.buf, data...)
+	w.buf = binary.BigEndian.AppendUint32(w.buf, crc32.Checksum(data, castagnoli))
+	_, err = w.w.Write(w.buf)
+	return err
+}
+
+// Flush writes the buffered records to the underlying writer.
+func (w *Writer) Flush() error {
+	return w.w.Flush()
+}
+
+// Reader reads the records of a backup file.
+type Reader struct {
+	r *bufio.Reader
+}
+
+// NewReader checks the file header of r.
+func NewReader(r io.Reader) (*Reader, error) {
+	br := bufio.NewReader(r)
+	header := make([]byte, len(magic)+1)
+	if _, err := io.ReadFull(br, header); err != nil {
+		return nil, ErrNotBackup
+	}
+	if string(header[:len(magic)]) != magic {
+		return nil, ErrNotBackup
+	}
+	if header[len(magic)] != Version {
+		return nil, fmt.Errorf("%w: %d", ErrVersion, header[len(magic)])
+	}
+	return &Reader{r: br}, nil
+}
+
+// Read returns the next record, io.EOF after the last one.
+func (r *Reader) Read() (*desc.BackupRecord, error) {
+	n, err := binary.ReadUvarint(r.r)
+	if errors.Is(err, io.EOF) {
+		return nil, io.EOF
+	}
+	if err != nil || n > maxRecordSize {
+		return nil, ErrCorrupt
+	}
+	data := make([]byte, n+4)
+	if _, err := io.ReadFull(r.r, data); err != nil {
+		return nil, ErrCorrupt
+	}
+	if crc32.Checksum(data[:n], castagnoli) != binary.BigEndian.Uint32(data[n:]) {
+		return nil, ErrCorrupt
+	}
+	var record desc.BackupRecord
+	if err := proto.Unmarshal(data[:n], &record); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrCorrupt, err)
+	}
+	return &record, nil
+}
+
+// Digest hashes the namespaces, entries and chunks of a backup in a fixed
+// layout, independent of their protobuf encoding: a tag byte followed by
+// the fields, strings and bytes prefixed with their uvarint length and
+// integers as uvarints of their bits.
+type Digest struct {
+	h   hash.Hash
+	buf []byte
+}
+
+func NewDigest() *Digest {
+	return &Digest{h: sha256.New()}
+}
+
+// Add hashes the record, headers and trailers are not part of the digest.
+func (d *Digest) Add(record *desc.BackupRecord) {
+	buf := d.buf[:0]
+	switch r := record.Record.(type) {
+	case *desc.BackupRecord_Namespace:
+		quota := r.Namespace.GetQuota()
+		buf = append(buf, 'n')
+		buf = appendString(buf, r.Namespace.Name)
+		buf = binary.AppendUvarint(buf, uint64(quota.GetMaxKeys()))
+		buf = binary.AppendUvarint(buf, uint64(quota.GetMaxBytes()))
+		buf = binary.AppendUvarint(buf, math.Float64bits(quota.GetRequestsPerSecond()))
+		buf = appendString(buf, quota.GetCompression())
+	case *desc.BackupRecord_Entry:
+		e := r.Entry
+		buf = append(buf, 'e')
+		buf = appendString(buf, e.Namespace)
+		buf = appendString(buf, e.Key)
+		buf = appendString(buf, string(e.Value))
+		buf = appendString(buf, e.ContentType)
+		buf = binary.AppendUvarint(buf, uint64(e.Expiration))
+		buf = binary.AppendUvarint(buf, uint64(e.Revision))
+		if e.Large {
+			buf = append(buf, 1)
+		} else {
+			buf = append(buf, 0)
+		}
+		buf = binary.AppendUvarint(buf, uint64(e.Size))
+	case *desc.BackupRecord_Chunk:
+		buf = append(buf, 'c')
+		buf = binary.AppendUvarint(buf, uint64(len(r.Chunk)))
+		_, _ = d.h.Write(buf)
+		_, _ = d.h.Write(r.Chunk)
+		d.buf = buf
+		return
+	default:
+		return
+	}
+	_, _ = d.h.Write(buf)
+	d.buf = buf
+}
+
+func (d *Digest) Sum() []byte {
+	return d.h.Sum(nil)
+}
+
+func appendString(buf []byte, s string) []byte {
+	buf = binary.AppendUvarint(buf, uint64(len(s)))
+	return append(buf, s...)
+}
+
+// Checker follows the records of a backup and checks that they come in
+// order, that large values are complete and that the trailer matches.
+type Checker struct {
+	digest     *Digest
+	header     *desc.BackupHeader
+	namespaces int64
+	entries    int64
+	// pending is the part of the last large value still to come in chunks.
+	pending   int64
+	trailer   bool
+	inEntries bool
+}
+
+func NewChecker() *Checker {
+	return &Checker{digest: NewDigest()}
+}
+
+// Check takes the next record.
+func (c *Checker) Check(record *desc.BackupRecord) error {
+	if c.trailer {
+		return fmt.Errorf("%w: record after the trailer", ErrCorrupt)
+	}
+	if c.header == nil {
+		header := record.GetHeader()
+		if header == nil {
+			return fmt.Errorf("%w: missing header", ErrCorrupt)
+		}
+		if header.Version != Version {
+			return fmt.Errorf("%w: %d", ErrVersion, header.Version)
+		}
+		c.header = header
+		return nil
+	}
+
+	if chunk, ok := record.Record.(*desc.BackupRecord_Chunk); ok {
+		if int64(len(chunk.Chunk)) > c.pending {
+			return fmt.Errorf("%w: unexpected chunk", ErrCorrupt)
+		}
+		c.pending -= int64(len(chunk.Chunk))
+		c.digest.Add(record)
+		return nil
+	}
+	if c.pending > 0 {
+		return fmt.Errorf("%w: large value is missing %d bytes", ErrCorrupt, c.pending)
+	}
+
+	switch r := record.Record.(type) {
+	case *desc.BackupRecord_Namespace:
+		if c.inEntries {
+			return fmt.Errorf("%w: namespace after the entries", ErrCorrupt)
+		}
+		c.namespaces++
+	case *desc.BackupRecord_Entry:
+		if r.Entry.Large {
+			if r.Entry.Size < 0 || len(r.Entry.Value) > 0 {
+				return fmt.Errorf("%w: large value of %q", ErrCorrupt, r.Entry.Key)
+			}
+			c.pending = r.Entry.Size
+		}
+		c.inEntries = true
+		c.entries++
+	case *desc.BackupRecord_Trailer:
+		c.trailer = true
+		if r.Trailer.Namespaces != c.namespaces || r.Trailer.Entries != c.entries {
+			return fmt.Errorf("%w: %d namespaces and %d entries, trailer has %d and %d",
+				ErrChecksum, c.namespaces, c.entries, r.Trailer.Namespaces, r.Trailer.Entries)
+		}
+		if !bytes.Equal(r.Trailer.Checksum, c.digest.Sum()) {
+			return ErrChecksum
+		}
+		return nil
+	default:
+		return fmt.Errorf("%w: unexpected record", ErrCorrupt)
+	}
+	c.digest.Add(record)
+	return nil
+}
+
+// Done reports an error unless the trailer was checked.
+func (c *Checker) Done() error {
+	if !c.trailer {
+		return ErrIncomplete
+	}
+	return nil
+}
+
+// Header returns the header, nil before the first record.
+func (c *Checker) Header() *desc.BackupHeader {
+	return c.header
+}
+
+// Pending returns the number of bytes of the last large value still to
+// come in chunks.
+func (c *Checker) Pending() int64 {
+	return c.pending
+}
+
+// Entries returns the number of entries checked so far.
+func (c *Checker) Entries() int64 {
+	return c.entries
+}
+
+// Namespaces returns the number of namespaces checked so far.
+func (c *Checker) Namespaces() int64 {
+	return c.namespaces
+}
@@ -0,0 +1,250 @@
+package backup_test
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Na322Pr/kv-storage-service/internal/backup"
+	"github.com/Na322Pr/kv-storage-service/internal/config"
+	"github.com/Na322Pr/kv-storage-service/internal/storage"
+	desc "github.com/Na322Pr/kv-storage-service/pkg/api"
+)
+
+func newStore(t *testing.T) *storage.Store {
+	t.Helper()
+	s, err := storage.NewStore(storage.NewMemoryEngine(), config.Storage{EvictionPolicy: "noeviction", MaxValueSize: 64 << 20})
+	if err != nil {
+		t.Fatalf("new store: %v", err)
+	}
+	t.Cleanup(func() { s.Close() })
+	return s
+}
+
+func set(t *testing.T, s *storage.Store, ns, key string, item storage.Item) {
+	t.Helper()
+	if _, err := s.SetUntil(ns, key, item); err != nil {
+		t.Fatalf("set %s/%s: %v", ns, key, err)
+	}
+}
+
+// large is a value split over several chunk records.
+var large = bytes.Repeat([]byte("0123456789"), 250_000)
+
+// source returns a store with namespaces, plain keys and a large value.
+func source(t *testing.T) *storage.Store {
+	t.Helper()
+	s := newStore(t)
+	if _, err := s.CreateNamespace("team", storage.Quota{MaxKeys: 100, Compression: "zstd"}); err != nil {
+		t.Fatalf("create namespace: %v", err)
+	}
+	set(t, s, "", "a", storage.Item{Value: "1", ContentType: "text/plain"})
+	set(t, s, "", "b", storage.Item{Value: "2", Expiration: time.Now().Add(time.Hour).UnixNano()})
+	set(t, s, "team", "c", storage.Item{Value: "3"})
+
+	blob, err := s.NewBlob("team", 0)
+	if err != nil {
+		t.Fatalf("new blob: %v", err)
+	}
+	if _, err := blob.Write(large); err != nil {
+		t.Fatalf("write blob: %v", err)
+	}
+	set(t, s, "team", "big", storage.Item{Blob: blob.ID(), Size: blob.Size()})
+	return s
+}
+
+// records returns the records of a backup of the store.
+func records(t *testing.T, s *storage.Store) []*desc.BackupRecord {
+	t.Helper()
+	dump, err := s.Dump()
+	if err != nil {
+		t.Fatalf("dump: %v", err)
+	}
+	defer dump.Release()
+	var out []*desc.BackupRecord
+	_, err = backup.Stream(dump, "node1", func(record *desc.BackupRecord) error {
+		out = append(out, record)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("stream: %v", err)
+	}
+	return out
+}
+
+func encode(t *testing.T, records []*desc.BackupRecord) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	w, err := backup.NewWriter(&buf)
+	if err != nil {
+		t.Fatalf("new writer: %v", err)
+	}
+	for _, record := range records {
+		if err := w.Write(record); err != nil {
+			t.Fatalf("write: %v", err)
+		}
+	}
+	if err := w.Flush(); err != nil {
+		t.Fatalf("flush: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func load(t *testing.T, data []byte) (*storage.Store, *desc.BackupHeader, error) {
+	t.Helper()
+	r, err := backup.NewReader(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("new reader: %v", err)
+	}
+	s := newStore(t)
+	header, err := backup.Load(r, s)
+	return s, header, err
+}
+
+func TestRoundTrip(t *testing.T) {
+	src := source(t)
+	recs := records(t, src)
+	chunks := 0
+	for _, record := range recs {
+		if record.GetChunk() != nil {
+			chunks++
+		}
+	}
+	if chunks < 2 {
+		t.Fatalf("large value sent in %d chunks", chunks)
+	}
+
+	dst, header, err := load(t, encode(t, recs))
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	if header.NodeId != "node1" || header.Revision != recs[0].GetHeader().Revision {
+		t.Fatalf("header %+v", header)
+	}
+
+	info, ok := dst.Namespace("team")
+	if !ok || info.Quota.MaxKeys != 100 || info.Quota.Compression != "zstd" {
+		t.Fatalf("namespace team: %+v %v", info, ok)
+	}
+	for _, key := range []struct{ ns, key string }{{"", "a"}, {"", "b"}, {"team", "c"}} {
+		want, _ := src.Get(key.ns, key.key)
+		got, ok := dst.Get(key.ns, key.key)
+		if !ok || got.Value != want.Value || got.ContentType != want.ContentType ||
+			got.Expiration != want.Expiration || got.Revision != want.Revision {
+			t.Fatalf("%s/%s: %+v, want %+v", key.ns, key.key, got, want)
+		}
+	}
+	item, ok := dst.Get("team", "big")
+	if !ok || !item.Large() {
+		t.Fatalf("large value: %+v %v", item, ok)
+	}
+	var value []byte
+	err = dst.ReadBlob("team", item, func(chunk []byte) error {
+		value = append(value, chunk...)
+		return nil
+	})
+	if err != nil || !bytes.Equal(value, large) {
+		t.Fatalf("large value read %d bytes: %v", len(value), err)
+	}
+
+	// New writes continue after the revision of the backup.
+	revision, err := dst.SetUntil("", "d", storage.Item{Value: "4"})
+	if err != nil || revision <= header.Revision {
+		t.Fatalf("write after restore at %d: %v", revision, err)
+	}
+}
+
+func TestChecksum(t *testing.T) {
+	src := source(t)
+
+	for _, tt := range []struct {
+		name   string
+		change func([]*desc.BackupRecord) []*desc.BackupRecord
+		err    error
+	}{
+		{"changed value", func(recs []*desc.BackupRecord) []*desc.BackupRecord {
+			for _, record := range recs {
+				if entry := record.GetEntry(); entry != nil && entry.Key == "a" {
+					entry.Value = []byte("9")
+				}
+			}
+			return recs
+		}, backup.ErrChecksum},
+		{"missing entry", func(recs []*desc.BackupRecord) []*desc.BackupRecord {
+			for i, record := range recs {
+				if entry := record.GetEntry(); entry != nil && entry.Key == "c" {
+					return append(recs[:i:i], recs[i+1:]...)
+				}
+			}
+			return recs
+		}, backup.ErrChecksum},
+		{"missing chunk", func(recs []*desc.BackupRecord) []*desc.BackupRecord {
+			for i, record := range recs {
+				if record.GetChunk() != nil {
+					return append(recs[:i:i], recs[i+1:]...)
+				}
+			}
+			return recs
+		}, backup.ErrCorrupt},
+		{"no trailer", func(recs []*desc.BackupRecord) []*desc.BackupRecord {
+			return recs[:len(recs)-1]
+		}, backup.ErrIncomplete},
+		{"no header", func(recs []*desc.BackupRecord) []*desc.BackupRecord {
+			return recs[1:]
+		}, backup.ErrCorrupt},
+	} {
+		_, _, err := load(t, encode(t, tt.change(records(t, src))))
+		if !errors.Is(err, tt.err) {
+			t.Errorf("%s: %v, want %v", tt.name, err, tt.err)
+		}
+	}
+}
+
+func TestCorruptFile(t *testing.T) {
+	data := encode(t, records(t, source(t)))
+
+	if _, err := backup.NewReader(bytes.NewReader([]byte("not a backup"))); !errors.Is(err, backup.ErrNotBackup) {
+		t.Fatalf("other file: %v", err)
+	}
+	newer := bytes.Clone(data)
+	newer[len("KVBACKUP")] = backup.Version + 1
+	if _, err := backup.NewReader(bytes.NewReader(newer)); !errors.Is(err, backup.ErrVersion) {
+		t.Fatalf("newer format: %v", err)
+	}
+
+	// A flipped byte fails the record checksum.
+	flipped := bytes.Clone(data)
+	flipped[len(flipped)/2] ^= 0xff
+	if _, _, err := load(t, flipped); !errors.Is(err, backup.ErrCorrupt) {
+		t.Fatalf("flipped byte: %v", err)
+	}
+
+	r, err := backup.NewReader(bytes.NewReader(data[:len(data)-3]))
+	if err != nil {
+		t.Fatalf("new reader: %v", err)
+	}
+	for {
+		_, err = r.Read()
+		if err != nil {
+			break
+		}
+	}
+	// A truncated file does not read as a clean end.
+	if !errors.Is(err, backup.ErrCorrupt) {
+		t.Fatalf("truncated file: %v", err)
+	}
+}
+
+func TestLoadNeedsEmptyStore(t *testing.T) {
+	data := encode(t, records(t, source(t)))
+	r, err := backup.NewReader(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("new reader: %v", err)
+	}
+	dst := newStore(t)
+	set(t, dst, "", "x", storage.Item{Value: "1"})
+	if _, err := backup.Load(r, dst); err == nil {
+		t.Fatal("backup loaded over existing keys")
+	}
+}
@@ -0,0 +1,188 @@
+package backup
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/Na322Pr/kv-storage-service/internal/storage"
+	desc "github.com/Na322Pr/kv-storage-service/pkg/api"
+)
+
+// chunkSize bounds the chunk records of large values.
+const chunkSize = 1 << 20
+
+// Stream sends the records of a backup of the dump taken on the node, and
+// returns the trailer sent last.
+func Stream(dump *storage.Dump, nodeID string, send func(*desc.BackupRecord) error) (*desc.BackupTrailer, error) {
+	namespaces, err := dump.Namespaces()
+	if err != nil {
+		return nil, err
+	}
+
+	err = send(&desc.BackupRecord{Record: &desc.BackupRecord_Header{Header: &desc.BackupHeader{
+		Version:     Version,
+		Revision:    dump.Revision,
+		LeaderEpoch: dump.Epoch,
+		CreatedAt:   time.Now().UnixNano(),
+		NodeId:      nodeID,
+	}}})
+	if err != nil {
+		return nil, err
+	}
+
+	digest := NewDigest()
+	add := func(record *desc.BackupRecord) error {
+		digest.Add(record)
+		return send(record)
+	}
+
+	for _, ns := range namespaces {
+		err := add(&desc.BackupRecord{Record: &desc.BackupRecord_Namespace{Namespace: &desc.BackupNamespace{
+			Name: ns.Name,
+			Quota: &desc.NamespaceQuota{
+				MaxKeys:           ns.Quota.MaxKeys,
+				MaxBytes:          ns.Quota.MaxBytes,
+				RequestsPerSecond: ns.Quota.RequestsPerSecond,
+				Compression:       ns.Quota.Compression,
+			},
+		}}})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var entries int64
+	for _, ns := range namespaces {
+		err := dump.Items(ns.Name, "", func(key string, item storage.Item) error {
+			entries++
+			entry := &desc.BackupEntry{
+				Namespace:   ns.Name,
+				Key:         key,
+				ContentType: item.ContentType,
+				Expiration:  item.Expiration,
+				Revision:    item.Revision,
+			}
+			if !item.Large() {
+				entry.Value = []byte(item.Value)
+				return add(&desc.BackupRecord{Record: &desc.BackupRecord_Entry{Entry: entry}})
+			}
+
+			entry.Large = true
+			entry.Size = item.Size
+			if err := add(&desc.BackupRecord{Record: &desc.BackupRecord_Entry{Entry: entry}}); err != nil {
+				return err
+			}
+			return dump.ReadBlob(ns.Name, item, func(chunk []byte) error {
+				for len(chunk) > 0 {
+					n := min(len(chunk), chunkSize)
+					if err := add(&desc.BackupRecord{Record: &desc.BackupRecord_Chunk{Chunk: chunk[:n]}}); err != nil {
+						return err
+					}
+					chunk = chunk[n:]
+				}
+				return nil
+			})
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	trailer := &desc.BackupTrailer{
+		Namespaces: int64(len(namespaces)),
+		Entries:    entries,
+		Checksum:   digest.Sum(),
+	}
+	return trailer, send(&desc.BackupRecord{Record: &desc.BackupRecord_Trailer{Trailer: trailer}})
+}
+
+// Load restores the backup into an empty store without replicating it, as
+// the offline tools do, and returns its header. The store may hold part of
+// the backup after an error.
+func Load(r *Reader, store *storage.Store) (*desc.BackupHeader, error) {
+	if !store.Empty() {
+		return nil, errors.New("store is not empty")
+	}
+
+	var (
+		checker = NewChecker()
+		entry   *desc.BackupEntry
+		blob    *storage.BlobWriter
+	)
+	for {
+		record, err := r.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if err := checker.Check(record); err != nil {
+			return nil, err
+		}
+
+		switch rec := record.Record.(type) {
+		case *desc.BackupRecord_Namespace:
+			quota := storage.Quota{
+				MaxKeys:           rec.Namespace.GetQuota().GetMaxKeys(),
+				MaxBytes:          rec.Namespace.GetQuota().GetMaxBytes(),
+				RequestsPerSecond: rec.Namespace.GetQuota().GetRequestsPerSecond(),
+				Compression:       rec.Namespace.GetQuota().GetCompression(),
+			}
+			// The default namespace always exists, it only needs a quota.
+			if rec.Namespace.Name == storage.DefaultNamespace && quota == (storage.Quota{}) {
+				continue
+			}
+			if _, err := store.SetQuota(rec.Namespace.Name, quota); err != nil {
+				return nil, err
+			}
+		case *desc.BackupRecord_Entry:
+			entry = rec.Entry
+			item := storage.Item{
+				ContentType: entry.ContentType,
+				Expiration:  entry.Expiration,
+				Revision:    entry.Revision,
+			}
+			if !entry.Large {
+				item.Value = string(entry.Value)
+				if err := store.Restore(entry.Namespace, entry.Key, item); err != nil {
+					return nil, err
+				}
+				continue
+			}
+			if blob, err = store.NewBlob(entry.Namespace, 0); err != nil {
+				return nil, err
+			}
+		case *desc.BackupRecord_Chunk:
+			if _, err := blob.Write(rec.Chunk); err != nil {
+				return nil, err
+			}
+		}
+
+		// The large value is stored once its last chunk is in.
+		if blob != nil && checker.Pending() == 0 {
+			item := storage.Item{
+				ContentType: entry.ContentType,
+				Expiration:  entry.Expiration,
+				Revision:    entry.Revision,
+				Blob:        blob.ID(),
+				Size:        blob.Size(),
+			}
+			if err := store.Restore(entry.Namespace, entry.Key, item); err != nil {
+				return nil, fmt.Errorf("large value %q: %w", entry.Key, err)
+			}
+			blob = nil
+		}
+	}
+	if err := checker.Done(); err != nil {
+		return nil, err
+	}
+
+	header := checker.Header()
+	if _, err := store.RestoreVersion(header.Revision); err != nil {
+		return nil, err
+	}
+	return header, nil
+}
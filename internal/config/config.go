@@ -9,17 +9,34 @@ import (
 	"strconv"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/ilyakaznacheev/cleanenv"
 )
 
 type Config struct {
-	Node `yaml:"node" env-required:"true"`
-	GRPC `yaml:"grpc" env-required:"true"`
+	Node      `yaml:"node" env-required:"true"`
+	GRPC      `yaml:"grpc" env-required:"true"`
+	RESP      `yaml:"resp"`
+	HTTP      `yaml:"http"`
+	Memcached `yaml:"memcached"`
+	Metrics   `yaml:"metrics"`
+	Admin     `yaml:"admin"`
+	Tracing   `yaml:"tracing"`
+	Logger    `yaml:"logger"`
+	Shutdown  `yaml:"shutdown"`
+	Health    `yaml:"health"`
+	TLS       `yaml:"tls"`
+	Auth      `yaml:"auth"`
+	Storage   `yaml:"storage"`
+	RateLimit `yaml:"rate_limit"`
 }
 
 type Node struct {
-	ID        int      `yaml:"id" env:"NODE_ID" env-required:"true"`
+	ID int `yaml:"id" env:"NODE_ID" env-required:"true"`
+	// NomadID is the id the cluster manager announces the leader with,
+	// the node ID when empty.
+	NomadID   string   `yaml:"nomad_id" env:"NOMAD_ALLOC_ID"`
 	SeedNodes []string `yaml:"seed_nodes" env:"SEED_NODES" env-separator:","`
 }
 
@@ -28,6 +45,253 @@ type GRPC struct {
 	Port int    `yaml:"port" env:"GRPC_PORT" env-required:"true"`
 }
 
+type Logger struct {
+	Level  string `yaml:"level" env:"LOG_LEVEL" env-default:"info"`
+	Format string `yaml:"format" env:"LOG_FORMAT" env-default:"console"`
+	// File enables logging to a rotated file instead of stderr.
+	File         string `yaml:"file" env:"LOG_FILE"`
+	RedactValues bool   `yaml:"redact_values" env:"LOG_REDACT_VALUES" env-default:"true"`
+	Sampling     struct {
+		// Initial entries with the same message per tick are logged, then every Thereafter-th one.
+		// Zero disables sampling.
+		Initial    int           `yaml:"initial" env:"LOG_SAMPLING_INITIAL" env-default:"0"`
+		Thereafter int           `yaml:"thereafter" env:"LOG_SAMPLING_THEREAFTER" env-default:"100"`
+		Tick       time.Duration `yaml:"tick" env:"LOG_SAMPLING_TICK" env-default:"1s"`
+	} `yaml:"sampling"`
+	Rotation struct {
+		MaxSizeMB  int  `yaml:"max_size_mb" env:"LOG_ROTATION_MAX_SIZE_MB" env-default:"100"`
+		MaxBackups int  `yaml:"max_backups" env:"LOG_ROTATION_MAX_BACKUPS" env-default:"5"`
+		MaxAgeDays int  `yaml:"max_age_days" env:"LOG_ROTATION_MAX_AGE_DAYS" env-default:"30"`
+		Compress   bool `yaml:"compress" env:"LOG_ROTATION_COMPRESS" env-default:"false"`
+	} `yaml:"rotation"`
+}
+
+// Metrics configures the Prometheus endpoint.
+type Metrics struct {
+	Host string `yaml:"host" env:"METRICS_HOST" env-default:"0.0.0.0"`
+	Port int    `yaml:"port" env:"METRICS_PORT" env-default:"2112"`
+}
+
+// Admin configures the optional endpoint changing the log level at runtime.
+// It listens on the loopback interface by default, over HTTPS when TLS is
+// enabled, and callers need the admin permission when auth is enabled.
+type Admin struct {
+	Enabled bool   `yaml:"enabled" env:"ADMIN_ENABLED" env-default:"false"`
+	Host    string `yaml:"host" env:"ADMIN_HOST" env-default:"127.0.0.1"`
+	Port    int    `yaml:"port" env:"ADMIN_PORT" env-default:"2113"`
+}
+
+// Tracing configures the OpenTelemetry exporter: none, otlp, stdout or file.
+type Tracing struct {
+	Exporter    string  `yaml:"exporter" env:"TRACING_EXPORTER" env-default:"none"`
+	Endpoint    string  `yaml:"endpoint" env:"TRACING_OTLP_ENDPOINT" env-default:"localhost:4317"`
+	Insecure    bool    `yaml:"insecure" env:"TRACING_OTLP_INSECURE" env-default:"true"`
+	File        string  `yaml:"file" env:"TRACING_FILE" env-default:"traces.jsonl"`
+	SampleRatio float64 `yaml:"sample_ratio" env:"TRACING_SAMPLE_RATIO" env-default:"1"`
+}
+
+// HTTP configures the optional HTTP/JSON gateway, served over HTTPS with
+// the node certificate when TLS is enabled.
+type HTTP struct {
+	Enabled bool   `yaml:"enabled" env:"HTTP_ENABLED" env-default:"false"`
+	Host    string `yaml:"host" env:"HTTP_HOST" env-default:"0.0.0.0"`
+	Port    int    `yaml:"port" env:"HTTP_PORT" env-default:"8081"`
+}
+
+// RESP configures the optional Redis protocol listener. It does not
+// authenticate callers and cannot be enabled together with auth.
+type RESP struct {
+	Enabled bool   `yaml:"enabled" env:"RESP_ENABLED" env-default:"false"`
+	Host    string `yaml:"host" env:"RESP_HOST" env-default:"0.0.0.0"`
+	Port    int    `yaml:"port" env:"RESP_PORT" env-default:"6379"`
+}
+
+// Memcached configures the optional memcached text protocol listener. It
+// does not authenticate callers and cannot be enabled together with auth.
+type Memcached struct {
+	Enabled bool   `yaml:"enabled" env:"MEMCACHED_ENABLED" env-default:"false"`
+	Host    string `yaml:"host" env:"MEMCACHED_HOST" env-default:"0.0.0.0"`
+	Port    int    `yaml:"port" env:"MEMCACHED_PORT" env-default:"11211"`
+}
+
+// Shutdown configures the graceful shutdown sequence.
+type Shutdown struct {
+	// DrainTimeout bounds GracefulStop, calls still running after it are cancelled.
+	DrainTimeout time.Duration `yaml:"drain_timeout" env:"SHUTDOWN_DRAIN_TIMEOUT" env-default:"10s"`
+	// CatchUpTimeout bounds the wait for replicas to acknowledge every replicated write.
+	CatchUpTimeout time.Duration `yaml:"catch_up_timeout" env:"SHUTDOWN_CATCH_UP_TIMEOUT" env-default:"5s"`
+	// HandOffLeadership makes a stopping leader promote the most up-to-date replica.
+	HandOffLeadership bool          `yaml:"hand_off_leadership" env:"SHUTDOWN_HAND_OFF_LEADERSHIP" env-default:"false"`
+	HandOffTimeout    time.Duration `yaml:"hand_off_timeout" env:"SHUTDOWN_HAND_OFF_TIMEOUT" env-default:"5s"`
+}
+
+// Health configures how health statuses are derived from the node state.
+type Health struct {
+	CheckInterval time.Duration `yaml:"check_interval" env:"HEALTH_CHECK_INTERVAL" env-default:"1s"`
+	// MaxReplicationLag is the number of unacknowledged messages after which
+	// the leader reports leader-writes as not serving.
+	MaxReplicationLag int64 `yaml:"max_replication_lag" env:"HEALTH_MAX_REPLICATION_LAG" env-default:"1000"`
+	// RequireReplicas makes a leader without reachable replicas report leader-writes as not serving.
+	RequireReplicas bool `yaml:"require_replicas" env:"HEALTH_REQUIRE_REPLICAS" env-default:"true"`
+}
+
+// TLS configures transport security of the gRPC server and of the
+// connections this node opens to other nodes. Files are reloaded from disk
+// every ReloadInterval.
+type TLS struct {
+	Enabled  bool   `yaml:"enabled" env:"TLS_ENABLED" env-default:"false"`
+	CertFile string `yaml:"cert_file" env:"TLS_CERT_FILE"`
+	KeyFile  string `yaml:"key_file" env:"TLS_KEY_FILE"`
+	// ClientCAFile verifies certificates presented by callers of client-facing RPCs.
+	ClientCAFile      string `yaml:"client_ca_file" env:"TLS_CLIENT_CA_FILE"`
+	RequireClientCert bool   `yaml:"require_client_cert" env:"TLS_REQUIRE_CLIENT_CERT" env-default:"false"`
+	// PeerCAFile verifies other nodes and the cluster manager: callers of
+	// internal RPCs and servers this node connects to.
+	PeerCAFile string `yaml:"peer_ca_file" env:"TLS_PEER_CA_FILE"`
+	// PeerNames restricts peers to certificates carrying one of these DNS names
+	// or common names, empty accepts any certificate issued by PeerCAFile.
+	PeerNames      []string      `yaml:"peer_names" env:"TLS_PEER_NAMES" env-separator:","`
+	ReloadInterval time.Duration `yaml:"reload_interval" env:"TLS_RELOAD_INTERVAL" env-default:"30s"`
+}
+
+// Auth configures authentication of gRPC and HTTP gateway callers and the
+// access rules granted to their roles.
+type Auth struct {
+	Enabled bool        `yaml:"enabled" env:"AUTH_ENABLED" env-default:"false"`
+	Tokens  []AuthToken `yaml:"tokens"`
+	// CertificateRoles maps common names of verified TLS client certificates to roles.
+	// Certificates whose name is not listed are not used for authentication.
+	CertificateRoles map[string][]string `yaml:"certificate_roles"`
+	JWT              JWT                 `yaml:"jwt"`
+	// Roles grants every role a list of per-prefix rules.
+	Roles map[string][]ACLRule `yaml:"roles"`
+}
+
+// Storage configures the key-value storage.
+type Storage struct {
+	// Engine is memory, or bolt or lsm to keep the data on disk in DataDir.
+	Engine  string  `yaml:"engine" env:"STORAGE_ENGINE" env-default:"memory"`
+	DataDir string  `yaml:"data_dir" env:"STORAGE_DATA_DIR" env-default:"data"`
+	LSM     LSM     `yaml:"lsm"`
+	History History `yaml:"history"`
+	// MaxMemory limits the approximate memory held by the live keys and
+	// values in bytes, zero disables the limit. The past versions kept for
+	// reads at a revision are not counted, History bounds them instead.
+	MaxMemory int64 `yaml:"max_memory" env:"STORAGE_MAX_MEMORY" env-default:"0"`
+	// EvictionPolicy is one of noeviction, allkeys-lru, allkeys-lfu and volatile-ttl.
+	EvictionPolicy string `yaml:"eviction_policy" env:"STORAGE_EVICTION_POLICY" env-default:"noeviction"`
+	// MaxValueSize limits the values streamed in chunks in bytes, zero
+	// disables the limit.
+	MaxValueSize int64       `yaml:"max_value_size" env:"STORAGE_MAX_VALUE_SIZE" env-default:"1073741824"`
+	Compression  Compression `yaml:"compression"`
+	Encryption   Encryption  `yaml:"encryption"`
+	Archive      Archive     `yaml:"archive"`
+}
+
+// Archive keeps the WAL segments of the lsm engine once flushed, to recover
+// the data at a point in time from a backup and the segments after it.
+type Archive struct {
+	// Dir is the archive directory, empty disables archiving.
+	Dir string `yaml:"dir" env:"STORAGE_ARCHIVE_DIR"`
+}
+
+// Encryption encrypts the WAL segments, tables and manifest of the lsm
+// engine with AES-GCM. Keys are given as id:base64 pairs, the highest id
+// encrypts new files and the others keep older files readable.
+type Encryption struct {
+	Enabled bool `yaml:"enabled" env:"STORAGE_ENCRYPTION_ENABLED" env-default:"false"`
+	// KeyFile holds a key per line, empty lines and lines starting with #
+	// are skipped.
+	KeyFile string `yaml:"key_file" env:"STORAGE_ENCRYPTION_KEY_FILE"`
+	// Keys lists keys separated by commas, it is only read from the
+	// environment.
+	Keys string `yaml:"-" env:"STORAGE_ENCRYPTION_KEYS"`
+}
+
+// Compression compresses values inside the storage, namespaces may pick
+// their own codec.
+type Compression struct {
+	// Codec is none, snappy or zstd.
+	Codec string `yaml:"codec" env:"STORAGE_COMPRESSION_CODEC" env-default:"none"`
+	// MinSize is the size in bytes from which values are compressed.
+	MinSize int `yaml:"min_size" env:"STORAGE_COMPRESSION_MIN_SIZE" env-default:"256"`
+}
+
+// LSM tunes the lsm storage engine, sizes are in bytes.
+type LSM struct {
+	MemtableSize int64 `yaml:"memtable_size" env:"STORAGE_LSM_MEMTABLE_SIZE" env-default:"4194304"`
+	TableSize    int64 `yaml:"table_size" env:"STORAGE_LSM_TABLE_SIZE" env-default:"2097152"`
+	// L0CompactionTrigger is the number of flushed tables merged into level 1.
+	L0CompactionTrigger int   `yaml:"l0_compaction_trigger" env:"STORAGE_LSM_L0_COMPACTION_TRIGGER" env-default:"4"`
+	LevelBaseSize       int64 `yaml:"level_base_size" env:"STORAGE_LSM_LEVEL_BASE_SIZE" env-default:"10485760"`
+	// CompactionRate limits compaction writes in bytes per second, zero is unlimited.
+	CompactionRate int64 `yaml:"compaction_rate" env:"STORAGE_LSM_COMPACTION_RATE" env-default:"0"`
+	SyncWrites     bool  `yaml:"sync_writes" env:"STORAGE_LSM_SYNC_WRITES" env-default:"true"`
+}
+
+// History keeps the past versions of keys for reads at a revision.
+type History struct {
+	// Retention is how long a replaced version stays readable, zero keeps
+	// every version.
+	Retention time.Duration `yaml:"retention" env:"STORAGE_HISTORY_RETENTION" env-default:"1h"`
+	// MaxVersions bounds the versions kept per key, the oldest are dropped
+	// first even within the retention. Zero keeps any number of versions.
+	MaxVersions        int           `yaml:"max_versions" env:"STORAGE_HISTORY_MAX_VERSIONS" env-default:"100"`
+	CompactionInterval time.Duration `yaml:"compaction_interval" env:"STORAGE_HISTORY_COMPACTION_INTERVAL" env-default:"1m"`
+}
+
+// RateLimit configures the admission of client requests, over gRPC, the
+// HTTP gateway, RESP and memcached alike. Commands of the other protocols
+// count as the gRPC method they map to. Calls between nodes and health
+// checks are never limited.
+type RateLimit struct {
+	Enabled bool `yaml:"enabled" env:"RATE_LIMIT_ENABLED" env-default:"false"`
+	// ClientRate limits the requests per second of every client over all
+	// methods, zero disables it. ClientBurst defaults to ClientRate.
+	ClientRate  float64 `yaml:"client_rate" env:"RATE_LIMIT_CLIENT_RATE" env-default:"0"`
+	ClientBurst float64 `yaml:"client_burst" env:"RATE_LIMIT_CLIENT_BURST" env-default:"0"`
+	// Methods limits every client per method, keyed by the method name such as Set.
+	Methods map[string]MethodRateLimit `yaml:"methods"`
+	// MaxInflightWrites caps the write RPCs handled at once, zero disables it.
+	MaxInflightWrites int `yaml:"max_inflight_writes" env:"RATE_LIMIT_MAX_INFLIGHT_WRITES" env-default:"0"`
+	// WriteQueueSize writes over the cap wait up to WriteQueueTimeout for a
+	// slot, writes finding the queue full are rejected right away.
+	WriteQueueSize    int           `yaml:"write_queue_size" env:"RATE_LIMIT_WRITE_QUEUE_SIZE" env-default:"128"`
+	WriteQueueTimeout time.Duration `yaml:"write_queue_timeout" env:"RATE_LIMIT_WRITE_QUEUE_TIMEOUT" env-default:"100ms"`
+	// ClientIdleTimeout is how long the buckets of a silent client are kept.
+	ClientIdleTimeout time.Duration `yaml:"client_idle_timeout" env:"RATE_LIMIT_CLIENT_IDLE_TIMEOUT" env-default:"10m"`
+}
+
+// MethodRateLimit is a token bucket, Burst defaults to Rate.
+type MethodRateLimit struct {
+	Rate  float64 `yaml:"rate"`
+	Burst float64 `yaml:"burst"`
+}
+
+// AuthToken is a static bearer token.
+type AuthToken struct {
+	Token   string   `yaml:"token"`
+	Subject string   `yaml:"subject"`
+	Roles   []string `yaml:"roles"`
+}
+
+// JWT configures bearer JWTs verified against a local JWKS file.
+type JWT struct {
+	JWKSFile string `yaml:"jwks_file" env:"AUTH_JWT_JWKS_FILE"`
+	Issuer   string `yaml:"issuer" env:"AUTH_JWT_ISSUER"`
+	Audience string `yaml:"audience" env:"AUTH_JWT_AUDIENCE"`
+	// RolesClaim names the claim holding the list of roles.
+	RolesClaim string `yaml:"roles_claim" env:"AUTH_JWT_ROLES_CLAIM" env-default:"roles"`
+}
+
+// ACLRule grants permissions (read, write, watch, admin) on keys starting with
+// Prefix in Namespace. An empty Namespace is the default one, "*" matches any.
+type ACLRule struct {
+	Namespace   string   `yaml:"namespace"`
+	Prefix      string   `yaml:"prefix"`
+	Permissions []string `yaml:"permissions"`
+}
+
 var (
 	once           sync.Once
 	configInstance *Config
@@ -143,9 +407,142 @@ func validateConfig(cfg *Config) error {
 		return fmt.Errorf("gRPC port must be between 1 and 65535")
 	}
 
+	if cfg.Metrics.Port <= 0 || cfg.Metrics.Port > 65535 {
+		return fmt.Errorf("metrics port must be between 1 and 65535")
+	}
+
+	if cfg.Admin.Enabled && (cfg.Admin.Port <= 0 || cfg.Admin.Port > 65535) {
+		return fmt.Errorf("admin port must be between 1 and 65535")
+	}
+
+	if cfg.Tracing.SampleRatio < 0 || cfg.Tracing.SampleRatio > 1 {
+		return fmt.Errorf("tracing sample ratio must be between 0 and 1")
+	}
+
+	if cfg.HTTP.Enabled && (cfg.HTTP.Port <= 0 || cfg.HTTP.Port > 65535) {
+		return fmt.Errorf("HTTP port must be between 1 and 65535")
+	}
+
+	if cfg.RESP.Enabled && (cfg.RESP.Port <= 0 || cfg.RESP.Port > 65535) {
+		return fmt.Errorf("RESP port must be between 1 and 65535")
+	}
+
+	if cfg.Memcached.Enabled && (cfg.Memcached.Port <= 0 || cfg.Memcached.Port > 65535) {
+		return fmt.Errorf("memcached port must be between 1 and 65535")
+	}
+
+	// Anyone reaching these listeners could read and write every key.
+	if cfg.Auth.Enabled && cfg.RESP.Enabled {
+		return fmt.Errorf("the RESP listener does not authenticate callers, disable it when auth is enabled")
+	}
+	if cfg.Auth.Enabled && cfg.Memcached.Enabled {
+		return fmt.Errorf("the memcached listener does not authenticate callers, disable it when auth is enabled")
+	}
+
+	if cfg.Storage.Engine != "memory" && cfg.Storage.DataDir == "" {
+		return fmt.Errorf("storage data dir is required for the %s engine", cfg.Storage.Engine)
+	}
+
+	if lsm := cfg.Storage.LSM; lsm.MemtableSize < 0 || lsm.TableSize < 0 || lsm.L0CompactionTrigger < 0 ||
+		lsm.LevelBaseSize < 0 || lsm.CompactionRate < 0 {
+		return fmt.Errorf("storage lsm settings must not be negative")
+	}
+
+	if history := cfg.Storage.History; history.Retention < 0 || history.MaxVersions < 0 {
+		return fmt.Errorf("storage history retention and max versions must not be negative")
+	} else if (history.Retention > 0 || history.MaxVersions > 0) && history.CompactionInterval <= 0 {
+		return fmt.Errorf("storage history compaction interval must be positive")
+	}
+
+	if cfg.Storage.MaxMemory < 0 {
+		return fmt.Errorf("storage max memory must not be negative")
+	}
+	if cfg.Storage.MaxValueSize < 0 {
+		return fmt.Errorf("storage max value size must not be negative")
+	}
+	if cfg.Storage.Compression.MinSize < 0 {
+		return fmt.Errorf("storage compression min size must not be negative")
+	}
+	if encryption := cfg.Storage.Encryption; encryption.Enabled {
+		if cfg.Storage.Engine != "lsm" {
+			return fmt.Errorf("storage encryption requires the lsm engine")
+		}
+		if encryption.KeyFile == "" && encryption.Keys == "" {
+			return fmt.Errorf("storage encryption requires a key file or keys")
+		}
+	}
+	if cfg.Storage.Archive.Dir != "" && cfg.Storage.Engine != "lsm" {
+		return fmt.Errorf("storage archive requires the lsm engine")
+	}
+
+	if cfg.RateLimit.Enabled {
+		if cfg.RateLimit.ClientRate < 0 || cfg.RateLimit.MaxInflightWrites < 0 || cfg.RateLimit.WriteQueueSize < 0 {
+			return fmt.Errorf("rate limits must not be negative")
+		}
+		if cfg.RateLimit.ClientIdleTimeout <= 0 || cfg.RateLimit.WriteQueueTimeout <= 0 {
+			return fmt.Errorf("rate limit timeouts must be positive")
+		}
+	}
+
+	if cfg.Health.CheckInterval <= 0 {
+		return fmt.Errorf("health check interval must be positive")
+	}
+
+	if cfg.TLS.Enabled {
+		if cfg.TLS.CertFile == "" || cfg.TLS.KeyFile == "" {
+			return fmt.Errorf("TLS certificate and key files are required")
+		}
+		if cfg.TLS.PeerCAFile == "" {
+			return fmt.Errorf("TLS peer CA file is required")
+		}
+		if cfg.TLS.RequireClientCert && cfg.TLS.ClientCAFile == "" {
+			return fmt.Errorf("TLS client CA file is required to require client certificates")
+		}
+		if cfg.TLS.ReloadInterval <= 0 {
+			return fmt.Errorf("TLS reload interval must be positive")
+		}
+	}
+
+	if cfg.Auth.Enabled && (cfg.RESP.Enabled || cfg.Memcached.Enabled) {
+		return fmt.Errorf("RESP and memcached listeners do not support authentication")
+	}
+
+	if cfg.Shutdown.DrainTimeout < 0 || cfg.Shutdown.CatchUpTimeout < 0 || cfg.Shutdown.HandOffTimeout < 0 {
+		return fmt.Errorf("shutdown timeouts cannot be negative")
+	}
+
 	return nil
 }
 
 func (c *Config) GetGRPCAddress() string {
 	return fmt.Sprintf("%s:%d", c.GRPC.Host, c.GRPC.Port)
 }
+
+// GetNomadID returns the identifier the cluster manager uses to address this
+// node, falling back to the numeric node ID outside of Nomad.
+func (c *Config) GetNomadID() string {
+	if c.Node.NomadID != "" {
+		return c.Node.NomadID
+	}
+	return strconv.Itoa(c.Node.ID)
+}
+
+func (c *Config) GetMetricsAddress() string {
+	return fmt.Sprintf("%s:%d", c.Metrics.Host, c.Metrics.Port)
+}
+
+func (c *Config) GetAdminAddress() string {
+	return fmt.Sprintf("%s:%d", c.Admin.Host, c.Admin.Port)
+}
+
+func (c *Config) GetHTTPAddress() string {
+	return fmt.Sprintf("%s:%d", c.HTTP.Host, c.HTTP.Port)
+}
+
+func (c *Config) GetRESPAddress() string {
+	return fmt.Sprintf("%s:%d", c.RESP.Host, c.RESP.Port)
+}
+
+func (c *Config) GetMemcachedAddress() string {
+	return fmt.Sprintf("%s:%d", c.Memcached.Host, c.Memcached.Port)
+}
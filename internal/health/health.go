@@ -0,0 +1,226 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/Na322Pr/kv-storage-service/internal/config"
+	"github.com/Na322Pr/kv-storage-service/internal/model"
+	"github.com/Na322Pr/kv-storage-service/internal/service"
+	"go.uber.org/zap"
+	grpchealth "google.golang.org/grpc/health"
+	"google.golang.org/grpc/health/grpc_health_v1"
+)
+
+// Names of the services reported through the gRPC health protocol. The empty
+// name is the overall status of the node.
+const (
+	ServiceOverall      = ""
+	ServiceStorage      = "kv_storage_service.KeyValueStorage"
+	ServiceLeaderWrites = "leader-writes"
+	ServiceReplicaReads = "replica-reads"
+)
+
+var services = []string{ServiceOverall, ServiceStorage, ServiceLeaderWrites, ServiceReplicaReads}
+
+const (
+	serving    = grpc_health_v1.HealthCheckResponse_SERVING
+	notServing = grpc_health_v1.HealthCheckResponse_NOT_SERVING
+)
+
+// Checker periodically derives health statuses from the node role, recovery
+// state and replication lag and publishes them to the gRPC health server.
+type Checker struct {
+	server *grpchealth.Server
+	node   *model.Node
+	cm     *service.ConnectionManagerService
+	cfg    config.Health
+	logger *zap.Logger
+
+	recovering   atomic.Bool
+	shuttingDown atomic.Bool
+	// lastCheck is the unix nano time of the last evaluation, used by /livez.
+	lastCheck atomic.Int64
+
+	mu       sync.RWMutex
+	statuses map[string]grpc_health_v1.HealthCheckResponse_ServingStatus
+}
+
+func NewChecker(
+	server *grpchealth.Server,
+	node *model.Node,
+	cm *service.ConnectionManagerService,
+	cfg config.Health,
+	logger *zap.Logger,
+) *Checker {
+	c := &Checker{
+		server:   server,
+		node:     node,
+		cm:       cm,
+		cfg:      cfg,
+		logger:   logger,
+		statuses: make(map[string]grpc_health_v1.HealthCheckResponse_ServingStatus),
+	}
+	// Nothing is served until the first evaluation.
+	for _, name := range services {
+		c.statuses[name] = notServing
+		server.SetServingStatus(name, notServing)
+	}
+	return c
+}
+
+// SetRecovering reports the node as not serving while storage is being restored.
+func (c *Checker) SetRecovering(recovering bool) {
+	c.recovering.Store(recovering)
+	c.Check()
+}
+
+// Run evaluates the statuses every check interval until ctx is done.
+func (c *Checker) Run(ctx context.Context) {
+	ticker := time.NewTicker(c.cfg.CheckInterval)
+	defer ticker.Stop()
+
+	c.Check()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			c.Check()
+		}
+	}
+}
+
+// Shutdown reports every service as not serving for good.
+func (c *Checker) Shutdown() {
+	c.shuttingDown.Store(true)
+	c.server.Shutdown()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for name := range c.statuses {
+		c.statuses[name] = notServing
+	}
+}
+
+func (c *Checker) Check() {
+	c.lastCheck.Store(time.Now().UnixNano())
+	if c.shuttingDown.Load() {
+		return
+	}
+
+	up := !c.recovering.Load()
+	isLeader := c.node.IsLeader()
+
+	writes := up && isLeader
+	if writes {
+		if reason := c.degradation(); reason != "" {
+			c.logger.Debug("leader writes degraded", zap.String("reason", reason))
+			writes = false
+		}
+	}
+	// The leader always has the latest data, a replica only while it follows the leader.
+	reads := up && (isLeader || c.node.Following())
+
+	c.set(ServiceOverall, up)
+	c.set(ServiceStorage, up)
+	c.set(ServiceLeaderWrites, writes)
+	c.set(ServiceReplicaReads, reads)
+}
+
+// degradation returns why the leader should not take writes, or an empty string.
+func (c *Checker) degradation() string {
+	reachable := 0
+	for _, replica := range c.cm.Stats() {
+		if !replica.Up {
+			continue
+		}
+		reachable++
+		if replica.Lag > c.cfg.MaxReplicationLag {
+			return fmt.Sprintf("replica %s lags by %d messages", replica.ID, replica.Lag)
+		}
+	}
+	if c.cfg.RequireReplicas && reachable == 0 {
+		return "no reachable replicas"
+	}
+	return ""
+}
+
+func (c *Checker) set(name string, ok bool) {
+	status := notServing
+	if ok {
+		status = serving
+	}
+
+	c.mu.Lock()
+	if c.shuttingDown.Load() {
+		c.mu.Unlock()
+		return
+	}
+	changed := c.statuses[name] != status
+	c.statuses[name] = status
+	c.mu.Unlock()
+
+	if !changed {
+		return
+	}
+	c.logger.Info("health status changed",
+		zap.String("service", name),
+		zap.String("status", status.String()),
+	)
+	c.server.SetServingStatus(name, status)
+}
+
+func (c *Checker) status(name string) grpc_health_v1.HealthCheckResponse_ServingStatus {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.statuses[name]
+}
+
+// Livez answers 200 while the evaluation loop keeps running, so a stuck
+// process is restarted by the orchestrator. The loop stops on shutdown,
+// a draining node stays alive.
+func (c *Checker) Livez() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		last := time.Unix(0, c.lastCheck.Load())
+		if !c.shuttingDown.Load() && time.Since(last) > 3*c.cfg.CheckInterval {
+			http.Error(w, "health checks stalled", http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte("ok\n"))
+	})
+}
+
+// Readyz answers 200 when the node can serve its current role: writes on the
+// leader, reads on a replica. The body lists every service status.
+func (c *Checker) Readyz() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		role := ServiceReplicaReads
+		if c.node.IsLeader() {
+			role = ServiceLeaderWrites
+		}
+
+		body := make(map[string]string, len(services))
+		for _, name := range services {
+			key := name
+			if key == ServiceOverall {
+				key = "overall"
+			}
+			body[key] = c.status(name).String()
+		}
+
+		code := http.StatusOK
+		if c.status(ServiceOverall) != serving || c.status(role) != serving {
+			code = http.StatusServiceUnavailable
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(code)
+		json.NewEncoder(w).Encode(body)
+	})
+}
@@ -1 +1,77 @@
 package logger
+
+import (
+	"fmt"
+	"os"
+	"sync/atomic"
+
+	"github.com/Na322Pr/kv-storage-service/internal/config"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"gopkg.in/natefinch/lumberjack.v2"
+)
+
+const (
+	FormatJSON    = "json"
+	FormatConsole = "console"
+)
+
+var redactValues atomic.Bool
+
+func init() {
+	redactValues.Store(true)
+}
+
+// New builds the service logger from config. The returned level can be changed
+// at runtime and doubles as an HTTP handler for that purpose.
+func New(cfg config.Logger) (*zap.Logger, zap.AtomicLevel, error) {
+	level, err := zap.ParseAtomicLevel(cfg.Level)
+	if err != nil {
+		return nil, zap.AtomicLevel{}, fmt.Errorf("invalid log level: %w", err)
+	}
+
+	encoderConfig := zap.NewProductionEncoderConfig()
+	encoderConfig.EncodeTime = zapcore.ISO8601TimeEncoder
+
+	var encoder zapcore.Encoder
+	switch cfg.Format {
+	case FormatJSON:
+		encoder = zapcore.NewJSONEncoder(encoderConfig)
+	case FormatConsole:
+		encoderConfig.EncodeLevel = zapcore.CapitalLevelEncoder
+		encoder = zapcore.NewConsoleEncoder(encoderConfig)
+	default:
+		return nil, zap.AtomicLevel{}, fmt.Errorf("unknown log format %q", cfg.Format)
+	}
+
+	core := zapcore.NewCore(encoder, output(cfg), level)
+	if cfg.Sampling.Initial > 0 {
+		core = zapcore.NewSamplerWithOptions(core, cfg.Sampling.Tick, cfg.Sampling.Initial, cfg.Sampling.Thereafter)
+	}
+
+	redactValues.Store(cfg.RedactValues)
+
+	return zap.New(core, zap.AddCaller(), zap.AddStacktrace(zapcore.ErrorLevel)), level, nil
+}
+
+func output(cfg config.Logger) zapcore.WriteSyncer {
+	if cfg.File == "" {
+		return zapcore.Lock(os.Stderr)
+	}
+
+	return zapcore.AddSync(&lumberjack.Logger{
+		Filename:   cfg.File,
+		MaxSize:    cfg.Rotation.MaxSizeMB,
+		MaxBackups: cfg.Rotation.MaxBackups,
+		MaxAge:     cfg.Rotation.MaxAgeDays,
+		Compress:   cfg.Rotation.Compress,
+	})
+}
+
+// Value logs a stored value, or only its size when values are redacted.
+func Value(value string) zap.Field {
+	if redactValues.Load() {
+		return zap.String("value", fmt.Sprintf("<redacted %d bytes>", len(value)))
+	}
+	return zap.String("value", value)
+}
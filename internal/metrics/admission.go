@@ -0,0 +1,68 @@
+package metrics
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// AdmissionMetrics observes the rate limits and the write concurrency cap.
+type AdmissionMetrics struct {
+	rejected       *prometheus.CounterVec
+	inflightWrites prometheus.Gauge
+	queuedWrites   prometheus.Gauge
+	queueWait      prometheus.Histogram
+}
+
+func NewAdmissionMetrics(registerer prometheus.Registerer) *AdmissionMetrics {
+	m := &AdmissionMetrics{
+		rejected: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "admission",
+			Name:      "rejected_total",
+			Help:      "Requests rejected with ResourceExhausted by method and reason.",
+		}, []string{"method", "reason"}),
+		inflightWrites: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Subsystem: "admission",
+			Name:      "inflight_writes",
+			Help:      "Write RPCs being handled.",
+		}),
+		queuedWrites: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Subsystem: "admission",
+			Name:      "queued_writes",
+			Help:      "Write RPCs waiting for a free slot.",
+		}),
+		queueWait: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: "admission",
+			Name:      "queue_wait_seconds",
+			Help:      "Time write RPCs spent waiting for a free slot, including rejected ones.",
+			Buckets:   []float64{.0001, .0005, .001, .005, .01, .05, .1, .5, 1},
+		}),
+	}
+
+	registerer.MustRegister(m.rejected, m.inflightWrites, m.queuedWrites, m.queueWait)
+	return m
+}
+
+func (m *AdmissionMetrics) Rejected(method, reason string) {
+	m.rejected.WithLabelValues(method, reason).Inc()
+}
+
+func (m *AdmissionMetrics) WriteStarted() {
+	m.inflightWrites.Inc()
+}
+
+func (m *AdmissionMetrics) WriteFinished() {
+	m.inflightWrites.Dec()
+}
+
+func (m *AdmissionMetrics) Queued(delta float64) {
+	m.queuedWrites.Add(delta)
+}
+
+func (m *AdmissionMetrics) Waited(d time.Duration) {
+	m.queueWait.Observe(d.Seconds())
+}
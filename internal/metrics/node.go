@@ -0,0 +1,179 @@
+package metrics
+
+import (
+	"context"
+
+	"github.com/Na322Pr/kv-storage-service/internal/service"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const namespace = "kv_storage"
+
+var (
+	keysDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "storage", "keys"),
+		"Number of stored keys, including expired keys not yet removed.",
+		nil, nil,
+	)
+	memoryDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "storage", "memory_bytes"),
+		"Approximate memory held by keys and values.",
+		nil, nil,
+	)
+	maxMemoryDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "storage", "max_memory_bytes"),
+		"Configured memory limit, 0 if unlimited.",
+		nil, nil,
+	)
+	evictedDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "storage", "evicted_keys_total"),
+		"Keys evicted to stay under the memory limit.",
+		nil, nil,
+	)
+	compressionRatioDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "storage", "compression_ratio"),
+		"Size of the values compressed by this node before compression divided by their stored size, 1 if none were compressed.",
+		nil, nil,
+	)
+	dataVersionDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "storage", "data_version"),
+		"Data version used for leader election.",
+		nil, nil,
+	)
+	leaderEpochDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "node", "leader_epoch"),
+		"Epoch of the last leader announced to the node.",
+		nil, nil,
+	)
+	leaderDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "node", "is_leader"),
+		"1 if the node is the leader, 0 if it is a replica.",
+		nil, nil,
+	)
+	replicaUpDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "replication", "stream_up"),
+		"1 if the replication stream to the replica is healthy.",
+		[]string{"replica"}, nil,
+	)
+	replicaLagDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "replication", "lag_messages"),
+		"Messages sent to the replica and not yet acknowledged.",
+		[]string{"replica"}, nil,
+	)
+	namespaceKeysDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "namespace", "keys"),
+		"Number of keys stored in the namespace.",
+		[]string{"namespace"}, nil,
+	)
+	namespaceBytesDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "namespace", "bytes"),
+		"Approximate memory held by keys and values of the namespace.",
+		[]string{"namespace"}, nil,
+	)
+	namespaceMaxKeysDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "namespace", "quota_max_keys"),
+		"Key count quota of the namespace, 0 if unlimited.",
+		[]string{"namespace"}, nil,
+	)
+	namespaceMaxBytesDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "namespace", "quota_max_bytes"),
+		"Size quota of the namespace, 0 if unlimited.",
+		[]string{"namespace"}, nil,
+	)
+	namespaceCompressedRawDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "namespace", "compressed_raw_bytes_total"),
+		"Size of the values of the namespace compressed by this node, before compression.",
+		[]string{"namespace"}, nil,
+	)
+	namespaceCompressedStoredDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "namespace", "compressed_stored_bytes_total"),
+		"Size of the values of the namespace compressed by this node, as stored.",
+		[]string{"namespace"}, nil,
+	)
+	namespaceRequestsDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "namespace", "requests_total"),
+		"Requests to the namespace served by this node.",
+		[]string{"namespace"}, nil,
+	)
+	namespaceThrottledDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "namespace", "throttled_total"),
+		"Requests to the namespace rejected by its request rate quota.",
+		[]string{"namespace"}, nil,
+	)
+)
+
+// nodeCollector reads storage and replication state on every scrape.
+type nodeCollector struct {
+	storageService *service.StorageService
+	cm             *service.ConnectionManagerService
+}
+
+func NewNodeCollector(storageService *service.StorageService, cm *service.ConnectionManagerService) prometheus.Collector {
+	return &nodeCollector{
+		storageService: storageService,
+		cm:             cm,
+	}
+}
+
+func (c *nodeCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- keysDesc
+	ch <- memoryDesc
+	ch <- maxMemoryDesc
+	ch <- evictedDesc
+	ch <- compressionRatioDesc
+	ch <- dataVersionDesc
+	ch <- leaderEpochDesc
+	ch <- leaderDesc
+	ch <- replicaUpDesc
+	ch <- replicaLagDesc
+	ch <- namespaceKeysDesc
+	ch <- namespaceBytesDesc
+	ch <- namespaceMaxKeysDesc
+	ch <- namespaceMaxBytesDesc
+	ch <- namespaceCompressedRawDesc
+	ch <- namespaceCompressedStoredDesc
+	ch <- namespaceRequestsDesc
+	ch <- namespaceThrottledDesc
+}
+
+func (c *nodeCollector) Collect(ch chan<- prometheus.Metric) {
+	ch <- prometheus.MustNewConstMetric(keysDesc, prometheus.GaugeValue, float64(c.storageService.KeyCount()))
+	ch <- prometheus.MustNewConstMetric(memoryDesc, prometheus.GaugeValue, float64(c.storageService.MemoryUsage()))
+	ch <- prometheus.MustNewConstMetric(maxMemoryDesc, prometheus.GaugeValue, float64(c.storageService.MaxMemory()))
+	ch <- prometheus.MustNewConstMetric(evictedDesc, prometheus.CounterValue, float64(c.storageService.EvictedCount()))
+	ch <- prometheus.MustNewConstMetric(dataVersionDesc, prometheus.GaugeValue, float64(c.storageService.GetDataVersion(context.Background())))
+	ch <- prometheus.MustNewConstMetric(leaderEpochDesc, prometheus.GaugeValue, float64(c.storageService.LeaderEpoch()))
+	ch <- prometheus.MustNewConstMetric(leaderDesc, prometheus.GaugeValue, boolToFloat(c.storageService.IsLeader()))
+
+	for _, replica := range c.cm.Stats() {
+		ch <- prometheus.MustNewConstMetric(replicaUpDesc, prometheus.GaugeValue, boolToFloat(replica.Up), replica.ID)
+		ch <- prometheus.MustNewConstMetric(replicaLagDesc, prometheus.GaugeValue, float64(replica.Lag), replica.ID)
+	}
+
+	var raw, stored int64
+	for _, ns := range c.storageService.Namespaces() {
+		ch <- prometheus.MustNewConstMetric(namespaceKeysDesc, prometheus.GaugeValue, float64(ns.Keys), ns.Name)
+		ch <- prometheus.MustNewConstMetric(namespaceBytesDesc, prometheus.GaugeValue, float64(ns.Bytes), ns.Name)
+		ch <- prometheus.MustNewConstMetric(namespaceMaxKeysDesc, prometheus.GaugeValue, float64(ns.Quota.MaxKeys), ns.Name)
+		ch <- prometheus.MustNewConstMetric(namespaceMaxBytesDesc, prometheus.GaugeValue, float64(ns.Quota.MaxBytes), ns.Name)
+		ch <- prometheus.MustNewConstMetric(namespaceCompressedRawDesc, prometheus.CounterValue, float64(ns.Compression.RawBytes), ns.Name)
+		ch <- prometheus.MustNewConstMetric(namespaceCompressedStoredDesc, prometheus.CounterValue, float64(ns.Compression.StoredBytes), ns.Name)
+		ch <- prometheus.MustNewConstMetric(namespaceRequestsDesc, prometheus.CounterValue, float64(ns.Requests), ns.Name)
+		ch <- prometheus.MustNewConstMetric(namespaceThrottledDesc, prometheus.CounterValue, float64(ns.Throttled), ns.Name)
+		raw += ns.Compression.RawBytes
+		stored += ns.Compression.StoredBytes
+	}
+
+	ratio := 1.0
+	if stored > 0 {
+		ratio = float64(raw) / float64(stored)
+	}
+	ch <- prometheus.MustNewConstMetric(compressionRatioDesc, prometheus.GaugeValue, ratio)
+}
+
+func boolToFloat(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
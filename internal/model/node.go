@@ -1,18 +1,35 @@
 package model
 
+import "sync"
+
 type Node struct {
-	id       string
-	nomadID  string
-	address  string
-	isLeader bool
+	id      string
+	nomadID string
+	address string
+
+	mu            sync.RWMutex
+	isLeader      bool
+	leaderAddress string
+	// upstreams counts the replication streams currently open from the leader.
+	upstreams int
 }
 
+// NewNode creates a node that leads until told otherwise. The nomad id
+// identifies it in leader announcements, the id and then the address stand
+// in for an empty one so it is never empty.
 func NewNode(id, nomadID, address string) *Node {
+	if nomadID == "" {
+		nomadID = id
+	}
+	if nomadID == "" {
+		nomadID = address
+	}
 	return &Node{
-		id:       id,
-		nomadID:  nomadID,
-		address:  address,
-		isLeader: true,
+		id:            id,
+		nomadID:       nomadID,
+		address:       address,
+		isLeader:      true,
+		leaderAddress: address,
 	}
 }
 
@@ -24,14 +41,56 @@ func (node *Node) NomadID() string {
 	return node.nomadID
 }
 
+// Is reports whether the nomad id names this node, an empty one names none.
+func (node *Node) Is(nomadID string) bool {
+	return nomadID != "" && nomadID == node.nomadID
+}
+
 func (node *Node) Address() string {
 	return node.address
 }
 
 func (node *Node) IsLeader() bool {
+	node.mu.RLock()
+	defer node.mu.RUnlock()
 	return node.isLeader
 }
 
 func (node *Node) SetLeader(isLeader bool) {
+	node.mu.Lock()
+	defer node.mu.Unlock()
 	node.isLeader = isLeader
 }
+
+// LeaderAddress returns the address of the current leader as last announced
+// by the cluster manager, or an empty string if it is not known yet.
+func (node *Node) LeaderAddress() string {
+	node.mu.RLock()
+	defer node.mu.RUnlock()
+	return node.leaderAddress
+}
+
+func (node *Node) SetLeaderAddress(address string) {
+	node.mu.Lock()
+	defer node.mu.Unlock()
+	node.leaderAddress = address
+}
+
+func (node *Node) AttachUpstream() {
+	node.mu.Lock()
+	defer node.mu.Unlock()
+	node.upstreams++
+}
+
+func (node *Node) DetachUpstream() {
+	node.mu.Lock()
+	defer node.mu.Unlock()
+	node.upstreams--
+}
+
+// Following reports whether the leader has a replication stream open to this node.
+func (node *Node) Following() bool {
+	node.mu.RLock()
+	defer node.mu.RUnlock()
+	return node.upstreams > 0
+}
@@ -0,0 +1,148 @@
+package mtls
+
+import (
+	"context"
+	"crypto/x509"
+	"net"
+	"net/http"
+	"net/netip"
+
+	desc "github.com/Na322Pr/kv-storage-service/pkg/api"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// internalMethods are called only by other nodes and the cluster manager.
+var internalMethods = map[string]bool{
+	desc.KeyValueStorage_SetStream_FullMethodName:       true,
+	desc.KeyValueStorage_LeMeta_FullMethodName:          true,
+	desc.KeyValueStorage_UpdateLeader_FullMethodName:    true,
+	desc.KeyValueStorage_UpdateAddresses_FullMethodName: true,
+}
+
+// IsInternal reports whether the method is reserved for cluster members.
+func IsInternal(method string) bool {
+	return internalMethods[method]
+}
+
+func (r *Reloader) UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		ctx, err := r.Authorize(ctx, info.FullMethod)
+		if err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+func (r *Reloader) StreamServerInterceptor() grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, err := r.Authorize(ss.Context(), info.FullMethod)
+		if err != nil {
+			return err
+		}
+		return handler(srv, &serverStream{ServerStream: ss, ctx: ctx})
+	}
+}
+
+// Authorize applies the trust policy of the method: internal RPCs require a
+// certificate issued by the peer CA with an allowed name, client-facing RPCs
+// verify an optional certificate against the client CA or the peer CA.
+// The returned context carries the verified certificate.
+func (r *Reloader) Authorize(ctx context.Context, method string) (context.Context, error) {
+	chain := peerChain(ctx)
+	clientCAs, peerCAs := r.pools()
+
+	if IsInternal(method) {
+		leaf, err := verify(chain, peerCAs, x509.ExtKeyUsageClientAuth)
+		if err != nil {
+			return nil, status.Errorf(codes.Unauthenticated, "peer certificate: %v", err)
+		}
+		if err := r.checkPeerName(leaf); err != nil {
+			return nil, status.Error(codes.PermissionDenied, err.Error())
+		}
+		return withPeerCertificate(ctx, leaf), nil
+	}
+
+	if len(chain) == 0 {
+		if r.cfg.RequireClientCert {
+			return nil, status.Error(codes.Unauthenticated, "client certificate required")
+		}
+		return ctx, nil
+	}
+	// Cluster members may call client-facing RPCs as well.
+	if leaf, err := verify(chain, peerCAs, x509.ExtKeyUsageClientAuth); err == nil {
+		return withPeerCertificate(ctx, leaf), nil
+	}
+	if clientCAs == nil {
+		return ctx, nil
+	}
+	leaf, err := verify(chain, clientCAs, x509.ExtKeyUsageClientAuth)
+	if err != nil {
+		return nil, status.Errorf(codes.Unauthenticated, "client certificate: %v", err)
+	}
+	return withPeerCertificate(ctx, leaf), nil
+}
+
+// WithHTTPPeer returns ctx with the peer of the HTTP request, its address
+// and the TLS state of its connection, as gRPC attaches them to calls.
+func WithHTTPPeer(ctx context.Context, req *http.Request) context.Context {
+	p := &peer.Peer{}
+	if addr, err := netip.ParseAddrPort(req.RemoteAddr); err == nil {
+		p.Addr = net.TCPAddrFromAddrPort(addr)
+	}
+	if req.TLS != nil {
+		p.AuthInfo = credentials.TLSInfo{State: *req.TLS}
+	}
+	return peer.NewContext(ctx, p)
+}
+
+// Handler verifies the client certificate of the requests to h like for a
+// client-facing RPC, h finds the verified certificate in the context.
+func (r *Reloader) Handler(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		ctx, err := r.Authorize(WithHTTPPeer(req.Context(), req), req.URL.Path)
+		if err != nil {
+			http.Error(w, status.Convert(err).Message(), http.StatusUnauthorized)
+			return
+		}
+		h.ServeHTTP(w, req.WithContext(ctx))
+	})
+}
+
+type peerCertificateKey struct{}
+
+func withPeerCertificate(ctx context.Context, cert *x509.Certificate) context.Context {
+	return context.WithValue(ctx, peerCertificateKey{}, cert)
+}
+
+// PeerCertificate returns the caller certificate once it has been verified
+// by the interceptors.
+func PeerCertificate(ctx context.Context) (*x509.Certificate, bool) {
+	cert, ok := ctx.Value(peerCertificateKey{}).(*x509.Certificate)
+	return cert, ok
+}
+
+type serverStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *serverStream) Context() context.Context {
+	return s.ctx
+}
+
+func peerChain(ctx context.Context) []*x509.Certificate {
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return nil
+	}
+	info, ok := p.AuthInfo.(credentials.TLSInfo)
+	if !ok {
+		return nil
+	}
+	return info.State.PeerCertificates
+}
@@ -0,0 +1,265 @@
+package mtls
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/Na322Pr/kv-storage-service/internal/config"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/credentials"
+)
+
+var ErrPeerNotAllowed = errors.New("peer name is not allowed")
+
+// Reloader holds the node certificate and trust pools and reloads them when
+// the files change on disk, so certificates are rotated without a restart.
+type Reloader struct {
+	cfg    config.TLS
+	logger *zap.Logger
+
+	mu        sync.RWMutex
+	cert      *tls.Certificate
+	clientCAs *x509.CertPool
+	peerCAs   *x509.CertPool
+	modTimes  map[string]time.Time
+}
+
+func NewReloader(cfg config.TLS, logger *zap.Logger) (*Reloader, error) {
+	r := &Reloader{
+		cfg:    cfg,
+		logger: logger,
+	}
+	if err := r.load(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Run checks the files every reload interval until ctx is done. A failed
+// reload keeps the previous certificates.
+func (r *Reloader) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.cfg.ReloadInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if !r.changed() {
+				continue
+			}
+			if err := r.load(); err != nil {
+				r.logger.Error("failed to reload TLS certificates", zap.Error(err))
+				continue
+			}
+			r.logger.Info("TLS certificates reloaded")
+		}
+	}
+}
+
+func (r *Reloader) files() []string {
+	files := []string{r.cfg.CertFile, r.cfg.KeyFile, r.cfg.PeerCAFile}
+	if r.cfg.ClientCAFile != "" {
+		files = append(files, r.cfg.ClientCAFile)
+	}
+	return files
+}
+
+func (r *Reloader) changed() bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, file := range r.files() {
+		info, err := os.Stat(file)
+		if err != nil {
+			r.logger.Warn("failed to stat TLS file", zap.String("file", file), zap.Error(err))
+			continue
+		}
+		if !info.ModTime().Equal(r.modTimes[file]) {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *Reloader) load() error {
+	modTimes := make(map[string]time.Time)
+	for _, file := range r.files() {
+		info, err := os.Stat(file)
+		if err != nil {
+			return err
+		}
+		modTimes[file] = info.ModTime()
+	}
+
+	cert, err := tls.LoadX509KeyPair(r.cfg.CertFile, r.cfg.KeyFile)
+	if err != nil {
+		return fmt.Errorf("load key pair: %w", err)
+	}
+
+	peerCAs, err := loadPool(r.cfg.PeerCAFile)
+	if err != nil {
+		return err
+	}
+
+	var clientCAs *x509.CertPool
+	if r.cfg.ClientCAFile != "" {
+		if clientCAs, err = loadPool(r.cfg.ClientCAFile); err != nil {
+			return err
+		}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.cert = &cert
+	r.peerCAs = peerCAs
+	r.clientCAs = clientCAs
+	r.modTimes = modTimes
+	return nil
+}
+
+func loadPool(file string) (*x509.CertPool, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(data) {
+		return nil, fmt.Errorf("no certificates found in %s", file)
+	}
+	return pool, nil
+}
+
+func (r *Reloader) certificate() *tls.Certificate {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.cert
+}
+
+func (r *Reloader) pools() (clientCAs, peerCAs *x509.CertPool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.clientCAs, r.peerCAs
+}
+
+// ServerCredentials serves the current certificate and asks callers for theirs.
+// Client certificates are verified per RPC by the interceptors, because
+// client-facing and internal RPCs trust different authorities.
+func (r *Reloader) ServerCredentials() credentials.TransportCredentials {
+	return credentials.NewTLS(r.ServerConfig())
+}
+
+// ServerConfig is the TLS configuration of ServerCredentials, for the
+// servers of other protocols. They verify client certificates per request
+// with Authorize.
+func (r *Reloader) ServerConfig() *tls.Config {
+	return &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		GetCertificate: func(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+			return r.certificate(), nil
+		},
+		ClientAuth: tls.RequestClientCert,
+	}
+}
+
+// ClientCredentials is used for connections to other nodes: it presents the
+// node certificate and verifies the server against the peer trust pool.
+func (r *Reloader) ClientCredentials() credentials.TransportCredentials {
+	return &peerCredentials{
+		TransportCredentials: credentials.NewTLS(r.clientConfig("")),
+		reloader:             r,
+	}
+}
+
+func (r *Reloader) clientConfig(host string) *tls.Config {
+	return &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		ServerName: host,
+		GetClientCertificate: func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+			return r.certificate(), nil
+		},
+		// The chain is verified in VerifyConnection, against the pool loaded last.
+		InsecureSkipVerify: true,
+		VerifyConnection: func(state tls.ConnectionState) error {
+			_, peerCAs := r.pools()
+			leaf, err := verify(state.PeerCertificates, peerCAs, x509.ExtKeyUsageServerAuth)
+			if err != nil {
+				return err
+			}
+			if err := leaf.VerifyHostname(host); err != nil {
+				return err
+			}
+			return r.checkPeerName(leaf)
+		},
+	}
+}
+
+// peerCredentials builds the TLS config per handshake, so the server
+// certificate is checked against the dialed host, IP addresses included.
+type peerCredentials struct {
+	credentials.TransportCredentials
+	reloader *Reloader
+}
+
+func (c *peerCredentials) ClientHandshake(ctx context.Context, authority string, conn net.Conn) (net.Conn, credentials.AuthInfo, error) {
+	host := authority
+	if h, _, err := net.SplitHostPort(authority); err == nil {
+		host = h
+	}
+	return credentials.NewTLS(c.reloader.clientConfig(host)).ClientHandshake(ctx, authority, conn)
+}
+
+func (c *peerCredentials) Clone() credentials.TransportCredentials {
+	return &peerCredentials{
+		TransportCredentials: c.TransportCredentials.Clone(),
+		reloader:             c.reloader,
+	}
+}
+
+// verify checks the chain presented by the remote side and returns its leaf.
+func verify(chain []*x509.Certificate, roots *x509.CertPool, usage x509.ExtKeyUsage) (*x509.Certificate, error) {
+	if len(chain) == 0 {
+		return nil, errors.New("no certificate presented")
+	}
+
+	intermediates := x509.NewCertPool()
+	for _, cert := range chain[1:] {
+		intermediates.AddCert(cert)
+	}
+
+	_, err := chain[0].Verify(x509.VerifyOptions{
+		Roots:         roots,
+		Intermediates: intermediates,
+		KeyUsages:     []x509.ExtKeyUsage{usage},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return chain[0], nil
+}
+
+// checkPeerName accepts the certificate if one of its DNS names or its common
+// name is in PeerNames.
+func (r *Reloader) checkPeerName(cert *x509.Certificate) error {
+	if len(r.cfg.PeerNames) == 0 {
+		return nil
+	}
+	if slices.Contains(r.cfg.PeerNames, cert.Subject.CommonName) {
+		return nil
+	}
+	for _, name := range cert.DNSNames {
+		if slices.Contains(r.cfg.PeerNames, name) {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: %s", ErrPeerNotAllowed, cert.Subject.CommonName)
+}
@@ -0,0 +1,311 @@
+package mtls_test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Na322Pr/kv-storage-service/internal/config"
+	"github.com/Na322Pr/kv-storage-service/internal/mtls"
+	desc "github.com/Na322Pr/kv-storage-service/pkg/api"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/status"
+)
+
+type authority struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	pem  []byte
+}
+
+func newAuthority(t *testing.T, name string) authority {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: name},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("create certificate: %v", err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("parse certificate: %v", err)
+	}
+	return authority{cert: cert, key: key, pem: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})}
+}
+
+// issue returns the PEM certificate and key of name, valid for both ends of
+// a connection on localhost.
+func (a authority) issue(t *testing.T, name string) ([]byte, []byte) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: name},
+		DNSNames:     []string{name, "localhost"},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, a.cert, &key.PublicKey, a.key)
+	if err != nil {
+		t.Fatalf("create certificate: %v", err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatalf("marshal key: %v", err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+}
+
+// clientConfig returns a client presenting a certificate of name issued by
+// the authority, or no certificate for a nil authority.
+func (a *authority) clientConfig(t *testing.T, peers authority, name string) *tls.Config {
+	t.Helper()
+	roots := x509.NewCertPool()
+	roots.AddCert(peers.cert)
+	cfg := &tls.Config{RootCAs: roots, ServerName: "localhost"}
+	if a != nil {
+		cert, key := a.issue(t, name)
+		pair, err := tls.X509KeyPair(cert, key)
+		if err != nil {
+			t.Fatalf("key pair: %v", err)
+		}
+		cfg.Certificates = []tls.Certificate{pair}
+	}
+	return cfg
+}
+
+func write(t *testing.T, dir, name string, data []byte) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatalf("write %s: %v", name, err)
+	}
+	return path
+}
+
+type pki struct {
+	peers, clients authority
+	cfg            config.TLS
+}
+
+func newPKI(t *testing.T) pki {
+	t.Helper()
+	dir := t.TempDir()
+	peers, clients := newAuthority(t, "peer-ca"), newAuthority(t, "client-ca")
+	cert, key := peers.issue(t, "node1")
+	return pki{
+		peers:   peers,
+		clients: clients,
+		cfg: config.TLS{
+			Enabled:        true,
+			CertFile:       write(t, dir, "node.crt", cert),
+			KeyFile:        write(t, dir, "node.key", key),
+			PeerCAFile:     write(t, dir, "peer-ca.pem", peers.pem),
+			ClientCAFile:   write(t, dir, "client-ca.pem", clients.pem),
+			PeerNames:      []string{"node1", "node2", "cluster-manager"},
+			ReloadInterval: 20 * time.Millisecond,
+		},
+	}
+}
+
+type server struct {
+	desc.UnimplementedKeyValueStorageServer
+}
+
+func (server) LeMeta(context.Context, *desc.LeMetaRequest) (*desc.LeMetaResponse, error) {
+	return &desc.LeMetaResponse{}, nil
+}
+
+func (server) Get(context.Context, *desc.GetRequest) (*desc.GetResponse, error) {
+	return &desc.GetResponse{}, nil
+}
+
+func serve(t *testing.T, r *mtls.Reloader) string {
+	t.Helper()
+	s := grpc.NewServer(
+		grpc.Creds(r.ServerCredentials()),
+		grpc.UnaryInterceptor(r.UnaryServerInterceptor()),
+	)
+	desc.RegisterKeyValueStorageServer(s, server{})
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	go s.Serve(lis)
+	t.Cleanup(s.Stop)
+	return lis.Addr().String()
+}
+
+// calls returns the codes of an internal and a client-facing RPC.
+func calls(t *testing.T, addr string, creds credentials.TransportCredentials) (codes.Code, codes.Code) {
+	t.Helper()
+	conn, err := grpc.NewClient(addr, grpc.WithTransportCredentials(creds))
+	if err != nil {
+		t.Fatalf("new client: %v", err)
+	}
+	defer conn.Close()
+	client := desc.NewKeyValueStorageClient(conn)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	_, internalErr := client.LeMeta(ctx, &desc.LeMetaRequest{})
+	_, clientErr := client.Get(ctx, &desc.GetRequest{Key: "a"})
+	return status.Code(internalErr), status.Code(clientErr)
+}
+
+func TestAuthorize(t *testing.T) {
+	p := newPKI(t)
+	r, err := mtls.NewReloader(p.cfg, zap.NewNop())
+	if err != nil {
+		t.Fatalf("new reloader: %v", err)
+	}
+	addr := serve(t, r)
+	other := newAuthority(t, "other-ca")
+
+	for _, tt := range []struct {
+		name                string
+		issuer              *authority
+		cn                  string
+		internal, clientRPC codes.Code
+	}{
+		{"no certificate", nil, "", codes.Unauthenticated, codes.OK},
+		{"client", &p.clients, "alice", codes.Unauthenticated, codes.OK},
+		{"peer", &p.peers, "node2", codes.OK, codes.OK},
+		{"peer not allowed", &p.peers, "evil", codes.PermissionDenied, codes.OK},
+		{"unknown issuer", &other, "alice", codes.Unauthenticated, codes.Unauthenticated},
+	} {
+		internal, clientRPC := calls(t, addr, credentials.NewTLS(tt.issuer.clientConfig(t, p.peers, tt.cn)))
+		if internal != tt.internal || clientRPC != tt.clientRPC {
+			t.Errorf("%s: internal %s, client-facing %s, want %s and %s", tt.name, internal, clientRPC, tt.internal, tt.clientRPC)
+		}
+	}
+}
+
+func TestRequireClientCert(t *testing.T) {
+	p := newPKI(t)
+	p.cfg.RequireClientCert = true
+	r, err := mtls.NewReloader(p.cfg, zap.NewNop())
+	if err != nil {
+		t.Fatalf("new reloader: %v", err)
+	}
+	addr := serve(t, r)
+
+	if _, code := calls(t, addr, credentials.NewTLS((*authority)(nil).clientConfig(t, p.peers, ""))); code != codes.Unauthenticated {
+		t.Fatalf("without a certificate: %s", code)
+	}
+	if _, code := calls(t, addr, credentials.NewTLS(p.clients.clientConfig(t, p.peers, "alice"))); code != codes.OK {
+		t.Fatalf("with a certificate: %s", code)
+	}
+}
+
+func TestPeerCredentialsAndReload(t *testing.T) {
+	p := newPKI(t)
+	r, err := mtls.NewReloader(p.cfg, zap.NewNop())
+	if err != nil {
+		t.Fatalf("new reloader: %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go r.Run(ctx)
+	addr := serve(t, r)
+
+	// Another node dials with its own reloader.
+	dir := t.TempDir()
+	cert, key := p.peers.issue(t, "node2")
+	cfg := p.cfg
+	cfg.CertFile, cfg.KeyFile = write(t, dir, "node2.crt", cert), write(t, dir, "node2.key", key)
+	node2, err := mtls.NewReloader(cfg, zap.NewNop())
+	if err != nil {
+		t.Fatalf("new reloader: %v", err)
+	}
+	if internal, _ := calls(t, addr, node2.ClientCredentials()); internal != codes.OK {
+		t.Fatalf("peer call: %s", internal)
+	}
+
+	// A certificate rotated to another authority is served without a
+	// restart, peers no longer trust it.
+	other := newAuthority(t, "other-ca")
+	cert, key = other.issue(t, "node1")
+	time.Sleep(20 * time.Millisecond)
+	write(t, filepath.Dir(p.cfg.CertFile), "node.crt", cert)
+	write(t, filepath.Dir(p.cfg.KeyFile), "node.key", key)
+	for deadline := time.Now().Add(5 * time.Second); ; {
+		if internal, _ := calls(t, addr, node2.ClientCredentials()); internal == codes.Unavailable {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("rotated certificate never served")
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
+
+func TestHandler(t *testing.T) {
+	p := newPKI(t)
+	p.cfg.RequireClientCert = true
+	r, err := mtls.NewReloader(p.cfg, zap.NewNop())
+	if err != nil {
+		t.Fatalf("new reloader: %v", err)
+	}
+	ts := httptest.NewUnstartedServer(r.Handler(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		cert, ok := mtls.PeerCertificate(req.Context())
+		if !ok {
+			http.Error(w, "no verified certificate", http.StatusInternalServerError)
+			return
+		}
+		w.Write([]byte(cert.Subject.CommonName))
+	})))
+	ts.TLS = r.ServerConfig()
+	ts.StartTLS()
+	defer ts.Close()
+
+	get := func(cfg *tls.Config) int {
+		t.Helper()
+		client := &http.Client{Transport: &http.Transport{TLSClientConfig: cfg}}
+		resp, err := client.Get(ts.URL)
+		if err != nil {
+			t.Fatalf("get: %v", err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+	if code := get((*authority)(nil).clientConfig(t, p.peers, "")); code != http.StatusUnauthorized {
+		t.Fatalf("without a certificate: %d", code)
+	}
+	if code := get(p.clients.clientConfig(t, p.peers, "alice")); code != http.StatusOK {
+		t.Fatalf("with a certificate: %d", code)
+	}
+}
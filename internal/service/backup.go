@@ -0,0 +1,171 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+
+	"github.com/Na322Pr/kv-storage-service/internal/storage"
+	desc "github.com/Na322Pr/kv-storage-service/pkg/api"
+)
+
+var (
+	ErrNotEmpty  = errors.New("storage is not empty, restore needs a fresh leader")
+	ErrRestoring = errors.New("a restore is in progress")
+)
+
+// Dump returns a consistent view of the data to back up, it must be released.
+func (s *StorageService) Dump() (*storage.Dump, error) {
+	return s.store.Dump()
+}
+
+// Restorer loads a backup into an empty leader. Every change is sent to the
+// replicas as it is applied, so they are seeded with the backup as well.
+type Restorer struct {
+	s *StorageService
+}
+
+// BeginRestore starts a restore. Replicas may hold data from before the
+// restore, they are reset first. Other writes fail with ErrRestoring until
+// the restore is committed or aborted.
+func (s *StorageService) BeginRestore(ctx context.Context) (*Restorer, error) {
+	defer s.flush()
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+
+	if !s.restoring.CompareAndSwap(false, true) {
+		return nil, ErrRestoring
+	}
+	if !s.store.Empty() {
+		s.restoring.Store(false)
+		return nil, ErrNotEmpty
+	}
+	s.queueOperation(ctx, OperationReset, 0)
+	return &Restorer{s: s}, nil
+}
+
+// Namespace creates the namespace with its quota.
+func (r *Restorer) Namespace(ctx context.Context, name string, quota storage.Quota) error {
+	value, err := json.Marshal(quota)
+	if err != nil {
+		return err
+	}
+	defer r.s.flush()
+	r.s.writeMu.Lock()
+	defer r.s.writeMu.Unlock()
+
+	revision, err := r.s.store.SetQuota(name, quota)
+	if err != nil {
+		return err
+	}
+	r.s.limits.set(name, quota.RequestsPerSecond)
+	r.s.propagate(ctx, SetMessage{Namespace: name, Value: string(value), Operation: OperationCreateNamespace, Revision: revision})
+	return nil
+}
+
+// Item stores an inline value with the revision it has in the backup. Lock
+// keys are skipped, the leases holding them are not part of backups.
+func (r *Restorer) Item(ctx context.Context, msg SetMessage) error {
+	if reserved(msg) {
+		return nil
+	}
+	msg.Operation = OperationRestore
+	_, err := r.s.Set(ctx, msg)
+	return err
+}
+
+// Large starts a large value of the backup, its Commit stores the key with
+// the revision of msg.
+func (r *Restorer) Large(msg SetMessage) (*LargeUpload, error) {
+	msg.Operation = OperationRestore
+	return r.s.BeginLarge(msg)
+}
+
+// Commit moves the data version to the revision the backup was taken at
+// and returns the data version.
+func (r *Restorer) Commit(ctx context.Context, revision int64) (int64, error) {
+	defer r.s.flush()
+	r.s.writeMu.Lock()
+	defer r.s.writeMu.Unlock()
+
+	version, err := r.s.store.RestoreVersion(revision)
+	if err != nil {
+		return 0, err
+	}
+	r.s.queueOperation(ctx, OperationRestoreDone, revision)
+	r.s.restoring.Store(false)
+	return version, nil
+}
+
+// Abort drops what was restored so far, here and on the replicas. No other
+// write got in since BeginRestore, the store is empty again.
+func (r *Restorer) Abort(ctx context.Context) error {
+	defer r.s.flush()
+	r.s.writeMu.Lock()
+	defer r.s.writeMu.Unlock()
+
+	err := r.s.reset()
+	r.s.queueOperation(ctx, OperationReset, 0)
+	r.s.restoring.Store(false)
+	return err
+}
+
+// restore stores the item of a backup, on the leader or on a replica. Items
+// of a resync carry their lease.
+func (s *StorageService) restore(msg SetMessage) (int64, error) {
+	item := storage.Item{
+		Value:       msg.Value,
+		Expiration:  msg.Expiration,
+		ContentType: msg.ContentType,
+		Blob:        msg.Blob,
+		Size:        msg.Size,
+		Codec:       msg.Codec,
+		Revision:    msg.Revision,
+		Lease:       msg.Lease,
+	}
+	if err := s.store.Restore(msg.Namespace, msg.Key, item); err != nil {
+		return 0, err
+	}
+	if msg.Replicated && item.Large() {
+		s.uploads.take(item.Blob)
+	}
+	return msg.Revision, nil
+}
+
+// applyRestore applies a reset or the end of a restore the leader replicated.
+func (s *StorageService) applyRestore(msg SetMessage) (int64, error) {
+	switch msg.Operation {
+	case OperationReset:
+		return 0, s.reset()
+	case OperationRestoreDone:
+		return s.store.RestoreVersion(msg.Revision)
+	}
+	return 0, nil
+}
+
+// reset drops all the data with the leases attached to it and the large
+// values being received.
+func (s *StorageService) reset() error {
+	if err := s.store.Reset(); err != nil {
+		return err
+	}
+	s.leases.reset()
+	s.uploads.reset()
+	return nil
+}
+
+// queueOperation queues a reset or the end of a restore for the replicas,
+// it must be called with writeMu held.
+func (s *StorageService) queueOperation(ctx context.Context, operation Operation, revision int64) {
+	op := string(operation)
+	s.queue(ctx, &desc.SetRequest{Operation: &op, Revision: revision})
+}
+
+// writable fails the writes of clients while a restore is in progress, it
+// must be called with writeMu held.
+func (s *StorageService) writable() error {
+	if s.restoring.Load() {
+		return ErrRestoring
+	}
+	return nil
+}
@@ -0,0 +1,61 @@
+package service
+
+import (
+	"context"
+
+	"github.com/Na322Pr/kv-storage-service/internal/storage"
+	desc "github.com/Na322Pr/kv-storage-service/pkg/api"
+)
+
+// SetBatch stores the items of the namespace in one write and sends them to
+// the replicas in one message, and returns the revision of the last item.
+// Keys of the batch are detached from their leases.
+func (s *StorageService) SetBatch(ctx context.Context, namespace string, items []storage.BatchItem) (int64, error) {
+	return s.Set(ctx, SetMessage{Namespace: namespace, Batch: items, Operation: OperationBatch})
+}
+
+// setBatch stores a batch on the leader or on a replica.
+func (s *StorageService) setBatch(msg SetMessage) (int64, error) {
+	if msg.Replicated {
+		return s.store.ApplyBatch(msg.Namespace, msg.Batch, msg.Revision)
+	}
+	for i := range msg.Batch {
+		msg.Batch[i].Item = s.store.Compress(msg.Namespace, msg.Batch[i].Item)
+	}
+	return s.store.SetBatch(msg.Namespace, msg.Batch)
+}
+
+// propagateBatch notifies the watchers of every key of the batch and queues
+// it for the replicas as one request, it must be called with writeMu held.
+func (s *StorageService) propagateBatch(ctx context.Context, msg SetMessage) {
+	entries := make([]*desc.SetBatchEntry, 0, len(msg.Batch))
+	for _, bi := range msg.Batch {
+		if s.watchers.watching(msg.Namespace, bi.Key) {
+			value := bi.Item.Value
+			if bi.Item.Codec != storage.CodecNone {
+				value, _ = storage.Decode(bi.Item.Codec, bi.Item.Value)
+			}
+			s.watchers.publish(WatchEvent{
+				Namespace: msg.Namespace,
+				Key:       bi.Key,
+				Value:     value,
+				Operation: OperationSet,
+			})
+		}
+		entries = append(entries, &desc.SetBatchEntry{
+			Key:         bi.Key,
+			Value:       []byte(bi.Item.Value),
+			ContentType: bi.Item.ContentType,
+			Expiration:  bi.Item.Expiration,
+			Codec:       uint32(bi.Item.Codec),
+		})
+	}
+
+	operation := string(OperationBatch)
+	s.queue(ctx, &desc.SetRequest{
+		Namespace: msg.Namespace,
+		Operation: &operation,
+		Entries:   entries,
+		Revision:  msg.Revision,
+	})
+}
@@ -1,25 +1,100 @@
 package service
 
 import (
+	"context"
+	"errors"
 	desc "github.com/Na322Pr/kv-storage-service/pkg/api"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
 	"sync"
+	"sync/atomic"
+	"time"
 )
 
+// ReplicaStats describes the replication stream to a single replica.
+type ReplicaStats struct {
+	ID string
+	Up bool
+	// Lag is the number of messages sent to the replica and not yet acknowledged.
+	Lag int64
+}
+
+type replicaConnection struct {
+	stream desc.KeyValueStorage_SetStreamClient
+	// sendMu serializes Send, gRPC streams do not allow concurrent senders.
+	sendMu sync.Mutex
+	// buffering is set while the replica receives a snapshot, the messages
+	// broadcast meanwhile wait in pending. Both are guarded by sendMu.
+	buffering bool
+	pending   []*desc.SetRequest
+	sent      atomic.Int64
+	acked     atomic.Int64
+	up        atomic.Bool
+	// closed is set once the stream failed, it is never up again.
+	closed atomic.Bool
+}
+
+// receiveAcks counts the responses the replica sends for every applied message.
+func (c *replicaConnection) receiveAcks() {
+	for {
+		if _, err := c.stream.Recv(); err != nil {
+			c.closed.Store(true)
+			c.up.Store(false)
+			return
+		}
+		c.acked.Add(1)
+	}
+}
+
+var errStreamClosed = errors.New("replication stream closed")
+
 type ConnectionManagerService struct {
-	connections map[string]desc.KeyValueStorage_SetStreamClient
+	connections map[string]*replicaConnection
 	mu          sync.RWMutex
 }
 
 func NewConnectionManagerService() *ConnectionManagerService {
 	return &ConnectionManagerService{
-		connections: make(map[string]desc.KeyValueStorage_SetStreamClient),
+		connections: make(map[string]*replicaConnection),
 	}
 }
 
 func (cm *ConnectionManagerService) AddConnection(id string, stream desc.KeyValueStorage_SetStreamClient) {
+	conn := &replicaConnection{stream: stream}
+	conn.up.Store(true)
+	go conn.receiveAcks()
+
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+	cm.connections[id] = conn
+}
+
+// replace swaps the stream of the replica for a new one, which gets no
+// broadcast message until the replica is resynced.
+func (cm *ConnectionManagerService) replace(id string, stream desc.KeyValueStorage_SetStreamClient) *replicaConnection {
+	conn := &replicaConnection{stream: stream}
+	go conn.receiveAcks()
+
 	cm.mu.Lock()
 	defer cm.mu.Unlock()
-	cm.connections[id] = stream
+	cm.connections[id] = conn
+	return conn
+}
+
+// Down returns the replicas whose stream failed.
+func (cm *ConnectionManagerService) Down() []string {
+	cm.mu.RLock()
+	defer cm.mu.RUnlock()
+
+	var ids []string
+	for id, conn := range cm.connections {
+		if !conn.up.Load() {
+			ids = append(ids, id)
+		}
+	}
+	return ids
 }
 
 func (cm *ConnectionManagerService) RemoveConnection(id string) {
@@ -31,18 +106,137 @@ func (cm *ConnectionManagerService) RemoveConnection(id string) {
 func (cm *ConnectionManagerService) GetConnection(id string) (desc.KeyValueStorage_SetStreamClient, bool) {
 	cm.mu.RLock()
 	defer cm.mu.RUnlock()
-	stream, ok := cm.connections[id]
-	return stream, ok
+	conn, ok := cm.connections[id]
+	if !ok {
+		return nil, false
+	}
+	return conn.stream, true
+}
+
+// Broadcast sends the message to every healthy replica, carrying the trace
+// context so the replica apply joins the leader's trace.
+func (cm *ConnectionManagerService) Broadcast(ctx context.Context, msg *desc.SetRequest) {
+	cm.mu.RLock()
+	defer cm.mu.RUnlock()
+
+	ctx, span := tracer.Start(ctx, "ConnectionManagerService.Broadcast", trace.WithAttributes(
+		attribute.Int("kv.replicas", len(cm.connections)),
+	))
+	defer span.End()
+
+	msg.TraceContext = make(map[string]string)
+	otel.GetTextMapPropagator().Inject(ctx, propagation.MapCarrier(msg.TraceContext))
+
+	for _, conn := range cm.connections {
+		conn.send(msg)
+	}
+}
+
+// send sends the message to a healthy replica, or keeps it for a replica
+// being resynced.
+func (c *replicaConnection) send(msg *desc.SetRequest) {
+	c.sendMu.Lock()
+	defer c.sendMu.Unlock()
+
+	if c.buffering {
+		c.pending = append(c.pending, msg)
+		return
+	}
+	if !c.up.Load() {
+		return
+	}
+	if err := c.stream.Send(msg); err != nil {
+		c.up.Store(false)
+		return
+	}
+	c.sent.Add(1)
 }
 
-func (cm *ConnectionManagerService) Broadcast(msg *desc.SetRequest) {
+// buffer keeps the broadcast messages from now on for the replica.
+func (c *replicaConnection) buffer() {
+	c.sendMu.Lock()
+	defer c.sendMu.Unlock()
+	c.buffering = true
+}
+
+// sendDirect sends a message of the snapshot to the replica being resynced.
+func (c *replicaConnection) sendDirect(msg *desc.SetRequest) error {
+	c.sendMu.Lock()
+	defer c.sendMu.Unlock()
+
+	if err := c.stream.Send(msg); err != nil {
+		return err
+	}
+	c.sent.Add(1)
+	return nil
+}
+
+// resume sends the kept messages and marks the replica healthy, or drops
+// them and leaves it down if the stream fails.
+func (c *replicaConnection) resume() error {
+	c.sendMu.Lock()
+	defer c.sendMu.Unlock()
+
+	pending := c.pending
+	c.buffering, c.pending = false, nil
+	for _, msg := range pending {
+		if err := c.stream.Send(msg); err != nil {
+			return err
+		}
+		c.sent.Add(1)
+	}
+	c.up.Store(true)
+	if c.closed.Load() {
+		c.up.Store(false)
+		return errStreamClosed
+	}
+	return nil
+}
+
+// abort drops the kept messages of a resync that failed.
+func (c *replicaConnection) abort() {
+	c.sendMu.Lock()
+	defer c.sendMu.Unlock()
+	c.buffering, c.pending = false, nil
+}
+
+func (cm *ConnectionManagerService) Stats() []ReplicaStats {
 	cm.mu.RLock()
 	defer cm.mu.RUnlock()
 
-	for _, stream := range cm.connections {
-		if err := stream.Send(msg); err != nil {
-			// Handle error - maybe remove this connection
-			//go cm.RemoveConnection(id)
+	stats := make([]ReplicaStats, 0, len(cm.connections))
+	for id, conn := range cm.connections {
+		stats = append(stats, ReplicaStats{
+			ID:  id,
+			Up:  conn.up.Load(),
+			Lag: conn.sent.Load() - conn.acked.Load(),
+		})
+	}
+	return stats
+}
+
+// WaitCaughtUp blocks until every healthy replica has acknowledged all the
+// messages sent to it or ctx is done.
+func (cm *ConnectionManagerService) WaitCaughtUp(ctx context.Context) error {
+	ticker := time.NewTicker(50 * time.Millisecond)
+	defer ticker.Stop()
+
+	for {
+		caughtUp := true
+		for _, stats := range cm.Stats() {
+			if stats.Up && stats.Lag > 0 {
+				caughtUp = false
+				break
+			}
+		}
+		if caughtUp {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
 		}
 	}
 }
@@ -0,0 +1,84 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	desc "github.com/Na322Pr/kv-storage-service/pkg/api"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+)
+
+var ErrNoReplicas = errors.New("no replica to hand off leadership to")
+
+// HandOff promotes the most up-to-date healthy replica and announces it to the
+// other replicas with the next leader epoch, returning its address. Replicas
+// are ranked by their leader epoch, then by their data version. Replication
+// connections are keyed by the replica gRPC address, which is what
+// UpdateLeader carries as well. The node steps down before the promotion so
+// it accepts no more writes.
+func (s *LeService) HandOff(ctx context.Context) (string, error) {
+	clients := make(map[string]desc.KeyValueStorageClient)
+	for _, stats := range s.cm.Stats() {
+		if !stats.Up {
+			continue
+		}
+
+		conn, err := grpc.NewClient(stats.ID, s.dialOptions...)
+		if err != nil {
+			s.logger.Warn("failed to dial replica", zap.String("replica", stats.ID), zap.Error(err))
+			continue
+		}
+		defer conn.Close()
+		clients[stats.ID] = desc.NewKeyValueStorageClient(conn)
+	}
+
+	var (
+		address string
+		best    *desc.LeMetaResponse
+		// nomadIDs counts the replicas answering with each nomad id.
+		nomadIDs = make(map[string]int)
+	)
+	for replica, client := range clients {
+		meta, err := client.LeMeta(ctx, &desc.LeMetaRequest{})
+		if err != nil {
+			s.logger.Warn("failed to get replica meta", zap.String("replica", replica), zap.Error(err))
+			continue
+		}
+		nomadIDs[meta.NomadId]++
+		if best == nil || meta.LeaderEpoch > best.LeaderEpoch ||
+			meta.LeaderEpoch == best.LeaderEpoch && meta.DataVersion > best.DataVersion {
+			address, best = replica, meta
+		}
+	}
+	if best == nil {
+		return "", ErrNoReplicas
+	}
+	// The replica must be told apart from this node and the others, or
+	// the announcement would promote the wrong ones.
+	if best.NomadId == "" || s.node.Is(best.NomadId) || nomadIDs[best.NomadId] > 1 {
+		return "", fmt.Errorf("replica %s has no nomad id of its own: %q", address, best.NomadId)
+	}
+
+	epoch := max(s.storageService.LeaderEpoch(), best.LeaderEpoch) + 1
+	if err := s.SetLeader(best.NomadId, address, epoch); err != nil {
+		return "", err
+	}
+
+	req := &desc.UpdateLeaderRequest{NomadId: best.NomadId, Address: address, Epoch: epoch}
+	if _, err := clients[address].UpdateLeader(ctx, req); err != nil {
+		return "", fmt.Errorf("promote %s: %w", address, err)
+	}
+
+	for replica, client := range clients {
+		if replica == address {
+			continue
+		}
+		if _, err := client.UpdateLeader(ctx, req); err != nil {
+			s.logger.Warn("failed to announce new leader", zap.String("replica", replica), zap.Error(err))
+		}
+	}
+
+	return address, nil
+}
@@ -0,0 +1,61 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/Na322Pr/kv-storage-service/internal/config"
+	"go.uber.org/zap"
+)
+
+// HistoryCompactor drops the versions of keys replaced longer than the
+// retention ago and those past the versions kept per key. Every node
+// compacts its own history.
+type HistoryCompactor struct {
+	storageService *StorageService
+	cfg            config.History
+	logger         *zap.Logger
+}
+
+func NewHistoryCompactor(storageService *StorageService, cfg config.History, logger *zap.Logger) *HistoryCompactor {
+	return &HistoryCompactor{
+		storageService: storageService,
+		cfg:            cfg,
+		logger:         logger,
+	}
+}
+
+// Run compacts the history every compaction interval until ctx is done. It
+// returns right away when the history keeps every version.
+func (c *HistoryCompactor) Run(ctx context.Context) {
+	if c.cfg.Retention <= 0 && c.cfg.MaxVersions <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(c.cfg.CompactionInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			// Zero cutoff drops no version for its age.
+			var cutoff int64
+			if c.cfg.Retention > 0 {
+				cutoff = time.Now().Add(-c.cfg.Retention).UnixNano()
+			}
+			dropped, err := c.storageService.store.CompactHistory(cutoff, c.cfg.MaxVersions)
+			if err != nil {
+				c.logger.Error("failed to compact history", zap.Error(err))
+				continue
+			}
+			if dropped > 0 {
+				c.logger.Debug("history compacted",
+					zap.Int("dropped", dropped),
+					zap.Int64("compactedRevision", c.storageService.CompactedRevision()),
+				)
+			}
+		}
+	}
+}
@@ -0,0 +1,171 @@
+package service
+
+import (
+	"context"
+	"sync"
+
+	"github.com/Na322Pr/kv-storage-service/internal/storage"
+	desc "github.com/Na322Pr/kv-storage-service/pkg/api"
+)
+
+// LargeUpload writes a large value chunk by chunk on the leader and sends
+// every chunk to the replicas as it arrives, so the value is never held
+// whole in memory. The value is applied by Commit.
+type LargeUpload struct {
+	s    *StorageService
+	msg  SetMessage
+	blob *storage.BlobWriter
+	done bool
+}
+
+// BeginLarge starts a large value to be stored as the set message says.
+func (s *StorageService) BeginLarge(msg SetMessage) (*LargeUpload, error) {
+	if msg.Operation != OperationRestore && reserved(msg) {
+		return nil, ErrReservedKey
+	}
+	// Commit checks again, this only fails the upload before its chunks.
+	if msg.Operation != OperationRestore && s.restoring.Load() {
+		return nil, ErrRestoring
+	}
+	blob, err := s.store.NewBlob(msg.Namespace, 0)
+	if err != nil {
+		return nil, err
+	}
+	return &LargeUpload{s: s, msg: msg, blob: blob}, nil
+}
+
+// Write stores the chunk and forwards it to the replicas.
+func (u *LargeUpload) Write(ctx context.Context, chunk []byte) error {
+	if len(chunk) == 0 {
+		return nil
+	}
+	if _, err := u.blob.Write(chunk); err != nil {
+		return err
+	}
+
+	operation := string(OperationBlobChunk)
+	u.s.replicate(ctx, &desc.SetRequest{
+		Namespace: u.msg.Namespace,
+		Operation: &operation,
+		RawValue:  chunk,
+		Blob:      u.blob.ID(),
+	})
+	return nil
+}
+
+// Size returns the number of bytes written so far.
+func (u *LargeUpload) Size() int64 {
+	return u.blob.Size()
+}
+
+// Commit stores the key referencing the written value and returns the
+// revision of the write. The value is dropped if the write fails.
+func (u *LargeUpload) Commit(ctx context.Context) (int64, error) {
+	msg := u.msg
+	if msg.Operation != OperationRestore {
+		msg.Operation = OperationSet
+	}
+	msg.Value = ""
+	msg.Blob, msg.Size = u.blob.ID(), u.blob.Size()
+
+	revision, err := u.s.Set(ctx, msg)
+	if err != nil {
+		_ = u.Abort(ctx)
+		return 0, err
+	}
+	u.done = true
+	return revision, nil
+}
+
+// Abort drops the value written so far, here and on the replicas. It does
+// nothing after a successful Commit.
+func (u *LargeUpload) Abort(ctx context.Context) error {
+	if u.done {
+		return nil
+	}
+	u.done = true
+
+	err := u.blob.Abort()
+	operation := string(OperationBlobAbort)
+	u.s.replicate(ctx, &desc.SetRequest{
+		Namespace: u.msg.Namespace,
+		Operation: &operation,
+		Blob:      u.blob.ID(),
+	})
+	return err
+}
+
+// ReadLarge calls fn with the chunks of the value of the item in order,
+// inline values come as a single chunk.
+func (s *StorageService) ReadLarge(_ context.Context, namespace string, item storage.Item, fn func(chunk []byte) error) error {
+	return s.store.ReadBlob(namespace, item, fn)
+}
+
+// MaxValueSize returns the limit of large values, zero is unlimited.
+func (s *StorageService) MaxValueSize() int64 {
+	return s.store.MaxValueSize()
+}
+
+// blobUploads keeps the large values a replica receives from the leader
+// until the write referencing them is applied.
+type blobUploads struct {
+	mu      sync.Mutex
+	writers map[int64]*storage.BlobWriter
+}
+
+func newBlobUploads() *blobUploads {
+	return &blobUploads{
+		writers: make(map[int64]*storage.BlobWriter),
+	}
+}
+
+// writer returns the writer of the blob, starting it on its first chunk.
+func (u *blobUploads) writer(store *storage.Store, namespace string, blob int64) (*storage.BlobWriter, error) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	if w, ok := u.writers[blob]; ok {
+		return w, nil
+	}
+	w, err := store.NewBlob(namespace, blob)
+	if err != nil {
+		return nil, err
+	}
+	u.writers[blob] = w
+	return w, nil
+}
+
+// take forgets the writer of the blob and returns it, nil if there is none.
+func (u *blobUploads) take(blob int64) *storage.BlobWriter {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	w := u.writers[blob]
+	delete(u.writers, blob)
+	return w
+}
+
+// reset aborts the blobs being received, their chunks are gone with the data.
+func (u *blobUploads) reset() {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	clear(u.writers)
+}
+
+// applyBlob applies a chunk or an abort the leader replicated.
+func (s *StorageService) applyBlob(msg SetMessage) error {
+	switch msg.Operation {
+	case OperationBlobChunk:
+		w, err := s.uploads.writer(s.store, msg.Namespace, msg.Blob)
+		if err != nil {
+			return err
+		}
+		_, err = w.Write([]byte(msg.Value))
+		return err
+	case OperationBlobAbort:
+		if w := s.uploads.take(msg.Blob); w != nil {
+			return w.Abort()
+		}
+	}
+	return nil
+}
@@ -1,47 +1,93 @@
 package service
 
 import (
-	"github.com/Na322Pr/kv-storage-service/pkg/nodemodel"
+	"errors"
+
+	"github.com/Na322Pr/kv-storage-service/internal/model"
 	"go.uber.org/zap"
+	"google.golang.org/grpc"
 )
 
 type Meta struct {
-	NodeID      int
+	NomadID     string
 	DataVersion int64
+	// LeaderEpoch is the epoch of the last leader this node heard of, data
+	// versions are only comparable between nodes of the same epoch.
+	LeaderEpoch int64
 }
 
+var (
+	ErrStaleEpoch = errors.New("leader epoch is older than the known one")
+	ErrNoLeaderID = errors.New("leader announced without a nomad id")
+)
+
 type LeService struct {
-	node           *nodemodel.Node
+	node           *model.Node
 	storageService *StorageService
+	cm             *ConnectionManagerService
+	leaseService   *LeaseService
+	// dialOptions are used to reach replicas during a leadership handoff.
+	dialOptions []grpc.DialOption
 
 	logger *zap.Logger
 }
 
 func NewLeService(
-	node *nodemodel.Node,
+	node *model.Node,
 	storageService *StorageService,
+	cm *ConnectionManagerService,
+	leaseService *LeaseService,
+	dialOptions []grpc.DialOption,
 	logger *zap.Logger,
 ) *LeService {
 	return &LeService{
 		node:           node,
 		storageService: storageService,
+		cm:             cm,
+		leaseService:   leaseService,
+		dialOptions:    dialOptions,
 		logger:         logger,
 	}
 }
 
 func (s *LeService) Meta() *Meta {
 	return &Meta{
-		NodeID:      s.node.ID,
+		NomadID:     s.node.NomadID(),
 		DataVersion: s.storageService.GetDataVersion(nil),
+		LeaderEpoch: s.storageService.LeaderEpoch(),
 	}
 }
 
-func (s *LeService) SetLeader(leaderID int) {
-	if leaderID == s.node.ID {
-		s.node.BecomeLeader()
-		s.logger.Sugar().Infof("Leader %d is become the leader", s.node.ID)
+// SetLeader follows the announced leader. Announcements of an epoch older
+// than the known one are stale and rejected, zero keeps the current epoch.
+// An announcement without a nomad id names no node and is rejected too.
+func (s *LeService) SetLeader(nomadID, address string, epoch int64) error {
+	if nomadID == "" {
+		return ErrNoLeaderID
+	}
+	if epoch > 0 {
+		ok, err := s.storageService.SaveLeaderEpoch(epoch)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return ErrStaleEpoch
+		}
+	}
+
+	s.node.SetLeaderAddress(address)
+
+	wasLeader := s.node.IsLeader()
+	if s.node.Is(nomadID) {
+		s.node.SetLeader(true)
+		s.logger.Sugar().Infof("Node %s is become the leader", s.node.ID())
+		if !wasLeader {
+			return s.leaseService.Resume()
+		}
 	} else {
-		s.node.BecomeReplica()
-		s.logger.Sugar().Infof("Leader %d is become the replica", s.node.ID)
+		s.node.SetLeader(false)
+		s.logger.Sugar().Infof("Node %s is become the replica", s.node.ID())
+		s.leaseService.Suspend()
 	}
+	return nil
 }
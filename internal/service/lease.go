@@ -0,0 +1,337 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/Na322Pr/kv-storage-service/internal/storage"
+	desc "github.com/Na322Pr/kv-storage-service/pkg/api"
+	"go.uber.org/zap"
+)
+
+var (
+	ErrLeaseNotFound = errors.New("lease not found")
+	ErrLeaseNotOwned = errors.New("lease is granted to another caller")
+)
+
+// leaseKey identifies a key attached to a lease.
+type leaseKey struct {
+	namespace string
+	key       string
+}
+
+// leaseIndex tracks which keys are attached to which lease.
+type leaseIndex struct {
+	mu     sync.Mutex
+	keys   map[int64]map[leaseKey]struct{}
+	leases map[leaseKey]int64
+}
+
+func newLeaseIndex() *leaseIndex {
+	return &leaseIndex{
+		keys:   make(map[int64]map[leaseKey]struct{}),
+		leases: make(map[leaseKey]int64),
+	}
+}
+
+// attach moves the key to the lease, zero lease detaches it.
+func (i *leaseIndex) attach(namespace, key string, lease int64) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	k := leaseKey{namespace: namespace, key: key}
+	i.detachLocked(k)
+	if lease == 0 {
+		return
+	}
+
+	if i.keys[lease] == nil {
+		i.keys[lease] = make(map[leaseKey]struct{})
+	}
+	i.keys[lease][k] = struct{}{}
+	i.leases[k] = lease
+}
+
+func (i *leaseIndex) detach(namespace, key string) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.detachLocked(leaseKey{namespace: namespace, key: key})
+}
+
+func (i *leaseIndex) detachLocked(key leaseKey) {
+	lease, ok := i.leases[key]
+	if !ok {
+		return
+	}
+	delete(i.leases, key)
+	delete(i.keys[lease], key)
+	if len(i.keys[lease]) == 0 {
+		delete(i.keys, lease)
+	}
+}
+
+// reset forgets every attachment.
+func (i *leaseIndex) reset() {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	clear(i.keys)
+	clear(i.leases)
+}
+
+// release forgets the lease and returns the keys that were attached to it.
+func (i *leaseIndex) release(lease int64) []leaseKey {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	keys := make([]leaseKey, 0, len(i.keys[lease]))
+	for key := range i.keys[lease] {
+		keys = append(keys, key)
+		delete(i.leases, key)
+	}
+	delete(i.keys, lease)
+	return keys
+}
+
+// GrantLease stores the lease here and on the replicas.
+func (s *StorageService) GrantLease(ctx context.Context, lease storage.Lease) error {
+	value, err := json.Marshal(lease)
+	if err != nil {
+		return err
+	}
+
+	defer s.flush()
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+
+	if err := s.writable(); err != nil {
+		return err
+	}
+	if err := s.store.PutLease(lease); err != nil {
+		return err
+	}
+	s.queueLease(ctx, OperationLeaseGrant, lease.ID, string(value))
+	return nil
+}
+
+// RevokeLease forgets the lease here and on the replicas and deletes every
+// key attached to it. It returns the revision of the last removal, zero if
+// no key was removed.
+func (s *StorageService) RevokeLease(ctx context.Context, id int64) (int64, error) {
+	defer s.flush()
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+
+	if _, err := s.store.DeleteLease(id); err != nil {
+		return 0, err
+	}
+	s.queueLease(ctx, OperationLeaseRevoke, id, "")
+
+	var revision int64
+	for _, key := range s.leases.release(id) {
+		_, deleted, err := s.remove(ctx, key.namespace, key.key)
+		if err != nil {
+			return revision, err
+		}
+		revision = deleted
+	}
+	return revision, nil
+}
+
+// Leases returns the stored leases.
+func (s *StorageService) Leases() ([]storage.Lease, error) {
+	return s.store.Leases()
+}
+
+// applyLease stores or forgets a lease the leader replicated.
+func (s *StorageService) applyLease(msg SetMessage) error {
+	if msg.Operation == OperationLeaseRevoke {
+		_, err := s.store.DeleteLease(msg.Lease)
+		return err
+	}
+	lease := storage.Lease{ID: msg.Lease}
+	if err := json.Unmarshal([]byte(msg.Value), &lease); err != nil {
+		return err
+	}
+	return s.store.PutLease(lease)
+}
+
+// loadLeaseIndex attaches the stored keys to their leases, it must be
+// called before the keys are written.
+func (s *StorageService) loadLeaseIndex() error {
+	return s.store.LeasedKeys(func(ns, key string, lease int64) {
+		s.leases.attach(ns, key, lease)
+	})
+}
+
+// queueLease queues a grant or a revoke for the replicas, it must be called
+// with writeMu held.
+func (s *StorageService) queueLease(ctx context.Context, operation Operation, id int64, value string) {
+	op := string(operation)
+	s.queue(ctx, &desc.SetRequest{Operation: &op, Lease: &id, Value: value})
+}
+
+type lease struct {
+	ttl      time.Duration
+	owner    string
+	deadline time.Time
+	timer    *time.Timer
+}
+
+// LeaseService grants leases with a TTL and deletes the keys attached to a
+// lease once it expires or is revoked. Leases are stored and replicated
+// through the StorageService, their deadlines are kept by the leader only: a
+// node taking over the leadership starts every lease over with its full TTL.
+type LeaseService struct {
+	storageService *StorageService
+
+	mu     sync.Mutex
+	leases map[int64]*lease
+	nextID atomic.Int64
+
+	logger *zap.Logger
+}
+
+func NewLeaseService(storageService *StorageService, logger *zap.Logger) (*LeaseService, error) {
+	s := &LeaseService{
+		storageService: storageService,
+		leases:         make(map[int64]*lease),
+		logger:         logger,
+	}
+	// Seed identifiers with the clock so a restarted leader does not reuse them.
+	s.nextID.Store(time.Now().UnixNano())
+
+	if err := storageService.loadLeaseIndex(); err != nil {
+		return nil, err
+	}
+	if storageService.IsLeader() {
+		if err := s.Resume(); err != nil {
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+// Grant grants a lease to the owner. Only the owner may keep it alive or
+// revoke it.
+func (s *LeaseService) Grant(ctx context.Context, ttl time.Duration, owner string) (int64, error) {
+	id := s.nextID.Add(1)
+	err := s.storageService.GrantLease(ctx, storage.Lease{ID: id, TTL: ttl, Owner: owner})
+	if err != nil {
+		return 0, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.start(id, ttl, owner)
+	return id, nil
+}
+
+// start arms the lease with its full TTL, it must be called with mu held.
+func (s *LeaseService) start(id int64, ttl time.Duration, owner string) {
+	s.leases[id] = &lease{
+		ttl:      ttl,
+		owner:    owner,
+		deadline: time.Now().Add(ttl),
+		timer:    time.AfterFunc(ttl, func() { s.expire(id) }),
+	}
+}
+
+// Resume starts the stored leases over when the node becomes the leader.
+func (s *LeaseService) Resume() error {
+	leases, err := s.storageService.Leases()
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, l := range leases {
+		if _, ok := s.leases[l.ID]; !ok {
+			s.start(l.ID, l.TTL, l.Owner)
+		}
+		// A lease granted by an earlier leader may be ahead of the clock seed.
+		if l.ID > s.nextID.Load() {
+			s.nextID.Store(l.ID)
+		}
+	}
+	return nil
+}
+
+// Suspend stops every lease when the node stops being the leader, the
+// next leader keeps them.
+func (s *LeaseService) Suspend() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for id, l := range s.leases {
+		l.timer.Stop()
+		delete(s.leases, id)
+	}
+}
+
+// KeepAlive extends the lease by its TTL and returns the TTL.
+func (s *LeaseService) KeepAlive(id int64, owner string) (time.Duration, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	l, ok := s.leases[id]
+	if !ok {
+		return 0, ErrLeaseNotFound
+	}
+	if l.owner != owner {
+		return 0, ErrLeaseNotOwned
+	}
+	l.deadline = time.Now().Add(l.ttl)
+	l.timer.Reset(l.ttl)
+	return l.ttl, nil
+}
+
+func (s *LeaseService) Exists(id int64) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, ok := s.leases[id]
+	return ok
+}
+
+// Revoke drops the lease right away and deletes its keys. It returns the
+// revision of the last removed key, zero if the lease held none.
+func (s *LeaseService) Revoke(ctx context.Context, id int64, owner string) (int64, error) {
+	s.mu.Lock()
+	l, ok := s.leases[id]
+	if ok && l.owner != owner {
+		s.mu.Unlock()
+		return 0, ErrLeaseNotOwned
+	}
+	if ok {
+		l.timer.Stop()
+		delete(s.leases, id)
+	}
+	s.mu.Unlock()
+
+	if !ok {
+		return 0, ErrLeaseNotFound
+	}
+
+	return s.storageService.RevokeLease(ctx, id)
+}
+
+func (s *LeaseService) expire(id int64) {
+	s.mu.Lock()
+	l, ok := s.leases[id]
+	// The timer may fire concurrently with a keep-alive that already moved the
+	// deadline, or after the node stopped being the leader.
+	if !ok || time.Now().Before(l.deadline) || !s.storageService.IsLeader() {
+		s.mu.Unlock()
+		return
+	}
+	delete(s.leases, id)
+	s.mu.Unlock()
+
+	s.logger.Debug("lease expired", zap.Int64("lease", id))
+	if _, err := s.storageService.RevokeLease(context.Background(), id); err != nil {
+		s.logger.Error("failed to revoke expired lease", zap.Int64("lease", id), zap.Error(err))
+	}
+}
@@ -0,0 +1,199 @@
+package service_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Na322Pr/kv-storage-service/internal/config"
+	"github.com/Na322Pr/kv-storage-service/internal/model"
+	"github.com/Na322Pr/kv-storage-service/internal/service"
+	"github.com/Na322Pr/kv-storage-service/internal/storage"
+	"go.uber.org/zap"
+)
+
+func newLeader(t *testing.T) (*service.StorageService, *service.LeaseService) {
+	t.Helper()
+	store, err := storage.NewStore(storage.NewMemoryEngine(), config.Storage{EvictionPolicy: "noeviction"})
+	if err != nil {
+		t.Fatalf("new store: %v", err)
+	}
+	node := model.NewNode("1", "1", "127.0.0.1:2110")
+	node.SetLeader(true)
+	ss := service.NewStorageService(store, node, service.NewConnectionManagerService())
+	leases, err := service.NewLeaseService(ss, zap.NewNop())
+	if err != nil {
+		t.Fatalf("new lease service: %v", err)
+	}
+	t.Cleanup(leases.Suspend)
+	return ss, leases
+}
+
+func setLeased(t *testing.T, ss *service.StorageService, key string, lease int64) {
+	t.Helper()
+	_, err := ss.Set(context.Background(), service.SetMessage{Key: key, Value: "v", Operation: service.OperationSet, Lease: lease})
+	if err != nil {
+		t.Fatalf("set %s: %v", key, err)
+	}
+}
+
+func exists(ss *service.StorageService, key string) bool {
+	_, ok := ss.GetItem(context.Background(), storage.DefaultNamespace, key)
+	return ok
+}
+
+// eventually waits for cond, the leases expire on timers.
+func eventually(t *testing.T, cond func() bool) {
+	t.Helper()
+	for deadline := time.Now().Add(5 * time.Second); !cond(); {
+		if time.Now().After(deadline) {
+			t.Fatal("condition not met in time")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestLeaseExpiry(t *testing.T) {
+	ss, leases := newLeader(t)
+	ctx := context.Background()
+
+	id, err := leases.Grant(ctx, 200*time.Millisecond, "alice")
+	if err != nil {
+		t.Fatalf("grant: %v", err)
+	}
+	setLeased(t, ss, "a", id)
+	setLeased(t, ss, "b", id)
+	setLeased(t, ss, "kept", 0)
+
+	// Keep-alives hold the keys past the TTL.
+	for i := 0; i < 4; i++ {
+		time.Sleep(100 * time.Millisecond)
+		if ttl, err := leases.KeepAlive(id, "alice"); err != nil || ttl != 200*time.Millisecond {
+			t.Fatalf("keep alive: %v %v", ttl, err)
+		}
+	}
+	if !exists(ss, "a") || !exists(ss, "b") {
+		t.Fatal("keys of a live lease were removed")
+	}
+	if _, err := leases.KeepAlive(id, "bob"); !errors.Is(err, service.ErrLeaseNotOwned) {
+		t.Fatalf("keep alive by another caller: %v", err)
+	}
+
+	eventually(t, func() bool { return !leases.Exists(id) })
+	if exists(ss, "a") || exists(ss, "b") {
+		t.Fatal("keys of an expired lease remain")
+	}
+	if !exists(ss, "kept") {
+		t.Fatal("key without lease was removed")
+	}
+	if _, err := leases.KeepAlive(id, "alice"); !errors.Is(err, service.ErrLeaseNotFound) {
+		t.Fatalf("keep alive after expiry: %v", err)
+	}
+}
+
+func TestLeaseRevoke(t *testing.T) {
+	ss, leases := newLeader(t)
+	ctx := context.Background()
+
+	id, err := leases.Grant(ctx, time.Minute, "alice")
+	if err != nil {
+		t.Fatalf("grant: %v", err)
+	}
+	setLeased(t, ss, "a", id)
+
+	if _, err := leases.Revoke(ctx, id, "bob"); !errors.Is(err, service.ErrLeaseNotOwned) {
+		t.Fatalf("revoke by another caller: %v", err)
+	}
+	if !exists(ss, "a") {
+		t.Fatal("key removed by a refused revoke")
+	}
+	if _, err := leases.Revoke(ctx, id, "alice"); err != nil {
+		t.Fatalf("revoke: %v", err)
+	}
+	if exists(ss, "a") {
+		t.Fatal("key of a revoked lease remains")
+	}
+	if _, err := leases.Revoke(ctx, id, "alice"); !errors.Is(err, service.ErrLeaseNotFound) {
+		t.Fatalf("revoke twice: %v", err)
+	}
+
+	// Rewriting a key without its lease detaches it.
+	id, err = leases.Grant(ctx, time.Minute, "alice")
+	if err != nil {
+		t.Fatalf("grant: %v", err)
+	}
+	setLeased(t, ss, "b", id)
+	setLeased(t, ss, "b", 0)
+	if _, err := leases.Revoke(ctx, id, "alice"); err != nil {
+		t.Fatalf("revoke: %v", err)
+	}
+	if !exists(ss, "b") {
+		t.Fatal("detached key was removed with the lease")
+	}
+}
+
+func TestLockFencing(t *testing.T) {
+	ss, leases := newLeader(t)
+	locks := service.NewLockService(ss)
+	ctx := context.Background()
+
+	first, err := leases.Grant(ctx, 200*time.Millisecond, "alice")
+	if err != nil {
+		t.Fatalf("grant: %v", err)
+	}
+	second, err := leases.Grant(ctx, time.Minute, "bob")
+	if err != nil {
+		t.Fatalf("grant: %v", err)
+	}
+
+	key, token, err := locks.Lock(ctx, "", "job", first)
+	if err != nil {
+		t.Fatalf("lock: %v", err)
+	}
+	if key != service.LockKey("job") || token <= 0 {
+		t.Fatalf("lock returned %q %d", key, token)
+	}
+	// The holder locking again gets the same token.
+	if _, again, err := locks.Lock(ctx, "", "job", first); err != nil || again != token {
+		t.Fatalf("relock: %d %v, want %d", again, err, token)
+	}
+	if _, err := ss.Set(ctx, service.SetMessage{Key: key, Value: "x", Operation: service.OperationSet}); !errors.Is(err, service.ErrReservedKey) {
+		t.Fatalf("plain write to the lock key: %v", err)
+	}
+
+	// The other lease waits until the holder stops keeping its lease alive.
+	waitCtx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+	_, _, err = locks.Lock(waitCtx, "", "job", second)
+	cancel()
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("lock held elsewhere: %v", err)
+	}
+	_, next, err := locks.Lock(ctx, "", "job", second)
+	if err != nil {
+		t.Fatalf("lock after expiry: %v", err)
+	}
+	if next <= token {
+		t.Fatalf("fencing token %d after %d", next, token)
+	}
+
+	// The stale holder cannot release the lock it lost.
+	if _, err := locks.Unlock(ctx, "", "job", token); !errors.Is(err, service.ErrConditionNotMet) {
+		t.Fatalf("unlock with a stale token: %v", err)
+	}
+	if _, err := locks.Unlock(ctx, "", "job", next); err != nil {
+		t.Fatalf("unlock: %v", err)
+	}
+	if exists(ss, key) {
+		t.Fatal("lock key remains after unlock")
+	}
+}
+
+func TestLockNeedsLiveLease(t *testing.T) {
+	ss, _ := newLeader(t)
+	locks := service.NewLockService(ss)
+
+	if _, _, err := locks.Lock(context.Background(), "", "job", 12345); !errors.Is(err, service.ErrLeaseNotFound) {
+		t.Fatalf("lock with an unknown lease: %v", err)
+	}
+}
@@ -0,0 +1,105 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+)
+
+// lockPrefix keeps lock keys apart from user data, other writes to them are
+// rejected with ErrReservedKey.
+const lockPrefix = "__lock/"
+
+// LockService implements mutexes on top of leases: the lock is a key attached
+// to the holder's lease, so it is released when the holder stops keeping the
+// lease alive. The revision of the lock key serves as a fencing token.
+type LockService struct {
+	storageService *StorageService
+}
+
+func NewLockService(storageService *StorageService) *LockService {
+	return &LockService{
+		storageService: storageService,
+	}
+}
+
+func LockKey(name string) string {
+	return lockPrefix + name
+}
+
+// IsLockKey reports whether the key holds a lock.
+func IsLockKey(key string) bool {
+	return strings.HasPrefix(key, lockPrefix)
+}
+
+// Lock blocks until the lock is acquired for the lease or ctx is done and
+// returns the lock key with its fencing token.
+func (s *LockService) Lock(ctx context.Context, namespace, name string, lease int64) (string, int64, error) {
+	key := LockKey(name)
+	owner := strconv.FormatInt(lease, 10)
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// Subscribe before trying so a release between the attempt and the wait is not missed.
+	events := s.storageService.Watch(ctx, namespace, key, false)
+
+	for {
+		// One write checks the lease, takes the free lock and attaches it.
+		revision, err := s.storageService.Set(ctx, SetMessage{
+			Namespace: namespace,
+			Key:       key,
+			Value:     owner,
+			Operation: OperationSet,
+			Condition: ConditionNotExists,
+			Lease:     lease,
+			lock:      true,
+		})
+		if err == nil {
+			return key, revision, nil
+		}
+		if !errors.Is(err, ErrConditionNotMet) {
+			return "", 0, err
+		}
+
+		if item, ok := s.storageService.GetItem(ctx, namespace, key); ok && item.Lease == lease {
+			return key, item.Revision, nil
+		}
+
+		if events, err = s.waitRelease(ctx, namespace, key, events); err != nil {
+			return "", 0, err
+		}
+	}
+}
+
+// waitRelease waits for the lock key to be deleted. A watcher that fell behind
+// is replaced, the caller retries the acquisition in any case.
+func (s *LockService) waitRelease(ctx context.Context, namespace, key string, events <-chan WatchEvent) (<-chan WatchEvent, error) {
+	for {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case event, ok := <-events:
+			if !ok {
+				return s.storageService.Watch(ctx, namespace, key, false), nil
+			}
+			if event.Operation == OperationDelete {
+				return events, nil
+			}
+		}
+	}
+}
+
+// Unlock releases the lock if it is still held with the given fencing token
+// and returns the revision of the release.
+func (s *LockService) Unlock(ctx context.Context, namespace, name string, token int64) (int64, error) {
+	return s.storageService.Set(ctx, SetMessage{
+		Namespace: namespace,
+		Key:       LockKey(name),
+		Operation: OperationDelete,
+		Condition: ConditionRevision,
+		Revision:  token,
+		lock:      true,
+	})
+}
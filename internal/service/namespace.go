@@ -0,0 +1,207 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"sync/atomic"
+
+	"github.com/Na322Pr/kv-storage-service/internal/ratelimit"
+	"github.com/Na322Pr/kv-storage-service/internal/storage"
+)
+
+var ErrRateLimited = errors.New("namespace request rate exceeded")
+
+// NamespaceStats is the namespace description with the requests it received
+// on this node.
+type NamespaceStats struct {
+	storage.NamespaceInfo
+	Requests  int64
+	Throttled int64
+}
+
+type namespaceLimit struct {
+	// bucket is nil when the namespace has no request rate quota.
+	bucket    *ratelimit.Bucket
+	requests  atomic.Int64
+	throttled atomic.Int64
+}
+
+// namespaceLimits enforces the request rate quotas. Every node limits the
+// requests it serves itself.
+type namespaceLimits struct {
+	mu     sync.RWMutex
+	limits map[string]*namespaceLimit
+}
+
+func newNamespaceLimits() *namespaceLimits {
+	return &namespaceLimits{
+		limits: make(map[string]*namespaceLimit),
+	}
+}
+
+// set replaces the limit of the namespace, a rate of one second is allowed as burst.
+func (l *namespaceLimits) set(name string, rate float64) {
+	limit := &namespaceLimit{}
+	if rate > 0 {
+		limit.bucket = ratelimit.NewBucket(rate, rate)
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.limits[name] = limit
+}
+
+func (l *namespaceLimits) remove(name string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	delete(l.limits, name)
+}
+
+func (l *namespaceLimits) get(name string) *namespaceLimit {
+	l.mu.RLock()
+	limit, ok := l.limits[name]
+	l.mu.RUnlock()
+	if ok {
+		return limit
+	}
+
+	// Namespaces created implicitly by replicated writes have no rate quota.
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if limit, ok = l.limits[name]; !ok {
+		limit = &namespaceLimit{}
+		l.limits[name] = limit
+	}
+	return limit
+}
+
+func (l *namespaceLimits) allow(name string) bool {
+	limit := l.get(name)
+	limit.requests.Add(1)
+	if limit.bucket != nil && !limit.bucket.Allow() {
+		limit.throttled.Add(1)
+		return false
+	}
+	return true
+}
+
+// Admit checks that the namespace exists and the request fits its rate quota.
+// Handlers call it once per request before touching the namespace.
+func (s *StorageService) Admit(namespace string) error {
+	if !s.store.HasNamespace(namespace) {
+		return storage.ErrNamespaceNotFound
+	}
+	if !s.limits.allow(namespace) {
+		return ErrRateLimited
+	}
+	return nil
+}
+
+// CreateNamespace registers the namespace on the leader and its replicas
+// and returns the revision of the change.
+func (s *StorageService) CreateNamespace(ctx context.Context, name string, quota storage.Quota) (int64, error) {
+	value, err := json.Marshal(quota)
+	if err != nil {
+		return 0, err
+	}
+	return s.Set(ctx, SetMessage{
+		Namespace: name,
+		Value:     string(value),
+		Operation: OperationCreateNamespace,
+	})
+}
+
+// createNamespace fails on the leader if the namespace exists, replicas take
+// the quota as is.
+func (s *StorageService) createNamespace(msg SetMessage) (int64, error) {
+	var quota storage.Quota
+	if err := json.Unmarshal([]byte(msg.Value), &quota); err != nil {
+		return 0, err
+	}
+
+	var (
+		revision int64
+		err      error
+	)
+	if msg.Replicated {
+		revision, err = s.store.ApplyQuota(msg.Namespace, quota, msg.Revision)
+	} else {
+		revision, err = s.store.CreateNamespace(msg.Namespace, quota)
+	}
+	if err != nil {
+		return 0, err
+	}
+	s.limits.set(msg.Namespace, quota.RequestsPerSecond)
+	return revision, nil
+}
+
+// DropNamespace removes the namespace with its keys everywhere and returns
+// the number of keys removed with the revision of the removal.
+func (s *StorageService) DropNamespace(ctx context.Context, name string) (int, int64, error) {
+	defer s.flush()
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+
+	if err := s.writable(); err != nil {
+		return 0, 0, err
+	}
+	msg := SetMessage{Namespace: name, Operation: OperationDropNamespace}
+	keys, revision, err := s.dropNamespace(msg)
+	if err != nil {
+		return 0, 0, err
+	}
+	msg.Revision = revision
+	s.propagate(ctx, msg)
+	return len(keys), revision, nil
+}
+
+// dropNamespace notifies watchers of every removed key. A replica may miss
+// the namespace if it never received a write for it, its data version still
+// moves to the revision of the removal.
+func (s *StorageService) dropNamespace(msg SetMessage) ([]string, int64, error) {
+	var (
+		keys     []string
+		revision int64
+		err      error
+	)
+	if msg.Replicated {
+		keys, revision, err = s.store.ApplyDropNamespace(msg.Namespace, msg.Revision)
+		if errors.Is(err, storage.ErrNamespaceNotFound) {
+			revision, err := s.store.RestoreVersion(msg.Revision)
+			return nil, revision, err
+		}
+	} else {
+		keys, revision, err = s.store.DropNamespace(msg.Namespace)
+	}
+	if err != nil {
+		return nil, 0, err
+	}
+
+	for _, key := range keys {
+		s.leases.detach(msg.Namespace, key)
+		s.watchers.publish(WatchEvent{
+			Namespace: msg.Namespace,
+			Key:       key,
+			Operation: OperationDelete,
+		})
+	}
+	s.limits.remove(msg.Namespace)
+	return keys, revision, nil
+}
+
+// Namespaces lists the namespaces with their usage and request counters.
+func (s *StorageService) Namespaces() []NamespaceStats {
+	infos := s.store.Namespaces()
+	stats := make([]NamespaceStats, 0, len(infos))
+	for _, info := range infos {
+		limit := s.limits.get(info.Name)
+		stats = append(stats, NamespaceStats{
+			NamespaceInfo: info,
+			Requests:      limit.requests.Load(),
+			Throttled:     limit.throttled.Load(),
+		})
+	}
+	return stats
+}
@@ -0,0 +1,221 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/Na322Pr/kv-storage-service/internal/storage"
+	desc "github.com/Na322Pr/kv-storage-service/pkg/api"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+)
+
+// resyncInterval is how often the leader looks for replicas whose stream
+// failed.
+const resyncInterval = time.Second
+
+// Resync brings a replica that missed messages back in line over a new
+// stream: the replica is reset and seeded with a snapshot of the data, then
+// receives the messages broadcast meanwhile and every one from then on.
+// A large value whose upload started before the snapshot reaches the
+// replica without its first chunks, the next write of the key fixes it.
+func (s *StorageService) Resync(ctx context.Context, id string, stream desc.KeyValueStorage_SetStreamClient) error {
+	conn := s.cm.replace(id, stream)
+
+	// The requests queued before the snapshot are in it, the ones queued
+	// after are kept for the replica until it is seeded.
+	s.writeMu.Lock()
+	dump, err := s.store.Dump()
+	if err == nil {
+		s.outboxMu.Lock()
+		s.outbox = append(s.outbox, outgoing{ctx: ctx, resync: conn})
+		s.outboxMu.Unlock()
+	}
+	s.writeMu.Unlock()
+	if err != nil {
+		return err
+	}
+	defer dump.Release()
+	s.flush()
+
+	if err := sendSnapshot(dump, conn.sendDirect); err != nil {
+		conn.abort()
+		return err
+	}
+	return conn.resume()
+}
+
+// sendSnapshot sends the dump as replication messages: a reset, the
+// namespaces, the items with the chunks of large values, the leases and
+// the data version of the dump.
+func sendSnapshot(dump *storage.Dump, send func(*desc.SetRequest) error) error {
+	operation := func(op Operation) *string {
+		name := string(op)
+		return &name
+	}
+
+	if err := send(&desc.SetRequest{Operation: operation(OperationReset)}); err != nil {
+		return err
+	}
+
+	namespaces, err := dump.Namespaces()
+	if err != nil {
+		return err
+	}
+	for _, ns := range namespaces {
+		if ns.Name == storage.DefaultNamespace && ns.Quota == (storage.Quota{}) {
+			continue
+		}
+		value, err := json.Marshal(ns.Quota)
+		if err != nil {
+			return err
+		}
+		err = send(&desc.SetRequest{
+			Namespace: ns.Name,
+			Value:     string(value),
+			Operation: operation(OperationCreateNamespace),
+			Revision:  dump.Revision,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, ns := range namespaces {
+		err := dump.Items(ns.Name, "", func(key string, item storage.Item) error {
+			if item.Large() {
+				err := dump.ReadBlob(ns.Name, item, func(chunk []byte) error {
+					return send(&desc.SetRequest{
+						Namespace: ns.Name,
+						Operation: operation(OperationBlobChunk),
+						RawValue:  chunk,
+						Blob:      item.Blob,
+					})
+				})
+				if err != nil {
+					return err
+				}
+			}
+
+			req := &desc.SetRequest{
+				Namespace:   ns.Name,
+				Key:         key,
+				Operation:   operation(OperationRestore),
+				RawValue:    []byte(item.Value),
+				ContentType: item.ContentType,
+				Blob:        item.Blob,
+				Size:        item.Size,
+				Revision:    item.Revision,
+			}
+			if item.Expiration > 0 {
+				req.Expiration = &item.Expiration
+			}
+			if item.Lease != 0 {
+				req.Lease = &item.Lease
+			}
+			return send(req)
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	leases, err := dump.Leases()
+	if err != nil {
+		return err
+	}
+	for _, lease := range leases {
+		value, err := json.Marshal(lease)
+		if err != nil {
+			return err
+		}
+		err = send(&desc.SetRequest{Operation: operation(OperationLeaseGrant), Lease: &lease.ID, Value: string(value)})
+		if err != nil {
+			return err
+		}
+	}
+
+	return send(&desc.SetRequest{Operation: operation(OperationRestoreDone), Revision: dump.Revision})
+}
+
+// ReplicaResyncer reconnects the leader to the replicas whose replication
+// stream failed and resyncs them. Replicas are dialed at their id, the gRPC
+// address they were connected with.
+type ReplicaResyncer struct {
+	storageService *StorageService
+	dialOptions    []grpc.DialOption
+	logger         *zap.Logger
+
+	// clients are kept across resyncs, gRPC reconnects them on its own.
+	clients map[string]*grpc.ClientConn
+	// streams cancels the stream of every replica on shutdown.
+	streams map[string]context.CancelFunc
+}
+
+func NewReplicaResyncer(storageService *StorageService, dialOptions []grpc.DialOption, logger *zap.Logger) *ReplicaResyncer {
+	return &ReplicaResyncer{
+		storageService: storageService,
+		dialOptions:    dialOptions,
+		logger:         logger,
+		clients:        make(map[string]*grpc.ClientConn),
+		streams:        make(map[string]context.CancelFunc),
+	}
+}
+
+// Run resyncs the failed replicas every resync interval while the node is
+// the leader, until ctx is done.
+func (r *ReplicaResyncer) Run(ctx context.Context) {
+	defer func() {
+		for _, cancel := range r.streams {
+			cancel()
+		}
+		for _, conn := range r.clients {
+			conn.Close()
+		}
+	}()
+
+	ticker := time.NewTicker(resyncInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if !r.storageService.IsLeader() {
+				continue
+			}
+			for _, id := range r.storageService.cm.Down() {
+				if err := r.resync(ctx, id); err != nil {
+					r.logger.Warn("failed to resync replica", zap.String("replica", id), zap.Error(err))
+					continue
+				}
+				r.logger.Info("replica resynced", zap.String("replica", id))
+			}
+		}
+	}
+}
+
+func (r *ReplicaResyncer) resync(ctx context.Context, id string) error {
+	conn, ok := r.clients[id]
+	if !ok {
+		var err error
+		if conn, err = grpc.NewClient(id, r.dialOptions...); err != nil {
+			return err
+		}
+		r.clients[id] = conn
+	}
+
+	if cancel, ok := r.streams[id]; ok {
+		cancel()
+	}
+	streamCtx, cancel := context.WithCancel(ctx)
+	r.streams[id] = cancel
+
+	stream, err := desc.NewKeyValueStorageClient(conn).SetStream(streamCtx)
+	if err != nil {
+		return err
+	}
+	return r.storageService.Resync(ctx, id, stream)
+}
@@ -2,72 +2,683 @@ package service
 
 import (
 	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"unicode/utf8"
+
 	"github.com/Na322Pr/kv-storage-service/internal/model"
 	"github.com/Na322Pr/kv-storage-service/internal/storage"
 	desc "github.com/Na322Pr/kv-storage-service/pkg/api"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	otelcodes "go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 )
 
+var tracer = otel.Tracer("github.com/Na322Pr/kv-storage-service/internal/service")
+
 type Operation string
 
 const (
-	operationEmpty  Operation = "empty"
-	operationSet    Operation = "set"
-	operationDelete Operation = "delete"
+	OperationEmpty  Operation = "empty"
+	OperationSet    Operation = "set"
+	OperationDelete Operation = "delete"
+	OperationExpire Operation = "expire"
+	// OperationCreateNamespace carries the namespace name in Key and its
+	// quota as JSON in Value.
+	OperationCreateNamespace Operation = "create_namespace"
+	OperationDropNamespace   Operation = "drop_namespace"
+	// OperationBlobChunk carries the next chunk of the large value SetMessage.Blob
+	// in Value, OperationBlobAbort drops the chunks received so far. Both are
+	// sent to the replicas only, the write of the key follows as a set.
+	OperationBlobChunk Operation = "blob_chunk"
+	OperationBlobAbort Operation = "blob_abort"
+	// OperationRestore stores a key of a backup with the revision it has
+	// there in SetMessage.Revision. OperationReset drops all the data of a
+	// replica before a restore and OperationRestoreDone moves its data
+	// version to the revision of the backup, both are sent to the replicas
+	// only.
+	OperationRestore     Operation = "restore"
+	OperationReset       Operation = "reset"
+	OperationRestoreDone Operation = "restore_done"
+	// OperationBatch stores the keys of SetMessage.Batch in the namespace
+	// with one write, Key and Value are empty.
+	OperationBatch Operation = "batch"
+	// OperationLeaseGrant stores the lease SetMessage.Lease with its TTL and
+	// owner as JSON in Value, OperationLeaseRevoke forgets it. Both are sent
+	// to the replicas only, the keys of a revoked lease follow as deletes.
+	OperationLeaseGrant  Operation = "lease_grant"
+	OperationLeaseRevoke Operation = "lease_revoke"
+)
+
+// Condition restricts when a set operation is applied.
+type Condition int
+
+const (
+	ConditionNone Condition = iota
+	ConditionNotExists
+	ConditionExists
+	// ConditionRevision applies the set only if the key was last modified at SetMessage.Revision.
+	ConditionRevision
+)
+
+var (
+	ErrConditionNotMet = errors.New("condition not met")
+	ErrNotFound        = errors.New("key not found")
+	ErrReservedKey     = errors.New("keys starting with " + lockPrefix + " are reserved for locks")
 )
 
 type SetMessage struct {
+	Namespace string
 	Key       string
-	Value     string
+	// Value holds arbitrary bytes.
+	Value       string
+	ContentType string
+	// Blob and Size reference a large value written through LargeUpload,
+	// Value is then empty.
+	Blob int64
+	Size int64
+	// Codec tells how Value is compressed. The leader compresses the values
+	// it stores, replicas store and watchers receive them as sent.
+	Codec     storage.Codec
 	Operation Operation
+	// Expiration is an absolute deadline in unix nanoseconds, zero means no TTL.
+	Expiration int64
+	Condition  Condition
+	// Revision is the revision the condition expects, or the revision of a
+	// restored key.
+	Revision int64
+	// Lease attaches the key to a lease, zero detaches it from any lease.
+	Lease int64
+	// Batch holds the keys of an OperationBatch.
+	Batch []storage.BatchItem
+	// Replicated marks writes received from the leader, which already
+	// checked the namespace quota.
+	Replicated bool
+
+	// lock marks the writes of the LockService, the only ones allowed on
+	// lock keys.
+	lock bool
+}
+
+type IncrementMessage struct {
+	Namespace string
+	Key       string
+	Delta     int64
+	// Expiration is applied only when the counter is created.
+	Expiration int64
+	Min        *int64
+	Max        *int64
+}
+
+type KeyValue struct {
+	Key         string
+	Value       string
+	ContentType string
+	Revision    int64
+	// Large values come without Value, they are read with ReadLarge.
+	Large bool
+	Size  int64
 }
 
 type StorageService struct {
-	store *storage.KeyValueInMemoryStorage
-	node  *model.Node
-	cm    *ConnectionManagerService
+	store    *storage.Store
+	node     *model.Node
+	cm       *ConnectionManagerService
+	watchers *watchHub
+	leases   *leaseIndex
+	limits   *namespaceLimits
+	uploads  *blobUploads
+
+	// writeMu is held by every mutation from its write to the store until
+	// its replication is queued, so replicas receive the mutations in the
+	// order the leader applied them.
+	writeMu sync.Mutex
+	// outbox holds the requests queued for the replicas, flushMu keeps
+	// them in order while they are sent.
+	outboxMu sync.Mutex
+	outbox   []outgoing
+	flushMu  sync.Mutex
+	// restoring is set from BeginRestore until the restore is committed
+	// or aborted, it changes with writeMu held.
+	restoring atomic.Bool
+}
+
+// outgoing is a request queued for the replicas with the context of the
+// mutation, which carries its trace.
+type outgoing struct {
+	ctx context.Context
+	req *desc.SetRequest
+	// resync, set instead of req, starts keeping the requests queued after
+	// it for a replica being resynced.
+	resync *replicaConnection
 }
 
 func NewStorageService(
-	store *storage.KeyValueInMemoryStorage,
+	store *storage.Store,
 	node *model.Node,
 	cm *ConnectionManagerService,
 ) *StorageService {
 	return &StorageService{
-		store: store,
-		node:  node,
-		cm:    cm,
+		store:    store,
+		node:     node,
+		cm:       cm,
+		watchers: newWatchHub(),
+		leases:   newLeaseIndex(),
+		limits:   newNamespaceLimits(),
+		uploads:  newBlobUploads(),
 	}
 }
 
-func (s *StorageService) Set(_ context.Context, msg SetMessage) error {
+// Set applies the mutation and returns its revision, zero for operations
+// that change nothing.
+func (s *StorageService) Set(ctx context.Context, msg SetMessage) (revision int64, err error) {
+	ctx, span := tracer.Start(ctx, "StorageService.Set", trace.WithAttributes(
+		attribute.String("kv.namespace", msg.Namespace),
+		attribute.String("kv.key", msg.Key),
+		attribute.String("kv.operation", string(msg.Operation)),
+	))
+	defer func() {
+		if err != nil {
+			span.SetStatus(otelcodes.Error, err.Error())
+		}
+		span.End()
+	}()
 	switch msg.Operation {
-	case operationSet:
-		s.store.Set(msg.Key, msg.Value)
-	case operationDelete:
-		s.store.Delete(msg.Key)
+	case OperationBlobChunk, OperationBlobAbort:
+		// Chunks are neither watched nor forwarded, the leader streams them.
+		return 0, s.applyBlob(msg)
+	case OperationReset, OperationRestoreDone:
+		return s.applyRestore(msg)
+	case OperationLeaseGrant, OperationLeaseRevoke:
+		return 0, s.applyLease(msg)
+	}
+	if !msg.Replicated && !msg.lock && msg.Operation != OperationRestore && reserved(msg) {
+		return 0, ErrReservedKey
 	}
 
-	if !s.node.IsLeader() {
-		return nil
+	defer s.flush()
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+	if !msg.Replicated && msg.Operation != OperationRestore {
+		if err := s.writable(); err != nil {
+			return 0, err
+		}
+	}
+	// A failed write may still have evicted keys.
+	defer s.propagateEvictions(ctx)
+
+	switch msg.Operation {
+	case OperationSet:
+		if !msg.Replicated {
+			msg = s.compress(msg)
+		}
+		if revision, err = s.set(msg); err != nil {
+			return 0, err
+		}
+		s.leases.attach(msg.Namespace, msg.Key, msg.Lease)
+	case OperationRestore:
+		if !msg.Replicated {
+			msg = s.compress(msg)
+		}
+		if revision, err = s.restore(msg); err != nil {
+			return 0, err
+		}
+		s.leases.attach(msg.Namespace, msg.Key, msg.Lease)
+	case OperationBatch:
+		if revision, err = s.setBatch(msg); err != nil {
+			return 0, err
+		}
+		for _, bi := range msg.Batch {
+			s.leases.detach(msg.Namespace, bi.Key)
+		}
+		msg.Revision = revision
+		s.propagateEvictions(ctx)
+		s.propagateBatch(ctx, msg)
+		return revision, nil
+	case OperationDelete:
+		if revision, err = s.delete(msg); err != nil {
+			return 0, err
+		}
+		s.leases.detach(msg.Namespace, msg.Key)
+	case OperationExpire:
+		if revision, err = s.expire(msg); err != nil {
+			return 0, err
+		}
+	case OperationCreateNamespace:
+		if revision, err = s.createNamespace(msg); err != nil {
+			return 0, err
+		}
+	case OperationDropNamespace:
+		if _, revision, err = s.dropNamespace(msg); err != nil {
+			return 0, err
+		}
+	}
+
+	// Keys evicted to make room for the write were removed before it.
+	s.propagateEvictions(ctx)
+	// Replicas write the mutation at the revision the leader gave it.
+	msg.Revision = revision
+	s.propagate(ctx, msg)
+
+	return revision, nil
+}
+
+// compress replaces the value with its compressed form when the storage
+// compresses values of the namespace.
+func (s *StorageService) compress(msg SetMessage) SetMessage {
+	item := s.store.Compress(msg.Namespace, storage.Item{Value: msg.Value, Codec: msg.Codec, Blob: msg.Blob})
+	msg.Value, msg.Codec = item.Value, item.Codec
+	return msg
+}
+
+func (s *StorageService) set(msg SetMessage) (int64, error) {
+	item := storage.Item{
+		Value:       msg.Value,
+		Expiration:  msg.Expiration,
+		ContentType: msg.ContentType,
+		Blob:        msg.Blob,
+		Size:        msg.Size,
+		Codec:       msg.Codec,
+		Lease:       msg.Lease,
+	}
+	if msg.Replicated {
+		item.Revision = msg.Revision
+		revision, err := s.store.Apply(msg.Namespace, msg.Key, item)
+		if err == nil && item.Large() {
+			s.uploads.take(item.Blob)
+		}
+		return revision, err
+	}
+	// Checked with writeMu held, a revoke cannot slip in before the write.
+	if msg.Lease != 0 {
+		ok, err := s.store.HasLease(msg.Lease)
+		if err != nil {
+			return 0, err
+		}
+		if !ok {
+			return 0, ErrLeaseNotFound
+		}
+	}
+
+	switch msg.Condition {
+	case ConditionNotExists:
+		ok, revision, err := s.store.SetNX(msg.Namespace, msg.Key, item)
+		if err != nil {
+			return 0, err
+		}
+		if !ok {
+			return 0, ErrConditionNotMet
+		}
+		return revision, nil
+	case ConditionExists:
+		ok, revision, err := s.store.SetXX(msg.Namespace, msg.Key, item)
+		if err != nil {
+			return 0, err
+		}
+		if !ok {
+			return 0, ErrConditionNotMet
+		}
+		return revision, nil
+	case ConditionRevision:
+		revision, err := s.store.CompareAndSwap(msg.Namespace, msg.Key, item, msg.Revision)
+		switch {
+		case errors.Is(err, storage.ErrKeyNotFound):
+			return 0, ErrNotFound
+		case errors.Is(err, storage.ErrRevisionMismatch):
+			return 0, ErrConditionNotMet
+		}
+		return revision, err
+	default:
+		return s.store.SetUntil(msg.Namespace, msg.Key, item)
+	}
+}
+
+func (s *StorageService) delete(msg SetMessage) (int64, error) {
+	if msg.Replicated {
+		return s.store.ApplyDelete(msg.Namespace, msg.Key, msg.Revision)
+	}
+	if msg.Condition != ConditionRevision {
+		_, revision, err := s.store.Delete(msg.Namespace, msg.Key)
+		return revision, err
+	}
+
+	revision, err := s.store.CompareAndDelete(msg.Namespace, msg.Key, msg.Revision)
+	switch {
+	case errors.Is(err, storage.ErrKeyNotFound):
+		return 0, ErrNotFound
+	case errors.Is(err, storage.ErrRevisionMismatch):
+		return 0, ErrConditionNotMet
+	}
+	return revision, err
+}
+
+// expire changes the expiration of an existing key. Replicas apply it even
+// when they already expired the key, the leader still had it.
+func (s *StorageService) expire(msg SetMessage) (int64, error) {
+	if msg.Replicated {
+		return s.store.ApplyExpire(msg.Namespace, msg.Key, msg.Expiration, msg.Revision)
+	}
+	ok, revision, err := s.store.Expire(msg.Namespace, msg.Key, msg.Expiration)
+	if err != nil {
+		return 0, err
+	}
+	if !ok {
+		return 0, ErrNotFound
+	}
+	return revision, nil
+}
+
+// Delete removes the key and reports whether it existed, with the revision
+// of the removal.
+func (s *StorageService) Delete(ctx context.Context, namespace, key string) (bool, int64, error) {
+	if IsLockKey(key) {
+		return false, 0, ErrReservedKey
+	}
+
+	defer s.flush()
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+
+	if err := s.writable(); err != nil {
+		return false, 0, err
+	}
+	return s.remove(ctx, namespace, key)
+}
+
+// remove deletes the key and queues the delete, it must be called with
+// writeMu held.
+func (s *StorageService) remove(ctx context.Context, namespace, key string) (bool, int64, error) {
+	existed, revision, err := s.store.Delete(namespace, key)
+	if err != nil {
+		return false, 0, err
+	}
+	s.leases.detach(namespace, key)
+	s.propagate(ctx, SetMessage{Namespace: namespace, Key: key, Operation: OperationDelete, Revision: revision})
+	return existed, revision, nil
+}
+
+// Increment atomically adds delta to the integer stored at key and replicates
+// the resulting value rather than the delta, so replaying it is idempotent.
+// It returns the new value and the revision of the write.
+func (s *StorageService) Increment(ctx context.Context, msg IncrementMessage) (int64, int64, error) {
+	if IsLockKey(msg.Key) {
+		return 0, 0, ErrReservedKey
+	}
+
+	defer s.flush()
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+
+	if err := s.writable(); err != nil {
+		return 0, 0, err
+	}
+	defer s.propagateEvictions(ctx)
+
+	item, value, err := s.store.Increment(msg.Namespace, msg.Key, msg.Delta, storage.IncrementOptions{
+		Min:        msg.Min,
+		Max:        msg.Max,
+		Expiration: msg.Expiration,
+	})
+	if err != nil {
+		return 0, 0, err
+	}
+
+	s.propagateEvictions(ctx)
+	s.propagate(ctx, SetMessage{
+		Namespace:   msg.Namespace,
+		Key:         msg.Key,
+		Value:       item.Value,
+		ContentType: item.ContentType,
+		Operation:   OperationSet,
+		Expiration:  item.Expiration,
+		Revision:    item.Revision,
+		Lease:       item.Lease,
+	})
+
+	return value, item.Revision, nil
+}
+
+// reserved reports whether the mutation touches a lock key.
+func reserved(msg SetMessage) bool {
+	if IsLockKey(msg.Key) {
+		return true
+	}
+	for _, bi := range msg.Batch {
+		if IsLockKey(bi.Key) {
+			return true
+		}
+	}
+	return false
+}
+
+// propagateEvictions replicates the keys evicted to make room for writes as
+// deletes, it must be called with writeMu held. Evictions happen on the
+// leader only, replicas never exceed the memory the leader holds.
+func (s *StorageService) propagateEvictions(ctx context.Context) {
+	for _, evicted := range s.store.Evictions() {
+		s.leases.detach(evicted.Namespace, evicted.Key)
+		s.propagate(ctx, SetMessage{
+			Namespace: evicted.Namespace,
+			Key:       evicted.Key,
+			Operation: OperationDelete,
+			Revision:  evicted.Revision,
+		})
+	}
+}
+
+// propagate notifies watchers about an applied mutation and queues it for the
+// replicas when this node is the leader, it must be called with writeMu held.
+// Conditions are evaluated on the leader only, replicas receive the outcome
+// with the revision the leader gave it.
+func (s *StorageService) propagate(ctx context.Context, msg SetMessage) {
+	if s.watchers.watching(msg.Namespace, msg.Key) {
+		value := msg.Value
+		if msg.Codec != storage.CodecNone {
+			// The value was just stored compressed, it decodes.
+			value, _ = storage.Decode(msg.Codec, msg.Value)
+		}
+		s.watchers.publish(WatchEvent{
+			Namespace: msg.Namespace,
+			Key:       msg.Key,
+			Value:     value,
+			Operation: msg.Operation,
+		})
 	}
 
 	operationString := string(msg.Operation)
 	broadcastMsg := &desc.SetRequest{
-		Key:       msg.Key,
-		Value:     msg.Value,
-		Operation: &operationString,
+		Namespace:   msg.Namespace,
+		Key:         msg.Key,
+		Operation:   &operationString,
+		ContentType: msg.ContentType,
+		Blob:        msg.Blob,
+		Size:        msg.Size,
+		Codec:       uint32(msg.Codec),
+		Revision:    msg.Revision,
+	}
+	// Proto strings must be UTF-8, binary values travel as bytes.
+	if utf8.ValidString(msg.Value) {
+		broadcastMsg.Value = msg.Value
+	} else {
+		broadcastMsg.RawValue = []byte(msg.Value)
+	}
+	if msg.Expiration > 0 {
+		broadcastMsg.Expiration = &msg.Expiration
+	}
+	if msg.Lease != 0 {
+		broadcastMsg.Lease = &msg.Lease
 	}
 
-	s.cm.Broadcast(broadcastMsg)
+	s.queue(ctx, broadcastMsg)
+}
 
-	return nil
+// queue adds the request to the outbox when this node is the leader.
+func (s *StorageService) queue(ctx context.Context, req *desc.SetRequest) {
+	if !s.node.IsLeader() {
+		return
+	}
+	s.outboxMu.Lock()
+	s.outbox = append(s.outbox, outgoing{ctx: ctx, req: req})
+	s.outboxMu.Unlock()
+}
+
+// flush sends the queued requests to the replicas in the order they were
+// queued. It must be called without writeMu held, a slow replica then only
+// delays the writers waiting for their own requests to leave.
+func (s *StorageService) flush() {
+	s.flushMu.Lock()
+	defer s.flushMu.Unlock()
+
+	for {
+		s.outboxMu.Lock()
+		queued := s.outbox
+		s.outbox = nil
+		s.outboxMu.Unlock()
+		if len(queued) == 0 {
+			return
+		}
+		for _, o := range queued {
+			if o.resync != nil {
+				o.resync.buffer()
+				continue
+			}
+			s.cm.Broadcast(o.ctx, o.req)
+		}
+	}
+}
+
+// replicate queues the request and sends it to the replicas, for requests
+// that are not mutations of the store.
+func (s *StorageService) replicate(ctx context.Context, req *desc.SetRequest) {
+	s.queue(ctx, req)
+	s.flush()
 }
 
-func (s *StorageService) Get(_ context.Context, key string) (string, bool) {
-	value, ok := s.store.Get(key)
+// Get returns the value of the key. Large values are read with ReadLarge,
+// Get reports them with an empty value.
+func (s *StorageService) Get(_ context.Context, namespace, key string) (string, bool) {
+	value, ok := s.store.Get(namespace, key)
 	return string(value.Value), ok
 }
 
+// GetAt returns the item the key held at the revision.
+func (s *StorageService) GetAt(_ context.Context, namespace, key string, revision int64) (storage.Item, bool, error) {
+	return s.store.GetAt(namespace, key, revision)
+}
+
+func (s *StorageService) GetItem(_ context.Context, namespace, key string) (storage.Item, bool) {
+	return s.store.Get(namespace, key)
+}
+
+// Keys returns the sorted list of live keys of the namespace starting with prefix.
+func (s *StorageService) Keys(_ context.Context, namespace, prefix string) []string {
+	return s.store.Keys(namespace, prefix)
+}
+
+// Scan returns up to limit live keys with the given prefix that sort after
+// startAfter, and whether more keys remain. A positive revision reads the
+// keys as they were at that revision.
+func (s *StorageService) Scan(_ context.Context, namespace, prefix, startAfter string, limit int, revision int64) ([]KeyValue, bool, error) {
+	var (
+		items []KeyValue
+		more  bool
+	)
+	collect := func(key string, item storage.Item) bool {
+		if len(items) == limit {
+			more = true
+			return false
+		}
+		items = append(items, KeyValue{
+			Key:         key,
+			Value:       item.Value,
+			ContentType: item.ContentType,
+			Revision:    item.Revision,
+			Large:       item.Large(),
+			Size:        item.Len(),
+		})
+		return true
+	}
+
+	if revision > 0 {
+		if err := s.store.ScanAt(namespace, prefix, startAfter, revision, collect); err != nil {
+			return nil, false, err
+		}
+		return items, more, nil
+	}
+	_ = s.store.Range(namespace, prefix, startAfter, collect)
+	return items, more, nil
+}
+
+// History returns up to limit versions of the key from startRevision on and
+// whether more remain.
+func (s *StorageService) History(_ context.Context, namespace, key string, startRevision int64, limit int) ([]storage.Version, bool, error) {
+	return s.store.History(namespace, key, startRevision, limit)
+}
+
+// CompactedRevision returns the oldest revision reads may still ask for.
+func (s *StorageService) CompactedRevision() int64 {
+	return s.store.CompactedRevision()
+}
+
+// Watch streams mutations of key in the namespace, or of every key starting
+// with it when prefix is set, until ctx is done. The channel is closed when
+// the subscription ends.
+func (s *StorageService) Watch(ctx context.Context, namespace, key string, prefix bool) <-chan WatchEvent {
+	return s.watchers.subscribe(ctx, namespace, key, prefix)
+}
+
 func (s *StorageService) GetDataVersion(_ context.Context) int64 {
 	return s.store.GetDataVersion()
 }
+
+// LeaderEpoch returns the leader epoch last announced to this node.
+func (s *StorageService) LeaderEpoch() int64 {
+	return s.store.LeaderEpoch()
+}
+
+// SaveLeaderEpoch persists a newer leader epoch and reports whether the
+// epoch is not older than the known one.
+func (s *StorageService) SaveLeaderEpoch(epoch int64) (bool, error) {
+	return s.store.SaveLeaderEpoch(epoch)
+}
+
+// Close flushes and releases the underlying storage.
+func (s *StorageService) Close() error {
+	return s.store.Close()
+}
+
+func (s *StorageService) KeyCount() int64 {
+	return s.store.Len()
+}
+
+func (s *StorageService) MemoryUsage() int64 {
+	return s.store.MemoryUsage()
+}
+
+func (s *StorageService) MaxMemory() int64 {
+	return s.store.MaxMemory()
+}
+
+func (s *StorageService) EvictedCount() int64 {
+	return s.store.EvictedCount()
+}
+
+func (s *StorageService) NodeID() string {
+	return s.node.ID()
+}
+
+func (s *StorageService) IsLeader() bool {
+	return s.node.IsLeader()
+}
+
+func (s *StorageService) LeaderAddress() string {
+	return s.node.LeaderAddress()
+}
+
+// Follow marks the node as following the leader until the returned function is called.
+func (s *StorageService) Follow() func() {
+	s.node.AttachUpstream()
+	return s.node.DetachUpstream
+}
@@ -0,0 +1,109 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"sync"
+)
+
+const watchBufferSize = 64
+
+type WatchEvent struct {
+	Namespace string
+	Key       string
+	Value     string
+	Operation Operation
+}
+
+type watcher struct {
+	namespace string
+	key       string
+	prefix    bool
+	events    chan WatchEvent
+}
+
+func (w *watcher) matches(namespace, key string) bool {
+	if w.namespace != namespace {
+		return false
+	}
+	if w.prefix {
+		return strings.HasPrefix(key, w.key)
+	}
+	return w.key == key
+}
+
+// watchHub fans applied mutations out to subscribers. Slow subscribers are
+// disconnected instead of blocking writers.
+type watchHub struct {
+	mu       sync.RWMutex
+	watchers map[*watcher]struct{}
+}
+
+func newWatchHub() *watchHub {
+	return &watchHub{
+		watchers: make(map[*watcher]struct{}),
+	}
+}
+
+func (h *watchHub) subscribe(ctx context.Context, namespace, key string, prefix bool) <-chan WatchEvent {
+	w := &watcher{
+		namespace: namespace,
+		key:       key,
+		prefix:    prefix,
+		events:    make(chan WatchEvent, watchBufferSize),
+	}
+
+	h.mu.Lock()
+	h.watchers[w] = struct{}{}
+	h.mu.Unlock()
+
+	go func() {
+		<-ctx.Done()
+		h.remove(w)
+	}()
+
+	return w.events
+}
+
+func (h *watchHub) remove(w *watcher) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if _, ok := h.watchers[w]; ok {
+		delete(h.watchers, w)
+		close(w.events)
+	}
+}
+
+// watching reports whether a subscriber follows the key.
+func (h *watchHub) watching(namespace, key string) bool {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	for w := range h.watchers {
+		if w.matches(namespace, key) {
+			return true
+		}
+	}
+	return false
+}
+
+func (h *watchHub) publish(event WatchEvent) {
+	h.mu.RLock()
+	var slow []*watcher
+	for w := range h.watchers {
+		if !w.matches(event.Namespace, event.Key) {
+			continue
+		}
+		select {
+		case w.events <- event:
+		default:
+			slow = append(slow, w)
+		}
+	}
+	h.mu.RUnlock()
+
+	for _, w := range slow {
+		h.remove(w)
+	}
+}
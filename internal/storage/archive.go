@@ -0,0 +1,159 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Na322Pr/kv-storage-service/internal/config"
+	"github.com/Na322Pr/kv-storage-service/internal/storage/lsm"
+)
+
+var ErrArchiveGap = errors.New("archive does not reach back to the backup revision")
+
+// errTargetReached stops the replay at the recovery target.
+var errTargetReached = errors.New("recovery target reached")
+
+// OpenArchive opens the archive of the configuration, nil when archiving
+// is disabled.
+func OpenArchive(cfg config.Archive) (lsm.Archive, error) {
+	if cfg.Dir == "" {
+		return nil, nil
+	}
+	return lsm.NewDirArchive(cfg.Dir)
+}
+
+// RecoveryTarget is the point in time to recover the data at, zero fields
+// do not bound the replay.
+type RecoveryTarget struct {
+	// Revision is the last revision replayed.
+	Revision int64
+	// Time is the time of the last write replayed.
+	Time time.Time
+}
+
+// Recovery is where a replay of the archive ended.
+type Recovery struct {
+	// Revision is the revision reached.
+	Revision int64
+	// Live is set when the WAL segments of the data directory were
+	// replayed after the archive, the replay then got to the last write of
+	// the node. Without them the writes not flushed yet are missing.
+	Live bool
+	// Reached is set when the replay stopped at a write past the target,
+	// the writes up to the target are all in.
+	Reached bool
+}
+
+// ReplayArchive applies to the engine the archived writes made after the
+// revision base up to the target, followed by the ones of the WAL segments
+// still in the lsm directory of the configuration, flushed or not. The
+// engine holds the data as of base, usually restored from a backup: the
+// archive must hold the write of base or an earlier one, zero replays the
+// archive from its start. A store must be opened on the engine afterwards
+// to see the writes.
+func ReplayArchive(engine Engine, cfg config.Storage, base int64, target RecoveryTarget) (Recovery, error) {
+	archive, err := OpenArchive(cfg.Archive)
+	if err != nil {
+		return Recovery{}, err
+	}
+	if archive == nil {
+		return Recovery{}, fmt.Errorf("archiving is disabled")
+	}
+	keyring, err := LoadKeyring(cfg.Encryption)
+	if err != nil {
+		return Recovery{}, err
+	}
+	recovery := Recovery{Revision: base}
+	var dir string
+	if info, err := os.Stat(lsmDir(cfg)); err == nil && info.IsDir() {
+		dir, recovery.Live = lsmDir(cfg), true
+	}
+
+	// reached is set once the archive got to base, the writes up to it are
+	// in the engine already. past is set once they are applied from the
+	// archive, a restore may write older revisions again. last is the
+	// version of the last write skipped.
+	reached, past := base == 0, false
+	var last int64
+	err = lsm.ReplayArchive(archive, dir, keyring, func(batch lsm.Batch) error {
+		if !target.Time.IsZero() && batch.Time > target.Time.UnixNano() {
+			return errTargetReached
+		}
+		ops, version, err := decodeArchivedBatch(batch)
+		if err != nil {
+			return err
+		}
+
+		switch {
+		case version == 0:
+			// The write comes after the last versioned one. Chunks of
+			// large values are kept before base, the write of their item
+			// may come after it.
+			if !past && last != base && !blobBatch(ops) {
+				return nil
+			}
+		case target.Revision > 0 && version > target.Revision:
+			return errTargetReached
+		case version <= base && !past:
+			reached, last = true, version
+			return nil
+		case !reached:
+			return fmt.Errorf("%w: the archive starts at revision %d, the backup is at %d", ErrArchiveGap, version, base)
+		default:
+			recovery.Revision, past = version, true
+		}
+		return engine.Batch(ops)
+	})
+	if errors.Is(err, errTargetReached) {
+		recovery.Reached = true
+	} else if err != nil {
+		return Recovery{}, err
+	}
+	if !reached {
+		return Recovery{}, fmt.Errorf("%w: the archive ends before revision %d", ErrArchiveGap, base)
+	}
+	return recovery, nil
+}
+
+// decodeArchivedBatch returns the operations of a batch of the lsm engine
+// with the data version it persists, zero for the writes that leave the
+// version alone.
+func decodeArchivedBatch(batch lsm.Batch) ([]Op, int64, error) {
+	ops := make([]Op, len(batch.Ops))
+	var version int64
+	for i, op := range batch.Ops {
+		ns, key, err := parseLSMKey(op.Key)
+		if err != nil {
+			return nil, 0, err
+		}
+		ops[i] = Op{Namespace: ns, Key: key, Delete: op.Delete}
+		if op.Delete {
+			continue
+		}
+		if ops[i].Item, err = decodeItem(op.Value); err != nil {
+			return nil, 0, fmt.Errorf("%q in namespace %q: %w", key, ns, err)
+		}
+		if ns == metaNamespace && key == metaVersionKey {
+			if version, err = strconv.ParseInt(ops[i].Item.Value, 10, 64); err != nil {
+				return nil, 0, errCorruptItem
+			}
+		}
+	}
+	return ops, version, nil
+}
+
+// blobBatch reports whether the batch only writes chunks of large values
+// and the markers of their uploads.
+func blobBatch(ops []Op) bool {
+	for _, op := range ops {
+		upload := op.Namespace == metaNamespace && strings.HasPrefix(op.Key, metaUploadPrefix)
+		if !upload && !strings.HasPrefix(op.Namespace, blobPrefix) {
+			return false
+		}
+	}
+	return len(ops) > 0
+}
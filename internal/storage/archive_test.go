@@ -0,0 +1,283 @@
+package storage_test
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Na322Pr/kv-storage-service/internal/backup"
+	"github.com/Na322Pr/kv-storage-service/internal/config"
+	"github.com/Na322Pr/kv-storage-service/internal/storage"
+	"github.com/Na322Pr/kv-storage-service/internal/storage/lsm"
+	desc "github.com/Na322Pr/kv-storage-service/pkg/api"
+)
+
+// archived returns the configuration of an lsm node archiving its WAL, with
+// a small memtable so the writes span several segments.
+func archived(t *testing.T) config.Storage {
+	t.Helper()
+	dir := t.TempDir()
+	return config.Storage{
+		Engine:         "lsm",
+		DataDir:        dir,
+		EvictionPolicy: "noeviction",
+		LSM:            config.LSM{MemtableSize: 16 << 10},
+		Archive:        config.Archive{Dir: filepath.Join(dir, "archive")},
+	}
+}
+
+func openStore(t *testing.T, cfg config.Storage) *storage.Store {
+	t.Helper()
+	engine, err := storage.OpenEngine(cfg)
+	if err != nil {
+		t.Fatalf("open engine: %v", err)
+	}
+	s, err := storage.NewStore(engine, cfg)
+	if err != nil {
+		t.Fatalf("new store: %v", err)
+	}
+	return s
+}
+
+func setValue(t *testing.T, s *storage.Store, ns, key, value string) int64 {
+	t.Helper()
+	revision, err := s.SetUntil(ns, key, storage.Item{Value: value})
+	if err != nil {
+		t.Fatalf("set %s: %v", key, err)
+	}
+	return revision
+}
+
+func takeBackup(t *testing.T, s *storage.Store) []byte {
+	t.Helper()
+	dump, err := s.Dump()
+	if err != nil {
+		t.Fatalf("dump: %v", err)
+	}
+	defer dump.Release()
+	var buf bytes.Buffer
+	w, err := backup.NewWriter(&buf)
+	if err != nil {
+		t.Fatalf("new writer: %v", err)
+	}
+	if _, err := backup.Stream(dump, "node1", w.Write); err != nil {
+		t.Fatalf("stream: %v", err)
+	}
+	if err := w.Flush(); err != nil {
+		t.Fatalf("flush: %v", err)
+	}
+	return buf.Bytes()
+}
+
+// rebuild recovers the data of the node as kv-recover does: the backup, if
+// any, is loaded into a new engine and the archive replayed over it.
+func rebuild(t *testing.T, cfg config.Storage, data []byte, target storage.RecoveryTarget) (*storage.Store, storage.Recovery, error) {
+	t.Helper()
+	engine, err := storage.OpenLSMEngine(t.TempDir(), lsm.Options{})
+	if err != nil {
+		t.Fatalf("open engine: %v", err)
+	}
+	storeCfg := config.Storage{EvictionPolicy: "noeviction", MaxValueSize: 64 << 20}
+	var base int64
+	if data != nil {
+		s, err := storage.NewStore(engine, storeCfg)
+		if err != nil {
+			t.Fatalf("new store: %v", err)
+		}
+		r, err := backup.NewReader(bytes.NewReader(data))
+		if err != nil {
+			t.Fatalf("new reader: %v", err)
+		}
+		header, err := backup.Load(r, s)
+		if err != nil {
+			t.Fatalf("load: %v", err)
+		}
+		base = header.Revision
+	}
+
+	recovery, err := storage.ReplayArchive(engine, cfg, base, target)
+	if err != nil {
+		engine.Close()
+		return nil, recovery, err
+	}
+	s, err := storage.NewStore(engine, storeCfg)
+	if err != nil {
+		t.Fatalf("new store: %v", err)
+	}
+	t.Cleanup(func() { s.Close() })
+	return s, recovery, nil
+}
+
+func TestReplayArchiveToTarget(t *testing.T) {
+	cfg := archived(t)
+	s := openStore(t, cfg)
+	if _, err := s.CreateNamespace("team", storage.Quota{MaxKeys: 1000}); err != nil {
+		t.Fatalf("create namespace: %v", err)
+	}
+	for i := 0; i < 300; i++ {
+		setValue(t, s, "", fmt.Sprintf("k%03d", i), fmt.Sprintf("v%d-%s", i, bytes.Repeat([]byte("x"), 50)))
+	}
+
+	// The chunks of a large value are written before the backup, its key
+	// after it.
+	blob, err := s.NewBlob("team", 0)
+	if err != nil {
+		t.Fatalf("new blob: %v", err)
+	}
+	large := bytes.Repeat([]byte("0123456789"), 10_000)
+	if _, err := blob.Write(large[:50_000]); err != nil {
+		t.Fatalf("write blob: %v", err)
+	}
+	data := takeBackup(t, s)
+	if _, err := blob.Write(large[50_000:]); err != nil {
+		t.Fatalf("write blob: %v", err)
+	}
+	largeRevision, err := s.SetUntil("team", "big", storage.Item{Blob: blob.ID(), Size: blob.Size()})
+	if err != nil {
+		t.Fatalf("set large value: %v", err)
+	}
+	for i := 300; i < 400; i++ {
+		setValue(t, s, "", fmt.Sprintf("k%03d", i), "after")
+	}
+
+	// Every key is deleted by mistake after the cut.
+	beforeDelete := s.GetDataVersion()
+	time.Sleep(5 * time.Millisecond)
+	cut := time.Now()
+	time.Sleep(5 * time.Millisecond)
+	for i := 0; i < 400; i++ {
+		if _, _, err := s.Delete("", fmt.Sprintf("k%03d", i)); err != nil {
+			t.Fatalf("delete: %v", err)
+		}
+	}
+	// A restart archives the live segment.
+	s.Close()
+	openStore(t, cfg).Close()
+
+	check := func(name string, r *storage.Store, recovery storage.Recovery) {
+		t.Helper()
+		if recovery.Revision != beforeDelete || !recovery.Reached || r.GetDataVersion() != beforeDelete {
+			t.Fatalf("%s: recovered %+v at %d, want revision %d", name, recovery, r.GetDataVersion(), beforeDelete)
+		}
+		for i := 0; i < 400; i++ {
+			item, ok := r.Get("", fmt.Sprintf("k%03d", i))
+			if !ok || (i >= 300 && item.Value != "after") {
+				t.Fatalf("%s: k%03d %+v %v", name, i, item, ok)
+			}
+		}
+		item, ok := r.Get("team", "big")
+		if !ok || item.Revision != largeRevision {
+			t.Fatalf("%s: large value %+v %v", name, item, ok)
+		}
+		var value []byte
+		err := r.ReadBlob("team", item, func(chunk []byte) error {
+			value = append(value, chunk...)
+			return nil
+		})
+		if err != nil || !bytes.Equal(value, large) {
+			t.Fatalf("%s: large value read %d bytes: %v", name, len(value), err)
+		}
+	}
+
+	for _, tt := range []struct {
+		name   string
+		data   []byte
+		target storage.RecoveryTarget
+	}{
+		{"backup to revision", data, storage.RecoveryTarget{Revision: beforeDelete}},
+		{"backup to time", data, storage.RecoveryTarget{Time: cut}},
+		{"archive start to revision", nil, storage.RecoveryTarget{Revision: beforeDelete}},
+	} {
+		r, recovery, err := rebuild(t, cfg, tt.data, tt.target)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		check(tt.name, r, recovery)
+	}
+
+	// Without a target the deletes are replayed too.
+	r, recovery, err := rebuild(t, cfg, data, storage.RecoveryTarget{})
+	if err != nil {
+		t.Fatalf("replay everything: %v", err)
+	}
+	if _, ok := r.Get("", "k000"); ok || recovery.Reached || recovery.Revision <= beforeDelete {
+		t.Fatalf("replay everything: %+v", recovery)
+	}
+}
+
+func TestReplayArchiveGap(t *testing.T) {
+	cfg := archived(t)
+	// The first writes are made before archiving is enabled.
+	unarchived := cfg
+	unarchived.Archive.Dir = ""
+	s := openStore(t, unarchived)
+	for i := 0; i < 300; i++ {
+		setValue(t, s, "", fmt.Sprintf("k%03d", i), "v")
+	}
+	s.Close()
+
+	s = openStore(t, cfg)
+	data := takeBackup(t, s)
+	for i := 0; i < 300; i++ {
+		setValue(t, s, "", fmt.Sprintf("k%03d", i), "w")
+	}
+	s.Close()
+	openStore(t, cfg).Close()
+
+	r, _, err := rebuild(t, cfg, data, storage.RecoveryTarget{})
+	if err != nil {
+		t.Fatalf("replay after a recent backup: %v", err)
+	}
+	if item, ok := r.Get("", "k299"); !ok || item.Value != "w" {
+		t.Fatalf("k299: %+v %v", item, ok)
+	}
+
+	// A backup older than the archive leaves writes out.
+	var old bytes.Buffer
+	w, err := backup.NewWriter(&old)
+	if err != nil {
+		t.Fatalf("new writer: %v", err)
+	}
+	w.Write(&desc.BackupRecord{Record: &desc.BackupRecord_Header{Header: &desc.BackupHeader{Version: backup.Version, Revision: 5}}})
+	w.Write(&desc.BackupRecord{Record: &desc.BackupRecord_Trailer{Trailer: &desc.BackupTrailer{Checksum: backup.NewDigest().Sum()}}})
+	if err := w.Flush(); err != nil {
+		t.Fatalf("flush: %v", err)
+	}
+	if _, _, err := rebuild(t, cfg, old.Bytes(), storage.RecoveryTarget{}); !errors.Is(err, storage.ErrArchiveGap) {
+		t.Fatalf("replay after an old backup: %v", err)
+	}
+}
+
+func TestReplayLiveSegment(t *testing.T) {
+	cfg := archived(t)
+	s := openStore(t, cfg)
+	defer s.Close()
+	for i := 0; i < 300; i++ {
+		setValue(t, s, "", fmt.Sprintf("k%03d", i), "v")
+	}
+	// The node is still running, its last write is only in the live segment.
+	last := setValue(t, s, "", "last", "live")
+
+	r, recovery, err := rebuild(t, cfg, nil, storage.RecoveryTarget{})
+	if err != nil {
+		t.Fatalf("replay: %v", err)
+	}
+	if item, ok := r.Get("", "last"); !ok || item.Value != "live" || !recovery.Live || recovery.Revision != last {
+		t.Fatalf("last write: %+v %v, recovered %+v", item, ok, recovery)
+	}
+
+	// A target past the last write is not reached.
+	if _, recovery, err = rebuild(t, cfg, nil, storage.RecoveryTarget{Revision: last + 10}); err != nil || recovery.Reached {
+		t.Fatalf("replay past the end: %+v %v", recovery, err)
+	}
+
+	// Without the data directory only the archive is replayed.
+	archiveOnly := cfg
+	archiveOnly.DataDir = filepath.Join(t.TempDir(), "missing")
+	if _, recovery, err = rebuild(t, archiveOnly, nil, storage.RecoveryTarget{Revision: 5}); err != nil || recovery.Live || !recovery.Reached {
+		t.Fatalf("replay of the archive only: %+v %v", recovery, err)
+	}
+}
@@ -0,0 +1,190 @@
+package storage
+
+import (
+	"sort"
+	"strings"
+	"time"
+)
+
+// Dump is a consistent view of the live data for a backup. It must be
+// released after use.
+type Dump struct {
+	snapshot Snapshot
+	// Revision is the data version the view was taken at.
+	Revision int64
+	Epoch    int64
+}
+
+// DumpNamespace is a namespace of a dump with its quota.
+type DumpNamespace struct {
+	Name  string
+	Quota Quota
+}
+
+// Dump takes a consistent view of the data.
+func (s *Store) Dump() (*Dump, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	snapshot, err := s.engine.Snapshot()
+	if err != nil {
+		return nil, err
+	}
+	return &Dump{snapshot: snapshot, Revision: s.version.Load(), Epoch: s.epoch.Load()}, nil
+}
+
+// Namespaces lists the namespaces with a quota or with keys, and the
+// default one, sorted by name.
+func (d *Dump) Namespaces() ([]DumpNamespace, error) {
+	quotas := map[string]Quota{DefaultNamespace: {}}
+	var quotaErr error
+	err := d.snapshot.Iterate(metaNamespace, metaQuotaPrefix, func(key string, item Item) bool {
+		quota, err := decodeQuota(item.Value)
+		if err != nil {
+			quotaErr = err
+			return false
+		}
+		quotas[strings.TrimPrefix(key, metaQuotaPrefix)] = quota
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	if quotaErr != nil {
+		return nil, quotaErr
+	}
+
+	names, err := d.snapshot.Namespaces()
+	if err != nil {
+		return nil, err
+	}
+	for _, name := range names {
+		if _, ok := quotas[name]; !ok && !strings.HasPrefix(name, reservedPrefix) {
+			quotas[name] = Quota{}
+		}
+	}
+
+	namespaces := make([]DumpNamespace, 0, len(quotas))
+	for name, quota := range quotas {
+		namespaces = append(namespaces, DumpNamespace{Name: name, Quota: quota})
+	}
+	sort.Slice(namespaces, func(i, j int) bool {
+		return namespaces[i].Name < namespaces[j].Name
+	})
+	return namespaces, nil
+}
+
+// Items calls fn with the live items of the namespace with keys starting
+// with prefix in key order, values decompressed, until fn returns an error.
+func (d *Dump) Items(ns, prefix string, fn func(key string, item Item) error) error {
+	now := time.Now().UnixNano()
+	var fnErr error
+	err := d.snapshot.Iterate(ns, prefix, func(key string, item Item) bool {
+		if item.Expired(now) {
+			return true
+		}
+		if item, fnErr = decompress(item); fnErr != nil {
+			return false
+		}
+		fnErr = fn(key, item)
+		return fnErr == nil
+	})
+	if err != nil {
+		return err
+	}
+	return fnErr
+}
+
+// ReadBlob calls fn with the chunks of the large value of the item as the
+// view holds it.
+func (d *Dump) ReadBlob(ns string, item Item, fn func(chunk []byte) error) error {
+	return readBlob(d.snapshot, ns, item, fn)
+}
+
+func (d *Dump) Release() {
+	d.snapshot.Release()
+}
+
+// Reset removes every key with its history and large values and every
+// namespace, and sets the data version back to zero. The leader epoch
+// stays. Replicas reset before a restored leader seeds them.
+func (s *Store) Reset() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	names, err := s.engine.Namespaces()
+	if err != nil {
+		return err
+	}
+	var ops []Op
+	for _, name := range names {
+		err := s.engine.Iterate(name, "", func(key string, _ Item) bool {
+			if name != metaNamespace || key != metaEpochKey {
+				ops = append(ops, Op{Namespace: name, Key: key, Delete: true})
+			}
+			return true
+		})
+		if err != nil {
+			return err
+		}
+	}
+	if err := s.engine.Batch(ops); err != nil {
+		return err
+	}
+
+	s.namespaces.Range(func(name, _ any) bool {
+		s.namespaces.Delete(name)
+		return true
+	})
+	s.namespaces.Store(DefaultNamespace, newNamespace(DefaultNamespace, Quota{}))
+	s.version.Store(0)
+	s.compacted.Store(0)
+	s.keys.Store(0)
+	s.bytes.Store(0)
+	return nil
+}
+
+// Empty reports whether the store holds no key and no namespace besides
+// the default one.
+func (s *Store) Empty() bool {
+	empty := s.keys.Load() == 0
+	s.namespaces.Range(func(name, _ any) bool {
+		empty = empty && name == DefaultNamespace
+		return empty
+	})
+	return empty
+}
+
+// Restore stores an item of a backup with the revision it has there,
+// creating the namespace if needed, and moves the data version up to the
+// revision. Quotas are not checked, the backup held the item.
+func (s *Store) Restore(ns, key string, item Item) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	n, err := s.namespace(ns)
+	if err != nil {
+		return err
+	}
+	prev, exists, err := s.engine.Get(ns, key)
+	if err != nil {
+		return err
+	}
+	return s.write(n, key, item, prev, exists, max(s.version.Load(), item.Revision))
+}
+
+// RestoreVersion moves the data version up to the revision a restored
+// backup was taken at, later writes get newer revisions.
+func (s *Store) RestoreVersion(version int64) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if version <= s.version.Load() {
+		return s.version.Load(), nil
+	}
+	if err := s.engine.Batch([]Op{s.versionOp(version)}); err != nil {
+		return 0, err
+	}
+	s.version.Store(version)
+	return version, nil
+}
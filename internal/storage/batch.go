@@ -0,0 +1,130 @@
+package storage
+
+import "time"
+
+// BatchItem is a key written by a batch.
+type BatchItem struct {
+	Key  string
+	Item Item
+}
+
+// SetBatch stores the items of the namespace in one write of the engine
+// and returns the revision of the last one, the items get consecutive
+// revisions. The quota and the memory are checked for the whole batch:
+// nothing is stored when it does not fit. Large values are not batched.
+func (s *Store) SetBatch(ns string, items []BatchItem) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	n := s.lookup(ns)
+	if n == nil {
+		return 0, ErrNamespaceNotFound
+	}
+	_, keys, bytes, err := s.batchOps(n, items, s.version.Load())
+	if err != nil {
+		return 0, err
+	}
+	if err := n.quota.check(n.keys.Load()+keys, n.bytes.Load()+bytes, keys > 0, bytes > 0); err != nil {
+		return 0, err
+	}
+	if err := s.reserve(n, "", bytes); err != nil {
+		return 0, err
+	}
+	// Evictions may have removed keys of the batch, the operations are
+	// built again.
+	return s.putBatch(n, items, 0)
+}
+
+// ApplyBatch stores the items without checking the namespace quota,
+// creating the namespace if needed. Replicas use it for the batches the
+// leader has already admitted, revision is the one the leader gave the last
+// item.
+func (s *Store) ApplyBatch(ns string, items []BatchItem, revision int64) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	n, err := s.namespace(ns)
+	if err != nil {
+		return 0, err
+	}
+	return s.putBatch(n, items, revision)
+}
+
+// putBatch must be called with mu held. The last item is written at the
+// revision, after the data version when zero.
+func (s *Store) putBatch(n *namespace, items []BatchItem, revision int64) (int64, error) {
+	if len(items) == 0 {
+		return s.version.Load(), nil
+	}
+	last := revision
+	if last == 0 {
+		last = s.version.Load() + int64(len(items))
+	}
+	ops, keys, bytes, err := s.batchOps(n, items, last-int64(len(items)))
+	if err != nil {
+		return 0, err
+	}
+	version := max(s.version.Load(), last)
+	if err := s.engine.Batch(append(ops, s.versionOp(version))); err != nil {
+		return 0, err
+	}
+	s.version.Store(version)
+	n.add(keys, bytes)
+	s.keys.Add(keys)
+	s.bytes.Add(bytes)
+	return last, nil
+}
+
+// batchOps returns the operations storing the items at the revisions after
+// revision with the key count and size they add, it must be called with mu
+// held. A key written twice takes the last item.
+func (s *Store) batchOps(n *namespace, items []BatchItem, revision int64) ([]Op, int64, int64, error) {
+	now := time.Now().UnixNano()
+	written := make(map[string]Item, len(items))
+
+	var (
+		ops         []Op
+		keys, bytes int64
+	)
+	for _, bi := range items {
+		prev, exists := written[bi.Key]
+		if exists {
+			// The version written earlier in the batch keeps a copy.
+			ops = append(ops, historyOp(n.name, bi.Key, Version{
+				Revision:    prev.Revision,
+				Value:       prev.Value,
+				Expiration:  prev.Expiration,
+				ContentType: prev.ContentType,
+				Blob:        prev.Blob,
+				Size:        prev.Size,
+				Codec:       prev.Codec,
+				Time:        now,
+			}))
+		} else {
+			var err error
+			if prev, exists, err = s.engine.Get(n.name, bi.Key); err != nil {
+				return nil, 0, 0, err
+			}
+			if exists {
+				op, ok, err := s.replacedOp(n, bi.Key, prev)
+				if err != nil {
+					return nil, 0, 0, err
+				}
+				if ok {
+					ops = append(ops, op)
+				}
+			}
+		}
+
+		revision++
+		item := bi.Item
+		item.Revision = revision
+		item.access = prev.access
+		k, b := delta(bi.Key, item, prev, exists)
+		keys += k
+		bytes += b
+		ops = append(ops, itemOps(n, bi.Key, item, now)...)
+		written[bi.Key] = item
+	}
+	return ops, keys, bytes, nil
+}
@@ -0,0 +1,266 @@
+package storage
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"math"
+	"strconv"
+	"strings"
+)
+
+const (
+	// blobPrefix starts the namespaces keeping the chunks of the large values
+	// of every namespace, keyed by blob and chunk index.
+	blobPrefix = reservedPrefix + "blob/"
+	// metaUploadPrefix marks the blobs being written. The mark is removed
+	// with the write of the item referencing the blob, the chunks of blobs
+	// still marked on start are dropped.
+	metaUploadPrefix = "upload/"
+)
+
+var (
+	ErrValueTooLarge  = errors.New("value exceeds the maximum size")
+	ErrBlobIncomplete = errors.New("large value is incomplete")
+	errBlobClosed     = errors.New("large value is already written")
+)
+
+func blobNamespace(ns string) string {
+	return blobPrefix + ns
+}
+
+func blobChunksPrefix(blob int64) string {
+	return string(binary.BigEndian.AppendUint64(nil, uint64(blob)))
+}
+
+func blobKey(blob int64, index uint32) string {
+	return blobChunksPrefix(blob) + string(binary.BigEndian.AppendUint32(nil, index))
+}
+
+func uploadKey(blob int64) string {
+	return metaUploadPrefix + strconv.FormatInt(blob, 10)
+}
+
+// newBlobID picks a random positive blob identifier, unique across
+// restarts and shared with the replicas.
+func newBlobID() (int64, error) {
+	var buf [8]byte
+	for {
+		if _, err := rand.Read(buf[:]); err != nil {
+			return 0, err
+		}
+		if id := int64(binary.BigEndian.Uint64(buf[:]) & math.MaxInt64); id != 0 {
+			return id, nil
+		}
+	}
+}
+
+// BlobWriter stores a large value chunk by chunk, so it is never held whole
+// in memory. The value becomes visible once an item referencing the blob is
+// written, until then Abort drops the chunks.
+type BlobWriter struct {
+	s      *Store
+	ns     string
+	id     int64
+	chunks uint32
+	size   int64
+	// limited writers check the value size, the quota and the memory as
+	// chunks arrive. Replicas write the blobs the leader has admitted.
+	limited bool
+	closed  bool
+}
+
+// NewBlob starts a large value of the namespace. A zero id picks a new blob
+// and fails when the namespace does not exist, replicas pass the id chosen
+// by the leader.
+func (s *Store) NewBlob(ns string, id int64) (*BlobWriter, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	limited := id == 0
+	if limited {
+		if s.lookup(ns) == nil {
+			return nil, ErrNamespaceNotFound
+		}
+		var err error
+		if id, err = newBlobID(); err != nil {
+			return nil, err
+		}
+	}
+	if err := s.engine.Put(metaNamespace, uploadKey(id), Item{Value: ns}); err != nil {
+		return nil, err
+	}
+	return &BlobWriter{s: s, ns: ns, id: id, limited: limited}, nil
+}
+
+// ID returns the blob to reference from the item.
+func (w *BlobWriter) ID() int64 {
+	return w.id
+}
+
+// Size returns the number of bytes written so far.
+func (w *BlobWriter) Size() int64 {
+	return w.size
+}
+
+// Write stores p as the next chunk.
+func (w *BlobWriter) Write(p []byte) (int, error) {
+	if len(p) == 0 {
+		return 0, nil
+	}
+
+	s := w.s
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if w.closed {
+		return 0, errBlobClosed
+	}
+	size := w.size + int64(len(p))
+	if w.limited {
+		if err := s.admitBlob(w.ns, size); err != nil {
+			return 0, err
+		}
+	}
+	if err := s.engine.Put(blobNamespace(w.ns), blobKey(w.id, w.chunks), Item{Value: string(p)}); err != nil {
+		return 0, err
+	}
+	w.chunks++
+	w.size = size
+	return len(p), nil
+}
+
+// admitBlob fails early for blobs that could never be written, it must be
+// called with mu held. The quota and the memory are reserved when the item
+// referencing the blob is written.
+func (s *Store) admitBlob(ns string, size int64) error {
+	if s.maxValueSize > 0 && size > s.maxValueSize {
+		return ErrValueTooLarge
+	}
+	n := s.lookup(ns)
+	if n == nil {
+		return ErrNamespaceNotFound
+	}
+	if err := n.quota.check(n.keys.Load(), n.bytes.Load()+size, false, true); err != nil {
+		return err
+	}
+	if s.maxMemory > 0 && s.policy == EvictionNone && s.bytes.Load()+size > s.maxMemory {
+		return ErrOutOfMemory
+	}
+	return nil
+}
+
+// Abort drops the chunks written so far. It does nothing once the writer
+// is aborted.
+func (w *BlobWriter) Abort() error {
+	s := w.s
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if w.closed {
+		return nil
+	}
+	w.closed = true
+	ops, err := s.dropBlobOps(w.ns, w.id)
+	if err != nil {
+		return err
+	}
+	return s.engine.Batch(ops)
+}
+
+// dropBlobOps returns the removal of the chunks of the blob and of its
+// upload mark.
+func (s *Store) dropBlobOps(ns string, blob int64) ([]Op, error) {
+	ops := []Op{{Namespace: metaNamespace, Key: uploadKey(blob), Delete: true}}
+	err := s.engine.Iterate(blobNamespace(ns), blobChunksPrefix(blob), func(key string, _ Item) bool {
+		ops = append(ops, Op{Namespace: blobNamespace(ns), Key: key, Delete: true})
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	return ops, nil
+}
+
+// ReadBlob calls fn with the chunks of the large value of the item in
+// order. Every chunk is read on its own, no read is held while fn runs.
+func (s *Store) ReadBlob(ns string, item Item, fn func(chunk []byte) error) error {
+	return readBlob(s.engine, ns, item, fn)
+}
+
+// itemReader is implemented by engines and their snapshots.
+type itemReader interface {
+	Get(ns, key string) (Item, bool, error)
+}
+
+func readBlob(r itemReader, ns string, item Item, fn func(chunk []byte) error) error {
+	if !item.Large() {
+		return fn([]byte(item.Value))
+	}
+
+	var read int64
+	for index := uint32(0); read < item.Size; index++ {
+		chunk, ok, err := r.Get(blobNamespace(ns), blobKey(item.Blob, index))
+		if err != nil {
+			return err
+		}
+		if !ok {
+			break
+		}
+		read += int64(len(chunk.Value))
+		if read > item.Size {
+			break
+		}
+		if err := fn([]byte(chunk.Value)); err != nil {
+			return err
+		}
+	}
+	if read != item.Size {
+		return ErrBlobIncomplete
+	}
+	return nil
+}
+
+// dropUploads removes the blobs left unreferenced by writes interrupted by
+// a restart. It must be called before the store is shared.
+func (s *Store) dropUploads() error {
+	type upload struct {
+		ns   string
+		blob int64
+	}
+	var (
+		uploads  []upload
+		parseErr error
+	)
+	err := s.engine.Iterate(metaNamespace, metaUploadPrefix, func(key string, item Item) bool {
+		blob, err := strconv.ParseInt(strings.TrimPrefix(key, metaUploadPrefix), 10, 64)
+		if err != nil {
+			parseErr = errCorruptItem
+			return false
+		}
+		uploads = append(uploads, upload{ns: item.Value, blob: blob})
+		return true
+	})
+	if err != nil {
+		return err
+	}
+	if parseErr != nil {
+		return parseErr
+	}
+
+	for _, u := range uploads {
+		ops, err := s.dropBlobOps(u.ns, u.blob)
+		if err != nil {
+			return err
+		}
+		if err := s.engine.Batch(ops); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// MaxValueSize returns the limit of large values, zero is unlimited.
+func (s *Store) MaxValueSize() int64 {
+	return s.maxValueSize
+}
@@ -0,0 +1,235 @@
+package storage
+
+import (
+	"bytes"
+	"crypto/rand"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+const (
+	// bucketPrefix keeps bucket names non-empty, the default namespace is "".
+	bucketPrefix = "ns/"
+	// boltMmapSize reserves address space for the file up front. Growing the
+	// mapping waits for open read transactions, snapshots included.
+	boltMmapSize = 1 << 30
+)
+
+// BoltEngine keeps items on disk in a B+tree file, one bucket per
+// namespace. Every write is a transaction synced to disk before it returns.
+type BoltEngine struct {
+	db *bolt.DB
+}
+
+func OpenBoltEngine(path string) (*BoltEngine, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, fmt.Errorf("create data directory: %w", err)
+	}
+	db, err := bolt.Open(path, 0o600, &bolt.Options{Timeout: time.Second, InitialMmapSize: boltMmapSize})
+	if err != nil {
+		return nil, fmt.Errorf("open %s: %w", path, err)
+	}
+	return &BoltEngine{db: db}, nil
+}
+
+func bucketName(ns string) []byte {
+	return []byte(bucketPrefix + ns)
+}
+
+func (e *BoltEngine) Get(ns, key string) (Item, bool, error) {
+	var (
+		item  Item
+		found bool
+	)
+	err := e.db.View(func(tx *bolt.Tx) error {
+		var err error
+		item, found, err = boltGet(tx, ns, key)
+		return err
+	})
+	return item, found, err
+}
+
+func boltGet(tx *bolt.Tx, ns, key string) (Item, bool, error) {
+	bucket := tx.Bucket(bucketName(ns))
+	if bucket == nil {
+		return Item{}, false, nil
+	}
+	value := bucket.Get([]byte(key))
+	if value == nil {
+		return Item{}, false, nil
+	}
+	item, err := decodeItem(value)
+	if err != nil {
+		return Item{}, false, fmt.Errorf("%q in namespace %q: %w", key, ns, err)
+	}
+	return item, true, nil
+}
+
+func (e *BoltEngine) Put(ns, key string, item Item) error {
+	return e.Batch([]Op{{Namespace: ns, Key: key, Item: item}})
+}
+
+func (e *BoltEngine) Delete(ns, key string) error {
+	return e.Batch([]Op{{Namespace: ns, Key: key, Delete: true}})
+}
+
+func (e *BoltEngine) Batch(ops []Op) error {
+	return e.db.Update(func(tx *bolt.Tx) error {
+		for _, op := range ops {
+			if op.Delete {
+				if err := boltDelete(tx, op.Namespace, op.Key); err != nil {
+					return err
+				}
+				continue
+			}
+
+			bucket, err := tx.CreateBucketIfNotExists(bucketName(op.Namespace))
+			if err != nil {
+				return err
+			}
+			if err := bucket.Put([]byte(op.Key), encodeItem(op.Item)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// boltDelete drops the bucket with its last key so Namespaces lists only
+// namespaces holding keys.
+func boltDelete(tx *bolt.Tx, ns, key string) error {
+	name := bucketName(ns)
+	bucket := tx.Bucket(name)
+	if bucket == nil {
+		return nil
+	}
+	if err := bucket.Delete([]byte(key)); err != nil {
+		return err
+	}
+	if k, _ := bucket.Cursor().First(); k == nil {
+		return tx.DeleteBucket(name)
+	}
+	return nil
+}
+
+func (e *BoltEngine) Iterate(ns, prefix string, fn func(key string, item Item) bool) error {
+	return e.db.View(func(tx *bolt.Tx) error {
+		return boltIterate(tx, ns, prefix, fn)
+	})
+}
+
+func boltIterate(tx *bolt.Tx, ns, prefix string, fn func(key string, item Item) bool) error {
+	bucket := tx.Bucket(bucketName(ns))
+	if bucket == nil {
+		return nil
+	}
+
+	c := bucket.Cursor()
+	for k, v := c.Seek([]byte(prefix)); k != nil && bytes.HasPrefix(k, []byte(prefix)); k, v = c.Next() {
+		item, err := decodeItem(v)
+		if err != nil {
+			return fmt.Errorf("%q in namespace %q: %w", k, ns, err)
+		}
+		if !fn(string(k), item) {
+			return nil
+		}
+	}
+	return nil
+}
+
+// Sample reads consecutive keys from a random position of the tree,
+// wrapping around at the end.
+func (e *BoltEngine) Sample(ns string, n int, fn func(key string, item Item) bool) error {
+	return e.db.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(bucketName(ns))
+		if bucket == nil {
+			return nil
+		}
+
+		seek := make([]byte, 8)
+		_, _ = rand.Read(seek)
+
+		c := bucket.Cursor()
+		k, v := c.Seek(seek)
+		if k == nil {
+			k, v = c.First()
+		}
+		start := k
+		for sampled := 0; k != nil && sampled < n; sampled++ {
+			item, err := decodeItem(v)
+			if err != nil {
+				return fmt.Errorf("%q in namespace %q: %w", k, ns, err)
+			}
+			if !fn(string(k), item) {
+				return nil
+			}
+
+			if k, v = c.Next(); k == nil {
+				k, v = c.First()
+			}
+			if bytes.Equal(k, start) {
+				return nil
+			}
+		}
+		return nil
+	})
+}
+
+func (e *BoltEngine) Snapshot() (Snapshot, error) {
+	tx, err := e.db.Begin(false)
+	if err != nil {
+		return nil, err
+	}
+	return &boltSnapshot{tx: tx}, nil
+}
+
+func (e *BoltEngine) Namespaces() ([]string, error) {
+	var names []string
+	err := e.db.View(func(tx *bolt.Tx) error {
+		names = boltNamespaces(tx)
+		return nil
+	})
+	return names, err
+}
+
+func boltNamespaces(tx *bolt.Tx) []string {
+	var names []string
+	_ = tx.ForEach(func(name []byte, _ *bolt.Bucket) error {
+		if bytes.HasPrefix(name, []byte(bucketPrefix)) {
+			names = append(names, string(name[len(bucketPrefix):]))
+		}
+		return nil
+	})
+	return names
+}
+
+func (e *BoltEngine) Close() error {
+	return e.db.Close()
+}
+
+// boltSnapshot holds a read transaction, which keeps the pages it reads from
+// being reused until it is released. Writes growing the file over
+// boltMmapSize wait for the release.
+type boltSnapshot struct {
+	tx *bolt.Tx
+}
+
+func (s *boltSnapshot) Get(ns, key string) (Item, bool, error) {
+	return boltGet(s.tx, ns, key)
+}
+
+func (s *boltSnapshot) Iterate(ns, prefix string, fn func(key string, item Item) bool) error {
+	return boltIterate(s.tx, ns, prefix, fn)
+}
+
+func (s *boltSnapshot) Namespaces() ([]string, error) {
+	return boltNamespaces(s.tx), nil
+}
+
+func (s *boltSnapshot) Release() {
+	_ = s.tx.Rollback()
+}
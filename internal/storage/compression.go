@@ -0,0 +1,139 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/klauspost/compress/snappy"
+	"github.com/klauspost/compress/zstd"
+)
+
+// Codec tags how the value of an item is compressed.
+type Codec uint8
+
+const (
+	CodecNone Codec = iota
+	CodecSnappy
+	CodecZstd
+)
+
+const (
+	CompressionNone   = "none"
+	CompressionSnappy = "snappy"
+	CompressionZstd   = "zstd"
+)
+
+var ErrInvalidCodec = errors.New("unknown compression codec")
+
+// ParseCodec returns the codec of the configured name.
+func ParseCodec(name string) (Codec, error) {
+	switch name {
+	case CompressionNone:
+		return CodecNone, nil
+	case CompressionSnappy:
+		return CodecSnappy, nil
+	case CompressionZstd:
+		return CodecZstd, nil
+	}
+	return CodecNone, fmt.Errorf("%w %q", ErrInvalidCodec, name)
+}
+
+func (c Codec) String() string {
+	switch c {
+	case CodecNone:
+		return CompressionNone
+	case CodecSnappy:
+		return CompressionSnappy
+	case CodecZstd:
+		return CompressionZstd
+	}
+	return fmt.Sprintf("codec(%d)", uint8(c))
+}
+
+// The zstd encoder and decoder are safe for concurrent EncodeAll and
+// DecodeAll calls and are shared by every store.
+var (
+	zstdEncoder, _ = zstd.NewWriter(nil, zstd.WithEncoderConcurrency(1))
+	zstdDecoder, _ = zstd.NewReader(nil, zstd.WithDecoderConcurrency(0))
+)
+
+func encode(codec Codec, value string) string {
+	switch codec {
+	case CodecSnappy:
+		return string(snappy.Encode(nil, []byte(value)))
+	case CodecZstd:
+		return string(zstdEncoder.EncodeAll([]byte(value), nil))
+	}
+	return value
+}
+
+// Decode returns the value compressed with the codec.
+func Decode(codec Codec, value string) (string, error) {
+	switch codec {
+	case CodecNone:
+		return value, nil
+	case CodecSnappy:
+		buf, err := snappy.Decode(nil, []byte(value))
+		if err != nil {
+			return "", errCorruptItem
+		}
+		return string(buf), nil
+	case CodecZstd:
+		buf, err := zstdDecoder.DecodeAll([]byte(value), nil)
+		if err != nil {
+			return "", errCorruptItem
+		}
+		return string(buf), nil
+	}
+	return "", errCorruptItem
+}
+
+// CompressionStats counts the values compressed on this node, by their size
+// before and after compression.
+type CompressionStats struct {
+	RawBytes    int64
+	StoredBytes int64
+}
+
+// Compress encodes the value of the item with the codec of the namespace,
+// or the default codec of the store, when the value reaches the size
+// threshold and shrinks. Large values and items already compressed are
+// returned as is. Replicas store the items the leader compressed unchanged.
+func (s *Store) Compress(ns string, item Item) Item {
+	if item.Codec != CodecNone || item.Large() || len(item.Value) < s.compressMinSize {
+		return item
+	}
+	n := s.lookup(ns)
+	if n == nil {
+		return item
+	}
+	codec, ok := n.codec()
+	if !ok {
+		codec = s.compression
+	}
+	if codec == CodecNone {
+		return item
+	}
+
+	compressed := encode(codec, item.Value)
+	if len(compressed) >= len(item.Value) {
+		return item
+	}
+	n.compressedRaw.Add(int64(len(item.Value)))
+	n.compressedStored.Add(int64(len(compressed)))
+	item.Value, item.Codec = compressed, codec
+	return item
+}
+
+// decompress returns the item with its value decoded.
+func decompress(item Item) (Item, error) {
+	if item.Codec == CodecNone {
+		return item, nil
+	}
+	value, err := Decode(item.Codec, item.Value)
+	if err != nil {
+		return Item{}, err
+	}
+	item.Value, item.Codec = value, CodecNone
+	return item, nil
+}
@@ -0,0 +1,84 @@
+package storage
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/Na322Pr/kv-storage-service/internal/config"
+	"github.com/Na322Pr/kv-storage-service/internal/storage/lsm"
+)
+
+// LoadKeyring reads the encryption keys from the key file and the
+// environment, it returns nil when encryption is disabled. Rotating a key
+// means adding it with a higher id: new files use it, and the old key stays
+// until Reencrypt has rewritten the files sealed with it.
+func LoadKeyring(cfg config.Encryption) (*lsm.Keyring, error) {
+	if !cfg.Enabled {
+		return nil, nil
+	}
+
+	keys := make(map[uint32][]byte)
+	if cfg.KeyFile != "" {
+		data, err := os.ReadFile(cfg.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("read key file: %w", err)
+		}
+		if err := parseKeys(keys, strings.Split(string(data), "\n")); err != nil {
+			return nil, fmt.Errorf("key file %s: %w", cfg.KeyFile, err)
+		}
+	}
+	if cfg.Keys != "" {
+		if err := parseKeys(keys, strings.Split(cfg.Keys, ",")); err != nil {
+			return nil, fmt.Errorf("keys: %w", err)
+		}
+	}
+	return lsm.NewKeyring(keys)
+}
+
+// parseKeys adds the id:base64 keys to keys, an id may only repeat with the
+// same key.
+func parseKeys(keys map[uint32][]byte, entries []string) error {
+	for _, entry := range entries {
+		entry = strings.TrimSpace(entry)
+		if entry == "" || strings.HasPrefix(entry, "#") {
+			continue
+		}
+		idText, keyText, ok := strings.Cut(entry, ":")
+		if !ok {
+			return fmt.Errorf("key %q: want id:base64", entry)
+		}
+		id, err := strconv.ParseUint(strings.TrimSpace(idText), 10, 32)
+		if err != nil {
+			return fmt.Errorf("key id %q: %w", idText, err)
+		}
+		key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(keyText))
+		if err != nil {
+			return fmt.Errorf("key %d: %w", id, err)
+		}
+		if existing, ok := keys[uint32(id)]; ok && !bytes.Equal(existing, key) {
+			return fmt.Errorf("key %d is defined twice", id)
+		}
+		keys[uint32(id)] = key
+	}
+	return nil
+}
+
+// Reencrypt rewrites the files of the stopped lsm engine with the primary
+// key of the configuration.
+func Reencrypt(cfg config.Storage) error {
+	if cfg.Engine != EngineLSM {
+		return fmt.Errorf("encryption is not supported by the %s engine", cfg.Engine)
+	}
+	opts, err := lsmOptions(cfg)
+	if err != nil {
+		return err
+	}
+	if opts.Keyring == nil {
+		return fmt.Errorf("encryption is disabled")
+	}
+	return lsm.Reencrypt(lsmDir(cfg), opts)
+}
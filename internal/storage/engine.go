@@ -0,0 +1,105 @@
+package storage
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/Na322Pr/kv-storage-service/internal/config"
+	"github.com/Na322Pr/kv-storage-service/internal/storage/lsm"
+)
+
+const (
+	EngineMemory = "memory"
+	EngineBolt   = "bolt"
+	EngineLSM    = "lsm"
+)
+
+// Engine keeps items by namespace and key. Engines do not interpret items:
+// expiration, revisions, quotas and eviction are handled by Store, which also
+// serializes the writes.
+type Engine interface {
+	Get(ns, key string) (Item, bool, error)
+	Put(ns, key string, item Item) error
+	// Delete succeeds when the key does not exist.
+	Delete(ns, key string) error
+	// Iterate calls fn for the keys of the namespace starting with prefix in
+	// ascending order until fn returns false. fn must not modify the engine.
+	Iterate(ns, prefix string, fn func(key string, item Item) bool) error
+	// Sample calls fn for up to n keys of the namespace in no particular
+	// order until fn returns false. fn must not modify the engine.
+	Sample(ns string, n int, fn func(key string, item Item) bool) error
+	// Batch applies the operations in order, all of them or none.
+	Batch(ops []Op) error
+	// Snapshot returns a consistent read-only view of the data, it must be
+	// released after use.
+	Snapshot() (Snapshot, error)
+	// Namespaces lists the namespaces holding at least one key.
+	Namespaces() ([]string, error)
+	Close() error
+}
+
+// Snapshot is a point-in-time view of an engine.
+type Snapshot interface {
+	Get(ns, key string) (Item, bool, error)
+	Iterate(ns, prefix string, fn func(key string, item Item) bool) error
+	Namespaces() ([]string, error)
+	Release()
+}
+
+// Op is a write of a batch.
+type Op struct {
+	Namespace string
+	Key       string
+	Item      Item
+	// Delete removes the key instead of storing Item.
+	Delete bool
+}
+
+// OpenEngine opens the engine selected by the configuration.
+func OpenEngine(cfg config.Storage) (Engine, error) {
+	if cfg.Encryption.Enabled && cfg.Engine != EngineLSM {
+		return nil, fmt.Errorf("encryption is not supported by the %s engine", cfg.Engine)
+	}
+	if cfg.Archive.Dir != "" && cfg.Engine != EngineLSM {
+		return nil, fmt.Errorf("archiving is not supported by the %s engine", cfg.Engine)
+	}
+
+	switch cfg.Engine {
+	case EngineMemory:
+		return NewMemoryEngine(), nil
+	case EngineBolt:
+		return OpenBoltEngine(filepath.Join(cfg.DataDir, "kv.db"))
+	case EngineLSM:
+		opts, err := lsmOptions(cfg)
+		if err != nil {
+			return nil, err
+		}
+		return OpenLSMEngine(lsmDir(cfg), opts)
+	}
+	return nil, fmt.Errorf("unknown storage engine %q", cfg.Engine)
+}
+
+func lsmDir(cfg config.Storage) string {
+	return filepath.Join(cfg.DataDir, "lsm")
+}
+
+func lsmOptions(cfg config.Storage) (lsm.Options, error) {
+	keyring, err := LoadKeyring(cfg.Encryption)
+	if err != nil {
+		return lsm.Options{}, err
+	}
+	archive, err := OpenArchive(cfg.Archive)
+	if err != nil {
+		return lsm.Options{}, err
+	}
+	return lsm.Options{
+		MemtableSize:        cfg.LSM.MemtableSize,
+		TableSize:           cfg.LSM.TableSize,
+		L0CompactionTrigger: cfg.LSM.L0CompactionTrigger,
+		LevelBaseSize:       cfg.LSM.LevelBaseSize,
+		CompactionRate:      cfg.LSM.CompactionRate,
+		SyncWrites:          cfg.LSM.SyncWrites,
+		Keyring:             keyring,
+		Archive:             archive,
+	}, nil
+}
@@ -0,0 +1,37 @@
+package storage_test
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/Na322Pr/kv-storage-service/internal/storage"
+	"github.com/Na322Pr/kv-storage-service/internal/storage/enginetest"
+	"github.com/Na322Pr/kv-storage-service/internal/storage/lsm"
+)
+
+func TestMemoryEngine(t *testing.T) {
+	enginetest.Run(t, func(t *testing.T) storage.Engine {
+		return storage.NewMemoryEngine()
+	})
+}
+
+func TestBoltEngine(t *testing.T) {
+	enginetest.Run(t, func(t *testing.T) storage.Engine {
+		e, err := storage.OpenBoltEngine(filepath.Join(t.TempDir(), "kv.db"))
+		if err != nil {
+			t.Fatalf("open: %v", err)
+		}
+		return e
+	})
+}
+
+func TestLSMEngine(t *testing.T) {
+	enginetest.Run(t, func(t *testing.T) storage.Engine {
+		// Small tables make the suite go through flushes and compactions.
+		e, err := storage.OpenLSMEngine(t.TempDir(), lsm.Options{MemtableSize: 1 << 10, TableSize: 1 << 10, BlockSize: 128})
+		if err != nil {
+			t.Fatalf("open: %v", err)
+		}
+		return e
+	})
+}
@@ -0,0 +1,307 @@
+// Package enginetest checks that a storage engine behaves the way Store
+// expects. Every engine runs the same suite:
+//
+//	func TestEngine(t *testing.T) {
+//		enginetest.Run(t, func(t *testing.T) storage.Engine {
+//			return storage.NewMemoryEngine()
+//		})
+//	}
+package enginetest
+
+import (
+	"fmt"
+	"slices"
+	"testing"
+
+	"github.com/Na322Pr/kv-storage-service/internal/storage"
+)
+
+// Run runs the suite, open returns a new empty engine for every subtest.
+// Engines are closed by the suite.
+func Run(t *testing.T, open func(t *testing.T) storage.Engine) {
+	tests := []struct {
+		name string
+		run  func(t *testing.T, e storage.Engine)
+	}{
+		{"PutGetDelete", testPutGetDelete},
+		{"ItemFields", testItemFields},
+		{"NamespaceIsolation", testNamespaceIsolation},
+		{"Iterate", testIterate},
+		{"Sample", testSample},
+		{"Batch", testBatch},
+		{"Snapshot", testSnapshot},
+		{"Namespaces", testNamespaces},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := open(t)
+			defer func() {
+				if err := e.Close(); err != nil {
+					t.Errorf("close: %v", err)
+				}
+			}()
+			tt.run(t, e)
+		})
+	}
+}
+
+func mustPut(t *testing.T, e storage.Engine, ns, key, value string) {
+	t.Helper()
+	if err := e.Put(ns, key, storage.Item{Value: value}); err != nil {
+		t.Fatalf("put %q/%q: %v", ns, key, err)
+	}
+}
+
+func expectValue(t *testing.T, e storage.Engine, ns, key, want string) {
+	t.Helper()
+	item, ok, err := e.Get(ns, key)
+	if err != nil {
+		t.Fatalf("get %q/%q: %v", ns, key, err)
+	}
+	if !ok {
+		t.Fatalf("get %q/%q: not found, want %q", ns, key, want)
+	}
+	if item.Value != want {
+		t.Fatalf("get %q/%q = %q, want %q", ns, key, item.Value, want)
+	}
+}
+
+func expectMissing(t *testing.T, e storage.Engine, ns, key string) {
+	t.Helper()
+	if _, ok, err := e.Get(ns, key); err != nil || ok {
+		t.Fatalf("get %q/%q = found %v, err %v, want missing", ns, key, ok, err)
+	}
+}
+
+func collect(t *testing.T, iterate func(fn func(key string, item storage.Item) bool) error) []string {
+	t.Helper()
+	var keys []string
+	err := iterate(func(key string, _ storage.Item) bool {
+		keys = append(keys, key)
+		return true
+	})
+	if err != nil {
+		t.Fatalf("iterate: %v", err)
+	}
+	return keys
+}
+
+func testPutGetDelete(t *testing.T, e storage.Engine) {
+	expectMissing(t, e, "", "a")
+
+	mustPut(t, e, "", "a", "1")
+	expectValue(t, e, "", "a", "1")
+
+	mustPut(t, e, "", "a", "2")
+	expectValue(t, e, "", "a", "2")
+
+	if err := e.Delete("", "a"); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	expectMissing(t, e, "", "a")
+
+	if err := e.Delete("", "a"); err != nil {
+		t.Fatalf("delete of a missing key: %v", err)
+	}
+	if err := e.Delete("missing", "a"); err != nil {
+		t.Fatalf("delete in a missing namespace: %v", err)
+	}
+}
+
+func testItemFields(t *testing.T, e storage.Engine) {
+	want := storage.Item{Value: "value\x00with\xffbytes", Expiration: 1700000000000000000, Revision: 42}
+	if err := e.Put("", "k", want); err != nil {
+		t.Fatalf("put: %v", err)
+	}
+	got, ok, err := e.Get("", "k")
+	if err != nil || !ok {
+		t.Fatalf("get = found %v, err %v", ok, err)
+	}
+	if got.Value != want.Value || got.Expiration != want.Expiration || got.Revision != want.Revision {
+		t.Fatalf("get = %+v, want %+v", got, want)
+	}
+
+	if err := e.Put("", "empty", storage.Item{}); err != nil {
+		t.Fatalf("put empty: %v", err)
+	}
+	expectValue(t, e, "", "empty", "")
+}
+
+func testNamespaceIsolation(t *testing.T, e storage.Engine) {
+	mustPut(t, e, "", "k", "default")
+	mustPut(t, e, "a", "k", "a")
+	mustPut(t, e, "b", "k", "b")
+
+	expectValue(t, e, "", "k", "default")
+	expectValue(t, e, "a", "k", "a")
+	expectValue(t, e, "b", "k", "b")
+
+	if err := e.Delete("a", "k"); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	expectMissing(t, e, "a", "k")
+	expectValue(t, e, "b", "k", "b")
+
+	keys := collect(t, func(fn func(string, storage.Item) bool) error { return e.Iterate("b", "", fn) })
+	if !slices.Equal(keys, []string{"k"}) {
+		t.Fatalf("iterate b = %q", keys)
+	}
+}
+
+func testIterate(t *testing.T, e storage.Engine) {
+	for _, key := range []string{"b/2", "a", "b/1", "c", "b/10", "b"} {
+		mustPut(t, e, "", key, key)
+	}
+
+	all := collect(t, func(fn func(string, storage.Item) bool) error { return e.Iterate("", "", fn) })
+	if want := []string{"a", "b", "b/1", "b/10", "b/2", "c"}; !slices.Equal(all, want) {
+		t.Fatalf("iterate = %q, want %q", all, want)
+	}
+
+	prefixed := collect(t, func(fn func(string, storage.Item) bool) error { return e.Iterate("", "b/", fn) })
+	if want := []string{"b/1", "b/10", "b/2"}; !slices.Equal(prefixed, want) {
+		t.Fatalf("iterate b/ = %q, want %q", prefixed, want)
+	}
+
+	var visited []string
+	err := e.Iterate("", "", func(key string, item storage.Item) bool {
+		if item.Value != key {
+			t.Errorf("item of %q = %q", key, item.Value)
+		}
+		visited = append(visited, key)
+		return len(visited) < 2
+	})
+	if err != nil {
+		t.Fatalf("iterate: %v", err)
+	}
+	if want := []string{"a", "b"}; !slices.Equal(visited, want) {
+		t.Fatalf("stopped iterate = %q, want %q", visited, want)
+	}
+
+	missing := collect(t, func(fn func(string, storage.Item) bool) error { return e.Iterate("missing", "", fn) })
+	if len(missing) != 0 {
+		t.Fatalf("iterate missing namespace = %q", missing)
+	}
+}
+
+func testSample(t *testing.T, e storage.Engine) {
+	stored := make(map[string]bool)
+	for i := range 100 {
+		key := fmt.Sprintf("key-%03d", i)
+		mustPut(t, e, "", key, key)
+		stored[key] = true
+	}
+
+	seen := make(map[string]bool)
+	err := e.Sample("", 10, func(key string, item storage.Item) bool {
+		if !stored[key] || item.Value != key {
+			t.Errorf("sampled unknown key %q", key)
+		}
+		if seen[key] {
+			t.Errorf("sampled %q twice", key)
+		}
+		seen[key] = true
+		return true
+	})
+	if err != nil {
+		t.Fatalf("sample: %v", err)
+	}
+	if len(seen) == 0 || len(seen) > 10 {
+		t.Fatalf("sampled %d keys, want 1 to 10", len(seen))
+	}
+
+	count := 0
+	if err := e.Sample("", 10, func(string, storage.Item) bool { count++; return false }); err != nil {
+		t.Fatalf("sample: %v", err)
+	}
+	if count != 1 {
+		t.Fatalf("sample called fn %d times after it returned false", count)
+	}
+
+	mustPut(t, e, "small", "only", "only")
+	small := 0
+	if err := e.Sample("small", 10, func(string, storage.Item) bool { small++; return true }); err != nil {
+		t.Fatalf("sample: %v", err)
+	}
+	if small != 1 {
+		t.Fatalf("sampled %d keys of a namespace of one", small)
+	}
+}
+
+func testBatch(t *testing.T, e storage.Engine) {
+	mustPut(t, e, "", "old", "old")
+
+	err := e.Batch([]storage.Op{
+		{Namespace: "", Key: "a", Item: storage.Item{Value: "1"}},
+		{Namespace: "ns", Key: "b", Item: storage.Item{Value: "2"}},
+		{Namespace: "", Key: "old", Delete: true},
+		{Namespace: "", Key: "a", Item: storage.Item{Value: "3"}},
+		{Namespace: "ns", Key: "c", Item: storage.Item{Value: "4"}},
+		{Namespace: "ns", Key: "c", Delete: true},
+	})
+	if err != nil {
+		t.Fatalf("batch: %v", err)
+	}
+
+	expectValue(t, e, "", "a", "3")
+	expectValue(t, e, "ns", "b", "2")
+	expectMissing(t, e, "", "old")
+	expectMissing(t, e, "ns", "c")
+}
+
+func testSnapshot(t *testing.T, e storage.Engine) {
+	mustPut(t, e, "", "a", "1")
+	mustPut(t, e, "ns", "b", "2")
+
+	snapshot, err := e.Snapshot()
+	if err != nil {
+		t.Fatalf("snapshot: %v", err)
+	}
+	defer snapshot.Release()
+
+	// Bolt holds a read transaction, writes must not wait for the release.
+	mustPut(t, e, "", "a", "changed")
+	mustPut(t, e, "", "new", "new")
+	if err := e.Delete("ns", "b"); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+
+	item, ok, err := snapshot.Get("", "a")
+	if err != nil || !ok || item.Value != "1" {
+		t.Fatalf("snapshot get = %q, found %v, err %v, want 1", item.Value, ok, err)
+	}
+	if _, ok, _ := snapshot.Get("", "new"); ok {
+		t.Fatalf("snapshot sees a key written after it")
+	}
+	keys := collect(t, func(fn func(string, storage.Item) bool) error { return snapshot.Iterate("ns", "", fn) })
+	if !slices.Equal(keys, []string{"b"}) {
+		t.Fatalf("snapshot iterate ns = %q, want [b]", keys)
+	}
+	names, err := snapshot.Namespaces()
+	if err != nil {
+		t.Fatalf("snapshot namespaces: %v", err)
+	}
+	if !slices.Contains(names, "ns") {
+		t.Fatalf("snapshot namespaces = %q, want ns", names)
+	}
+
+	expectValue(t, e, "", "a", "changed")
+}
+
+func testNamespaces(t *testing.T, e storage.Engine) {
+	mustPut(t, e, "", "k", "v")
+	mustPut(t, e, "a", "k", "v")
+	mustPut(t, e, "b", "k", "v")
+	if err := e.Delete("a", "k"); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+
+	names, err := e.Namespaces()
+	if err != nil {
+		t.Fatalf("namespaces: %v", err)
+	}
+	slices.Sort(names)
+	if want := []string{"", "b"}; !slices.Equal(names, want) {
+		t.Fatalf("namespaces = %q, want %q", names, want)
+	}
+}
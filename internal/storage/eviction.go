@@ -0,0 +1,202 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"sync/atomic"
+	"time"
+)
+
+var ErrOutOfMemory = errors.New("max memory reached")
+
+// EvictionPolicy decides which keys are removed when a write needs memory
+// over the configured limit.
+type EvictionPolicy string
+
+const (
+	// EvictionNone rejects writes that need more memory.
+	EvictionNone EvictionPolicy = "noeviction"
+	// EvictionAllKeysLRU removes the least recently used keys.
+	EvictionAllKeysLRU EvictionPolicy = "allkeys-lru"
+	// EvictionAllKeysLFU removes the least frequently used keys.
+	EvictionAllKeysLFU EvictionPolicy = "allkeys-lfu"
+	// EvictionVolatileTTL removes the keys with a TTL closest to expiring.
+	EvictionVolatileTTL EvictionPolicy = "volatile-ttl"
+)
+
+func ParseEvictionPolicy(s string) (EvictionPolicy, error) {
+	switch policy := EvictionPolicy(s); policy {
+	case EvictionNone, EvictionAllKeysLRU, EvictionAllKeysLFU, EvictionVolatileTTL:
+		return policy, nil
+	}
+	return "", fmt.Errorf("unknown eviction policy %q", s)
+}
+
+const (
+	// evictionSamples is the number of keys per namespace compared to pick a
+	// victim, like Redis the policies are approximated by sampling.
+	evictionSamples = 16
+
+	// lfuInitial gives new keys a chance to be accessed before they are evicted.
+	lfuInitial = 5
+	// lfuLogFactor makes the counter grow logarithmically with the hits.
+	lfuLogFactor = 10
+	// lfuDecay is the idle time that takes one from the counter.
+	lfuDecay = time.Minute
+)
+
+// EvictedKey is a key removed to free memory. The leader replicates it as a delete.
+type EvictedKey struct {
+	Namespace string
+	Key       string
+	// Revision is the revision of the removal.
+	Revision int64
+}
+
+// access tracks how an item is used, it is shared by the copies of the Item.
+// A nil access, as read from disk, counts as never used.
+type access struct {
+	last atomic.Int64
+	// frequency is a logarithmic access counter decaying over time.
+	frequency atomic.Uint32
+}
+
+func newAccess(now int64) *access {
+	a := &access{}
+	a.last.Store(now)
+	a.frequency.Store(lfuInitial)
+	return a
+}
+
+func (a *access) touch(now int64) {
+	if a == nil {
+		return
+	}
+	counter := a.decayed(now)
+	if counter < 255 && rand.Float64() < 1/(float64(counter-min(counter, lfuInitial))*lfuLogFactor+1) {
+		counter++
+	}
+	a.frequency.Store(counter)
+	a.last.Store(now)
+}
+
+func (a *access) lastUsed() int64 {
+	if a == nil {
+		return 0
+	}
+	return a.last.Load()
+}
+
+func (a *access) decayed(now int64) uint32 {
+	if a == nil {
+		return 0
+	}
+	counter := a.frequency.Load()
+	periods := uint32(time.Duration(now-a.last.Load()) / lfuDecay)
+	return counter - min(counter, periods)
+}
+
+type victim struct {
+	ns    *namespace
+	key   string
+	score int64
+}
+
+// score orders candidates, the lowest score is evicted first. Expired items
+// always go first. Items the policy cannot evict are skipped, as are the
+// items attached to a lease, which end with the lease.
+func (s *Store) score(item Item, now int64) (int64, bool) {
+	if item.Expired(now) {
+		return -1, true
+	}
+	if item.Lease != 0 {
+		return 0, false
+	}
+
+	switch s.policy {
+	case EvictionAllKeysLRU:
+		return item.access.lastUsed(), true
+	case EvictionAllKeysLFU:
+		return int64(item.access.decayed(now)), true
+	case EvictionVolatileTTL:
+		return item.Expiration, item.Expiration > 0
+	}
+	return 0, false
+}
+
+// reserve makes room for grows more bytes, it must be called with mu held.
+// The key being written is never evicted to make room for itself. Only the
+// live items count: evicting a key keeps its versions, which the history
+// compaction bounds instead.
+func (s *Store) reserve(ns *namespace, key string, grows int64) error {
+	if s.maxMemory <= 0 || grows <= 0 {
+		return nil
+	}
+
+	for s.bytes.Load()+grows > s.maxMemory {
+		if s.policy == EvictionNone {
+			return ErrOutOfMemory
+		}
+		v, ok := s.sample(ns, key)
+		if !ok {
+			return ErrOutOfMemory
+		}
+
+		revision, err := s.remove(v.ns, v.key, 0)
+		if err != nil {
+			return err
+		}
+		s.evicted.Add(1)
+		s.evictionsMu.Lock()
+		s.evictions = append(s.evictions, EvictedKey{Namespace: v.ns.name, Key: v.key, Revision: revision})
+		s.evictionsMu.Unlock()
+	}
+	return nil
+}
+
+// sample picks the best victim among a few keys of every namespace. Keys
+// the policy cannot evict count as sampled, volatile-ttl finds nothing to
+// evict in namespaces of keys without TTL.
+func (s *Store) sample(skipNamespace *namespace, skipKey string) (victim, bool) {
+	now := time.Now().UnixNano()
+
+	var best victim
+	found := false
+	s.namespaces.Range(func(_, n any) bool {
+		ns := n.(*namespace)
+		_ = s.engine.Sample(ns.name, evictionSamples, func(key string, item Item) bool {
+			if ns == skipNamespace && key == skipKey {
+				return true
+			}
+			score, ok := s.score(item, now)
+			if ok && (!found || score < best.score) {
+				best = victim{ns: ns, key: key, score: score}
+				found = true
+			}
+			return true
+		})
+		return true
+	})
+	return best, found
+}
+
+// Evictions returns and forgets the keys evicted since the previous call.
+func (s *Store) Evictions() []EvictedKey {
+	s.evictionsMu.Lock()
+	defer s.evictionsMu.Unlock()
+
+	evictions := s.evictions
+	s.evictions = nil
+	return evictions
+}
+
+// EvictedCount returns the number of keys evicted since the start.
+func (s *Store) EvictedCount() int64 {
+	return s.evicted.Load()
+}
+
+// MaxMemory returns the memory limit in bytes, zero means unlimited.
+func (s *Store) MaxMemory() int64 {
+	return s.maxMemory
+}
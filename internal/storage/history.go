@@ -0,0 +1,523 @@
+package storage
+
+import (
+	"encoding/binary"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// historyPrefix starts the namespaces keeping the versions of the keys
+	// of every namespace, keyed by key and revision.
+	historyPrefix = reservedPrefix + "history/"
+	// historyKeyEnd terminates the escaped key in a history key.
+	historyKeyEnd = "\x00\x01"
+	// metaCompactedKey keeps the revision reads may go back to.
+	metaCompactedKey = "compacted"
+
+	// compactionBatchSize bounds the versions dropped in one engine batch,
+	// writes are held meanwhile.
+	compactionBatchSize = 1000
+	// scanPageSize bounds the versions ScanAt holds while it reads the
+	// values of current versions from the items.
+	scanPageSize = 256
+)
+
+const (
+	// versionDeleted marks the revision that removed the key.
+	versionDeleted = 1 << iota
+	// versionCurrent marks the version the item still holds, its value is
+	// kept by the item only.
+	versionCurrent
+)
+
+var (
+	ErrCompacted      = errors.New("revision has been compacted")
+	ErrFutureRevision = errors.New("revision is newer than the data version")
+)
+
+// Version is the value a key held from Revision on, until its next version.
+type Version struct {
+	Revision    int64
+	Value       string
+	Expiration  int64
+	ContentType string
+	// Blob and Size reference a large value, as in Item.
+	Blob int64
+	Size int64
+	// Codec tells how Value is compressed, versions returned by the reads
+	// of the store are always decoded.
+	Codec Codec
+	// Deleted marks the revision that removed the key.
+	Deleted bool
+	// Time is when the revision was applied, in unix nanoseconds.
+	Time int64
+
+	// current marks a version whose value is read from the item.
+	current bool
+}
+
+func (v Version) item() Item {
+	return Item{
+		Value:       v.Value,
+		Expiration:  v.Expiration,
+		Revision:    v.Revision,
+		ContentType: v.ContentType,
+		Blob:        v.Blob,
+		Size:        v.Size,
+		Codec:       v.Codec,
+	}
+}
+
+// decoded returns the version with its value decompressed.
+func (v Version) decoded() (Version, error) {
+	if v.Codec == CodecNone {
+		return v, nil
+	}
+	value, err := Decode(v.Codec, v.Value)
+	if err != nil {
+		return Version{}, err
+	}
+	v.Value, v.Codec = value, CodecNone
+	return v, nil
+}
+
+// Large reports whether the version references a large value.
+func (v Version) Large() bool {
+	return v.Blob != 0
+}
+
+// live reports whether the version is a value not expired by now.
+func (v Version) live(now int64) bool {
+	return !v.Deleted && !v.item().Expired(now)
+}
+
+func historyNamespace(ns string) string {
+	return historyPrefix + ns
+}
+
+// escapeHistoryKey doubles the zero bytes of the key, so a terminated key is
+// never the prefix of another and versions sort by key first.
+func escapeHistoryKey(key string) string {
+	return strings.ReplaceAll(key, "\x00", "\x00\xff")
+}
+
+func historyKey(key string, revision int64) string {
+	return escapeHistoryKey(key) + historyKeyEnd + string(binary.BigEndian.AppendUint64(nil, uint64(revision)))
+}
+
+func parseHistoryKey(hk string) (string, error) {
+	end := len(hk) - 8 - len(historyKeyEnd)
+	if end < 0 || hk[end:end+len(historyKeyEnd)] != historyKeyEnd {
+		return "", errCorruptItem
+	}
+	return strings.ReplaceAll(hk[:end], "\x00\xff", "\x00"), nil
+}
+
+// encodeVersion keeps the flags and the time ahead of the value. A current
+// version is written without its value.
+func encodeVersion(v Version) Item {
+	buf := make([]byte, 9, 9+len(v.Value))
+	if v.Deleted {
+		buf[0] |= versionDeleted
+	}
+	if v.current {
+		buf[0] |= versionCurrent
+		v.Value = ""
+	}
+	binary.BigEndian.PutUint64(buf[1:], uint64(v.Time))
+	buf = append(buf, v.Value...)
+	item := v.item()
+	item.Value = string(buf)
+	return item
+}
+
+func decodeVersion(item Item) (Version, error) {
+	if len(item.Value) < 9 {
+		return Version{}, errCorruptItem
+	}
+	return Version{
+		Revision:    item.Revision,
+		Value:       item.Value[9:],
+		Expiration:  item.Expiration,
+		ContentType: item.ContentType,
+		Blob:        item.Blob,
+		Size:        item.Size,
+		Codec:       item.Codec,
+		Deleted:     item.Value[0]&versionDeleted != 0,
+		Time:        int64(binary.BigEndian.Uint64([]byte(item.Value[1:9]))),
+		current:     item.Value[0]&versionCurrent != 0,
+	}, nil
+}
+
+// historyOp records the version of the key, written in the batch of the
+// mutation itself.
+func historyOp(ns, key string, v Version) Op {
+	return Op{Namespace: historyNamespace(ns), Key: historyKey(key, v.Revision), Item: encodeVersion(v)}
+}
+
+// replacedOp copies the value of prev into its version, so the version
+// outlives the item. It must be called with mu held, in the batch replacing
+// or removing the item.
+func (s *Store) replacedOp(ns *namespace, key string, prev Item) (Op, bool, error) {
+	stored, ok, err := s.engine.Get(historyNamespace(ns.name), historyKey(key, prev.Revision))
+	if err != nil || !ok {
+		return Op{}, false, err
+	}
+	v, err := decodeVersion(stored)
+	if err != nil || !v.current {
+		return Op{}, false, err
+	}
+	v.Value, v.current = prev.Value, false
+	return historyOp(ns.name, key, v), true, nil
+}
+
+// resolve fills in the value of a current version. The item is read first:
+// once it has been replaced the version holds a copy of the value. Engines
+// are not read while iterating, so resolve runs after the iteration.
+func (s *Store) resolve(ns, key string, v Version) (Version, error) {
+	if !v.current {
+		return v, nil
+	}
+	item, ok, err := s.engine.Get(ns, key)
+	if err != nil {
+		return Version{}, err
+	}
+	if ok && item.Revision == v.Revision {
+		v.Value, v.current = item.Value, false
+		return v, nil
+	}
+	stored, ok, err := s.engine.Get(historyNamespace(ns), historyKey(key, v.Revision))
+	if err != nil {
+		return Version{}, err
+	}
+	if !ok {
+		return Version{}, ErrCompacted
+	}
+	if v, err = decodeVersion(stored); err != nil {
+		return Version{}, err
+	}
+	if v.current {
+		// The item went away without its value being copied.
+		return Version{}, errCorruptItem
+	}
+	return v, nil
+}
+
+// versions calls fn for the versions of the namespace whose escaped key
+// starts with prefix, grouped by key and ordered by revision.
+func (s *Store) versions(ns, prefix string, fn func(key string, v Version) bool) error {
+	var decodeErr error
+	err := s.engine.Iterate(historyNamespace(ns), prefix, func(hk string, item Item) bool {
+		key, err := parseHistoryKey(hk)
+		if err != nil {
+			decodeErr = err
+			return false
+		}
+		v, err := decodeVersion(item)
+		if err != nil {
+			decodeErr = err
+			return false
+		}
+		return fn(key, v)
+	})
+	if err != nil {
+		return err
+	}
+	return decodeErr
+}
+
+// checkRevision fails for revisions not yet applied or already compacted.
+// It is checked again after a read, a compaction running meanwhile may
+// have dropped the versions read.
+func (s *Store) checkRevision(revision int64) error {
+	if revision > s.version.Load() {
+		return ErrFutureRevision
+	}
+	if revision < s.compacted.Load() {
+		return ErrCompacted
+	}
+	return nil
+}
+
+// GetAt returns the item the key held at the revision. Items expired by now
+// are reported missing, as Get does.
+func (s *Store) GetAt(ns, key string, revision int64) (Item, bool, error) {
+	if err := s.checkRevision(revision); err != nil {
+		return Item{}, false, err
+	}
+
+	var (
+		latest Version
+		found  bool
+	)
+	err := s.versions(ns, escapeHistoryKey(key)+historyKeyEnd, func(_ string, v Version) bool {
+		if v.Revision > revision {
+			return false
+		}
+		latest, found = v, true
+		return true
+	})
+	if err != nil {
+		return Item{}, false, err
+	}
+	if revision < s.compacted.Load() {
+		return Item{}, false, ErrCompacted
+	}
+	if !found || !latest.live(time.Now().UnixNano()) {
+		return Item{}, false, nil
+	}
+	if latest, err = s.resolve(ns, key, latest); err != nil {
+		return Item{}, false, err
+	}
+	item, err := decompress(latest.item())
+	if err != nil {
+		return Item{}, false, err
+	}
+	return item, true, nil
+}
+
+// ScanAt is Range over the items the namespace held at the revision. Reads
+// at the same revision always see the same items, unless they expire.
+func (s *Store) ScanAt(ns, prefix, startAfter string, revision int64, fn func(key string, item Item) bool) error {
+	if err := s.checkRevision(revision); err != nil {
+		return err
+	}
+
+	for {
+		page, more, err := s.versionsAt(ns, prefix, startAfter, revision)
+		if err != nil {
+			return err
+		}
+		for _, kv := range page {
+			v, err := s.resolve(ns, kv.key, kv.version)
+			if err != nil {
+				return err
+			}
+			item, err := decompress(v.item())
+			if err != nil {
+				return err
+			}
+			if !fn(kv.key, item) {
+				return nil
+			}
+		}
+		if !more {
+			return nil
+		}
+		startAfter = page[len(page)-1].key
+	}
+}
+
+type keyVersion struct {
+	key     string
+	version Version
+}
+
+// versionsAt returns up to scanPageSize live versions the keys after
+// startAfter held at the revision, and whether more may remain.
+func (s *Store) versionsAt(ns, prefix, startAfter string, revision int64) ([]keyVersion, bool, error) {
+	now := time.Now().UnixNano()
+	var (
+		page    []keyVersion
+		current string
+		latest  Version
+		found   bool
+	)
+	// add takes the version of the current key once all are read.
+	add := func() bool {
+		if found && latest.live(now) {
+			page = append(page, keyVersion{current, latest})
+		}
+		return len(page) < scanPageSize
+	}
+	full := false
+	err := s.versions(ns, escapeHistoryKey(prefix), func(key string, v Version) bool {
+		if key != current {
+			if !add() {
+				full = true
+				return false
+			}
+			current, found = key, false
+		}
+		if (startAfter == "" || key > startAfter) && v.Revision <= revision {
+			latest, found = v, true
+		}
+		return true
+	})
+	if err != nil {
+		return nil, false, err
+	}
+	if revision < s.compacted.Load() {
+		return nil, false, ErrCompacted
+	}
+	if !full {
+		add()
+	}
+	return page, full, nil
+}
+
+// History returns up to limit versions of the key from startRevision on, in
+// revision order, and whether more remain.
+func (s *Store) History(ns, key string, startRevision int64, limit int) ([]Version, bool, error) {
+	var (
+		versions []Version
+		more     bool
+	)
+	err := s.versions(ns, escapeHistoryKey(key)+historyKeyEnd, func(_ string, v Version) bool {
+		if v.Revision < startRevision {
+			return true
+		}
+		if len(versions) == limit {
+			more = true
+			return false
+		}
+		versions = append(versions, v)
+		return true
+	})
+	if err != nil {
+		return nil, false, err
+	}
+	for i, v := range versions {
+		if v, err = s.resolve(ns, key, v); err != nil {
+			return nil, false, err
+		}
+		if versions[i], err = v.decoded(); err != nil {
+			return nil, false, err
+		}
+	}
+	return versions, more, nil
+}
+
+// CompactedRevision returns the oldest revision reads may still ask for.
+func (s *Store) CompactedRevision() int64 {
+	return s.compacted.Load()
+}
+
+// CompactHistory drops the versions replaced before cutoff, in unix
+// nanoseconds, and the versions of a key past its newest maxVersions, with
+// the chunks of the large values no version references any more, and
+// returns how many entries were dropped. The newest version applied before
+// the cutoff is kept unless it removed the key or has expired. Zero
+// maxVersions keeps any number of versions. Reads at revisions older than
+// a dropped version fail with ErrCompacted from then on.
+func (s *Store) CompactHistory(cutoff int64, maxVersions int) (int, error) {
+	names, err := s.engine.Namespaces()
+	if err != nil {
+		return 0, err
+	}
+
+	now := time.Now().UnixNano()
+	var (
+		ops       []Op
+		compacted int64
+	)
+	for _, name := range names {
+		if !strings.HasPrefix(name, historyPrefix) {
+			continue
+		}
+		ns := strings.TrimPrefix(name, historyPrefix)
+
+		var (
+			current string
+			// versions of the current key, without their values.
+			versions []Version
+			// blobs tells for the large values of the current key whether a
+			// remaining version still references them.
+			blobs  = make(map[int64]bool)
+			unused []int64
+		)
+		drop := func(v Version) {
+			ops = append(ops, Op{Namespace: name, Key: historyKey(current, v.Revision), Delete: true})
+			if _, ok := blobs[v.Blob]; v.Large() && !ok {
+				blobs[v.Blob] = false
+			}
+		}
+		finish := func() {
+			if len(versions) == 0 {
+				return
+			}
+			// first is the oldest version kept: the newest one applied
+			// before the cutoff or the oldest of the newest maxVersions.
+			first := 0
+			for i, v := range versions {
+				if v.Time > cutoff {
+					break
+				}
+				first = i
+				compacted = max(compacted, v.Revision)
+			}
+			if maxVersions > 0 && len(versions)-first > maxVersions {
+				first = len(versions) - maxVersions
+				compacted = max(compacted, versions[first].Revision)
+			}
+			if v := versions[first]; v.Time <= cutoff && !v.live(now) {
+				first++
+			}
+			for _, v := range versions[first:] {
+				if v.Large() {
+					blobs[v.Blob] = true
+				}
+			}
+			for _, v := range versions[:first] {
+				drop(v)
+			}
+			for blob, referenced := range blobs {
+				if !referenced {
+					unused = append(unused, blob)
+				}
+			}
+			clear(blobs)
+			versions = versions[:0]
+		}
+		err := s.versions(ns, "", func(key string, v Version) bool {
+			if key != current {
+				finish()
+				current = key
+			}
+			v.Value = ""
+			versions = append(versions, v)
+			return true
+		})
+		if err != nil {
+			return 0, err
+		}
+		finish()
+
+		for _, blob := range unused {
+			blobOps, err := s.dropBlobOps(ns, blob)
+			if err != nil {
+				return 0, err
+			}
+			ops = append(ops, blobOps...)
+		}
+	}
+
+	if compacted > s.compacted.Load() {
+		// Reads are turned away before the versions they need are gone.
+		s.mu.Lock()
+		err := s.engine.Put(metaNamespace, metaCompactedKey, Item{Value: strconv.FormatInt(compacted, 10)})
+		if err == nil {
+			s.compacted.Store(compacted)
+		}
+		s.mu.Unlock()
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	dropped := 0
+	for dropped < len(ops) {
+		batch := ops[dropped:min(len(ops), dropped+compactionBatchSize)]
+		s.mu.Lock()
+		err := s.engine.Batch(batch)
+		s.mu.Unlock()
+		if err != nil {
+			return dropped, err
+		}
+		dropped += len(batch)
+	}
+	return dropped, nil
+}
@@ -0,0 +1,144 @@
+package storage
+
+import (
+	"encoding/binary"
+	"errors"
+)
+
+var errCorruptItem = errors.New("corrupt item")
+
+type Item struct {
+	// Value holds arbitrary bytes, encoded with Codec. It is empty for large
+	// values, kept in chunks apart from the item.
+	Value      string
+	Expiration int64
+	// Revision is the data version at which the item was last modified.
+	Revision int64
+	// ContentType is an optional media type supplied by the client.
+	ContentType string
+	// Blob identifies the chunks of a large value, zero for values kept inline.
+	Blob int64
+	// Size is the length of a large value.
+	Size int64
+	// Codec tells how Value is compressed. Items returned by the reads of
+	// the store are always decoded.
+	Codec Codec
+	// Lease is the lease the key is attached to, zero for none.
+	Lease int64
+
+	// access is kept by the memory engine only, items read from disk have none.
+	access *access
+}
+
+// Expired reports whether the item has a deadline that is already in the past.
+func (i Item) Expired(now int64) bool {
+	return i.Expiration > 0 && now > i.Expiration
+}
+
+// Large reports whether the value is kept in chunks.
+func (i Item) Large() bool {
+	return i.Blob != 0
+}
+
+// Len returns the length of the value, inline or large.
+func (i Item) Len() int64 {
+	if i.Large() {
+		return i.Size
+	}
+	return int64(len(i.Value))
+}
+
+const (
+	// itemHeaderSize is the encoded size of the expiration and the revision.
+	itemHeaderSize = 16
+	// itemMetadataFlag is set in the encoded revision when the content type
+	// and the large value reference follow the header. Revisions are never
+	// negative, items written before metadata existed decode as before.
+	itemMetadataFlag = 1 << 63
+	// itemCodecFlag is set in the encoded revision when a codec byte
+	// follows the header.
+	itemCodecFlag = 1 << 62
+	// itemLeaseFlag is set in the encoded revision when the uvarint lease
+	// follows the codec byte.
+	itemLeaseFlag = 1 << 61
+)
+
+// encodeItem lays the item out as big-endian expiration and revision
+// followed by the value. Compressed items keep the codec byte right after
+// the header, leased items then keep the uvarint lease and items with
+// metadata the uvarint length of the content type, the content type, the
+// blob and the size before the value.
+func encodeItem(item Item) []byte {
+	revision := uint64(item.Revision)
+	var meta []byte
+	if item.Codec != CodecNone {
+		revision |= itemCodecFlag
+		meta = append(meta, byte(item.Codec))
+	}
+	if item.Lease != 0 {
+		revision |= itemLeaseFlag
+		meta = binary.AppendUvarint(meta, uint64(item.Lease))
+	}
+	if item.ContentType != "" || item.Blob != 0 {
+		revision |= itemMetadataFlag
+		meta = binary.AppendUvarint(meta, uint64(len(item.ContentType)))
+		meta = append(meta, item.ContentType...)
+		meta = binary.AppendUvarint(meta, uint64(item.Blob))
+		meta = binary.AppendUvarint(meta, uint64(item.Size))
+	}
+
+	buf := make([]byte, itemHeaderSize, itemHeaderSize+len(meta)+len(item.Value))
+	binary.BigEndian.PutUint64(buf[0:8], uint64(item.Expiration))
+	binary.BigEndian.PutUint64(buf[8:16], revision)
+	buf = append(buf, meta...)
+	return append(buf, item.Value...)
+}
+
+func decodeItem(buf []byte) (Item, error) {
+	if len(buf) < itemHeaderSize {
+		return Item{}, errCorruptItem
+	}
+	revision := binary.BigEndian.Uint64(buf[8:16])
+	item := Item{
+		Expiration: int64(binary.BigEndian.Uint64(buf[0:8])),
+		Revision:   int64(revision &^ (itemMetadataFlag | itemCodecFlag | itemLeaseFlag)),
+	}
+	buf = buf[itemHeaderSize:]
+	if revision&itemCodecFlag != 0 {
+		if len(buf) == 0 {
+			return Item{}, errCorruptItem
+		}
+		item.Codec = Codec(buf[0])
+		buf = buf[1:]
+	}
+	if revision&itemLeaseFlag != 0 {
+		lease, read := binary.Uvarint(buf)
+		if read <= 0 {
+			return Item{}, errCorruptItem
+		}
+		item.Lease = int64(lease)
+		buf = buf[read:]
+	}
+	if revision&itemMetadataFlag != 0 {
+		n, read := binary.Uvarint(buf)
+		if read <= 0 || uint64(len(buf)-read) < n {
+			return Item{}, errCorruptItem
+		}
+		item.ContentType = string(buf[read : read+int(n)])
+		buf = buf[read+int(n):]
+
+		blob, read := binary.Uvarint(buf)
+		if read <= 0 {
+			return Item{}, errCorruptItem
+		}
+		buf = buf[read:]
+		size, read := binary.Uvarint(buf)
+		if read <= 0 {
+			return Item{}, errCorruptItem
+		}
+		buf = buf[read:]
+		item.Blob, item.Size = int64(blob), int64(size)
+	}
+	item.Value = string(buf)
+	return item, nil
+}
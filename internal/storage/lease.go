@@ -0,0 +1,120 @@
+package storage
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// metaLeasePrefix keeps the leases granted by the leader in the metadata
+// namespace, keyed by lease.
+const metaLeasePrefix = "lease/"
+
+// Lease is a lease granted by the leader. The keys attached to it carry it
+// in Item.Lease. Deadlines are kept by the leader only, a new leader starts
+// every lease over with its full TTL.
+type Lease struct {
+	ID  int64         `json:"-"`
+	TTL time.Duration `json:"ttl"`
+	// Owner is the subject of the caller the lease was granted to.
+	Owner string `json:"owner,omitempty"`
+}
+
+func leaseKey(id int64) string {
+	return metaLeasePrefix + strconv.FormatInt(id, 10)
+}
+
+// PutLease stores the lease.
+func (s *Store) PutLease(lease Lease) error {
+	value, err := json.Marshal(lease)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.engine.Put(metaNamespace, leaseKey(lease.ID), Item{Value: string(value)})
+}
+
+// DeleteLease forgets the lease and reports whether it existed. The keys
+// attached to it are left to the caller.
+func (s *Store) DeleteLease(id int64) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, ok, err := s.engine.Get(metaNamespace, leaseKey(id))
+	if err != nil || !ok {
+		return false, err
+	}
+	return true, s.engine.Delete(metaNamespace, leaseKey(id))
+}
+
+// HasLease reports whether the lease is stored.
+func (s *Store) HasLease(id int64) (bool, error) {
+	_, ok, err := s.engine.Get(metaNamespace, leaseKey(id))
+	return ok, err
+}
+
+// Leases returns the stored leases.
+func (s *Store) Leases() ([]Lease, error) {
+	return readLeases(s.engine)
+}
+
+// Leases returns the leases the view holds.
+func (d *Dump) Leases() ([]Lease, error) {
+	return readLeases(d.snapshot)
+}
+
+// itemIterator is implemented by engines and their snapshots.
+type itemIterator interface {
+	Iterate(ns, prefix string, fn func(key string, item Item) bool) error
+}
+
+func readLeases(r itemIterator) ([]Lease, error) {
+	var (
+		leases   []Lease
+		parseErr error
+	)
+	err := r.Iterate(metaNamespace, metaLeasePrefix, func(key string, item Item) bool {
+		var lease Lease
+		id, err := strconv.ParseInt(strings.TrimPrefix(key, metaLeasePrefix), 10, 64)
+		if err == nil {
+			err = json.Unmarshal([]byte(item.Value), &lease)
+		}
+		if err != nil {
+			parseErr = errCorruptItem
+			return false
+		}
+		lease.ID = id
+		leases = append(leases, lease)
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	return leases, parseErr
+}
+
+// LeasedKeys calls fn for every key attached to a lease.
+func (s *Store) LeasedKeys(fn func(ns, key string, lease int64)) error {
+	names, err := s.engine.Namespaces()
+	if err != nil {
+		return err
+	}
+	for _, name := range names {
+		if strings.HasPrefix(name, reservedPrefix) {
+			continue
+		}
+		err := s.engine.Iterate(name, "", func(key string, item Item) bool {
+			if item.Lease != 0 {
+				fn(name, key, item.Lease)
+			}
+			return true
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,227 @@
+package storage
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/binary"
+	"fmt"
+
+	"github.com/Na322Pr/kv-storage-service/internal/storage/lsm"
+)
+
+// LSMEngine keeps items in a log-structured merge tree, suited to write
+// heavy workloads. Keys of all namespaces share the tree, prefixed by the
+// length and the name of their namespace. Expired items stay until Store
+// deletes them, so its counters hold, and compactions drop their tombstones
+// once they reach the bottom level.
+type LSMEngine struct {
+	db *lsm.DB
+}
+
+func OpenLSMEngine(dir string, opts lsm.Options) (*LSMEngine, error) {
+	db, err := lsm.Open(dir, opts)
+	if err != nil {
+		return nil, fmt.Errorf("open %s: %w", dir, err)
+	}
+	return &LSMEngine{db: db}, nil
+}
+
+func lsmKey(ns, key string) []byte {
+	buf := binary.AppendUvarint(make([]byte, 0, binary.MaxVarintLen64+len(ns)+len(key)), uint64(len(ns)))
+	buf = append(buf, ns...)
+	return append(buf, key...)
+}
+
+func parseLSMKey(buf []byte) (string, string, error) {
+	n, size := binary.Uvarint(buf)
+	if size <= 0 || uint64(len(buf)-size) < n {
+		return "", "", errCorruptItem
+	}
+	return string(buf[size : size+int(n)]), string(buf[size+int(n):]), nil
+}
+
+// lsmReader is implemented by the tree and its snapshots.
+type lsmReader interface {
+	Get(key []byte) ([]byte, bool, error)
+	Scan(start []byte, fn func(key, value []byte) bool) error
+}
+
+func lsmGet(r lsmReader, ns, key string) (Item, bool, error) {
+	value, ok, err := r.Get(lsmKey(ns, key))
+	if err != nil || !ok {
+		return Item{}, false, err
+	}
+	item, err := decodeItem(value)
+	if err != nil {
+		return Item{}, false, fmt.Errorf("%q in namespace %q: %w", key, ns, err)
+	}
+	return item, true, nil
+}
+
+// lsmScan calls fn for the items of the namespace from start on while
+// their keys start with prefix.
+func lsmScan(r lsmReader, ns, prefix string, start []byte, fn func(key string, item Item) bool) error {
+	nsPrefix := lsmKey(ns, "")
+	keyPrefix := lsmKey(ns, prefix)
+
+	var iterErr error
+	err := r.Scan(start, func(k, v []byte) bool {
+		if !bytes.HasPrefix(k, keyPrefix) {
+			return false
+		}
+		item, err := decodeItem(v)
+		if err != nil {
+			iterErr = fmt.Errorf("%q in namespace %q: %w", k[len(nsPrefix):], ns, err)
+			return false
+		}
+		return fn(string(k[len(nsPrefix):]), item)
+	})
+	if err != nil {
+		return err
+	}
+	return iterErr
+}
+
+func lsmIterate(r lsmReader, ns, prefix string, fn func(key string, item Item) bool) error {
+	return lsmScan(r, ns, prefix, lsmKey(ns, prefix), fn)
+}
+
+// lsmNamespaces skips from namespace to namespace instead of reading every key.
+func lsmNamespaces(r lsmReader) ([]string, error) {
+	var (
+		names []string
+		start []byte
+	)
+	for {
+		var (
+			name     string
+			found    bool
+			parseErr error
+		)
+		err := r.Scan(start, func(k, _ []byte) bool {
+			name, _, parseErr = parseLSMKey(k)
+			found = true
+			return false
+		})
+		if err != nil {
+			return nil, err
+		}
+		if parseErr != nil {
+			return nil, parseErr
+		}
+		if !found {
+			return names, nil
+		}
+		names = append(names, name)
+
+		if start = prefixEnd(lsmKey(name, "")); start == nil {
+			return names, nil
+		}
+	}
+}
+
+// prefixEnd returns the smallest key after every key starting with prefix,
+// nil if there is none.
+func prefixEnd(prefix []byte) []byte {
+	end := bytes.Clone(prefix)
+	for i := len(end) - 1; i >= 0; i-- {
+		if end[i] < 0xff {
+			end[i]++
+			return end[:i+1]
+		}
+	}
+	return nil
+}
+
+func (e *LSMEngine) Get(ns, key string) (Item, bool, error) {
+	return lsmGet(e.db, ns, key)
+}
+
+func (e *LSMEngine) Put(ns, key string, item Item) error {
+	return e.Batch([]Op{{Namespace: ns, Key: key, Item: item}})
+}
+
+func (e *LSMEngine) Delete(ns, key string) error {
+	return e.Batch([]Op{{Namespace: ns, Key: key, Delete: true}})
+}
+
+func (e *LSMEngine) Batch(ops []Op) error {
+	batch := make([]lsm.Op, 0, len(ops))
+	for _, op := range ops {
+		if op.Delete {
+			batch = append(batch, lsm.Op{Key: lsmKey(op.Namespace, op.Key), Delete: true})
+			continue
+		}
+		batch = append(batch, lsm.Op{Key: lsmKey(op.Namespace, op.Key), Value: encodeItem(op.Item)})
+	}
+	return e.db.Apply(batch)
+}
+
+func (e *LSMEngine) Iterate(ns, prefix string, fn func(key string, item Item) bool) error {
+	return lsmIterate(e.db, ns, prefix, fn)
+}
+
+// Sample reads consecutive keys from a random position of the namespace,
+// wrapping around at the end.
+func (e *LSMEngine) Sample(ns string, n int, fn func(key string, item Item) bool) error {
+	seek := make([]byte, 8)
+	_, _ = rand.Read(seek)
+	start := append(lsmKey(ns, ""), seek...)
+
+	sampled, stopped := 0, false
+	visit := func(key string, item Item) bool {
+		if sampled == n {
+			return false
+		}
+		sampled++
+		if !fn(key, item) {
+			stopped = true
+			return false
+		}
+		return true
+	}
+	if err := lsmScan(e.db, ns, "", start, visit); err != nil || stopped || sampled == n {
+		return err
+	}
+
+	// Wrap around up to the random position.
+	return lsmIterate(e.db, ns, "", func(key string, item Item) bool {
+		return bytes.Compare(lsmKey(ns, key), start) < 0 && visit(key, item)
+	})
+}
+
+func (e *LSMEngine) Snapshot() (Snapshot, error) {
+	snapshot, err := e.db.Snapshot()
+	if err != nil {
+		return nil, err
+	}
+	return &lsmSnapshot{snapshot: snapshot}, nil
+}
+
+func (e *LSMEngine) Namespaces() ([]string, error) {
+	return lsmNamespaces(e.db)
+}
+
+func (e *LSMEngine) Close() error {
+	return e.db.Close()
+}
+
+type lsmSnapshot struct {
+	snapshot *lsm.Snapshot
+}
+
+func (s *lsmSnapshot) Get(ns, key string) (Item, bool, error) {
+	return lsmGet(s.snapshot, ns, key)
+}
+
+func (s *lsmSnapshot) Iterate(ns, prefix string, fn func(key string, item Item) bool) error {
+	return lsmIterate(s.snapshot, ns, prefix, fn)
+}
+
+func (s *lsmSnapshot) Namespaces() ([]string, error) {
+	return lsmNamespaces(s.snapshot)
+}
+
+func (s *lsmSnapshot) Release() {
+	s.snapshot.Release()
+}
@@ -0,0 +1,208 @@
+package lsm
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+var ErrArchiveConflict = errors.New("lsm: archive holds another segment with the same number")
+
+// Archive keeps the WAL segments the tree no longer needs, so their writes
+// can be replayed on top of a backup. A segment is archived once its writes
+// are flushed to a table, before it is removed. Archiving fails the
+// background work like a full disk, the archive never misses a segment.
+type Archive interface {
+	// Put stores the segment file under its number. A crash may make the
+	// tree put a segment again, it must then succeed.
+	Put(number uint64, path string) error
+	// Segments lists the numbers of the archived segments in ascending order.
+	Segments() ([]uint64, error)
+	// Open reads an archived segment.
+	Open(number uint64) (io.ReadCloser, error)
+	// Replace stores the segment file in place of the archived one with the
+	// same number, Reencrypt rewrites the segments sealed with older keys.
+	Replace(number uint64, path string) error
+}
+
+// DirArchive keeps the segments as files of a local directory. A directory
+// belongs to the tree of a single data directory, numbers restart in a new
+// one.
+type DirArchive struct {
+	dir string
+}
+
+func NewDirArchive(dir string) (*DirArchive, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("create archive directory: %w", err)
+	}
+	return &DirArchive{dir: dir}, nil
+}
+
+// Put copies the segment through a temporary file, an archived segment is
+// always complete.
+func (a *DirArchive) Put(number uint64, path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	if archived, err := os.ReadFile(fileName(a.dir, number, walExt)); err == nil {
+		if !bytes.Equal(archived, data) {
+			return fmt.Errorf("%w: %d", ErrArchiveConflict, number)
+		}
+		return nil
+	}
+	return a.write(number, data)
+}
+
+func (a *DirArchive) Replace(number uint64, path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	return a.write(number, data)
+}
+
+func (a *DirArchive) write(number uint64, data []byte) error {
+	target := fileName(a.dir, number, walExt)
+	tmp := fileName(a.dir, number, tmpExt)
+	f, err := os.OpenFile(tmp, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		_ = f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		_ = f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, target); err != nil {
+		return err
+	}
+	return syncDir(target)
+}
+
+func (a *DirArchive) Segments() ([]uint64, error) {
+	numbers, err := segments(a.dir)
+	if err != nil {
+		return nil, err
+	}
+	slices.Sort(numbers)
+	return numbers, nil
+}
+
+func (a *DirArchive) Open(number uint64) (io.ReadCloser, error) {
+	return os.Open(fileName(a.dir, number, walExt))
+}
+
+// Batch is a write read back from an archived segment.
+type Batch struct {
+	// Time is when the write was applied in unix nanoseconds, zero for
+	// segments written before the times were recorded.
+	Time int64
+	Ops  []Op
+}
+
+// ReplayArchive calls fn with the batches of the archived segments in the
+// order they were written, until fn returns an error. The segments of the
+// tree directory dir that are not archived yet follow, the live one
+// included, so the replay gets to the last write; an empty dir replays the
+// archive alone. The tree may be running: a segment archived and removed
+// meanwhile is read from the archive, a write still in progress is left
+// out. Segments sealed with an older key need it in the keyring.
+func ReplayArchive(archive Archive, dir string, keyring *Keyring, fn func(Batch) error) error {
+	// The directory is listed first, a segment removed from it afterwards
+	// is in the archive by then.
+	live, err := segments(dir)
+	if err != nil {
+		return err
+	}
+	numbers, err := archive.Segments()
+	if err != nil {
+		return err
+	}
+	archived := make(map[uint64]bool, len(numbers))
+	for _, number := range numbers {
+		archived[number] = true
+	}
+	for _, number := range live {
+		if !archived[number] {
+			numbers = append(numbers, number)
+		}
+	}
+	slices.Sort(numbers)
+
+	for _, number := range numbers {
+		data, err := readSegment(archive, archived[number], dir, number)
+		if err != nil {
+			return err
+		}
+
+		err = readWAL(data, keyring, func(at int64, payload []byte) error {
+			entries, err := decodeBatch(payload)
+			if err != nil {
+				return err
+			}
+			batch := Batch{Time: at, Ops: make([]Op, len(entries))}
+			for i, e := range entries {
+				batch.Ops[i] = Op{Key: e.key, Value: e.value, Delete: e.kind == kindDelete}
+			}
+			return fn(batch)
+		})
+		if err != nil {
+			return fmt.Errorf("wal %d: %w", number, err)
+		}
+	}
+	return nil
+}
+
+// segments lists the numbers of the WAL segments in the tree directory dir,
+// none when it is empty or missing.
+func segments(dir string) ([]uint64, error) {
+	if dir == "" {
+		return nil, nil
+	}
+	files, err := os.ReadDir(dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var numbers []uint64
+	for _, f := range files {
+		name := f.Name()
+		ext := filepath.Ext(name)
+		number, err := strconv.ParseUint(strings.TrimSuffix(name, ext), 10, 64)
+		if err == nil && ext == walExt {
+			numbers = append(numbers, number)
+		}
+	}
+	return numbers, nil
+}
+
+func readSegment(archive Archive, archived bool, dir string, number uint64) ([]byte, error) {
+	if !archived {
+		data, err := os.ReadFile(fileName(dir, number, walExt))
+		if !errors.Is(err, os.ErrNotExist) {
+			return data, err
+		}
+	}
+	r, err := archive.Open(number)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	return io.ReadAll(r)
+}
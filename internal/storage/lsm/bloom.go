@@ -0,0 +1,50 @@
+package lsm
+
+import "hash/fnv"
+
+// bloom is a filter over the keys of a table, the last byte is the number
+// of probes. Lookups of keys missing from a table skip its blocks.
+type bloom []byte
+
+func bloomHash(key []byte) uint64 {
+	h := fnv.New64a()
+	_, _ = h.Write(key)
+	return h.Sum64()
+}
+
+func newBloom(hashes []uint64, bitsPerKey int) bloom {
+	// ln(2) probes per bit per key minimize the false positive rate.
+	probes := max(1, min(30, bitsPerKey*69/100))
+	bits := max(64, len(hashes)*bitsPerKey)
+	bytes := (bits + 7) / 8
+	bits = bytes * 8
+
+	filter := make(bloom, bytes+1)
+	for _, h := range hashes {
+		h1, h2 := uint32(h), uint32(h>>32)
+		for i := range probes {
+			bit := (h1 + uint32(i)*h2) % uint32(bits)
+			filter[bit/8] |= 1 << (bit % 8)
+		}
+	}
+	filter[bytes] = byte(probes)
+	return filter
+}
+
+func (b bloom) mayContain(key []byte) bool {
+	if len(b) < 2 {
+		return true
+	}
+	bits := uint32(len(b)-1) * 8
+	probes := int(b[len(b)-1])
+
+	h := bloomHash(key)
+	h1, h2 := uint32(h), uint32(h>>32)
+	for i := range probes {
+		bit := (h1 + uint32(i)*h2) % bits
+		if b[bit/8]&(1<<(bit%8)) == 0 {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,365 @@
+package lsm
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var errClosing = errors.New("lsm: closing")
+
+// schedule wakes the background goroutine up.
+func (db *DB) schedule() {
+	select {
+	case db.work <- struct{}{}:
+	default:
+	}
+}
+
+// background flushes memtables and runs compactions one at a time, it is
+// the only goroutine changing the version after Open.
+func (db *DB) background() {
+	defer db.wg.Done()
+	for {
+		select {
+		case <-db.done:
+			return
+		case <-db.work:
+		}
+		for db.backgroundStep() {
+		}
+	}
+}
+
+// backgroundStep does one flush or compaction and reports whether more work
+// may be pending. A failure stops the background work and the writes, like
+// a full disk would.
+func (db *DB) backgroundStep() bool {
+	db.mu.Lock()
+	imm, failed := db.imm, db.bgErr != nil
+	db.mu.Unlock()
+	if failed {
+		return false
+	}
+
+	var err error
+	if imm != nil {
+		err = db.flush(imm)
+	} else if c := db.pickCompaction(); c != nil {
+		err = db.compact(c)
+	} else {
+		return false
+	}
+
+	switch {
+	case errors.Is(err, errClosing):
+		return false
+	case err != nil:
+		db.mu.Lock()
+		db.bgErr = fmt.Errorf("lsm: background work: %w", err)
+		db.cond.Broadcast()
+		db.mu.Unlock()
+		return false
+	}
+	return true
+}
+
+// flush writes the immutable memtable to level 0 and drops the WAL
+// segments it came from, archiving them first if the tree has an archive.
+func (db *DB) flush(imm *memtable) error {
+	tables, err := db.writeTables(imm.iterator(), false, nil)
+	if err != nil {
+		return err
+	}
+
+	db.mu.Lock()
+	levels := db.current.levels
+	levels[0] = append(slices.Clone(tables), levels[0]...)
+	logNumber := db.walNumber
+	err = db.logAndApply(levels, logNumber)
+	if err == nil {
+		db.imm = nil
+	}
+	db.mu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	files, err := os.ReadDir(db.dir)
+	if err != nil {
+		return nil
+	}
+	var flushed []uint64
+	for _, f := range files {
+		name := f.Name()
+		ext := filepath.Ext(name)
+		number, err := strconv.ParseUint(strings.TrimSuffix(name, ext), 10, 64)
+		if err == nil && ext == walExt && number < logNumber {
+			flushed = append(flushed, number)
+		}
+	}
+	slices.Sort(flushed)
+	return db.dropSegments(flushed)
+}
+
+type compaction struct {
+	level int
+	// inputs are the tables of level and the overlapping tables of level+1.
+	inputs [2][]*table
+	// bottommost is set when no deeper level holds the keys, tombstones
+	// have nothing left to hide there.
+	bottommost bool
+}
+
+// pickCompaction returns the level most over its target size, level 0 is
+// measured in tables and the others in bytes.
+func (db *DB) pickCompaction() *compaction {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	v := db.current
+	best, bestScore := -1, 1.0
+	target := float64(db.opts.LevelBaseSize)
+	for level := range numLevels - 1 {
+		var score float64
+		if level == 0 {
+			score = float64(len(v.levels[0])) / float64(db.opts.L0CompactionTrigger)
+		} else {
+			score = float64(levelSize(v.levels[level])) / target
+			target *= float64(db.opts.LevelSizeMultiplier)
+		}
+		if score >= bestScore {
+			best, bestScore = level, score
+		}
+	}
+	if best < 0 {
+		return nil
+	}
+
+	c := &compaction{level: best}
+	if best == 0 {
+		c.inputs[0] = slices.Clone(v.levels[0])
+	} else {
+		// Levels are compacted round-robin so every key range gets its turn.
+		tables := v.levels[best]
+		next := tables[0]
+		for _, t := range tables {
+			if bytes.Compare(t.Largest, db.compactPointer[best]) > 0 {
+				next = t
+				break
+			}
+		}
+		c.inputs[0] = []*table{next}
+	}
+
+	smallest, largest := keyRange(c.inputs[0])
+	c.inputs[1] = v.overlapping(best+1, smallest, largest)
+	if len(c.inputs[1]) > 0 {
+		smallest, largest = keyRange(append(slices.Clone(c.inputs[0]), c.inputs[1]...))
+	}
+	db.compactPointer[best] = largest
+
+	c.bottommost = true
+	for level := best + 2; level < numLevels; level++ {
+		if len(v.overlapping(level, smallest, largest)) > 0 {
+			c.bottommost = false
+		}
+	}
+	return c
+}
+
+// compact merges the inputs into new tables of the next level. A single
+// table without overlap is moved down as is.
+func (db *DB) compact(c *compaction) error {
+	var outputs []*table
+	if c.level > 0 && len(c.inputs[0]) == 1 && len(c.inputs[1]) == 0 {
+		outputs = c.inputs[0]
+	} else {
+		var its []iterator
+		if c.level == 0 {
+			for _, t := range c.inputs[0] {
+				its = append(its, t.iterator())
+			}
+		} else {
+			its = append(its, newLevelIterator(c.inputs[0]))
+		}
+		its = append(its, newLevelIterator(c.inputs[1]))
+
+		var err error
+		outputs, err = db.writeTables(newMergingIterator(its), c.bottommost, newThrottle(db.opts.CompactionRate, db.done))
+		if err != nil {
+			return err
+		}
+	}
+
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	levels := db.current.levels
+	for i, inputs := range c.inputs {
+		level := c.level + i
+		levels[level] = slices.DeleteFunc(slices.Clone(levels[level]), func(t *table) bool {
+			return slices.Contains(inputs, t)
+		})
+	}
+	levels[c.level+1] = append(levels[c.level+1], outputs...)
+
+	// Inputs are removed once the readers of older versions are done.
+	markObsolete := func(obsolete bool) {
+		for _, inputs := range c.inputs {
+			for _, t := range inputs {
+				if !slices.Contains(outputs, t) {
+					t.obsolete.Store(obsolete)
+				}
+			}
+		}
+	}
+	markObsolete(true)
+	if err := db.logAndApply(levels, db.logNumber); err != nil {
+		markObsolete(false)
+		return err
+	}
+	return nil
+}
+
+// writeTables writes the entries of the iterator to new tables, cut at the
+// table size on key boundaries so the tables of a level never share a key.
+// A version is dropped when a newer one is visible to every reader, a
+// tombstone when it is also bottommost.
+func (db *DB) writeTables(it iterator, bottommost bool, throttle *throttle) ([]*table, error) {
+	db.mu.Lock()
+	smallestSnapshot := db.smallestSnapshot()
+	db.mu.Unlock()
+
+	var (
+		tables  []*table
+		w       *tableWriter
+		number  uint64
+		lastKey []byte
+		hasKey  bool
+		// newerSeq is the sequence of the previous version of the key.
+		newerSeq uint64
+	)
+	abort := func(err error) ([]*table, error) {
+		if w != nil {
+			w.abort()
+		}
+		for _, t := range tables {
+			_ = t.f.Close()
+			_ = os.Remove(t.path)
+		}
+		return nil, err
+	}
+	finish := func() error {
+		meta, err := w.finish()
+		if err != nil {
+			return err
+		}
+		w = nil
+		meta.Number = number
+		t, err := openTable(fileName(db.dir, number, tableExt), meta, db.opts.Keyring)
+		if err != nil {
+			_ = os.Remove(fileName(db.dir, number, tableExt))
+			return err
+		}
+		tables = append(tables, t)
+		return nil
+	}
+
+	for it.seek(nil); it.valid(); it.next() {
+		e := it.entry()
+		if !hasKey || !bytes.Equal(e.key, lastKey) {
+			lastKey, hasKey = append(lastKey[:0], e.key...), true
+			newerSeq = math.MaxUint64
+			if w != nil && int64(w.size()) >= db.opts.TableSize {
+				if err := finish(); err != nil {
+					return abort(err)
+				}
+			}
+		}
+
+		drop := newerSeq <= smallestSnapshot ||
+			e.kind == kindDelete && bottommost && e.seq <= smallestSnapshot
+		newerSeq = e.seq
+		if drop {
+			continue
+		}
+
+		if w == nil {
+			db.mu.Lock()
+			number = db.nextFile
+			db.nextFile++
+			db.mu.Unlock()
+
+			var err error
+			if w, err = newTableWriter(fileName(db.dir, number, tableExt), db.opts.BlockSize, db.opts.BloomBitsPerKey, db.opts.Keyring); err != nil {
+				return abort(err)
+			}
+		}
+		before := w.size()
+		if err := w.add(*e); err != nil {
+			return abort(err)
+		}
+		if err := throttle.wait(w.size() - before); err != nil {
+			return abort(err)
+		}
+	}
+	if err := it.error(); err != nil {
+		return abort(err)
+	}
+	if w != nil {
+		if err := finish(); err != nil {
+			return abort(err)
+		}
+	}
+	return tables, nil
+}
+
+// throttle spreads the writes of a compaction over time, so the disk keeps
+// serving the foreground writes.
+type throttle struct {
+	rate    int64
+	start   time.Time
+	written uint64
+	done    <-chan struct{}
+}
+
+func newThrottle(rate int64, done <-chan struct{}) *throttle {
+	return &throttle{rate: rate, start: time.Now(), done: done}
+}
+
+// wait sleeps until n more bytes fit in the rate. A nil throttle never waits.
+func (t *throttle) wait(n uint64) error {
+	if t == nil {
+		return nil
+	}
+	select {
+	case <-t.done:
+		return errClosing
+	default:
+	}
+	if t.rate <= 0 {
+		return nil
+	}
+
+	t.written += n
+	ahead := time.Duration(float64(t.written)/float64(t.rate)*float64(time.Second)) - time.Since(t.start)
+	if ahead < 10*time.Millisecond {
+		return nil
+	}
+	timer := time.NewTimer(ahead)
+	defer timer.Stop()
+	select {
+	case <-t.done:
+		return errClosing
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,98 @@
+package lsm
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+)
+
+var ErrUnknownKey = errors.New("lsm: encryption key not in the keyring")
+
+// sealOverhead is the nonce and the tag added to every sealed record or block.
+const sealOverhead = 12 + 16
+
+// Keyring holds the AES keys the files are encrypted with by id. New files
+// are sealed with the primary key, the one with the highest id, files
+// sealed with an older key stay readable while it is in the ring. A nil
+// keyring writes plain files and reads encrypted ones as ErrUnknownKey.
+type Keyring struct {
+	primary uint32
+	aeads   map[uint32]cipher.AEAD
+}
+
+// NewKeyring takes keys of 16, 24 or 32 bytes for AES-128, AES-192 or
+// AES-256 with GCM, ids start at 1.
+func NewKeyring(keys map[uint32][]byte) (*Keyring, error) {
+	if len(keys) == 0 {
+		return nil, errors.New("lsm: keyring is empty")
+	}
+	k := &Keyring{aeads: make(map[uint32]cipher.AEAD, len(keys))}
+	for _, id := range slices.Sorted(maps.Keys(keys)) {
+		if id == 0 {
+			return nil, errors.New("lsm: key id 0 is reserved for plain files")
+		}
+		block, err := aes.NewCipher(keys[id])
+		if err != nil {
+			return nil, fmt.Errorf("lsm: key %d: %w", id, err)
+		}
+		if k.aeads[id], err = cipher.NewGCM(block); err != nil {
+			return nil, fmt.Errorf("lsm: key %d: %w", id, err)
+		}
+		k.primary = id
+	}
+	return k, nil
+}
+
+// Primary returns the id of the key new files are sealed with.
+func (k *Keyring) Primary() uint32 {
+	return k.primary
+}
+
+// sealer returns the sealer of new files, nil when k is nil.
+func (k *Keyring) sealer() *sealer {
+	if k == nil {
+		return nil
+	}
+	return &sealer{id: k.primary, aead: k.aeads[k.primary]}
+}
+
+// opener returns the sealer of a file sealed with the key id.
+func (k *Keyring) opener(id uint32) (*sealer, error) {
+	if k == nil || k.aeads[id] == nil {
+		return nil, fmt.Errorf("%w: key %d", ErrUnknownKey, id)
+	}
+	return &sealer{id: id, aead: k.aeads[id]}, nil
+}
+
+// sealer encrypts and authenticates the records or the blocks of a file
+// with one key. The additional data binds a sealed record or block to its
+// position, so they cannot be reordered unnoticed.
+type sealer struct {
+	id   uint32
+	aead cipher.AEAD
+}
+
+// seal appends a random nonce and the sealed plaintext to dst.
+func (s *sealer) seal(dst, plaintext []byte, position uint64) []byte {
+	nonce := make([]byte, s.aead.NonceSize())
+	_, _ = rand.Read(nonce)
+	dst = append(dst, nonce...)
+	return s.aead.Seal(dst, nonce, plaintext, binary.BigEndian.AppendUint64(nil, position))
+}
+
+func (s *sealer) open(sealed []byte, position uint64) ([]byte, error) {
+	n := s.aead.NonceSize()
+	if len(sealed) < n {
+		return nil, errCorrupt
+	}
+	plaintext, err := s.aead.Open(nil, sealed[:n], sealed[n:], binary.BigEndian.AppendUint64(nil, position))
+	if err != nil {
+		return nil, fmt.Errorf("key %d: %w", s.id, err)
+	}
+	return plaintext, nil
+}
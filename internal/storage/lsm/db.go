@@ -0,0 +1,531 @@
+// Package lsm is a log-structured merge tree of byte keys and values.
+//
+// Writes go to a write-ahead log and a memtable, full memtables are flushed
+// to level 0 tables in the background and compacted down the levels. Every
+// write gets a sequence number, reads and snapshots see the writes up to the
+// sequence they started at.
+package lsm
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+var ErrClosed = errors.New("lsm: database closed")
+
+// Options tune the tree, zero values take the defaults.
+type Options struct {
+	// MemtableSize is the memtable size flushed to a table.
+	MemtableSize int64
+	// TableSize is the size of the tables written by compactions.
+	TableSize int64
+	BlockSize int
+	// BloomBitsPerKey sizes the filters, 10 bits give about 1% false positives.
+	BloomBitsPerKey int
+	// L0CompactionTrigger is the number of level 0 tables compacted into level 1.
+	// Writes stall while level 0 holds three times as many.
+	L0CompactionTrigger int
+	// LevelBaseSize is the target size of level 1, each deeper level is
+	// LevelSizeMultiplier times larger.
+	LevelBaseSize       int64
+	LevelSizeMultiplier int
+	// CompactionRate limits the bytes per second written by compactions,
+	// zero is unlimited. Flushes are never throttled.
+	CompactionRate int64
+	// SyncWrites syncs the WAL before a write returns. Without it a crash
+	// loses the writes the operating system has not flushed yet.
+	SyncWrites bool
+	// Keyring encrypts the WAL segments, the tables and the manifest, nil
+	// keeps them plain. Tables sealed with an older key are rewritten with
+	// the primary key when compactions merge them, or by Reencrypt.
+	Keyring *Keyring
+	// Archive keeps the WAL segments once flushed, nil drops them.
+	Archive Archive
+}
+
+func (o *Options) setDefaults() {
+	if o.MemtableSize <= 0 {
+		o.MemtableSize = 4 << 20
+	}
+	if o.TableSize <= 0 {
+		o.TableSize = 2 << 20
+	}
+	if o.BlockSize <= 0 {
+		o.BlockSize = 4 << 10
+	}
+	if o.BloomBitsPerKey <= 0 {
+		o.BloomBitsPerKey = 10
+	}
+	if o.L0CompactionTrigger <= 0 {
+		o.L0CompactionTrigger = 4
+	}
+	if o.LevelBaseSize <= 0 {
+		o.LevelBaseSize = 10 << 20
+	}
+	if o.LevelSizeMultiplier <= 1 {
+		o.LevelSizeMultiplier = 10
+	}
+}
+
+// Op is a write of a batch.
+type Op struct {
+	Key   []byte
+	Value []byte
+	// Delete writes a tombstone instead of Value.
+	Delete bool
+}
+
+type DB struct {
+	dir  string
+	opts Options
+	lock *os.File
+
+	// writeMu serializes writers, they own wal and replace mem.
+	writeMu   sync.Mutex
+	wal       *walWriter
+	walNumber uint64
+
+	// mu guards the fields below, cond is broadcast when imm is flushed,
+	// a compaction ends or the background work fails.
+	mu             sync.Mutex
+	cond           *sync.Cond
+	mem            *memtable
+	imm            *memtable
+	current        *version
+	nextFile       uint64
+	logNumber      uint64
+	snapshots      map[uint64]int
+	compactPointer [numLevels][]byte
+	bgErr          error
+	closed         bool
+
+	// lastSeq is the sequence of the last applied write, reads see the
+	// entries up to it.
+	lastSeq atomic.Uint64
+
+	work chan struct{}
+	done chan struct{}
+	wg   sync.WaitGroup
+}
+
+// Open opens the tree in dir, creating it if needed. Writes not flushed to
+// a table before the last shutdown are replayed from the WAL.
+func Open(dir string, opts Options) (*DB, error) {
+	db, err := open(dir, opts)
+	if err != nil {
+		return nil, err
+	}
+	db.wg.Add(1)
+	go db.background()
+	db.schedule()
+	return db, nil
+}
+
+// open recovers the tree without starting the background work.
+func open(dir string, opts Options) (*DB, error) {
+	opts.setDefaults()
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("create data directory: %w", err)
+	}
+	lock, err := lockDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	db := &DB{
+		dir:       dir,
+		opts:      opts,
+		lock:      lock,
+		mem:       newMemtable(),
+		snapshots: make(map[uint64]int),
+		work:      make(chan struct{}, 1),
+		done:      make(chan struct{}),
+	}
+	db.cond = sync.NewCond(&db.mu)
+
+	if err := db.recover(); err != nil {
+		if db.current != nil {
+			db.current.unref()
+		}
+		_ = unlockDir(lock)
+		return nil, err
+	}
+	return db, nil
+}
+
+// recover loads the manifest, replays the WAL into a level 0 table and
+// starts a new WAL segment.
+func (db *DB) recover() error {
+	m, _, err := readManifest(db.dir, db.opts.Keyring)
+	if err != nil {
+		return err
+	}
+
+	var levels [numLevels][]*table
+	live := make(map[uint64]bool)
+	for level, metas := range m.Levels {
+		for _, meta := range metas {
+			t, err := openTable(fileName(db.dir, meta.Number, tableExt), meta, db.opts.Keyring)
+			if err != nil {
+				for _, opened := range levels {
+					for _, t := range opened {
+						_ = t.f.Close()
+					}
+				}
+				return err
+			}
+			levels[level] = append(levels[level], t)
+			live[meta.Number] = true
+		}
+	}
+	db.current = newVersion(levels)
+	db.nextFile = m.NextFile
+	db.lastSeq.Store(m.LastSequence)
+
+	files, err := os.ReadDir(db.dir)
+	if err != nil {
+		return err
+	}
+	var logs, flushed []uint64
+	for _, f := range files {
+		name := f.Name()
+		ext := filepath.Ext(name)
+		number, err := strconv.ParseUint(strings.TrimSuffix(name, ext), 10, 64)
+		switch {
+		case ext == tmpExt:
+			_ = os.Remove(filepath.Join(db.dir, name))
+		case err != nil:
+		case ext == walExt && number >= m.LogNumber:
+			logs = append(logs, number)
+			db.nextFile = max(db.nextFile, number+1)
+		case ext == walExt:
+			// Left by a crash or a failed archive after the flush.
+			flushed = append(flushed, number)
+		case ext == tableExt && !live[number]:
+			// Left by a crash before the manifest dropped them.
+			_ = os.Remove(filepath.Join(db.dir, name))
+		}
+	}
+	slices.Sort(logs)
+	slices.Sort(flushed)
+	if err := db.dropSegments(flushed); err != nil {
+		return err
+	}
+
+	for _, number := range logs {
+		err := replayWAL(fileName(db.dir, number, walExt), db.opts.Keyring, func(payload []byte) error {
+			entries, err := decodeBatch(payload)
+			if err != nil {
+				return err
+			}
+			for _, e := range entries {
+				e.key, e.value = bytes.Clone(e.key), bytes.Clone(e.value)
+				db.mem.add(e)
+				db.lastSeq.Store(max(db.lastSeq.Load(), e.seq))
+			}
+			return nil
+		})
+		if err != nil {
+			return fmt.Errorf("replay wal %d: %w", number, err)
+		}
+	}
+
+	if !db.mem.empty() {
+		flushed, err := db.writeTables(db.mem.iterator(), false, nil)
+		if err != nil {
+			return err
+		}
+		levels[0] = append(flushed, levels[0]...)
+		db.mem = newMemtable()
+	}
+
+	db.walNumber = db.nextFile
+	db.nextFile++
+	if db.wal, err = createWAL(fileName(db.dir, db.walNumber, walExt), db.opts.Keyring); err != nil {
+		return err
+	}
+	db.mu.Lock()
+	err = db.logAndApply(levels, db.walNumber)
+	db.mu.Unlock()
+	if err == nil {
+		err = db.dropSegments(logs)
+	}
+	if err != nil {
+		_ = db.wal.close()
+		return err
+	}
+	return nil
+}
+
+// dropSegments removes the flushed WAL segments in order, after archiving
+// them if the tree has an archive. A segment that fails to archive stays
+// with the ones after it, to be archived again.
+func (db *DB) dropSegments(numbers []uint64) error {
+	for _, number := range numbers {
+		path := fileName(db.dir, number, walExt)
+		if db.opts.Archive != nil {
+			if err := db.opts.Archive.Put(number, path); err != nil {
+				return fmt.Errorf("archive wal %d: %w", number, err)
+			}
+		}
+		_ = os.Remove(path)
+	}
+	return nil
+}
+
+// Apply writes the operations atomically, readers see all of them or none.
+func (db *DB) Apply(ops []Op) error {
+	db.writeMu.Lock()
+	defer db.writeMu.Unlock()
+
+	if err := db.makeRoom(); err != nil {
+		return err
+	}
+
+	seq := db.lastSeq.Load()
+	entries := make([]entry, len(ops))
+	for i, op := range ops {
+		seq++
+		entries[i] = entry{key: bytes.Clone(op.Key), seq: seq, kind: kindSet, value: bytes.Clone(op.Value)}
+		if op.Delete {
+			entries[i].kind, entries[i].value = kindDelete, nil
+		}
+	}
+	if err := db.wal.append(encodeBatch(entries), db.opts.SyncWrites); err != nil {
+		return err
+	}
+	for _, e := range entries {
+		db.mem.add(e)
+	}
+	db.lastSeq.Store(seq)
+	return nil
+}
+
+// makeRoom switches to a new memtable when the current one is full. It
+// waits while the previous memtable is being flushed or level 0 is too
+// deep for compactions to keep up.
+func (db *DB) makeRoom() error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	for {
+		switch {
+		case db.closed:
+			return ErrClosed
+		case db.bgErr != nil:
+			return db.bgErr
+		case len(db.current.levels[0]) >= 3*db.opts.L0CompactionTrigger:
+			db.cond.Wait()
+		case db.mem.approximateSize() < db.opts.MemtableSize:
+			return nil
+		case db.imm != nil:
+			db.cond.Wait()
+		default:
+			number := db.nextFile
+			db.nextFile++
+			wal, err := createWAL(fileName(db.dir, number, walExt), db.opts.Keyring)
+			if err != nil {
+				return err
+			}
+			if err := db.wal.close(); err != nil {
+				_ = wal.close()
+				return err
+			}
+			db.wal, db.walNumber = wal, number
+			db.imm, db.mem = db.mem, newMemtable()
+			db.schedule()
+			return nil
+		}
+	}
+}
+
+// view is a consistent state to read from.
+type view struct {
+	seq      uint64
+	mem, imm *memtable
+	v        *version
+}
+
+// view must be called with mu held, the caller releases the version.
+func (db *DB) view(seq uint64) view {
+	db.current.ref()
+	return view{seq: seq, mem: db.mem, imm: db.imm, v: db.current}
+}
+
+func (r view) get(key []byte) ([]byte, bool, error) {
+	e, ok := r.mem.get(key, r.seq)
+	if !ok && r.imm != nil {
+		e, ok = r.imm.get(key, r.seq)
+	}
+	if !ok {
+		var err error
+		if e, ok, err = r.v.get(key, r.seq); err != nil {
+			return nil, false, err
+		}
+	}
+	if !ok || e.kind == kindDelete {
+		return nil, false, nil
+	}
+	return e.value, true, nil
+}
+
+// scan calls fn with the live keys from start on in order until fn
+// returns false. The slices are valid until fn returns.
+func (r view) scan(start []byte, fn func(key, value []byte) bool) error {
+	its := []iterator{r.mem.iterator()}
+	if r.imm != nil {
+		its = append(its, r.imm.iterator())
+	}
+	it := newMergingIterator(append(its, r.v.iterators()...))
+
+	var last []byte
+	seen := false
+	for it.seek(start); it.valid(); it.next() {
+		e := it.entry()
+		if e.seq > r.seq || seen && bytes.Equal(e.key, last) {
+			continue
+		}
+		last, seen = append(last[:0], e.key...), true
+		if e.kind == kindSet && !fn(e.key, e.value) {
+			return nil
+		}
+	}
+	return it.error()
+}
+
+// Get returns the value of key.
+func (db *DB) Get(key []byte) ([]byte, bool, error) {
+	// The sequence is read before the memtables, the ones taken may hold
+	// newer writes but never miss older ones.
+	seq := db.lastSeq.Load()
+	db.mu.Lock()
+	if db.closed {
+		db.mu.Unlock()
+		return nil, false, ErrClosed
+	}
+	r := db.view(seq)
+	db.mu.Unlock()
+	defer r.v.unref()
+
+	return r.get(key)
+}
+
+// Scan calls fn with the keys from start on in order until fn returns false.
+// Writes applied during the scan may or may not be seen.
+func (db *DB) Scan(start []byte, fn func(key, value []byte) bool) error {
+	seq := db.lastSeq.Load()
+	db.mu.Lock()
+	if db.closed {
+		db.mu.Unlock()
+		return ErrClosed
+	}
+	r := db.view(seq)
+	db.mu.Unlock()
+	defer r.v.unref()
+
+	return r.scan(start, fn)
+}
+
+// Snapshot is a consistent read-only view of the tree. Compactions keep the
+// versions it reads until it is released.
+type Snapshot struct {
+	db   *DB
+	view view
+	once sync.Once
+}
+
+func (db *DB) Snapshot() (*Snapshot, error) {
+	seq := db.lastSeq.Load()
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	if db.closed {
+		return nil, ErrClosed
+	}
+	db.snapshots[seq]++
+	return &Snapshot{db: db, view: db.view(seq)}, nil
+}
+
+func (s *Snapshot) Get(key []byte) ([]byte, bool, error) {
+	return s.view.get(key)
+}
+
+func (s *Snapshot) Scan(start []byte, fn func(key, value []byte) bool) error {
+	return s.view.scan(start, fn)
+}
+
+func (s *Snapshot) Release() {
+	s.once.Do(func() {
+		s.db.mu.Lock()
+		if s.db.snapshots[s.view.seq]--; s.db.snapshots[s.view.seq] == 0 {
+			delete(s.db.snapshots, s.view.seq)
+		}
+		s.db.mu.Unlock()
+		s.view.v.unref()
+	})
+}
+
+// smallestSnapshot returns the oldest sequence a reader may ask for, it
+// must be called with mu held.
+func (db *DB) smallestSnapshot() uint64 {
+	smallest := db.lastSeq.Load()
+	for seq := range db.snapshots {
+		smallest = min(smallest, seq)
+	}
+	return smallest
+}
+
+// logAndApply persists the levels in the manifest and makes them the
+// current version, it must be called with mu held.
+func (db *DB) logAndApply(levels [numLevels][]*table, logNumber uint64) error {
+	for level := 1; level < numLevels; level++ {
+		sort.Slice(levels[level], func(i, j int) bool {
+			return bytes.Compare(levels[level][i].Smallest, levels[level][j].Smallest) < 0
+		})
+	}
+
+	v := newVersion(levels)
+	if err := writeManifest(db.dir, v.manifest(db.nextFile, logNumber, db.lastSeq.Load()), db.opts.Keyring); err != nil {
+		v.unref()
+		return err
+	}
+	old := db.current
+	db.current, db.logNumber = v, logNumber
+	old.unref()
+	db.cond.Broadcast()
+	return nil
+}
+
+// Close stops the background work. Unflushed writes stay in the WAL.
+// Snapshots still open keep their tables readable until released.
+func (db *DB) Close() error {
+	db.writeMu.Lock()
+	defer db.writeMu.Unlock()
+
+	db.mu.Lock()
+	if db.closed {
+		db.mu.Unlock()
+		return nil
+	}
+	db.closed = true
+	db.cond.Broadcast()
+	db.mu.Unlock()
+
+	close(db.done)
+	db.wg.Wait()
+
+	err := db.wal.close()
+	db.mu.Lock()
+	db.current.unref()
+	db.mu.Unlock()
+	return errors.Join(err, unlockDir(db.lock))
+}
@@ -0,0 +1,97 @@
+package lsm
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+)
+
+var errCorrupt = errors.New("corrupt data")
+
+const (
+	kindDelete byte = 0
+	kindSet    byte = 1
+)
+
+// entry is a version of a key. Every write gets a new sequence number,
+// tombstones are entries of kindDelete without a value.
+type entry struct {
+	key   []byte
+	seq   uint64
+	kind  byte
+	value []byte
+}
+
+// compare orders entries by key, newest version first.
+func compare(a, b *entry) int {
+	if c := bytes.Compare(a.key, b.key); c != 0 {
+		return c
+	}
+	switch {
+	case a.seq > b.seq:
+		return -1
+	case a.seq < b.seq:
+		return 1
+	}
+	return 0
+}
+
+// appendEntry encodes the entry as uvarint key length, key, uvarint
+// sequence, kind, uvarint value length and value. WAL records and table
+// blocks share the encoding.
+func appendEntry(buf []byte, e entry) []byte {
+	buf = binary.AppendUvarint(buf, uint64(len(e.key)))
+	buf = append(buf, e.key...)
+	buf = binary.AppendUvarint(buf, e.seq)
+	buf = append(buf, e.kind)
+	buf = binary.AppendUvarint(buf, uint64(len(e.value)))
+	return append(buf, e.value...)
+}
+
+// decodeEntry returns the first entry of buf and its encoded size. The
+// entry points into buf.
+func decodeEntry(buf []byte) (entry, int, error) {
+	var e entry
+	pos := 0
+
+	n, size := binary.Uvarint(buf)
+	if size <= 0 || uint64(len(buf)-size) < n {
+		return entry{}, 0, errCorrupt
+	}
+	pos += size
+	e.key = buf[pos : pos+int(n)]
+	pos += int(n)
+
+	e.seq, size = binary.Uvarint(buf[pos:])
+	if size <= 0 || pos+size >= len(buf) {
+		return entry{}, 0, errCorrupt
+	}
+	pos += size
+	e.kind = buf[pos]
+	pos++
+	if e.kind != kindSet && e.kind != kindDelete {
+		return entry{}, 0, errCorrupt
+	}
+
+	n, size = binary.Uvarint(buf[pos:])
+	if size <= 0 || uint64(len(buf)-pos-size) < n {
+		return entry{}, 0, errCorrupt
+	}
+	pos += size
+	e.value = buf[pos : pos+int(n)]
+	pos += int(n)
+	return e, pos, nil
+}
+
+func decodeEntries(buf []byte) ([]entry, error) {
+	var entries []entry
+	for len(buf) > 0 {
+		e, n, err := decodeEntry(buf)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, e)
+		buf = buf[n:]
+	}
+	return entries, nil
+}
@@ -0,0 +1,144 @@
+package lsm
+
+import (
+	"bytes"
+	"container/heap"
+	"sort"
+)
+
+// iterator walks entries in compare order. seek(nil) starts at the first entry.
+type iterator interface {
+	seek(key []byte)
+	next()
+	valid() bool
+	entry() *entry
+	error() error
+}
+
+// levelIterator concatenates the tables of a level, which are sorted and
+// do not overlap.
+type levelIterator struct {
+	tables []*table
+	i      int
+	cur    *tableIterator
+	err    error
+}
+
+func newLevelIterator(tables []*table) *levelIterator {
+	return &levelIterator{tables: tables}
+}
+
+func (it *levelIterator) seek(key []byte) {
+	it.open(sort.Search(len(it.tables), func(i int) bool {
+		return bytes.Compare(it.tables[i].Largest, key) >= 0
+	}))
+	if it.cur != nil {
+		it.cur.seek(key)
+		it.skipExhausted()
+	}
+}
+
+func (it *levelIterator) open(i int) {
+	it.i, it.cur = i, nil
+	if i < len(it.tables) {
+		it.cur = it.tables[i].iterator()
+	}
+}
+
+// skipExhausted moves to the next tables while the current one has no
+// more entries.
+func (it *levelIterator) skipExhausted() {
+	for it.cur != nil && !it.cur.valid() {
+		if it.err = it.cur.error(); it.err != nil {
+			return
+		}
+		it.open(it.i + 1)
+		if it.cur != nil {
+			it.cur.seek(nil)
+		}
+	}
+}
+
+func (it *levelIterator) next() {
+	it.cur.next()
+	it.skipExhausted()
+}
+
+func (it *levelIterator) valid() bool {
+	return it.err == nil && it.cur != nil && it.cur.valid()
+}
+
+func (it *levelIterator) entry() *entry {
+	return it.cur.entry()
+}
+
+func (it *levelIterator) error() error {
+	return it.err
+}
+
+// mergingIterator merges iterators into a single ordered stream.
+type mergingIterator struct {
+	children []iterator
+	heap     iteratorHeap
+	err      error
+}
+
+func newMergingIterator(children []iterator) *mergingIterator {
+	return &mergingIterator{children: children}
+}
+
+func (it *mergingIterator) seek(key []byte) {
+	it.heap = it.heap[:0]
+	for _, child := range it.children {
+		child.seek(key)
+		it.push(child)
+	}
+	heap.Init(&it.heap)
+}
+
+func (it *mergingIterator) push(child iterator) {
+	if child.valid() {
+		it.heap = append(it.heap, child)
+	} else if err := child.error(); err != nil && it.err == nil {
+		it.err = err
+	}
+}
+
+func (it *mergingIterator) next() {
+	top := it.heap[0]
+	top.next()
+	if top.valid() {
+		heap.Fix(&it.heap, 0)
+		return
+	}
+	if err := top.error(); err != nil && it.err == nil {
+		it.err = err
+	}
+	heap.Pop(&it.heap)
+}
+
+func (it *mergingIterator) valid() bool {
+	return it.err == nil && len(it.heap) > 0
+}
+
+func (it *mergingIterator) entry() *entry {
+	return it.heap[0].entry()
+}
+
+func (it *mergingIterator) error() error {
+	return it.err
+}
+
+type iteratorHeap []iterator
+
+func (h iteratorHeap) Len() int           { return len(h) }
+func (h iteratorHeap) Less(i, j int) bool { return compare(h[i].entry(), h[j].entry()) < 0 }
+func (h iteratorHeap) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+func (h *iteratorHeap) Push(x any)        { *h = append(*h, x.(iterator)) }
+
+func (h *iteratorHeap) Pop() any {
+	old := *h
+	x := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return x
+}
@@ -0,0 +1,20 @@
+//go:build !unix
+
+package lsm
+
+import (
+	"os"
+	"path/filepath"
+)
+
+const lockFile = "LOCK"
+
+// lockDir only creates the lock file, the tree is not protected from a
+// second process on this platform.
+func lockDir(dir string) (*os.File, error) {
+	return os.OpenFile(filepath.Join(dir, lockFile), os.O_CREATE|os.O_RDWR, 0o644)
+}
+
+func unlockDir(f *os.File) error {
+	return f.Close()
+}
@@ -0,0 +1,239 @@
+package lsm
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync/atomic"
+)
+
+const (
+	// numLevels is the depth of the tree, level 0 holds flushed memtables.
+	numLevels = 7
+
+	manifestFile = "MANIFEST"
+	tableExt     = ".sst"
+	walExt       = ".wal"
+	tmpExt       = ".tmp"
+
+	// manifestMagic and the id of the key start an encrypted manifest, the
+	// rest is the sealed JSON. A plain manifest starts with a brace.
+	manifestMagic  = "\x00KVM"
+	manifestHeader = len(manifestMagic) + 4
+)
+
+// tableMeta describes a table in the manifest.
+type tableMeta struct {
+	Number   uint64 `json:"number"`
+	Size     uint64 `json:"size"`
+	Smallest []byte `json:"smallest"`
+	Largest  []byte `json:"largest"`
+}
+
+// manifest lists the live tables. It is rewritten in full on every change
+// and replaced atomically by a rename, a crash leaves either the old or the
+// new manifest.
+type manifest struct {
+	NextFile uint64 `json:"next_file"`
+	// LogNumber is the oldest WAL segment not flushed to a table.
+	LogNumber    uint64        `json:"log_number"`
+	LastSequence uint64        `json:"last_sequence"`
+	Levels       [][]tableMeta `json:"levels"`
+}
+
+func readManifest(dir string, keyring *Keyring) (manifest, bool, error) {
+	data, err := os.ReadFile(filepath.Join(dir, manifestFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return manifest{NextFile: 1}, false, nil
+	}
+	if err != nil {
+		return manifest{}, false, err
+	}
+	if len(data) >= manifestHeader && string(data[:len(manifestMagic)]) == manifestMagic {
+		opener, err := keyring.opener(binary.BigEndian.Uint32(data[len(manifestMagic):]))
+		if err != nil {
+			return manifest{}, false, fmt.Errorf("manifest: %w", err)
+		}
+		if data, err = opener.open(data[manifestHeader:], 0); err != nil {
+			return manifest{}, false, fmt.Errorf("manifest: %w", err)
+		}
+	}
+
+	var m manifest
+	if err := json.Unmarshal(data, &m); err != nil {
+		return manifest{}, false, fmt.Errorf("manifest: %w", err)
+	}
+	if len(m.Levels) > numLevels {
+		return manifest{}, false, fmt.Errorf("manifest: %d levels, at most %d supported", len(m.Levels), numLevels)
+	}
+	return m, true, nil
+}
+
+func writeManifest(dir string, m manifest, keyring *Keyring) error {
+	data, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+	if s := keyring.sealer(); s != nil {
+		sealed := binary.BigEndian.AppendUint32([]byte(manifestMagic), s.id)
+		data = s.seal(sealed, data, 0)
+	}
+
+	path := filepath.Join(dir, manifestFile)
+	tmp := path + tmpExt
+	f, err := os.OpenFile(tmp, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		_ = f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		_ = f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return err
+	}
+	return syncDir(path)
+}
+
+// syncDir makes the creation or the rename of the file durable.
+func syncDir(path string) error {
+	d, err := os.Open(filepath.Dir(path))
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	return d.Sync()
+}
+
+func fileName(dir string, number uint64, ext string) string {
+	return filepath.Join(dir, fmt.Sprintf("%06d%s", number, ext))
+}
+
+// version is an immutable set of tables. Reads and snapshots hold a
+// reference, so compactions never remove a table still being read.
+type version struct {
+	// levels[0] is ordered newest first and may overlap, deeper levels are
+	// ordered by key and do not overlap.
+	levels [numLevels][]*table
+	refs   atomic.Int32
+}
+
+func newVersion(levels [numLevels][]*table) *version {
+	v := &version{levels: levels}
+	v.refs.Store(1)
+	for _, level := range levels {
+		for _, t := range level {
+			t.ref()
+		}
+	}
+	return v
+}
+
+func (v *version) ref() {
+	v.refs.Add(1)
+}
+
+func (v *version) unref() {
+	if v.refs.Add(-1) > 0 {
+		return
+	}
+	for _, level := range v.levels {
+		for _, t := range level {
+			t.unref()
+		}
+	}
+}
+
+// get returns the newest version of key not newer than seq. Levels are
+// searched top down, upper levels always hold the newer versions.
+func (v *version) get(key []byte, seq uint64) (entry, bool, error) {
+	for _, t := range v.levels[0] {
+		if bytes.Compare(key, t.Smallest) < 0 || bytes.Compare(key, t.Largest) > 0 {
+			continue
+		}
+		if e, ok, err := t.get(key, seq); err != nil || ok {
+			return e, ok, err
+		}
+	}
+
+	for _, level := range v.levels[1:] {
+		i := sort.Search(len(level), func(i int) bool {
+			return bytes.Compare(level[i].Largest, key) >= 0
+		})
+		if i == len(level) || bytes.Compare(key, level[i].Smallest) < 0 {
+			continue
+		}
+		if e, ok, err := level[i].get(key, seq); err != nil || ok {
+			return e, ok, err
+		}
+	}
+	return entry{}, false, nil
+}
+
+func (v *version) iterators() []iterator {
+	var its []iterator
+	for _, t := range v.levels[0] {
+		its = append(its, t.iterator())
+	}
+	for _, level := range v.levels[1:] {
+		if len(level) > 0 {
+			its = append(its, newLevelIterator(level))
+		}
+	}
+	return its
+}
+
+func (v *version) overlapping(level int, smallest, largest []byte) []*table {
+	var tables []*table
+	for _, t := range v.levels[level] {
+		if t.overlaps(smallest, largest) {
+			tables = append(tables, t)
+		}
+	}
+	return tables
+}
+
+func (v *version) manifest(nextFile, logNumber, lastSeq uint64) manifest {
+	m := manifest{NextFile: nextFile, LogNumber: logNumber, LastSequence: lastSeq}
+	for _, level := range v.levels {
+		metas := make([]tableMeta, 0, len(level))
+		for _, t := range level {
+			metas = append(metas, t.tableMeta)
+		}
+		m.Levels = append(m.Levels, metas)
+	}
+	return m
+}
+
+func levelSize(tables []*table) uint64 {
+	var size uint64
+	for _, t := range tables {
+		size += t.Size
+	}
+	return size
+}
+
+func keyRange(tables []*table) ([]byte, []byte) {
+	smallest, largest := tables[0].Smallest, tables[0].Largest
+	for _, t := range tables[1:] {
+		if bytes.Compare(t.Smallest, smallest) < 0 {
+			smallest = t.Smallest
+		}
+		if bytes.Compare(t.Largest, largest) > 0 {
+			largest = t.Largest
+		}
+	}
+	return smallest, largest
+}
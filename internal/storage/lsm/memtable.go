@@ -0,0 +1,135 @@
+package lsm
+
+import (
+	"bytes"
+	"math/rand/v2"
+	"sync"
+)
+
+const (
+	maxHeight = 12
+	// entryOverhead approximates the memory of a skiplist node next to the
+	// key and value.
+	entryOverhead = 48
+)
+
+type node struct {
+	entry
+	next []*node
+}
+
+// memtable is a skiplist of the latest writes. Entries are only added,
+// iterators and snapshots keep working while it grows.
+type memtable struct {
+	mu     sync.RWMutex
+	head   *node
+	height int
+	size   int64
+}
+
+func newMemtable() *memtable {
+	return &memtable{head: &node{next: make([]*node, maxHeight)}, height: 1}
+}
+
+func randomHeight() int {
+	h := 1
+	for h < maxHeight && rand.IntN(4) == 0 {
+		h++
+	}
+	return h
+}
+
+func (m *memtable) add(e entry) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var prev [maxHeight]*node
+	x := m.head
+	for level := m.height - 1; level >= 0; level-- {
+		for x.next[level] != nil && compare(&x.next[level].entry, &e) < 0 {
+			x = x.next[level]
+		}
+		prev[level] = x
+	}
+
+	h := randomHeight()
+	for ; m.height < h; m.height++ {
+		prev[m.height] = m.head
+	}
+	n := &node{entry: e, next: make([]*node, h)}
+	for level := range h {
+		n.next[level] = prev[level].next[level]
+		prev[level].next[level] = n
+	}
+	m.size += int64(len(e.key) + len(e.value) + entryOverhead)
+}
+
+// seek returns the first node of key or of the keys after it, it must be
+// called with mu held.
+func (m *memtable) seek(key []byte) *node {
+	x := m.head
+	for level := m.height - 1; level >= 0; level-- {
+		for x.next[level] != nil && bytes.Compare(x.next[level].key, key) < 0 {
+			x = x.next[level]
+		}
+	}
+	return x.next[0]
+}
+
+// get returns the newest version of key not newer than seq.
+func (m *memtable) get(key []byte, seq uint64) (entry, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for n := m.seek(key); n != nil && bytes.Equal(n.key, key); n = n.next[0] {
+		if n.seq <= seq {
+			return n.entry, true
+		}
+	}
+	return entry{}, false
+}
+
+func (m *memtable) approximateSize() int64 {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.size
+}
+
+func (m *memtable) empty() bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.head.next[0] == nil
+}
+
+type memIterator struct {
+	m *memtable
+	n *node
+}
+
+func (m *memtable) iterator() *memIterator {
+	return &memIterator{m: m}
+}
+
+func (it *memIterator) seek(key []byte) {
+	it.m.mu.RLock()
+	it.n = it.m.seek(key)
+	it.m.mu.RUnlock()
+}
+
+func (it *memIterator) next() {
+	it.m.mu.RLock()
+	it.n = it.n.next[0]
+	it.m.mu.RUnlock()
+}
+
+func (it *memIterator) valid() bool {
+	return it.n != nil
+}
+
+func (it *memIterator) entry() *entry {
+	return &it.n.entry
+}
+
+func (it *memIterator) error() error {
+	return nil
+}
@@ -0,0 +1,141 @@
+package lsm
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+)
+
+// Reencrypt rewrites the tree in dir with the primary key of opts.Keyring,
+// the tree must not be open. Opening replays the WAL into a table sealed
+// with the primary key and starts a new segment, the tables and the
+// archived segments sealed with another key or plain are then rewritten.
+// Afterwards the older keys can be dropped from the ring.
+func Reencrypt(dir string, opts Options) error {
+	if opts.Keyring == nil {
+		return errors.New("lsm: reencrypt needs a keyring")
+	}
+	db, err := open(dir, opts)
+	if err != nil {
+		return err
+	}
+
+	if err := db.reencrypt(); err != nil {
+		return errors.Join(err, db.Close())
+	}
+	if err := db.Close(); err != nil {
+		return err
+	}
+	if opts.Archive == nil {
+		return nil
+	}
+	return reencryptArchive(opts.Archive, opts.Keyring)
+}
+
+// reencryptArchive rewrites the archived segments sealed with another key
+// than the primary one or plain, keeping the times of their writes.
+func reencryptArchive(archive Archive, keyring *Keyring) error {
+	numbers, err := archive.Segments()
+	if err != nil {
+		return err
+	}
+	for _, number := range numbers {
+		if err := reencryptSegment(archive, keyring, number); err != nil {
+			return fmt.Errorf("archived wal %d: %w", number, err)
+		}
+	}
+	return nil
+}
+
+func reencryptSegment(archive Archive, keyring *Keyring, number uint64) error {
+	r, err := archive.Open(number)
+	if err != nil {
+		return err
+	}
+	data, err := io.ReadAll(r)
+	_ = r.Close()
+	if err != nil {
+		return err
+	}
+	if id, sealed := segmentKey(data); sealed && id == keyring.Primary() {
+		return nil
+	}
+
+	f, err := os.CreateTemp("", "kv-reencrypt-*"+walExt)
+	if err != nil {
+		return err
+	}
+	path := f.Name()
+	_ = f.Close()
+	defer os.Remove(path)
+
+	w, err := createWAL(path, keyring)
+	if err != nil {
+		return err
+	}
+	err = readWAL(data, keyring, func(at int64, payload []byte) error {
+		return w.appendAt(payload, at, false)
+	})
+	if err != nil {
+		_ = w.close()
+		return err
+	}
+	if err := w.close(); err != nil {
+		return err
+	}
+	return archive.Replace(number, path)
+}
+
+func (db *DB) reencrypt() error {
+	db.mu.Lock()
+	levels := db.current.levels
+	db.mu.Unlock()
+
+	var (
+		rewritten [numLevels][]*table
+		inputs    []*table
+		outputs   []*table
+	)
+	// Tables written so far are in no version yet.
+	fail := func(err error) error {
+		for _, t := range outputs {
+			_ = t.f.Close()
+			_ = os.Remove(t.path)
+		}
+		return err
+	}
+	for level, tables := range levels {
+		for _, t := range tables {
+			if t.opener != nil && t.opener.id == db.opts.Keyring.Primary() {
+				rewritten[level] = append(rewritten[level], t)
+				continue
+			}
+			// A table rewritten alone keeps within its key range, the tables
+			// of a level still do not overlap.
+			tables, err := db.writeTables(t.iterator(), false, nil)
+			if err != nil {
+				return fail(err)
+			}
+			rewritten[level] = append(rewritten[level], tables...)
+			inputs = append(inputs, t)
+			outputs = append(outputs, tables...)
+		}
+	}
+	if len(inputs) == 0 {
+		return nil
+	}
+
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	for _, t := range inputs {
+		t.obsolete.Store(true)
+	}
+	if err := db.logAndApply(rewritten, db.logNumber); err != nil {
+		for _, t := range inputs {
+			t.obsolete.Store(false)
+		}
+		return fail(err)
+	}
+	return nil
+}
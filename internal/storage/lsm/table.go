@@ -0,0 +1,411 @@
+package lsm
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"math"
+	"os"
+	"sort"
+	"sync/atomic"
+)
+
+const (
+	// tableMagic ends every plain table file, sealedTableMagic every
+	// encrypted one.
+	tableMagic       = 0x6b76736c736d7401
+	sealedTableMagic = 0x6b76736c736d7402
+	// footerSize holds the index and filter handles and the magic number,
+	// sealedFooterSize also the key id before the magic.
+	footerSize       = 40
+	sealedFooterSize = 48
+	// checksumSize trails every block.
+	checksumSize = 4
+)
+
+// A table file holds data blocks of sorted entries, a bloom filter of the
+// keys, an index with the last key of every block and a footer:
+//
+//	data block... | filter | index | index offset, index length,
+//	filter offset, filter length, magic
+//
+// Blocks end with a crc32 of their content, footer integers are big-endian.
+// Blocks of encrypted tables are sealed with their offset and the checksum
+// covers the sealed block, the footer stays plain.
+
+type blockHandle struct {
+	offset uint64
+	length uint64
+}
+
+type indexEntry struct {
+	// lastKey and lastSeq are the last entry of the block.
+	lastKey []byte
+	lastSeq uint64
+	blockHandle
+}
+
+type tableWriter struct {
+	path   string
+	f      *os.File
+	w      *bufio.Writer
+	offset uint64
+
+	block     []byte
+	blockSize int
+	lastKey   []byte
+	lastSeq   uint64
+	index     []indexEntry
+
+	hashes     []uint64
+	bitsPerKey int
+
+	smallest []byte
+	entries  int
+
+	sealer *sealer
+}
+
+func newTableWriter(path string, blockSize, bitsPerKey int, keyring *Keyring) (*tableWriter, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	return &tableWriter{
+		path:       path,
+		f:          f,
+		w:          bufio.NewWriter(f),
+		blockSize:  blockSize,
+		bitsPerKey: bitsPerKey,
+		sealer:     keyring.sealer(),
+	}, nil
+}
+
+// add appends an entry, entries must come in order.
+func (w *tableWriter) add(e entry) error {
+	if w.entries == 0 {
+		w.smallest = bytes.Clone(e.key)
+	}
+	if w.entries == 0 || !bytes.Equal(e.key, w.lastKey) {
+		w.hashes = append(w.hashes, bloomHash(e.key))
+		w.lastKey = bytes.Clone(e.key)
+	}
+	w.lastSeq = e.seq
+	w.entries++
+
+	w.block = appendEntry(w.block, e)
+	if len(w.block) >= w.blockSize {
+		return w.flushBlock()
+	}
+	return nil
+}
+
+// size returns the bytes written so far, including the pending block.
+func (w *tableWriter) size() uint64 {
+	return w.offset + uint64(len(w.block))
+}
+
+func (w *tableWriter) write(data []byte) (blockHandle, error) {
+	if w.sealer != nil {
+		data = w.sealer.seal(nil, data, w.offset)
+	}
+	h := blockHandle{offset: w.offset, length: uint64(len(data))}
+	if _, err := w.w.Write(data); err != nil {
+		return blockHandle{}, err
+	}
+	if _, err := w.w.Write(binary.BigEndian.AppendUint32(nil, crc32.ChecksumIEEE(data))); err != nil {
+		return blockHandle{}, err
+	}
+	w.offset += uint64(len(data)) + checksumSize
+	return h, nil
+}
+
+func (w *tableWriter) flushBlock() error {
+	if len(w.block) == 0 {
+		return nil
+	}
+	h, err := w.write(w.block)
+	if err != nil {
+		return err
+	}
+	w.index = append(w.index, indexEntry{lastKey: w.lastKey, lastSeq: w.lastSeq, blockHandle: h})
+	w.block = w.block[:0]
+	return nil
+}
+
+// finish writes the filter, the index and the footer and syncs the file.
+func (w *tableWriter) finish() (tableMeta, error) {
+	if err := w.flushBlock(); err != nil {
+		return tableMeta{}, err
+	}
+	filter, err := w.write(newBloom(w.hashes, w.bitsPerKey))
+	if err != nil {
+		return tableMeta{}, err
+	}
+
+	var index []byte
+	for _, e := range w.index {
+		index = binary.AppendUvarint(index, uint64(len(e.lastKey)))
+		index = append(index, e.lastKey...)
+		index = binary.AppendUvarint(index, e.lastSeq)
+		index = binary.AppendUvarint(index, e.offset)
+		index = binary.AppendUvarint(index, e.length)
+	}
+	indexHandle, err := w.write(index)
+	if err != nil {
+		return tableMeta{}, err
+	}
+
+	footer := make([]byte, 0, sealedFooterSize)
+	footer = binary.BigEndian.AppendUint64(footer, indexHandle.offset)
+	footer = binary.BigEndian.AppendUint64(footer, indexHandle.length)
+	footer = binary.BigEndian.AppendUint64(footer, filter.offset)
+	footer = binary.BigEndian.AppendUint64(footer, filter.length)
+	if w.sealer != nil {
+		footer = binary.BigEndian.AppendUint64(footer, uint64(w.sealer.id))
+		footer = binary.BigEndian.AppendUint64(footer, sealedTableMagic)
+	} else {
+		footer = binary.BigEndian.AppendUint64(footer, tableMagic)
+	}
+	if _, err := w.w.Write(footer); err != nil {
+		return tableMeta{}, err
+	}
+	w.offset += uint64(len(footer))
+
+	if err := w.w.Flush(); err != nil {
+		return tableMeta{}, err
+	}
+	if err := w.f.Sync(); err != nil {
+		return tableMeta{}, err
+	}
+	if err := w.f.Close(); err != nil {
+		return tableMeta{}, err
+	}
+	return tableMeta{Size: w.offset, Smallest: w.smallest, Largest: w.lastKey}, nil
+}
+
+// abort removes the partially written file.
+func (w *tableWriter) abort() {
+	_ = w.f.Close()
+	_ = os.Remove(w.path)
+}
+
+// table is an open table file. Versions holding the table keep it open,
+// obsolete tables are removed once the last version goes away.
+type table struct {
+	tableMeta
+	path  string
+	f     *os.File
+	index []indexEntry
+	bloom bloom
+	// opener is the key of an encrypted table, nil for a plain one.
+	opener *sealer
+
+	refs     atomic.Int32
+	obsolete atomic.Bool
+}
+
+func openTable(path string, meta tableMeta, keyring *Keyring) (*table, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	t := &table{tableMeta: meta, path: path, f: f}
+	if err := t.load(keyring); err != nil {
+		_ = f.Close()
+		return nil, fmt.Errorf("table %s: %w", path, err)
+	}
+	return t, nil
+}
+
+func (t *table) load(keyring *Keyring) error {
+	if t.Size < footerSize {
+		return errCorrupt
+	}
+	footer := make([]byte, footerSize)
+	if _, err := t.f.ReadAt(footer, int64(t.Size-footerSize)); err != nil {
+		return err
+	}
+	switch binary.BigEndian.Uint64(footer[32:40]) {
+	case tableMagic:
+	case sealedTableMagic:
+		if t.Size < sealedFooterSize {
+			return errCorrupt
+		}
+		footer = make([]byte, sealedFooterSize)
+		if _, err := t.f.ReadAt(footer, int64(t.Size-sealedFooterSize)); err != nil {
+			return err
+		}
+		id := binary.BigEndian.Uint64(footer[32:40])
+		if id > math.MaxUint32 {
+			return errCorrupt
+		}
+		var err error
+		if t.opener, err = keyring.opener(uint32(id)); err != nil {
+			return err
+		}
+	default:
+		return errCorrupt
+	}
+
+	filter, err := t.readBlock(blockHandle{
+		offset: binary.BigEndian.Uint64(footer[16:24]),
+		length: binary.BigEndian.Uint64(footer[24:32]),
+	})
+	if err != nil {
+		return err
+	}
+	t.bloom = filter
+
+	index, err := t.readBlock(blockHandle{
+		offset: binary.BigEndian.Uint64(footer[0:8]),
+		length: binary.BigEndian.Uint64(footer[8:16]),
+	})
+	if err != nil {
+		return err
+	}
+	for len(index) > 0 {
+		var e indexEntry
+		n, size := binary.Uvarint(index)
+		if size <= 0 || uint64(len(index)-size) < n {
+			return errCorrupt
+		}
+		e.lastKey = index[size : size+int(n)]
+		index = index[size+int(n):]
+		for _, v := range []*uint64{&e.lastSeq, &e.offset, &e.length} {
+			if *v, size = binary.Uvarint(index); size <= 0 {
+				return errCorrupt
+			}
+			index = index[size:]
+		}
+		t.index = append(t.index, e)
+	}
+	return nil
+}
+
+func (t *table) readBlock(h blockHandle) ([]byte, error) {
+	if h.offset+h.length+checksumSize > t.Size {
+		return nil, errCorrupt
+	}
+	buf := make([]byte, h.length+checksumSize)
+	if _, err := t.f.ReadAt(buf, int64(h.offset)); err != nil {
+		return nil, err
+	}
+	data := buf[:h.length]
+	if crc32.ChecksumIEEE(data) != binary.BigEndian.Uint32(buf[h.length:]) {
+		return nil, errCorrupt
+	}
+	if t.opener != nil {
+		return t.opener.open(data, h.offset)
+	}
+	return data, nil
+}
+
+func (t *table) blockEntries(i int) ([]entry, error) {
+	data, err := t.readBlock(t.index[i].blockHandle)
+	if err != nil {
+		return nil, fmt.Errorf("table %s: %w", t.path, err)
+	}
+	entries, err := decodeEntries(data)
+	if err != nil {
+		return nil, fmt.Errorf("table %s: %w", t.path, err)
+	}
+	return entries, nil
+}
+
+// findBlock returns the first block that may hold key.
+func (t *table) findBlock(key []byte) int {
+	return sort.Search(len(t.index), func(i int) bool {
+		return bytes.Compare(t.index[i].lastKey, key) >= 0
+	})
+}
+
+// get returns the newest version of key not newer than seq.
+func (t *table) get(key []byte, seq uint64) (entry, bool, error) {
+	if !t.bloom.mayContain(key) {
+		return entry{}, false, nil
+	}
+
+	for i := t.findBlock(key); i < len(t.index); i++ {
+		entries, err := t.blockEntries(i)
+		if err != nil {
+			return entry{}, false, err
+		}
+		for _, e := range entries {
+			switch c := bytes.Compare(e.key, key); {
+			case c < 0:
+				continue
+			case c > 0:
+				return entry{}, false, nil
+			case e.seq <= seq:
+				return e, true, nil
+			}
+		}
+	}
+	return entry{}, false, nil
+}
+
+func (t *table) ref() {
+	t.refs.Add(1)
+}
+
+func (t *table) unref() {
+	if t.refs.Add(-1) > 0 {
+		return
+	}
+	_ = t.f.Close()
+	if t.obsolete.Load() {
+		_ = os.Remove(t.path)
+	}
+}
+
+func (t *table) overlaps(smallest, largest []byte) bool {
+	return bytes.Compare(t.Largest, smallest) >= 0 && bytes.Compare(t.Smallest, largest) <= 0
+}
+
+type tableIterator struct {
+	t       *table
+	block   int
+	entries []entry
+	pos     int
+	err     error
+}
+
+func (t *table) iterator() *tableIterator {
+	return &tableIterator{t: t}
+}
+
+func (it *tableIterator) load(block int) {
+	it.block, it.entries, it.pos = block, nil, 0
+	if block >= len(it.t.index) {
+		return
+	}
+	it.entries, it.err = it.t.blockEntries(block)
+}
+
+func (it *tableIterator) seek(key []byte) {
+	it.load(it.t.findBlock(key))
+	for it.valid() && bytes.Compare(it.entries[it.pos].key, key) < 0 {
+		it.next()
+	}
+}
+
+func (it *tableIterator) next() {
+	if it.pos++; it.pos == len(it.entries) {
+		it.load(it.block + 1)
+	}
+}
+
+func (it *tableIterator) valid() bool {
+	return it.err == nil && it.pos < len(it.entries)
+}
+
+func (it *tableIterator) entry() *entry {
+	return &it.entries[it.pos]
+}
+
+func (it *tableIterator) error() error {
+	return it.err
+}
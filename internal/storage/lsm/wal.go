@@ -0,0 +1,210 @@
+package lsm
+
+import (
+	"bufio"
+	"encoding/binary"
+	"hash/crc32"
+	"os"
+	"time"
+)
+
+const (
+	// walHeaderSize is the size of the checksum and the length heading a record.
+	walHeaderSize = 8
+	// walMagic and an impossible record length start a segment, followed by
+	// the id of its key, zero when the segment is plain. The records of
+	// walTimedLength segments start with the unix time of the write in
+	// nanoseconds as a uvarint. Segments written before the times were
+	// recorded have walSealedLength when encrypted and no header when plain.
+	walMagic        = 0x4557564b
+	walSealedLength = 0xffffffff
+	walTimedLength  = 0xfffffffe
+	walSealedHeader = walHeaderSize + 4
+)
+
+// walWriter appends the batches applied to the memtable to a log segment.
+// The segment is archived, if the tree has an archive, and dropped once
+// the memtable is flushed to a table.
+type walWriter struct {
+	f *os.File
+	w *bufio.Writer
+
+	// sealer encrypts the payloads, records counts them for their position.
+	sealer  *sealer
+	records uint64
+}
+
+func createWAL(path string, keyring *Keyring) (*walWriter, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	if err := syncDir(path); err != nil {
+		_ = f.Close()
+		return nil, err
+	}
+	w := &walWriter{f: f, w: bufio.NewWriter(f), sealer: keyring.sealer()}
+	var id uint32
+	if w.sealer != nil {
+		id = w.sealer.id
+	}
+	header := binary.LittleEndian.AppendUint32(nil, walMagic)
+	header = binary.LittleEndian.AppendUint32(header, walTimedLength)
+	header = binary.LittleEndian.AppendUint32(header, id)
+	if _, err := w.w.Write(header); err != nil {
+		_ = f.Close()
+		return nil, err
+	}
+	return w, nil
+}
+
+// append writes a record as crc32 and length, both little-endian, followed
+// by the time and the payload. The checksum covers the sealed payload of
+// encrypted segments, a torn record is told apart from a wrong key.
+func (w *walWriter) append(payload []byte, sync bool) error {
+	return w.appendAt(payload, time.Now().UnixNano(), sync)
+}
+
+// appendAt writes a record of a write made at the given time, a rewritten
+// segment keeps the times of its writes.
+func (w *walWriter) appendAt(payload []byte, at int64, sync bool) error {
+	timed := binary.AppendUvarint(make([]byte, 0, binary.MaxVarintLen64+len(payload)), uint64(at))
+	payload = append(timed, payload...)
+	if w.sealer != nil {
+		payload = w.sealer.seal(nil, payload, w.records)
+		w.records++
+	}
+	var header [walHeaderSize]byte
+	binary.LittleEndian.PutUint32(header[0:4], crc32.ChecksumIEEE(payload))
+	binary.LittleEndian.PutUint32(header[4:8], uint32(len(payload)))
+	if _, err := w.w.Write(header[:]); err != nil {
+		return err
+	}
+	if _, err := w.w.Write(payload); err != nil {
+		return err
+	}
+	if err := w.w.Flush(); err != nil {
+		return err
+	}
+	if sync {
+		return w.f.Sync()
+	}
+	return nil
+}
+
+func (w *walWriter) close() error {
+	if err := w.w.Flush(); err != nil {
+		_ = w.f.Close()
+		return err
+	}
+	if err := w.f.Sync(); err != nil {
+		_ = w.f.Close()
+		return err
+	}
+	return w.f.Close()
+}
+
+// replayWAL calls fn for every record of the segment. A torn or corrupt
+// record ends the log, it is what a crash in the middle of a write leaves.
+// A sealed record that does not open with its key is an error.
+func replayWAL(path string, keyring *Keyring, fn func(payload []byte) error) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	return readWAL(data, keyring, func(_ int64, payload []byte) error {
+		return fn(payload)
+	})
+}
+
+// segmentKey returns the id of the key sealing the segment data, sealed is
+// false for plain segments.
+func segmentKey(data []byte) (id uint32, sealed bool) {
+	if len(data) < walSealedHeader || binary.LittleEndian.Uint32(data[0:4]) != walMagic {
+		return 0, false
+	}
+	switch binary.LittleEndian.Uint32(data[4:8]) {
+	case walSealedLength:
+		return binary.LittleEndian.Uint32(data[8:12]), true
+	case walTimedLength:
+		id := binary.LittleEndian.Uint32(data[8:12])
+		return id, id != 0
+	}
+	return 0, false
+}
+
+// readWAL calls fn with the time and the payload of every record of the
+// segment data, the time is zero for segments without times.
+func readWAL(data []byte, keyring *Keyring, fn func(time int64, payload []byte) error) error {
+	var (
+		opener *sealer
+		timed  bool
+		err    error
+	)
+	if len(data) >= walSealedHeader && binary.LittleEndian.Uint32(data[0:4]) == walMagic {
+		if length := binary.LittleEndian.Uint32(data[4:8]); length == walTimedLength || length == walSealedLength {
+			timed = length == walTimedLength
+			// Only segments with times may be plain, with key id zero.
+			if id := binary.LittleEndian.Uint32(data[8:12]); id != 0 || !timed {
+				if opener, err = keyring.opener(id); err != nil {
+					return err
+				}
+			}
+			data = data[walSealedHeader:]
+		}
+	}
+
+	for record := uint64(0); len(data) >= walHeaderSize; record++ {
+		checksum := binary.LittleEndian.Uint32(data[0:4])
+		n := int(binary.LittleEndian.Uint32(data[4:8]))
+		if len(data)-walHeaderSize < n {
+			return nil
+		}
+		payload := data[walHeaderSize : walHeaderSize+n]
+		if crc32.ChecksumIEEE(payload) != checksum {
+			return nil
+		}
+		if opener != nil {
+			if payload, err = opener.open(payload, record); err != nil {
+				return err
+			}
+		}
+		var at uint64
+		if timed {
+			var size int
+			if at, size = binary.Uvarint(payload); size <= 0 {
+				return errCorrupt
+			}
+			payload = payload[size:]
+		}
+		if err := fn(int64(at), payload); err != nil {
+			return err
+		}
+		data = data[walHeaderSize+n:]
+	}
+	return nil
+}
+
+// encodeBatch lays a batch out as the entry count followed by the entries.
+func encodeBatch(entries []entry) []byte {
+	buf := binary.AppendUvarint(nil, uint64(len(entries)))
+	for _, e := range entries {
+		buf = appendEntry(buf, e)
+	}
+	return buf
+}
+
+func decodeBatch(buf []byte) ([]entry, error) {
+	count, n := binary.Uvarint(buf)
+	if n <= 0 {
+		return nil, errCorrupt
+	}
+	entries, err := decodeEntries(buf[n:])
+	if err != nil {
+		return nil, err
+	}
+	if uint64(len(entries)) != count {
+		return nil, errCorrupt
+	}
+	return entries, nil
+}
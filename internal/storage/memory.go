@@ -0,0 +1,198 @@
+package storage
+
+import (
+	"sort"
+	"strings"
+	"sync"
+)
+
+// MemoryEngine keeps items in maps. Reads do not lock, writes and snapshots
+// are serialized, so readers may observe a batch half applied while
+// snapshots never do.
+type MemoryEngine struct {
+	// namespaces maps names to *memoryNamespace.
+	namespaces sync.Map
+	mu         sync.Mutex
+}
+
+type memoryNamespace struct {
+	// items maps keys to Item.
+	items sync.Map
+	// keys is changed with the engine mu held.
+	keys int
+}
+
+func NewMemoryEngine() *MemoryEngine {
+	return &MemoryEngine{}
+}
+
+func (e *MemoryEngine) items(ns string) *sync.Map {
+	n, ok := e.namespaces.Load(ns)
+	if !ok {
+		return nil
+	}
+	return &n.(*memoryNamespace).items
+}
+
+func (e *MemoryEngine) Get(ns, key string) (Item, bool, error) {
+	items := e.items(ns)
+	if items == nil {
+		return Item{}, false, nil
+	}
+	item, ok := items.Load(key)
+	if !ok {
+		return Item{}, false, nil
+	}
+	return item.(Item), true, nil
+}
+
+func (e *MemoryEngine) Put(ns, key string, item Item) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.put(ns, key, item)
+	return nil
+}
+
+func (e *MemoryEngine) put(ns, key string, item Item) {
+	n, _ := e.namespaces.LoadOrStore(ns, &memoryNamespace{})
+	namespace := n.(*memoryNamespace)
+	if _, loaded := namespace.items.Swap(key, item); !loaded {
+		namespace.keys++
+	}
+}
+
+func (e *MemoryEngine) Delete(ns, key string) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.delete(ns, key)
+	return nil
+}
+
+// delete drops the namespace with its last key, as the disk engine does.
+func (e *MemoryEngine) delete(ns, key string) {
+	n, ok := e.namespaces.Load(ns)
+	if !ok {
+		return
+	}
+	namespace := n.(*memoryNamespace)
+	if _, loaded := namespace.items.LoadAndDelete(key); loaded {
+		namespace.keys--
+	}
+	if namespace.keys == 0 {
+		e.namespaces.Delete(ns)
+	}
+}
+
+func (e *MemoryEngine) Iterate(ns, prefix string, fn func(key string, item Item) bool) error {
+	items := e.items(ns)
+	if items == nil {
+		return nil
+	}
+	return iterateMap(items, prefix, fn)
+}
+
+// iterateMap sorts the matching keys first, maps have no order.
+func iterateMap(items *sync.Map, prefix string, fn func(key string, item Item) bool) error {
+	var keys []string
+	values := make(map[string]Item)
+	items.Range(func(k, v any) bool {
+		key := k.(string)
+		if strings.HasPrefix(key, prefix) {
+			keys = append(keys, key)
+			values[key] = v.(Item)
+		}
+		return true
+	})
+
+	sort.Strings(keys)
+	for _, key := range keys {
+		if !fn(key, values[key]) {
+			break
+		}
+	}
+	return nil
+}
+
+// Sample relies on the random order of map ranges.
+func (e *MemoryEngine) Sample(ns string, n int, fn func(key string, item Item) bool) error {
+	items := e.items(ns)
+	if items == nil {
+		return nil
+	}
+
+	sampled := 0
+	items.Range(func(k, v any) bool {
+		if sampled == n {
+			return false
+		}
+		sampled++
+		return fn(k.(string), v.(Item))
+	})
+	return nil
+}
+
+func (e *MemoryEngine) Batch(ops []Op) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	for _, op := range ops {
+		if op.Delete {
+			e.delete(op.Namespace, op.Key)
+		} else {
+			e.put(op.Namespace, op.Key, op.Item)
+		}
+	}
+	return nil
+}
+
+// Snapshot copies the data, it takes time proportional to the number of keys.
+func (e *MemoryEngine) Snapshot() (Snapshot, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	snapshot := &memorySnapshot{}
+	e.namespaces.Range(func(name, n any) bool {
+		namespace := n.(*memoryNamespace)
+		copied := &memoryNamespace{keys: namespace.keys}
+		namespace.items.Range(func(k, v any) bool {
+			copied.items.Store(k, v)
+			return true
+		})
+		snapshot.engine.namespaces.Store(name, copied)
+		return true
+	})
+	return snapshot, nil
+}
+
+func (e *MemoryEngine) Namespaces() ([]string, error) {
+	var names []string
+	e.namespaces.Range(func(name, _ any) bool {
+		names = append(names, name.(string))
+		return true
+	})
+	sort.Strings(names)
+	return names, nil
+}
+
+// Close is a no-op, the memory engine keeps nothing to flush.
+func (e *MemoryEngine) Close() error {
+	return nil
+}
+
+type memorySnapshot struct {
+	engine MemoryEngine
+}
+
+func (s *memorySnapshot) Get(ns, key string) (Item, bool, error) {
+	return s.engine.Get(ns, key)
+}
+
+func (s *memorySnapshot) Iterate(ns, prefix string, fn func(key string, item Item) bool) error {
+	return s.engine.Iterate(ns, prefix, fn)
+}
+
+func (s *memorySnapshot) Namespaces() ([]string, error) {
+	return s.engine.Namespaces()
+}
+
+func (s *memorySnapshot) Release() {}
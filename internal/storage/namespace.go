@@ -0,0 +1,351 @@
+package storage
+
+import (
+	"encoding/json"
+	"errors"
+	"sort"
+	"strings"
+	"sync/atomic"
+)
+
+// DefaultNamespace holds the keys of requests that do not name a namespace.
+// It has no quota and cannot be dropped.
+const DefaultNamespace = ""
+
+const (
+	// reservedPrefix starts the names of namespaces used by the store itself.
+	reservedPrefix = "\x00"
+	// metaNamespace keeps the data version, the leader epoch, the compacted
+	// revision and the namespace quotas in the engine next to the data.
+	metaNamespace   = reservedPrefix + "meta"
+	metaVersionKey  = "version"
+	metaEpochKey    = "epoch"
+	metaQuotaPrefix = "namespace/"
+)
+
+var (
+	ErrNamespaceNotFound = errors.New("namespace not found")
+	ErrNamespaceExists   = errors.New("namespace already exists")
+	ErrQuotaExceeded     = errors.New("namespace quota exceeded")
+	ErrDefaultNamespace  = errors.New("default namespace cannot be dropped")
+	ErrInvalidNamespace  = errors.New("namespace name is reserved")
+)
+
+// Quota limits a namespace, zero values are unlimited. RequestsPerSecond is
+// enforced by the service layer, the storage only keeps it with the namespace.
+// Compression names the codec of the values of the namespace, empty uses the
+// default codec of the store.
+type Quota struct {
+	MaxKeys           int64   `json:"max_keys,omitempty"`
+	MaxBytes          int64   `json:"max_bytes,omitempty"`
+	RequestsPerSecond float64 `json:"requests_per_second,omitempty"`
+	Compression       string  `json:"compression,omitempty"`
+}
+
+// check fails if a growing key count or size goes over the limits. Writes
+// that shrink the namespace are always allowed.
+func (q Quota) check(keys, bytes int64, addsKey, grows bool) error {
+	if addsKey && q.MaxKeys > 0 && keys > q.MaxKeys {
+		return ErrQuotaExceeded
+	}
+	if grows && q.MaxBytes > 0 && bytes > q.MaxBytes {
+		return ErrQuotaExceeded
+	}
+	return nil
+}
+
+// codec returns the codec of the namespace and whether it overrides the
+// default of the store.
+func (q Quota) codec() (Codec, bool, error) {
+	if q.Compression == "" {
+		return CodecNone, false, nil
+	}
+	codec, err := ParseCodec(q.Compression)
+	return codec, err == nil, err
+}
+
+func decodeQuota(value string) (Quota, error) {
+	var quota Quota
+	if err := json.Unmarshal([]byte(value), &quota); err != nil {
+		return Quota{}, errCorruptItem
+	}
+	if _, _, err := quota.codec(); err != nil {
+		return Quota{}, errCorruptItem
+	}
+	return quota, nil
+}
+
+// quotaOp persists the quota of the namespace in the metadata namespace.
+func quotaOp(name string, quota Quota) Op {
+	value, _ := json.Marshal(quota)
+	return Op{Namespace: metaNamespace, Key: metaQuotaPrefix + name, Item: Item{Value: string(value)}}
+}
+
+// NamespaceInfo describes a namespace and its current usage.
+type NamespaceInfo struct {
+	Name        string
+	Quota       Quota
+	Keys        int64
+	Bytes       int64
+	Compression CompressionStats
+}
+
+type namespace struct {
+	name string
+	// quota is changed with the storage mu held.
+	quota Quota
+	// compression holds the codec of the quota plus one, zero when the
+	// namespace uses the default codec. Writes read it without mu.
+	compression atomic.Int32
+
+	keys  atomic.Int64
+	bytes atomic.Int64
+
+	compressedRaw    atomic.Int64
+	compressedStored atomic.Int64
+}
+
+func newNamespace(name string, quota Quota) *namespace {
+	n := &namespace{name: name}
+	n.setQuota(quota)
+	return n
+}
+
+// setQuota must be called with the storage mu held, the quota is valid.
+func (n *namespace) setQuota(quota Quota) {
+	n.quota = quota
+	if codec, ok, _ := quota.codec(); ok {
+		n.compression.Store(int32(codec) + 1)
+	} else {
+		n.compression.Store(0)
+	}
+}
+
+// codec returns the codec of the namespace and whether it overrides the
+// default of the store.
+func (n *namespace) codec() (Codec, bool) {
+	c := n.compression.Load()
+	if c == 0 {
+		return CodecNone, false
+	}
+	return Codec(c - 1), true
+}
+
+func (n *namespace) add(keys, bytes int64) {
+	n.keys.Add(keys)
+	n.bytes.Add(bytes)
+}
+
+func (n *namespace) info() NamespaceInfo {
+	return NamespaceInfo{
+		Name:  n.name,
+		Quota: n.quota,
+		Keys:  n.keys.Load(),
+		Bytes: n.bytes.Load(),
+		Compression: CompressionStats{
+			RawBytes:    n.compressedRaw.Load(),
+			StoredBytes: n.compressedStored.Load(),
+		},
+	}
+}
+
+// lookup returns the namespace or nil if it does not exist.
+func (s *Store) lookup(name string) *namespace {
+	n, ok := s.namespaces.Load(name)
+	if !ok {
+		return nil
+	}
+	return n.(*namespace)
+}
+
+// namespace returns the namespace, creating it without a quota if needed,
+// it must be called with mu held. Replicas apply writes of namespaces they
+// have not heard of yet this way. The creation is not a mutation of its own
+// and leaves the data version as is.
+func (s *Store) namespace(name string) (*namespace, error) {
+	if n := s.lookup(name); n != nil {
+		return n, nil
+	}
+	if err := checkQuota(name, Quota{}); err != nil {
+		return nil, err
+	}
+	if err := s.engine.Batch([]Op{quotaOp(name, Quota{})}); err != nil {
+		return nil, err
+	}
+	n := newNamespace(name, Quota{})
+	s.namespaces.Store(name, n)
+	return n, nil
+}
+
+func checkQuota(name string, quota Quota) error {
+	if strings.HasPrefix(name, reservedPrefix) {
+		return ErrInvalidNamespace
+	}
+	_, _, err := quota.codec()
+	return err
+}
+
+// saveQuota persists the quota at the revision, the next one when zero, and
+// returns it. It must be called with mu held.
+func (s *Store) saveQuota(name string, quota Quota, revision int64) (int64, error) {
+	if err := checkQuota(name, quota); err != nil {
+		return 0, err
+	}
+	revision = s.next(revision)
+	version := max(s.version.Load(), revision)
+	if err := s.engine.Batch([]Op{quotaOp(name, quota), s.versionOp(version)}); err != nil {
+		return 0, err
+	}
+	s.version.Store(version)
+	return revision, nil
+}
+
+// CreateNamespace registers a new namespace with the quota and returns the
+// revision of the change.
+func (s *Store) CreateNamespace(name string, quota Quota) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.lookup(name) != nil {
+		return 0, ErrNamespaceExists
+	}
+	revision, err := s.saveQuota(name, quota, 0)
+	if err != nil {
+		return 0, err
+	}
+	s.namespaces.Store(name, newNamespace(name, quota))
+	return revision, nil
+}
+
+// SetQuota changes the quota of the namespace, creating it if needed.
+func (s *Store) SetQuota(name string, quota Quota) (int64, error) {
+	return s.ApplyQuota(name, quota, 0)
+}
+
+// ApplyQuota sets the quota of the namespace like SetQuota, at the revision
+// the leader gave the change.
+func (s *Store) ApplyQuota(name string, quota Quota, revision int64) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	revision, err := s.saveQuota(name, quota, revision)
+	if err != nil {
+		return 0, err
+	}
+	if n := s.lookup(name); n != nil {
+		n.setQuota(quota)
+	} else {
+		s.namespaces.Store(name, newNamespace(name, quota))
+	}
+	return revision, nil
+}
+
+// DropNamespace removes the namespace with all its keys, their history and
+// their large values, and returns the removed keys with the revision of the
+// removal. The default namespace cannot be dropped.
+func (s *Store) DropNamespace(name string) ([]string, int64, error) {
+	return s.ApplyDropNamespace(name, 0)
+}
+
+// ApplyDropNamespace drops the namespace like DropNamespace, at the revision
+// the leader gave the removal.
+func (s *Store) ApplyDropNamespace(name string, revision int64) ([]string, int64, error) {
+	if name == DefaultNamespace {
+		return nil, 0, ErrDefaultNamespace
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	ns := s.lookup(name)
+	if ns == nil {
+		return nil, 0, ErrNamespaceNotFound
+	}
+
+	var keys []string
+	err := s.engine.Iterate(name, "", func(key string, _ Item) bool {
+		keys = append(keys, key)
+		return true
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var history []string
+	err = s.engine.Iterate(historyNamespace(name), "", func(hk string, _ Item) bool {
+		history = append(history, hk)
+		return true
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var chunks []string
+	err = s.engine.Iterate(blobNamespace(name), "", func(ck string, _ Item) bool {
+		chunks = append(chunks, ck)
+		return true
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+
+	revision = s.next(revision)
+	version := max(s.version.Load(), revision)
+	ops := make([]Op, 0, len(keys)+len(history)+len(chunks)+2)
+	for _, key := range keys {
+		ops = append(ops, Op{Namespace: name, Key: key, Delete: true})
+	}
+	for _, hk := range history {
+		ops = append(ops, Op{Namespace: historyNamespace(name), Key: hk, Delete: true})
+	}
+	for _, ck := range chunks {
+		ops = append(ops, Op{Namespace: blobNamespace(name), Key: ck, Delete: true})
+	}
+	ops = append(ops,
+		Op{Namespace: metaNamespace, Key: metaQuotaPrefix + name, Delete: true},
+		s.versionOp(version),
+	)
+	if err := s.engine.Batch(ops); err != nil {
+		return nil, 0, err
+	}
+
+	s.namespaces.Delete(name)
+	s.keys.Add(-ns.keys.Load())
+	s.bytes.Add(-ns.bytes.Load())
+	s.version.Store(version)
+	return keys, revision, nil
+}
+
+// HasNamespace reports whether the namespace exists.
+func (s *Store) HasNamespace(name string) bool {
+	return s.lookup(name) != nil
+}
+
+// Namespace returns the namespace description.
+func (s *Store) Namespace(name string) (NamespaceInfo, bool) {
+	n := s.lookup(name)
+	if n == nil {
+		return NamespaceInfo{}, false
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return n.info(), true
+}
+
+// Namespaces lists every namespace sorted by name.
+func (s *Store) Namespaces() []NamespaceInfo {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var namespaces []NamespaceInfo
+	s.namespaces.Range(func(_, n any) bool {
+		namespaces = append(namespaces, n.(*namespace).info())
+		return true
+	})
+
+	sort.Slice(namespaces, func(i, j int) bool {
+		return namespaces[i].Name < namespaces[j].Name
+	})
+	return namespaces
+}
@@ -0,0 +1,52 @@
+package storage_test
+
+import (
+	"testing"
+
+	"github.com/Na322Pr/kv-storage-service/internal/config"
+	"github.com/Na322Pr/kv-storage-service/internal/storage"
+)
+
+func newStore(t *testing.T) *storage.Store {
+	t.Helper()
+	s, err := storage.NewStore(storage.NewMemoryEngine(), config.Storage{EvictionPolicy: "noeviction"})
+	if err != nil {
+		t.Fatalf("new store: %v", err)
+	}
+	t.Cleanup(func() { s.Close() })
+	return s
+}
+
+// A namespace created through SetQuota is saved once, so a replica applying
+// the change stays at the revisions of the leader.
+func TestSetQuotaCreatesNamespaceOnce(t *testing.T) {
+	leader, replica := newStore(t), newStore(t)
+	quota := storage.Quota{MaxKeys: 10}
+
+	revision, err := leader.SetQuota("ns", quota)
+	if err != nil {
+		t.Fatalf("set quota: %v", err)
+	}
+	if revision != 1 || leader.GetDataVersion() != 1 {
+		t.Fatalf("leader at revision %d, data version %d, want 1", revision, leader.GetDataVersion())
+	}
+	applied, err := replica.ApplyQuota("ns", quota, revision)
+	if err != nil {
+		t.Fatalf("apply quota: %v", err)
+	}
+	if applied != revision || replica.GetDataVersion() != revision {
+		t.Fatalf("replica at revision %d, data version %d, want %d", applied, replica.GetDataVersion(), revision)
+	}
+	info, ok := replica.Namespace("ns")
+	if !ok || info.Quota != quota {
+		t.Fatalf("replica namespace %+v %v, want quota %+v", info, ok, quota)
+	}
+
+	revision, err = leader.SetUntil("ns", "a", storage.Item{Value: "1"})
+	if err != nil {
+		t.Fatalf("set: %v", err)
+	}
+	if applied, err = replica.Apply("ns", "a", storage.Item{Value: "1", Revision: revision}); err != nil || applied != revision {
+		t.Fatalf("apply: revision %d, %v, want %d", applied, err, revision)
+	}
+}
@@ -1,54 +1,739 @@
 package storage
 
 import (
+	"errors"
+	"math"
+	"strconv"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
+
+	"github.com/Na322Pr/kv-storage-service/internal/config"
 )
 
-type KeyValueInMemoryStorage struct {
-	items   sync.Map
-	version int64
-}
+// itemOverhead approximates the per-key cost of the map entry, Item header
+// and access statistics.
+const itemOverhead = 120
 
-type Item struct {
-	Value      string
-	Expiration int64
+var (
+	ErrNotInteger       = errors.New("value is not an integer or out of range")
+	ErrOverflow         = errors.New("increment or decrement would overflow")
+	ErrOutOfBounds      = errors.New("result is out of bounds")
+	ErrKeyNotFound      = errors.New("key not found")
+	ErrRevisionMismatch = errors.New("revision mismatch")
+)
+
+// Store keeps items per namespace on top of an Engine. Keys of different
+// namespaces never collide, DefaultNamespace always exists. Store owns
+// expiration, revisions, quotas and eviction, the engine only keeps the data.
+// Every mutation of a key also records a version of it, so reads can go back
+// to a past revision until the version is compacted.
+type Store struct {
+	engine Engine
+
+	// namespaces maps names to *namespace.
+	namespaces sync.Map
+	// version is the revision of the last applied mutation. It is changed
+	// with mu held and read without it.
+	version atomic.Int64
+	// compacted is the oldest revision the history still holds.
+	compacted atomic.Int64
+	// epoch is the leader epoch last announced to this node.
+	epoch atomic.Int64
+
+	// mu serializes the writes to the engine.
+	mu sync.Mutex
+
+	keys  atomic.Int64
+	bytes atomic.Int64
+
+	// maxMemory bounds bytes on writes admitted by this node, zero is unlimited.
+	maxMemory   int64
+	policy      EvictionPolicy
+	evicted     atomic.Int64
+	evictionsMu sync.Mutex
+	evictions   []EvictedKey
+
+	// maxValueSize bounds large values written through this node, zero is
+	// unlimited.
+	maxValueSize int64
+
+	// compression is the codec of namespaces without their own, applied to
+	// values of at least compressMinSize bytes.
+	compression     Codec
+	compressMinSize int
 }
 
-func NewKeyValueInMemoryStorage() *KeyValueInMemoryStorage {
-	return &KeyValueInMemoryStorage{
-		items:   sync.Map{},
-		version: 0,
+// NewStore restores the data version, the namespaces and the usage counters
+// kept by the engine. The engine is closed with the store.
+func NewStore(engine Engine, cfg config.Storage) (*Store, error) {
+	policy, err := ParseEvictionPolicy(cfg.EvictionPolicy)
+	if err != nil {
+		return nil, err
+	}
+	compression := CodecNone
+	if cfg.Compression.Codec != "" {
+		if compression, err = ParseCodec(cfg.Compression.Codec); err != nil {
+			return nil, err
+		}
 	}
+
+	s := &Store{
+		engine:       engine,
+		maxMemory:    cfg.MaxMemory,
+		maxValueSize: cfg.MaxValueSize,
+		policy:       policy,
+
+		compression:     compression,
+		compressMinSize: cfg.Compression.MinSize,
+	}
+	s.namespaces.Store(DefaultNamespace, newNamespace(DefaultNamespace, Quota{}))
+	if err := s.load(); err != nil {
+		return nil, err
+	}
+	return s, nil
 }
 
-func (s *KeyValueInMemoryStorage) Set(key string, value string) {
-	s.items.Store(key, Item{Value: value})
-	s.version++
+// SetUntil stores the value of the item with its content type and absolute
+// expiration time in unix nanoseconds, and returns the revision of the
+// write. Zero expiration means the key never expires. An item referencing a
+// blob makes the large value written by the BlobWriter visible.
+func (s *Store) SetUntil(ns, key string, item Item) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.put(ns, key, item)
 }
 
-func (s *KeyValueInMemoryStorage) SetWithExpiration(key string, value string, expiration time.Duration) {
-	expirationTime := time.Now().Add(expiration).UnixNano()
-	s.items.Store(key, Item{
-		Value:      value,
-		Expiration: expirationTime,
-	})
-	s.version++
+// SetNX stores the item only if the key does not exist.
+func (s *Store) SetNX(ns, key string, item Item) (bool, int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, ok, err := s.get(ns, key)
+	if err != nil || ok {
+		return false, 0, err
+	}
+	revision, err := s.put(ns, key, item)
+	if err != nil {
+		return false, 0, err
+	}
+	return true, revision, nil
 }
 
-func (s *KeyValueInMemoryStorage) Get(key string) (Item, bool) {
-	val, ok := s.items.Load(key)
+// SetXX stores the item only if the key already exists.
+func (s *Store) SetXX(ns, key string, item Item) (bool, int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, ok, err := s.get(ns, key)
+	if err != nil || !ok {
+		return false, 0, err
+	}
+	revision, err := s.put(ns, key, item)
+	if err != nil {
+		return false, 0, err
+	}
+	return true, revision, nil
+}
+
+// CompareAndSwap stores the item only if the key exists and was last modified
+// at the given revision.
+func (s *Store) CompareAndSwap(ns, key string, item Item, revision int64) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	current, ok, err := s.get(ns, key)
+	if err != nil {
+		return 0, err
+	}
 	if !ok {
+		return 0, ErrKeyNotFound
+	}
+	if current.Revision != revision {
+		return 0, ErrRevisionMismatch
+	}
+	return s.put(ns, key, item)
+}
+
+// put must be called with mu held and returns the revision of the write. It
+// fails without changes when the namespace does not exist, the write would
+// take it over its quota or no memory can be freed for it.
+func (s *Store) put(ns, key string, item Item) (int64, error) {
+	n := s.lookup(ns)
+	if n == nil {
+		return 0, ErrNamespaceNotFound
+	}
+	prev, exists, err := s.engine.Get(ns, key)
+	if err != nil {
+		return 0, err
+	}
+	keys, bytes := delta(key, item, prev, exists)
+	if err := n.quota.check(n.keys.Load()+keys, n.bytes.Load()+bytes, keys > 0, bytes > 0); err != nil {
+		return 0, err
+	}
+	if err := s.reserve(n, key, bytes); err != nil {
+		return 0, err
+	}
+	item.access = prev.access
+	return s.store(n, key, item, prev, exists)
+}
+
+// store must be called with mu held. The item is written together with its
+// version and the new data version, so all survive a restart of a persistent
+// engine.
+func (s *Store) store(ns *namespace, key string, item, prev Item, exists bool) (int64, error) {
+	item.Revision = s.version.Load() + 1
+	if err := s.write(ns, key, item, prev, exists, item.Revision); err != nil {
+		return 0, err
+	}
+	return item.Revision, nil
+}
+
+// write must be called with mu held. It stores the item in place of prev
+// with its version at the item revision and moves the data version to
+// version.
+func (s *Store) write(ns *namespace, key string, item, prev Item, exists bool, version int64) error {
+	var ops []Op
+	if exists {
+		op, ok, err := s.replacedOp(ns, key, prev)
+		if err != nil {
+			return err
+		}
+		if ok {
+			ops = append(ops, op)
+		}
+	}
+	ops = append(ops, itemOps(ns, key, item, time.Now().UnixNano())...)
+	if err := s.engine.Batch(append(ops, s.versionOp(version))); err != nil {
+		return err
+	}
+	keys, bytes := delta(key, item, prev, exists)
+	s.version.Store(version)
+	ns.add(keys, bytes)
+	s.keys.Add(keys)
+	s.bytes.Add(bytes)
+	return nil
+}
+
+// itemOps returns the operations storing the item and its version at the
+// item revision. The version leaves the value to the item, replacedOp
+// copies it once the item is replaced.
+func itemOps(ns *namespace, key string, item Item, now int64) []Op {
+	if item.access == nil {
+		item.access = newAccess(now)
+	} else {
+		item.access.touch(now)
+	}
+
+	ops := []Op{
+		{Namespace: ns.name, Key: key, Item: item},
+		historyOp(ns.name, key, Version{
+			Revision:    item.Revision,
+			Expiration:  item.Expiration,
+			ContentType: item.ContentType,
+			Blob:        item.Blob,
+			Size:        item.Size,
+			Codec:       item.Codec,
+			Time:        now,
+			current:     true,
+		}),
+	}
+	if item.Large() {
+		ops = append(ops, Op{Namespace: metaNamespace, Key: uploadKey(item.Blob), Delete: true})
+	}
+	return ops
+}
+
+// Apply stores the item at its revision without checking the namespace
+// quota, creating the namespace if needed. Replicas use it for writes the
+// leader has already admitted, with the revision the leader gave them.
+func (s *Store) Apply(ns, key string, item Item) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	n, err := s.namespace(ns)
+	if err != nil {
+		return 0, err
+	}
+	prev, exists, err := s.engine.Get(ns, key)
+	if err != nil {
+		return 0, err
+	}
+	item.access = prev.access
+	item.Revision = s.next(item.Revision)
+	if err := s.write(n, key, item, prev, exists, max(s.version.Load(), item.Revision)); err != nil {
+		return 0, err
+	}
+	return item.Revision, nil
+}
+
+// ApplyDelete removes the key at the revision the leader gave the removal.
+func (s *Store) ApplyDelete(ns, key string, revision int64) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.remove(s.lookup(ns), key, revision)
+}
+
+// ApplyExpire changes the expiration of the key at the revision the leader
+// gave the change. A key the replica already expired is left missing, the
+// data version still moves to the revision.
+func (s *Store) ApplyExpire(ns, key string, expiration, revision int64) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	revision = s.next(revision)
+	version := max(s.version.Load(), revision)
+	n := s.lookup(ns)
+	var (
+		item   Item
+		exists bool
+		err    error
+	)
+	if n != nil {
+		if item, exists, err = s.engine.Get(ns, key); err != nil {
+			return 0, err
+		}
+	}
+	if !exists {
+		if err := s.engine.Batch([]Op{s.versionOp(version)}); err != nil {
+			return 0, err
+		}
+		s.version.Store(version)
+		return revision, nil
+	}
+
+	prev := item
+	item.Expiration = expiration
+	item.Revision = revision
+	if err := s.write(n, key, item, prev, true, version); err != nil {
+		return 0, err
+	}
+	return revision, nil
+}
+
+// next returns the revision of a replicated mutation: the one the leader
+// gave it, or the one after the data version when it carries none.
+func (s *Store) next(revision int64) int64 {
+	if revision > 0 {
+		return revision
+	}
+	return s.version.Load() + 1
+}
+
+// remove must be called with mu held and returns the revision of the
+// removal, the next one unless the leader gave it one. Removing a missing
+// key still moves the data version, like any other applied mutation.
+func (s *Store) remove(ns *namespace, key string, revision int64) (int64, error) {
+	revision = s.next(revision)
+	version := max(s.version.Load(), revision)
+	if ns == nil {
+		if err := s.engine.Batch([]Op{s.versionOp(version)}); err != nil {
+			return 0, err
+		}
+		s.version.Store(version)
+		return revision, nil
+	}
+
+	prev, exists, err := s.engine.Get(ns.name, key)
+	if err != nil {
+		return 0, err
+	}
+	ops := []Op{
+		{Namespace: ns.name, Key: key, Delete: true},
+		s.versionOp(version),
+	}
+	if exists {
+		op, ok, err := s.replacedOp(ns, key, prev)
+		if err != nil {
+			return 0, err
+		}
+		if ok {
+			ops = append(ops, op)
+		}
+		ops = append(ops, historyOp(ns.name, key, Version{
+			Revision: revision,
+			Deleted:  true,
+			Time:     time.Now().UnixNano(),
+		}))
+	}
+	if err := s.engine.Batch(ops); err != nil {
+		return 0, err
+	}
+	s.version.Store(version)
+	if exists {
+		s.forget(ns, key, prev)
+	}
+	return revision, nil
+}
+
+func (s *Store) forget(ns *namespace, key string, item Item) {
+	size := itemSize(key, item)
+	ns.add(-1, -size)
+	s.keys.Add(-1)
+	s.bytes.Add(-size)
+}
+
+func itemSize(key string, item Item) int64 {
+	return int64(len(key)+len(item.ContentType)+itemOverhead) + item.Len()
+}
+
+// delta returns how replacing prev with the item changes the key count and size.
+func delta(key string, item, prev Item, exists bool) (int64, int64) {
+	size := itemSize(key, item)
+	if exists {
+		return 0, size - itemSize(key, prev)
+	}
+	return 1, size
+}
+
+// get must be called with mu held. Expired items are reported missing and
+// left to be overwritten or removed by the caller.
+func (s *Store) get(ns, key string) (Item, bool, error) {
+	if s.lookup(ns) == nil {
+		return Item{}, false, nil
+	}
+	item, ok, err := s.engine.Get(ns, key)
+	if err != nil || !ok || item.Expired(time.Now().UnixNano()) {
+		return Item{}, false, err
+	}
+	return item, true, nil
+}
+
+// Get returns a live item with its value decompressed. Expired items are
+// removed on the way unless a write holds the store, they are then removed
+// by a later read or overwritten. Engine read and decoding errors are
+// reported as missing keys.
+func (s *Store) Get(ns, key string) (Item, bool) {
+	n := s.lookup(ns)
+	if n == nil {
+		return Item{}, false
+	}
+
+	item, ok, err := s.engine.Get(ns, key)
+	if err != nil || !ok {
+		return Item{}, false
+	}
+
+	now := time.Now().UnixNano()
+	if item.Expired(now) {
+		if s.mu.TryLock() {
+			s.expire(n, key)
+			s.mu.Unlock()
+		}
+		return Item{}, false
+	}
+	item.access.touch(now)
+	if item, err = decompress(item); err != nil {
 		return Item{}, false
 	}
-	return val.(Item), true
+	return item, true
+}
+
+// expire removes the key if it is still expired, it must be called with mu
+// held. Expiration is not a mutation and leaves the data version as is, the
+// version of the item keeps a copy of its value.
+func (s *Store) expire(ns *namespace, key string) {
+	item, ok, err := s.engine.Get(ns.name, key)
+	if err != nil || !ok || !item.Expired(time.Now().UnixNano()) {
+		return
+	}
+	ops := []Op{{Namespace: ns.name, Key: key, Delete: true}}
+	op, ok, err := s.replacedOp(ns, key, item)
+	if err != nil {
+		return
+	}
+	if ok {
+		ops = append(ops, op)
+	}
+	if s.engine.Batch(ops) == nil {
+		s.forget(ns, key, item)
+	}
+}
+
+// GetDataVersion returns the revision of the last applied mutation.
+func (s *Store) GetDataVersion() int64 {
+	return s.version.Load()
+}
+
+// LeaderEpoch returns the leader epoch last saved.
+func (s *Store) LeaderEpoch() int64 {
+	return s.epoch.Load()
+}
+
+// SaveLeaderEpoch persists a newer leader epoch and reports whether the
+// epoch is not older than the saved one. Epochs are not data mutations and
+// leave the data version as is.
+func (s *Store) SaveLeaderEpoch(epoch int64) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	current := s.epoch.Load()
+	if epoch < current {
+		return false, nil
+	}
+	if epoch == current {
+		return true, nil
+	}
+	err := s.engine.Put(metaNamespace, metaEpochKey, Item{Value: strconv.FormatInt(epoch, 10)})
+	if err != nil {
+		return false, err
+	}
+	s.epoch.Store(epoch)
+	return true, nil
 }
 
-func (s *KeyValueInMemoryStorage) GetDataVersion() int64 {
-	return s.version
+// Snapshot returns a consistent view of the engine. Expired items are
+// included, the caller filters them.
+func (s *Store) Snapshot() (Snapshot, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.engine.Snapshot()
 }
 
-func (s *KeyValueInMemoryStorage) Delete(key string) {
-	s.items.Delete(key)
-	s.version++
+// Close releases the engine.
+func (s *Store) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.engine.Close()
+}
+
+// Len returns the number of stored keys, including expired ones not yet removed.
+func (s *Store) Len() int64 {
+	return s.keys.Load()
+}
+
+// MemoryUsage returns the approximate number of bytes held by keys and values.
+func (s *Store) MemoryUsage() int64 {
+	return s.bytes.Load()
+}
+
+// Delete removes the key and reports whether it held a live item.
+func (s *Store) Delete(ns, key string) (bool, int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, ok, err := s.get(ns, key)
+	if err != nil {
+		return false, 0, err
+	}
+	revision, err := s.remove(s.lookup(ns), key, 0)
+	if err != nil {
+		return false, 0, err
+	}
+	return ok, revision, nil
+}
+
+// CompareAndDelete removes the key only if it was last modified at the given revision.
+func (s *Store) CompareAndDelete(ns, key string, revision int64) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	item, ok, err := s.get(ns, key)
+	if err != nil {
+		return 0, err
+	}
+	if !ok {
+		return 0, ErrKeyNotFound
+	}
+	if item.Revision != revision {
+		return 0, ErrRevisionMismatch
+	}
+	return s.remove(s.lookup(ns), key, 0)
+}
+
+// Expire changes the expiration of an existing key. Zero expiration makes it persistent.
+func (s *Store) Expire(ns, key string, expiration int64) (bool, int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	item, ok, err := s.get(ns, key)
+	if err != nil || !ok {
+		return false, 0, err
+	}
+	item.Expiration = expiration
+	// The size does not change, the quota cannot be exceeded.
+	revision, err := s.put(ns, key, item)
+	if err != nil {
+		return false, 0, err
+	}
+	return true, revision, nil
+}
+
+// IncrementOptions restricts the result of Increment. Nil bounds are unbounded,
+// Expiration is applied only when the key is created.
+type IncrementOptions struct {
+	Min        *int64
+	Max        *int64
+	Expiration int64
+}
+
+// Increment parses the stored value as a signed 64-bit integer, adds delta and
+// stores the result keeping the current expiration. Missing keys start at zero.
+func (s *Store) Increment(ns, key string, delta int64, opts IncrementOptions) (Item, int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var current int64
+	item, ok, err := s.get(ns, key)
+	if err != nil {
+		return Item{}, 0, err
+	}
+	if ok {
+		if item, err = decompress(item); err != nil {
+			return Item{}, 0, err
+		}
+		n, err := strconv.ParseInt(item.Value, 10, 64)
+		if err != nil {
+			return Item{}, 0, ErrNotInteger
+		}
+		current = n
+	} else {
+		item.Expiration = opts.Expiration
+	}
+
+	if (delta > 0 && current > math.MaxInt64-delta) || (delta < 0 && current < math.MinInt64-delta) {
+		return Item{}, 0, ErrOverflow
+	}
+
+	current += delta
+	if (opts.Min != nil && current < *opts.Min) || (opts.Max != nil && current > *opts.Max) {
+		return Item{}, 0, ErrOutOfBounds
+	}
+
+	item.Value = strconv.FormatInt(current, 10)
+	if item.Revision, err = s.put(ns, key, item); err != nil {
+		return Item{}, 0, err
+	}
+	return item, current, nil
+}
+
+// Keys returns the sorted list of live keys of the namespace starting with prefix.
+func (s *Store) Keys(ns, prefix string) []string {
+	var keys []string
+	_ = s.Range(ns, prefix, "", func(key string, _ Item) bool {
+		keys = append(keys, key)
+		return true
+	})
+	return keys
+}
+
+// Range calls fn for the live items of the namespace starting with prefix
+// that sort after startAfter, in key order, until fn returns false. Values
+// are passed decompressed.
+func (s *Store) Range(ns, prefix, startAfter string, fn func(key string, item Item) bool) error {
+	if s.lookup(ns) == nil {
+		return nil
+	}
+
+	now := time.Now().UnixNano()
+	var decodeErr error
+	err := s.engine.Iterate(ns, prefix, func(key string, item Item) bool {
+		if (startAfter != "" && key <= startAfter) || item.Expired(now) {
+			return true
+		}
+		item, err := decompress(item)
+		if err != nil {
+			decodeErr = err
+			return false
+		}
+		return fn(key, item)
+	})
+	if err != nil {
+		return err
+	}
+	return decodeErr
+}
+
+// versionOp persists the data version in the metadata namespace.
+func (s *Store) versionOp(version int64) Op {
+	return Op{
+		Namespace: metaNamespace,
+		Key:       metaVersionKey,
+		Item:      Item{Value: strconv.FormatInt(version, 10)},
+	}
+}
+
+// load must be called before the store is shared.
+func (s *Store) load() error {
+	item, ok, err := s.engine.Get(metaNamespace, metaVersionKey)
+	if err != nil {
+		return err
+	}
+	if ok {
+		version, err := strconv.ParseInt(item.Value, 10, 64)
+		if err != nil {
+			return errCorruptItem
+		}
+		s.version.Store(version)
+	}
+
+	item, ok, err = s.engine.Get(metaNamespace, metaEpochKey)
+	if err != nil {
+		return err
+	}
+	if ok {
+		epoch, err := strconv.ParseInt(item.Value, 10, 64)
+		if err != nil {
+			return errCorruptItem
+		}
+		s.epoch.Store(epoch)
+	}
+
+	item, ok, err = s.engine.Get(metaNamespace, metaCompactedKey)
+	if err != nil {
+		return err
+	}
+	if ok {
+		compacted, err := strconv.ParseInt(item.Value, 10, 64)
+		if err != nil {
+			return errCorruptItem
+		}
+		s.compacted.Store(compacted)
+	}
+
+	if err := s.dropUploads(); err != nil {
+		return err
+	}
+
+	var quotaErr error
+	err = s.engine.Iterate(metaNamespace, metaQuotaPrefix, func(key string, item Item) bool {
+		name := strings.TrimPrefix(key, metaQuotaPrefix)
+		quota, err := decodeQuota(item.Value)
+		if err != nil {
+			quotaErr = err
+			return false
+		}
+		s.namespaces.Store(name, newNamespace(name, quota))
+		return true
+	})
+	if err != nil {
+		return err
+	}
+	if quotaErr != nil {
+		return quotaErr
+	}
+
+	names, err := s.engine.Namespaces()
+	if err != nil {
+		return err
+	}
+	for _, name := range names {
+		if strings.HasPrefix(name, reservedPrefix) {
+			continue
+		}
+		n, _ := s.namespaces.LoadOrStore(name, newNamespace(name, Quota{}))
+		ns := n.(*namespace)
+		err := s.engine.Iterate(name, "", func(key string, item Item) bool {
+			size := itemSize(key, item)
+			ns.add(1, size)
+			s.keys.Add(1)
+			s.bytes.Add(size)
+			return true
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
 }
@@ -23,8 +23,11 @@ const (
 )
 
 type GetRequest struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	Key           string                 `protobuf:"bytes,1,opt,name=key,proto3" json:"key,omitempty"`
+	state     protoimpl.MessageState `protogen:"open.v1"`
+	Key       string                 `protobuf:"bytes,1,opt,name=key,proto3" json:"key,omitempty"`
+	Namespace string                 `protobuf:"bytes,2,opt,name=namespace,proto3" json:"namespace,omitempty"`
+	// Чтение значения на момент ревизии, по умолчанию текущее значение
+	Revision      *int64 `protobuf:"varint,3,opt,name=revision,proto3,oneof" json:"revision,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -66,6 +69,20 @@ func (x *GetRequest) GetKey() string {
 	return ""
 }
 
+func (x *GetRequest) GetNamespace() string {
+	if x != nil {
+		return x.Namespace
+	}
+	return ""
+}
+
+func (x *GetRequest) GetRevision() int64 {
+	if x != nil && x.Revision != nil {
+		return *x.Revision
+	}
+	return 0
+}
+
 type GetResponse struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	Value         string                 `protobuf:"bytes,1,opt,name=value,proto3" json:"value,omitempty"`
@@ -119,10 +136,32 @@ func (x *GetResponse) GetFound() bool {
 }
 
 type SetRequest struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	Key           string                 `protobuf:"bytes,1,opt,name=key,proto3" json:"key,omitempty"`
-	Value         string                 `protobuf:"bytes,2,opt,name=value,proto3" json:"value,omitempty"`
-	Operation     *string                `protobuf:"bytes,3,opt,name=operation,proto3,oneof" json:"operation,omitempty"`
+	state     protoimpl.MessageState `protogen:"open.v1"`
+	Key       string                 `protobuf:"bytes,1,opt,name=key,proto3" json:"key,omitempty"`
+	Value     string                 `protobuf:"bytes,2,opt,name=value,proto3" json:"value,omitempty"`
+	Operation *string                `protobuf:"bytes,3,opt,name=operation,proto3,oneof" json:"operation,omitempty"`
+	// Время истечения ключа в unix-наносекундах
+	Expiration *int64 `protobuf:"varint,4,opt,name=expiration,proto3,oneof" json:"expiration,omitempty"`
+	// Аренда, при истечении которой ключ будет удалён
+	Lease *int64 `protobuf:"varint,5,opt,name=lease,proto3,oneof" json:"lease,omitempty"`
+	// W3C trace context, передаваемый от лидера репликам
+	TraceContext map[string]string `protobuf:"bytes,6,rep,name=trace_context,json=traceContext,proto3" json:"trace_context,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	Namespace    string            `protobuf:"bytes,7,opt,name=namespace,proto3" json:"namespace,omitempty"`
+	// Значение из произвольных байтов, передаётся репликам вместо value,
+	// если оно не является строкой UTF-8
+	RawValue []byte `protobuf:"bytes,8,opt,name=raw_value,json=rawValue,proto3,oneof" json:"raw_value,omitempty"`
+	// MIME-тип значения, задаётся через API v2
+	ContentType string `protobuf:"bytes,9,opt,name=content_type,json=contentType,proto3" json:"content_type,omitempty"`
+	// Большое значение, хранимое частями: идентификатор и размер в байтах
+	Blob int64 `protobuf:"varint,10,opt,name=blob,proto3" json:"blob,omitempty"`
+	Size int64 `protobuf:"varint,11,opt,name=size,proto3" json:"size,omitempty"`
+	// Кодек, которым лидер сжал value: 0 — без сжатия, 1 — snappy, 2 — zstd.
+	// Реплики сохраняют сжатое значение как есть
+	Codec uint32 `protobuf:"varint,12,opt,name=codec,proto3" json:"codec,omitempty"`
+	// Ревизия записи, восстановленной из резервной копии
+	Revision int64 `protobuf:"varint,13,opt,name=revision,proto3" json:"revision,omitempty"`
+	// Ключи пачки, записанной одной операцией batch в namespace
+	Entries       []*SetBatchEntry `protobuf:"bytes,14,rep,name=entries,proto3" json:"entries,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -178,26 +217,109 @@ func (x *SetRequest) GetOperation() string {
 	return ""
 }
 
-type SetResponse struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
+func (x *SetRequest) GetExpiration() int64 {
+	if x != nil && x.Expiration != nil {
+		return *x.Expiration
+	}
+	return 0
+}
+
+func (x *SetRequest) GetLease() int64 {
+	if x != nil && x.Lease != nil {
+		return *x.Lease
+	}
+	return 0
+}
+
+func (x *SetRequest) GetTraceContext() map[string]string {
+	if x != nil {
+		return x.TraceContext
+	}
+	return nil
+}
+
+func (x *SetRequest) GetNamespace() string {
+	if x != nil {
+		return x.Namespace
+	}
+	return ""
+}
+
+func (x *SetRequest) GetRawValue() []byte {
+	if x != nil {
+		return x.RawValue
+	}
+	return nil
+}
+
+func (x *SetRequest) GetContentType() string {
+	if x != nil {
+		return x.ContentType
+	}
+	return ""
+}
+
+func (x *SetRequest) GetBlob() int64 {
+	if x != nil {
+		return x.Blob
+	}
+	return 0
+}
+
+func (x *SetRequest) GetSize() int64 {
+	if x != nil {
+		return x.Size
+	}
+	return 0
+}
+
+func (x *SetRequest) GetCodec() uint32 {
+	if x != nil {
+		return x.Codec
+	}
+	return 0
+}
+
+func (x *SetRequest) GetRevision() int64 {
+	if x != nil {
+		return x.Revision
+	}
+	return 0
+}
+
+func (x *SetRequest) GetEntries() []*SetBatchEntry {
+	if x != nil {
+		return x.Entries
+	}
+	return nil
+}
+
+type SetBatchEntry struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Key   string                 `protobuf:"bytes,1,opt,name=key,proto3" json:"key,omitempty"`
+	// Значение, сжатое кодеком codec
+	Value         []byte `protobuf:"bytes,2,opt,name=value,proto3" json:"value,omitempty"`
+	ContentType   string `protobuf:"bytes,3,opt,name=content_type,json=contentType,proto3" json:"content_type,omitempty"`
+	Expiration    int64  `protobuf:"varint,4,opt,name=expiration,proto3" json:"expiration,omitempty"`
+	Codec         uint32 `protobuf:"varint,5,opt,name=codec,proto3" json:"codec,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *SetResponse) Reset() {
-	*x = SetResponse{}
+func (x *SetBatchEntry) Reset() {
+	*x = SetBatchEntry{}
 	mi := &file_api_kv_storage_proto_msgTypes[3]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *SetResponse) String() string {
+func (x *SetBatchEntry) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*SetResponse) ProtoMessage() {}
+func (*SetBatchEntry) ProtoMessage() {}
 
-func (x *SetResponse) ProtoReflect() protoreflect.Message {
+func (x *SetBatchEntry) ProtoReflect() protoreflect.Message {
 	mi := &file_api_kv_storage_proto_msgTypes[3]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
@@ -209,32 +331,68 @@ func (x *SetResponse) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use SetResponse.ProtoReflect.Descriptor instead.
-func (*SetResponse) Descriptor() ([]byte, []int) {
+// Deprecated: Use SetBatchEntry.ProtoReflect.Descriptor instead.
+func (*SetBatchEntry) Descriptor() ([]byte, []int) {
 	return file_api_kv_storage_proto_rawDescGZIP(), []int{3}
 }
 
-type GossipRequest struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	Node          string                 `protobuf:"bytes,1,opt,name=node,proto3" json:"node,omitempty"`
+func (x *SetBatchEntry) GetKey() string {
+	if x != nil {
+		return x.Key
+	}
+	return ""
+}
+
+func (x *SetBatchEntry) GetValue() []byte {
+	if x != nil {
+		return x.Value
+	}
+	return nil
+}
+
+func (x *SetBatchEntry) GetContentType() string {
+	if x != nil {
+		return x.ContentType
+	}
+	return ""
+}
+
+func (x *SetBatchEntry) GetExpiration() int64 {
+	if x != nil {
+		return x.Expiration
+	}
+	return 0
+}
+
+func (x *SetBatchEntry) GetCodec() uint32 {
+	if x != nil {
+		return x.Codec
+	}
+	return 0
+}
+
+type SetResponse struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Ревизия, в которой применена запись
+	Revision      int64 `protobuf:"varint,1,opt,name=revision,proto3" json:"revision,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *GossipRequest) Reset() {
-	*x = GossipRequest{}
+func (x *SetResponse) Reset() {
+	*x = SetResponse{}
 	mi := &file_api_kv_storage_proto_msgTypes[4]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *GossipRequest) String() string {
+func (x *SetResponse) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*GossipRequest) ProtoMessage() {}
+func (*SetResponse) ProtoMessage() {}
 
-func (x *GossipRequest) ProtoReflect() protoreflect.Message {
+func (x *SetResponse) ProtoReflect() protoreflect.Message {
 	mi := &file_api_kv_storage_proto_msgTypes[4]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
@@ -246,39 +404,40 @@ func (x *GossipRequest) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use GossipRequest.ProtoReflect.Descriptor instead.
-func (*GossipRequest) Descriptor() ([]byte, []int) {
+// Deprecated: Use SetResponse.ProtoReflect.Descriptor instead.
+func (*SetResponse) Descriptor() ([]byte, []int) {
 	return file_api_kv_storage_proto_rawDescGZIP(), []int{4}
 }
 
-func (x *GossipRequest) GetNode() string {
+func (x *SetResponse) GetRevision() int64 {
 	if x != nil {
-		return x.Node
+		return x.Revision
 	}
-	return ""
+	return 0
 }
 
-type GossipResponse struct {
+type DeleteRequest struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	IsLeader      bool                   `protobuf:"varint,1,opt,name=is_leader,json=isLeader,proto3" json:"is_leader,omitempty"`
+	Key           string                 `protobuf:"bytes,1,opt,name=key,proto3" json:"key,omitempty"`
+	Namespace     string                 `protobuf:"bytes,2,opt,name=namespace,proto3" json:"namespace,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *GossipResponse) Reset() {
-	*x = GossipResponse{}
+func (x *DeleteRequest) Reset() {
+	*x = DeleteRequest{}
 	mi := &file_api_kv_storage_proto_msgTypes[5]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *GossipResponse) String() string {
+func (x *DeleteRequest) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*GossipResponse) ProtoMessage() {}
+func (*DeleteRequest) ProtoMessage() {}
 
-func (x *GossipResponse) ProtoReflect() protoreflect.Message {
+func (x *DeleteRequest) ProtoReflect() protoreflect.Message {
 	mi := &file_api_kv_storage_proto_msgTypes[5]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
@@ -290,40 +449,47 @@ func (x *GossipResponse) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use GossipResponse.ProtoReflect.Descriptor instead.
-func (*GossipResponse) Descriptor() ([]byte, []int) {
+// Deprecated: Use DeleteRequest.ProtoReflect.Descriptor instead.
+func (*DeleteRequest) Descriptor() ([]byte, []int) {
 	return file_api_kv_storage_proto_rawDescGZIP(), []int{5}
 }
 
-func (x *GossipResponse) GetIsLeader() bool {
+func (x *DeleteRequest) GetKey() string {
 	if x != nil {
-		return x.IsLeader
+		return x.Key
 	}
-	return false
+	return ""
 }
 
-type LeaderVoteRequest struct {
-	state            protoimpl.MessageState `protogen:"open.v1"`
-	CandidateAddress string                 `protobuf:"bytes,1,opt,name=candidate_address,json=candidateAddress,proto3" json:"candidate_address,omitempty"`
-	Term             int64                  `protobuf:"varint,2,opt,name=term,proto3" json:"term,omitempty"`
-	unknownFields    protoimpl.UnknownFields
-	sizeCache        protoimpl.SizeCache
+func (x *DeleteRequest) GetNamespace() string {
+	if x != nil {
+		return x.Namespace
+	}
+	return ""
 }
 
-func (x *LeaderVoteRequest) Reset() {
-	*x = LeaderVoteRequest{}
+type DeleteResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Deleted       bool                   `protobuf:"varint,1,opt,name=deleted,proto3" json:"deleted,omitempty"`
+	Revision      int64                  `protobuf:"varint,2,opt,name=revision,proto3" json:"revision,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DeleteResponse) Reset() {
+	*x = DeleteResponse{}
 	mi := &file_api_kv_storage_proto_msgTypes[6]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *LeaderVoteRequest) String() string {
+func (x *DeleteResponse) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*LeaderVoteRequest) ProtoMessage() {}
+func (*DeleteResponse) ProtoMessage() {}
 
-func (x *LeaderVoteRequest) ProtoReflect() protoreflect.Message {
+func (x *DeleteResponse) ProtoReflect() protoreflect.Message {
 	mi := &file_api_kv_storage_proto_msgTypes[6]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
@@ -335,47 +501,47 @@ func (x *LeaderVoteRequest) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use LeaderVoteRequest.ProtoReflect.Descriptor instead.
-func (*LeaderVoteRequest) Descriptor() ([]byte, []int) {
+// Deprecated: Use DeleteResponse.ProtoReflect.Descriptor instead.
+func (*DeleteResponse) Descriptor() ([]byte, []int) {
 	return file_api_kv_storage_proto_rawDescGZIP(), []int{6}
 }
 
-func (x *LeaderVoteRequest) GetCandidateAddress() string {
+func (x *DeleteResponse) GetDeleted() bool {
 	if x != nil {
-		return x.CandidateAddress
+		return x.Deleted
 	}
-	return ""
+	return false
 }
 
-func (x *LeaderVoteRequest) GetTerm() int64 {
+func (x *DeleteResponse) GetRevision() int64 {
 	if x != nil {
-		return x.Term
+		return x.Revision
 	}
 	return 0
 }
 
-type LeaderVoteResponse struct {
+type KeyValue struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	VoteGranted   bool                   `protobuf:"varint,1,opt,name=vote_granted,json=voteGranted,proto3" json:"vote_granted,omitempty"`
-	Term          int64                  `protobuf:"varint,2,opt,name=term,proto3" json:"term,omitempty"`
+	Key           string                 `protobuf:"bytes,1,opt,name=key,proto3" json:"key,omitempty"`
+	Value         string                 `protobuf:"bytes,2,opt,name=value,proto3" json:"value,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *LeaderVoteResponse) Reset() {
-	*x = LeaderVoteResponse{}
+func (x *KeyValue) Reset() {
+	*x = KeyValue{}
 	mi := &file_api_kv_storage_proto_msgTypes[7]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *LeaderVoteResponse) String() string {
+func (x *KeyValue) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*LeaderVoteResponse) ProtoMessage() {}
+func (*KeyValue) ProtoMessage() {}
 
-func (x *LeaderVoteResponse) ProtoReflect() protoreflect.Message {
+func (x *KeyValue) ProtoReflect() protoreflect.Message {
 	mi := &file_api_kv_storage_proto_msgTypes[7]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
@@ -387,46 +553,53 @@ func (x *LeaderVoteResponse) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use LeaderVoteResponse.ProtoReflect.Descriptor instead.
-func (*LeaderVoteResponse) Descriptor() ([]byte, []int) {
+// Deprecated: Use KeyValue.ProtoReflect.Descriptor instead.
+func (*KeyValue) Descriptor() ([]byte, []int) {
 	return file_api_kv_storage_proto_rawDescGZIP(), []int{7}
 }
 
-func (x *LeaderVoteResponse) GetVoteGranted() bool {
+func (x *KeyValue) GetKey() string {
 	if x != nil {
-		return x.VoteGranted
+		return x.Key
 	}
-	return false
+	return ""
 }
 
-func (x *LeaderVoteResponse) GetTerm() int64 {
+func (x *KeyValue) GetValue() string {
 	if x != nil {
-		return x.Term
+		return x.Value
 	}
-	return 0
+	return ""
 }
 
-type FetchFromSeedRequest struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	Address       string                 `protobuf:"bytes,1,opt,name=address,proto3" json:"address,omitempty"`
+type ScanRequest struct {
+	state  protoimpl.MessageState `protogen:"open.v1"`
+	Prefix string                 `protobuf:"bytes,1,opt,name=prefix,proto3" json:"prefix,omitempty"`
+	// Ключ, после которого продолжить выдачу
+	StartAfter string `protobuf:"bytes,2,opt,name=start_after,json=startAfter,proto3" json:"start_after,omitempty"`
+	Limit      int32  `protobuf:"varint,3,opt,name=limit,proto3" json:"limit,omitempty"`
+	Namespace  string `protobuf:"bytes,4,opt,name=namespace,proto3" json:"namespace,omitempty"`
+	// Согласованный срез на момент ревизии, по умолчанию текущие значения.
+	// Для постраничной выдачи передаётся одна и та же ревизия
+	Revision      *int64 `protobuf:"varint,5,opt,name=revision,proto3,oneof" json:"revision,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *FetchFromSeedRequest) Reset() {
-	*x = FetchFromSeedRequest{}
+func (x *ScanRequest) Reset() {
+	*x = ScanRequest{}
 	mi := &file_api_kv_storage_proto_msgTypes[8]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *FetchFromSeedRequest) String() string {
+func (x *ScanRequest) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*FetchFromSeedRequest) ProtoMessage() {}
+func (*ScanRequest) ProtoMessage() {}
 
-func (x *FetchFromSeedRequest) ProtoReflect() protoreflect.Message {
+func (x *ScanRequest) ProtoReflect() protoreflect.Message {
 	mi := &file_api_kv_storage_proto_msgTypes[8]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
@@ -438,39 +611,69 @@ func (x *FetchFromSeedRequest) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use FetchFromSeedRequest.ProtoReflect.Descriptor instead.
-func (*FetchFromSeedRequest) Descriptor() ([]byte, []int) {
+// Deprecated: Use ScanRequest.ProtoReflect.Descriptor instead.
+func (*ScanRequest) Descriptor() ([]byte, []int) {
 	return file_api_kv_storage_proto_rawDescGZIP(), []int{8}
 }
 
-func (x *FetchFromSeedRequest) GetAddress() string {
+func (x *ScanRequest) GetPrefix() string {
 	if x != nil {
-		return x.Address
+		return x.Prefix
 	}
 	return ""
 }
 
-type FetchFromSeedResponse struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	Peers         []string               `protobuf:"bytes,1,rep,name=peers,proto3" json:"peers,omitempty"`
-	unknownFields protoimpl.UnknownFields
-	sizeCache     protoimpl.SizeCache
+func (x *ScanRequest) GetStartAfter() string {
+	if x != nil {
+		return x.StartAfter
+	}
+	return ""
 }
 
-func (x *FetchFromSeedResponse) Reset() {
-	*x = FetchFromSeedResponse{}
+func (x *ScanRequest) GetLimit() int32 {
+	if x != nil {
+		return x.Limit
+	}
+	return 0
+}
+
+func (x *ScanRequest) GetNamespace() string {
+	if x != nil {
+		return x.Namespace
+	}
+	return ""
+}
+
+func (x *ScanRequest) GetRevision() int64 {
+	if x != nil && x.Revision != nil {
+		return *x.Revision
+	}
+	return 0
+}
+
+type ScanResponse struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Items []*KeyValue            `protobuf:"bytes,1,rep,name=items,proto3" json:"items,omitempty"`
+	// Пустая строка, если ключей больше нет
+	NextStartAfter string `protobuf:"bytes,2,opt,name=next_start_after,json=nextStartAfter,proto3" json:"next_start_after,omitempty"`
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *ScanResponse) Reset() {
+	*x = ScanResponse{}
 	mi := &file_api_kv_storage_proto_msgTypes[9]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *FetchFromSeedResponse) String() string {
+func (x *ScanResponse) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*FetchFromSeedResponse) ProtoMessage() {}
+func (*ScanResponse) ProtoMessage() {}
 
-func (x *FetchFromSeedResponse) ProtoReflect() protoreflect.Message {
+func (x *ScanResponse) ProtoReflect() protoreflect.Message {
 	mi := &file_api_kv_storage_proto_msgTypes[9]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
@@ -482,38 +685,49 @@ func (x *FetchFromSeedResponse) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use FetchFromSeedResponse.ProtoReflect.Descriptor instead.
-func (*FetchFromSeedResponse) Descriptor() ([]byte, []int) {
+// Deprecated: Use ScanResponse.ProtoReflect.Descriptor instead.
+func (*ScanResponse) Descriptor() ([]byte, []int) {
 	return file_api_kv_storage_proto_rawDescGZIP(), []int{9}
 }
 
-func (x *FetchFromSeedResponse) GetPeers() []string {
+func (x *ScanResponse) GetItems() []*KeyValue {
 	if x != nil {
-		return x.Peers
+		return x.Items
 	}
 	return nil
 }
 
-type LeMetaRequest struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
+func (x *ScanResponse) GetNextStartAfter() string {
+	if x != nil {
+		return x.NextStartAfter
+	}
+	return ""
+}
+
+type WatchRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Key   string                 `protobuf:"bytes,1,opt,name=key,proto3" json:"key,omitempty"`
+	// Следить за всеми ключами, начинающимися с key
+	Prefix        bool   `protobuf:"varint,2,opt,name=prefix,proto3" json:"prefix,omitempty"`
+	Namespace     string `protobuf:"bytes,3,opt,name=namespace,proto3" json:"namespace,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *LeMetaRequest) Reset() {
-	*x = LeMetaRequest{}
+func (x *WatchRequest) Reset() {
+	*x = WatchRequest{}
 	mi := &file_api_kv_storage_proto_msgTypes[10]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *LeMetaRequest) String() string {
+func (x *WatchRequest) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*LeMetaRequest) ProtoMessage() {}
+func (*WatchRequest) ProtoMessage() {}
 
-func (x *LeMetaRequest) ProtoReflect() protoreflect.Message {
+func (x *WatchRequest) ProtoReflect() protoreflect.Message {
 	mi := &file_api_kv_storage_proto_msgTypes[10]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
@@ -525,34 +739,2338 @@ func (x *LeMetaRequest) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use LeMetaRequest.ProtoReflect.Descriptor instead.
-func (*LeMetaRequest) Descriptor() ([]byte, []int) {
+// Deprecated: Use WatchRequest.ProtoReflect.Descriptor instead.
+func (*WatchRequest) Descriptor() ([]byte, []int) {
 	return file_api_kv_storage_proto_rawDescGZIP(), []int{10}
 }
 
-type LeMetaResponse struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	NomadId       string                 `protobuf:"bytes,1,opt,name=nomad_id,json=nomadId,proto3" json:"nomad_id,omitempty"`
-	DataVersion   int64                  `protobuf:"varint,2,opt,name=data_version,json=dataVersion,proto3" json:"data_version,omitempty"`
-	unknownFields protoimpl.UnknownFields
-	sizeCache     protoimpl.SizeCache
+func (x *WatchRequest) GetKey() string {
+	if x != nil {
+		return x.Key
+	}
+	return ""
 }
 
-func (x *LeMetaResponse) Reset() {
-	*x = LeMetaResponse{}
+func (x *WatchRequest) GetPrefix() bool {
+	if x != nil {
+		return x.Prefix
+	}
+	return false
+}
+
+func (x *WatchRequest) GetNamespace() string {
+	if x != nil {
+		return x.Namespace
+	}
+	return ""
+}
+
+type WatchResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Key           string                 `protobuf:"bytes,1,opt,name=key,proto3" json:"key,omitempty"`
+	Value         string                 `protobuf:"bytes,2,opt,name=value,proto3" json:"value,omitempty"`
+	Operation     string                 `protobuf:"bytes,3,opt,name=operation,proto3" json:"operation,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *WatchResponse) Reset() {
+	*x = WatchResponse{}
+	mi := &file_api_kv_storage_proto_msgTypes[11]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *WatchResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*WatchResponse) ProtoMessage() {}
+
+func (x *WatchResponse) ProtoReflect() protoreflect.Message {
 	mi := &file_api_kv_storage_proto_msgTypes[11]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use WatchResponse.ProtoReflect.Descriptor instead.
+func (*WatchResponse) Descriptor() ([]byte, []int) {
+	return file_api_kv_storage_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *WatchResponse) GetKey() string {
+	if x != nil {
+		return x.Key
+	}
+	return ""
+}
+
+func (x *WatchResponse) GetValue() string {
+	if x != nil {
+		return x.Value
+	}
+	return ""
+}
+
+func (x *WatchResponse) GetOperation() string {
+	if x != nil {
+		return x.Operation
+	}
+	return ""
+}
+
+type GossipRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Node          string                 `protobuf:"bytes,1,opt,name=node,proto3" json:"node,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GossipRequest) Reset() {
+	*x = GossipRequest{}
+	mi := &file_api_kv_storage_proto_msgTypes[12]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GossipRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GossipRequest) ProtoMessage() {}
+
+func (x *GossipRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_kv_storage_proto_msgTypes[12]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GossipRequest.ProtoReflect.Descriptor instead.
+func (*GossipRequest) Descriptor() ([]byte, []int) {
+	return file_api_kv_storage_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *GossipRequest) GetNode() string {
+	if x != nil {
+		return x.Node
+	}
+	return ""
+}
+
+type GossipResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	IsLeader      bool                   `protobuf:"varint,1,opt,name=is_leader,json=isLeader,proto3" json:"is_leader,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GossipResponse) Reset() {
+	*x = GossipResponse{}
+	mi := &file_api_kv_storage_proto_msgTypes[13]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GossipResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GossipResponse) ProtoMessage() {}
+
+func (x *GossipResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_api_kv_storage_proto_msgTypes[13]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GossipResponse.ProtoReflect.Descriptor instead.
+func (*GossipResponse) Descriptor() ([]byte, []int) {
+	return file_api_kv_storage_proto_rawDescGZIP(), []int{13}
+}
+
+func (x *GossipResponse) GetIsLeader() bool {
+	if x != nil {
+		return x.IsLeader
+	}
+	return false
+}
+
+type LeaderVoteRequest struct {
+	state            protoimpl.MessageState `protogen:"open.v1"`
+	CandidateAddress string                 `protobuf:"bytes,1,opt,name=candidate_address,json=candidateAddress,proto3" json:"candidate_address,omitempty"`
+	Term             int64                  `protobuf:"varint,2,opt,name=term,proto3" json:"term,omitempty"`
+	unknownFields    protoimpl.UnknownFields
+	sizeCache        protoimpl.SizeCache
+}
+
+func (x *LeaderVoteRequest) Reset() {
+	*x = LeaderVoteRequest{}
+	mi := &file_api_kv_storage_proto_msgTypes[14]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *LeaderVoteRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*LeaderVoteRequest) ProtoMessage() {}
+
+func (x *LeaderVoteRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_kv_storage_proto_msgTypes[14]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use LeaderVoteRequest.ProtoReflect.Descriptor instead.
+func (*LeaderVoteRequest) Descriptor() ([]byte, []int) {
+	return file_api_kv_storage_proto_rawDescGZIP(), []int{14}
+}
+
+func (x *LeaderVoteRequest) GetCandidateAddress() string {
+	if x != nil {
+		return x.CandidateAddress
+	}
+	return ""
+}
+
+func (x *LeaderVoteRequest) GetTerm() int64 {
+	if x != nil {
+		return x.Term
+	}
+	return 0
+}
+
+type LeaderVoteResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	VoteGranted   bool                   `protobuf:"varint,1,opt,name=vote_granted,json=voteGranted,proto3" json:"vote_granted,omitempty"`
+	Term          int64                  `protobuf:"varint,2,opt,name=term,proto3" json:"term,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *LeaderVoteResponse) Reset() {
+	*x = LeaderVoteResponse{}
+	mi := &file_api_kv_storage_proto_msgTypes[15]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *LeaderVoteResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*LeaderVoteResponse) ProtoMessage() {}
+
+func (x *LeaderVoteResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_api_kv_storage_proto_msgTypes[15]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use LeaderVoteResponse.ProtoReflect.Descriptor instead.
+func (*LeaderVoteResponse) Descriptor() ([]byte, []int) {
+	return file_api_kv_storage_proto_rawDescGZIP(), []int{15}
+}
+
+func (x *LeaderVoteResponse) GetVoteGranted() bool {
+	if x != nil {
+		return x.VoteGranted
+	}
+	return false
+}
+
+func (x *LeaderVoteResponse) GetTerm() int64 {
+	if x != nil {
+		return x.Term
+	}
+	return 0
+}
+
+type FetchFromSeedRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Address       string                 `protobuf:"bytes,1,opt,name=address,proto3" json:"address,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *FetchFromSeedRequest) Reset() {
+	*x = FetchFromSeedRequest{}
+	mi := &file_api_kv_storage_proto_msgTypes[16]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *FetchFromSeedRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*FetchFromSeedRequest) ProtoMessage() {}
+
+func (x *FetchFromSeedRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_kv_storage_proto_msgTypes[16]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use FetchFromSeedRequest.ProtoReflect.Descriptor instead.
+func (*FetchFromSeedRequest) Descriptor() ([]byte, []int) {
+	return file_api_kv_storage_proto_rawDescGZIP(), []int{16}
+}
+
+func (x *FetchFromSeedRequest) GetAddress() string {
+	if x != nil {
+		return x.Address
+	}
+	return ""
+}
+
+type FetchFromSeedResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Peers         []string               `protobuf:"bytes,1,rep,name=peers,proto3" json:"peers,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *FetchFromSeedResponse) Reset() {
+	*x = FetchFromSeedResponse{}
+	mi := &file_api_kv_storage_proto_msgTypes[17]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *FetchFromSeedResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*FetchFromSeedResponse) ProtoMessage() {}
+
+func (x *FetchFromSeedResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_api_kv_storage_proto_msgTypes[17]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use FetchFromSeedResponse.ProtoReflect.Descriptor instead.
+func (*FetchFromSeedResponse) Descriptor() ([]byte, []int) {
+	return file_api_kv_storage_proto_rawDescGZIP(), []int{17}
+}
+
+func (x *FetchFromSeedResponse) GetPeers() []string {
+	if x != nil {
+		return x.Peers
+	}
+	return nil
+}
+
+type CounterRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Key   string                 `protobuf:"bytes,1,opt,name=key,proto3" json:"key,omitempty"`
+	// Неотрицательный шаг, по умолчанию 1
+	Delta *int64 `protobuf:"varint,2,opt,name=delta,proto3,oneof" json:"delta,omitempty"`
+	// TTL в миллисекундах, выставляется только при создании ключа
+	TtlMs *int64 `protobuf:"varint,3,opt,name=ttl_ms,json=ttlMs,proto3,oneof" json:"ttl_ms,omitempty"`
+	// Границы допустимого результата включительно
+	Min           *int64 `protobuf:"varint,4,opt,name=min,proto3,oneof" json:"min,omitempty"`
+	Max           *int64 `protobuf:"varint,5,opt,name=max,proto3,oneof" json:"max,omitempty"`
+	Namespace     string `protobuf:"bytes,6,opt,name=namespace,proto3" json:"namespace,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CounterRequest) Reset() {
+	*x = CounterRequest{}
+	mi := &file_api_kv_storage_proto_msgTypes[18]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CounterRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CounterRequest) ProtoMessage() {}
+
+func (x *CounterRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_kv_storage_proto_msgTypes[18]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CounterRequest.ProtoReflect.Descriptor instead.
+func (*CounterRequest) Descriptor() ([]byte, []int) {
+	return file_api_kv_storage_proto_rawDescGZIP(), []int{18}
+}
+
+func (x *CounterRequest) GetKey() string {
+	if x != nil {
+		return x.Key
+	}
+	return ""
+}
+
+func (x *CounterRequest) GetDelta() int64 {
+	if x != nil && x.Delta != nil {
+		return *x.Delta
+	}
+	return 0
+}
+
+func (x *CounterRequest) GetTtlMs() int64 {
+	if x != nil && x.TtlMs != nil {
+		return *x.TtlMs
+	}
+	return 0
+}
+
+func (x *CounterRequest) GetMin() int64 {
+	if x != nil && x.Min != nil {
+		return *x.Min
+	}
+	return 0
+}
+
+func (x *CounterRequest) GetMax() int64 {
+	if x != nil && x.Max != nil {
+		return *x.Max
+	}
+	return 0
+}
+
+func (x *CounterRequest) GetNamespace() string {
+	if x != nil {
+		return x.Namespace
+	}
+	return ""
+}
+
+type CounterResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Value         int64                  `protobuf:"varint,1,opt,name=value,proto3" json:"value,omitempty"`
+	Revision      int64                  `protobuf:"varint,2,opt,name=revision,proto3" json:"revision,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CounterResponse) Reset() {
+	*x = CounterResponse{}
+	mi := &file_api_kv_storage_proto_msgTypes[19]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CounterResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CounterResponse) ProtoMessage() {}
+
+func (x *CounterResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_api_kv_storage_proto_msgTypes[19]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CounterResponse.ProtoReflect.Descriptor instead.
+func (*CounterResponse) Descriptor() ([]byte, []int) {
+	return file_api_kv_storage_proto_rawDescGZIP(), []int{19}
+}
+
+func (x *CounterResponse) GetValue() int64 {
+	if x != nil {
+		return x.Value
+	}
+	return 0
+}
+
+func (x *CounterResponse) GetRevision() int64 {
+	if x != nil {
+		return x.Revision
+	}
+	return 0
+}
+
+type LeaseGrantRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	TtlMs         int64                  `protobuf:"varint,1,opt,name=ttl_ms,json=ttlMs,proto3" json:"ttl_ms,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *LeaseGrantRequest) Reset() {
+	*x = LeaseGrantRequest{}
+	mi := &file_api_kv_storage_proto_msgTypes[20]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *LeaseGrantRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*LeaseGrantRequest) ProtoMessage() {}
+
+func (x *LeaseGrantRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_kv_storage_proto_msgTypes[20]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use LeaseGrantRequest.ProtoReflect.Descriptor instead.
+func (*LeaseGrantRequest) Descriptor() ([]byte, []int) {
+	return file_api_kv_storage_proto_rawDescGZIP(), []int{20}
+}
+
+func (x *LeaseGrantRequest) GetTtlMs() int64 {
+	if x != nil {
+		return x.TtlMs
+	}
+	return 0
+}
+
+type LeaseGrantResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            int64                  `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	TtlMs         int64                  `protobuf:"varint,2,opt,name=ttl_ms,json=ttlMs,proto3" json:"ttl_ms,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *LeaseGrantResponse) Reset() {
+	*x = LeaseGrantResponse{}
+	mi := &file_api_kv_storage_proto_msgTypes[21]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *LeaseGrantResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*LeaseGrantResponse) ProtoMessage() {}
+
+func (x *LeaseGrantResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_api_kv_storage_proto_msgTypes[21]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use LeaseGrantResponse.ProtoReflect.Descriptor instead.
+func (*LeaseGrantResponse) Descriptor() ([]byte, []int) {
+	return file_api_kv_storage_proto_rawDescGZIP(), []int{21}
+}
+
+func (x *LeaseGrantResponse) GetId() int64 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+func (x *LeaseGrantResponse) GetTtlMs() int64 {
+	if x != nil {
+		return x.TtlMs
+	}
+	return 0
+}
+
+type LeaseKeepAliveRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            int64                  `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *LeaseKeepAliveRequest) Reset() {
+	*x = LeaseKeepAliveRequest{}
+	mi := &file_api_kv_storage_proto_msgTypes[22]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *LeaseKeepAliveRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*LeaseKeepAliveRequest) ProtoMessage() {}
+
+func (x *LeaseKeepAliveRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_kv_storage_proto_msgTypes[22]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use LeaseKeepAliveRequest.ProtoReflect.Descriptor instead.
+func (*LeaseKeepAliveRequest) Descriptor() ([]byte, []int) {
+	return file_api_kv_storage_proto_rawDescGZIP(), []int{22}
+}
+
+func (x *LeaseKeepAliveRequest) GetId() int64 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+type LeaseKeepAliveResponse struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Id    int64                  `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	// 0, если аренда уже истекла или отозвана
+	TtlMs         int64 `protobuf:"varint,2,opt,name=ttl_ms,json=ttlMs,proto3" json:"ttl_ms,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *LeaseKeepAliveResponse) Reset() {
+	*x = LeaseKeepAliveResponse{}
+	mi := &file_api_kv_storage_proto_msgTypes[23]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *LeaseKeepAliveResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*LeaseKeepAliveResponse) ProtoMessage() {}
+
+func (x *LeaseKeepAliveResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_api_kv_storage_proto_msgTypes[23]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use LeaseKeepAliveResponse.ProtoReflect.Descriptor instead.
+func (*LeaseKeepAliveResponse) Descriptor() ([]byte, []int) {
+	return file_api_kv_storage_proto_rawDescGZIP(), []int{23}
+}
+
+func (x *LeaseKeepAliveResponse) GetId() int64 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+func (x *LeaseKeepAliveResponse) GetTtlMs() int64 {
+	if x != nil {
+		return x.TtlMs
+	}
+	return 0
+}
+
+type LeaseRevokeRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            int64                  `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *LeaseRevokeRequest) Reset() {
+	*x = LeaseRevokeRequest{}
+	mi := &file_api_kv_storage_proto_msgTypes[24]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *LeaseRevokeRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*LeaseRevokeRequest) ProtoMessage() {}
+
+func (x *LeaseRevokeRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_kv_storage_proto_msgTypes[24]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use LeaseRevokeRequest.ProtoReflect.Descriptor instead.
+func (*LeaseRevokeRequest) Descriptor() ([]byte, []int) {
+	return file_api_kv_storage_proto_rawDescGZIP(), []int{24}
+}
+
+func (x *LeaseRevokeRequest) GetId() int64 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+type LeaseRevokeResponse struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Ревизия удаления последнего привязанного ключа, 0 если ключей не было
+	Revision      int64 `protobuf:"varint,1,opt,name=revision,proto3" json:"revision,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *LeaseRevokeResponse) Reset() {
+	*x = LeaseRevokeResponse{}
+	mi := &file_api_kv_storage_proto_msgTypes[25]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *LeaseRevokeResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*LeaseRevokeResponse) ProtoMessage() {}
+
+func (x *LeaseRevokeResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_api_kv_storage_proto_msgTypes[25]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use LeaseRevokeResponse.ProtoReflect.Descriptor instead.
+func (*LeaseRevokeResponse) Descriptor() ([]byte, []int) {
+	return file_api_kv_storage_proto_rawDescGZIP(), []int{25}
+}
+
+func (x *LeaseRevokeResponse) GetRevision() int64 {
+	if x != nil {
+		return x.Revision
+	}
+	return 0
+}
+
+type LockRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Name          string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Lease         int64                  `protobuf:"varint,2,opt,name=lease,proto3" json:"lease,omitempty"`
+	Namespace     string                 `protobuf:"bytes,3,opt,name=namespace,proto3" json:"namespace,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *LockRequest) Reset() {
+	*x = LockRequest{}
+	mi := &file_api_kv_storage_proto_msgTypes[26]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *LockRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*LockRequest) ProtoMessage() {}
+
+func (x *LockRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_kv_storage_proto_msgTypes[26]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use LockRequest.ProtoReflect.Descriptor instead.
+func (*LockRequest) Descriptor() ([]byte, []int) {
+	return file_api_kv_storage_proto_rawDescGZIP(), []int{26}
+}
+
+func (x *LockRequest) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *LockRequest) GetLease() int64 {
+	if x != nil {
+		return x.Lease
+	}
+	return 0
+}
+
+func (x *LockRequest) GetNamespace() string {
+	if x != nil {
+		return x.Namespace
+	}
+	return ""
+}
+
+type LockResponse struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Key   string                 `protobuf:"bytes,1,opt,name=key,proto3" json:"key,omitempty"`
+	// Ревизия ключа блокировки, монотонно растёт с каждым захватом
+	FencingToken  int64 `protobuf:"varint,2,opt,name=fencing_token,json=fencingToken,proto3" json:"fencing_token,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *LockResponse) Reset() {
+	*x = LockResponse{}
+	mi := &file_api_kv_storage_proto_msgTypes[27]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *LockResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*LockResponse) ProtoMessage() {}
+
+func (x *LockResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_api_kv_storage_proto_msgTypes[27]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use LockResponse.ProtoReflect.Descriptor instead.
+func (*LockResponse) Descriptor() ([]byte, []int) {
+	return file_api_kv_storage_proto_rawDescGZIP(), []int{27}
+}
+
+func (x *LockResponse) GetKey() string {
+	if x != nil {
+		return x.Key
+	}
+	return ""
+}
+
+func (x *LockResponse) GetFencingToken() int64 {
+	if x != nil {
+		return x.FencingToken
+	}
+	return 0
+}
+
+type UnlockRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Name          string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	FencingToken  int64                  `protobuf:"varint,2,opt,name=fencing_token,json=fencingToken,proto3" json:"fencing_token,omitempty"`
+	Namespace     string                 `protobuf:"bytes,3,opt,name=namespace,proto3" json:"namespace,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *UnlockRequest) Reset() {
+	*x = UnlockRequest{}
+	mi := &file_api_kv_storage_proto_msgTypes[28]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UnlockRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UnlockRequest) ProtoMessage() {}
+
+func (x *UnlockRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_kv_storage_proto_msgTypes[28]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UnlockRequest.ProtoReflect.Descriptor instead.
+func (*UnlockRequest) Descriptor() ([]byte, []int) {
+	return file_api_kv_storage_proto_rawDescGZIP(), []int{28}
+}
+
+func (x *UnlockRequest) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *UnlockRequest) GetFencingToken() int64 {
+	if x != nil {
+		return x.FencingToken
+	}
+	return 0
+}
+
+func (x *UnlockRequest) GetNamespace() string {
+	if x != nil {
+		return x.Namespace
+	}
+	return ""
+}
+
+type UnlockResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Revision      int64                  `protobuf:"varint,1,opt,name=revision,proto3" json:"revision,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *UnlockResponse) Reset() {
+	*x = UnlockResponse{}
+	mi := &file_api_kv_storage_proto_msgTypes[29]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UnlockResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UnlockResponse) ProtoMessage() {}
+
+func (x *UnlockResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_api_kv_storage_proto_msgTypes[29]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UnlockResponse.ProtoReflect.Descriptor instead.
+func (*UnlockResponse) Descriptor() ([]byte, []int) {
+	return file_api_kv_storage_proto_rawDescGZIP(), []int{29}
+}
+
+func (x *UnlockResponse) GetRevision() int64 {
+	if x != nil {
+		return x.Revision
+	}
+	return 0
+}
+
+type LeMetaRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *LeMetaRequest) Reset() {
+	*x = LeMetaRequest{}
+	mi := &file_api_kv_storage_proto_msgTypes[30]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *LeMetaRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*LeMetaRequest) ProtoMessage() {}
+
+func (x *LeMetaRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_kv_storage_proto_msgTypes[30]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use LeMetaRequest.ProtoReflect.Descriptor instead.
+func (*LeMetaRequest) Descriptor() ([]byte, []int) {
+	return file_api_kv_storage_proto_rawDescGZIP(), []int{30}
+}
+
+type LeMetaResponse struct {
+	state   protoimpl.MessageState `protogen:"open.v1"`
+	NomadId string                 `protobuf:"bytes,1,opt,name=nomad_id,json=nomadId,proto3" json:"nomad_id,omitempty"`
+	// Ревизия последней применённой записи
+	DataVersion int64 `protobuf:"varint,2,opt,name=data_version,json=dataVersion,proto3" json:"data_version,omitempty"`
+	// Эпоха последнего известного лидера, версии данных сравнимы только в одной эпохе
+	LeaderEpoch   int64 `protobuf:"varint,3,opt,name=leader_epoch,json=leaderEpoch,proto3" json:"leader_epoch,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *LeMetaResponse) Reset() {
+	*x = LeMetaResponse{}
+	mi := &file_api_kv_storage_proto_msgTypes[31]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *LeMetaResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*LeMetaResponse) ProtoMessage() {}
+
+func (x *LeMetaResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_api_kv_storage_proto_msgTypes[31]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use LeMetaResponse.ProtoReflect.Descriptor instead.
+func (*LeMetaResponse) Descriptor() ([]byte, []int) {
+	return file_api_kv_storage_proto_rawDescGZIP(), []int{31}
+}
+
+func (x *LeMetaResponse) GetNomadId() string {
+	if x != nil {
+		return x.NomadId
+	}
+	return ""
+}
+
+func (x *LeMetaResponse) GetDataVersion() int64 {
+	if x != nil {
+		return x.DataVersion
+	}
+	return 0
+}
+
+func (x *LeMetaResponse) GetLeaderEpoch() int64 {
+	if x != nil {
+		return x.LeaderEpoch
+	}
+	return 0
+}
+
+type UpdateLeaderRequest struct {
+	state   protoimpl.MessageState `protogen:"open.v1"`
+	NomadId string                 `protobuf:"bytes,1,opt,name=nomad_id,json=nomadId,proto3" json:"nomad_id,omitempty"`
+	Address string                 `protobuf:"bytes,2,opt,name=address,proto3" json:"address,omitempty"`
+	// Эпоха нового лидера, растёт с каждой сменой. Извещения со старой эпохой
+	// отклоняются, 0 оставляет текущую эпоху
+	Epoch         int64 `protobuf:"varint,3,opt,name=epoch,proto3" json:"epoch,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *UpdateLeaderRequest) Reset() {
+	*x = UpdateLeaderRequest{}
+	mi := &file_api_kv_storage_proto_msgTypes[32]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UpdateLeaderRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UpdateLeaderRequest) ProtoMessage() {}
+
+func (x *UpdateLeaderRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_kv_storage_proto_msgTypes[32]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UpdateLeaderRequest.ProtoReflect.Descriptor instead.
+func (*UpdateLeaderRequest) Descriptor() ([]byte, []int) {
+	return file_api_kv_storage_proto_rawDescGZIP(), []int{32}
+}
+
+func (x *UpdateLeaderRequest) GetNomadId() string {
+	if x != nil {
+		return x.NomadId
+	}
+	return ""
+}
+
+func (x *UpdateLeaderRequest) GetAddress() string {
+	if x != nil {
+		return x.Address
+	}
+	return ""
+}
+
+func (x *UpdateLeaderRequest) GetEpoch() int64 {
+	if x != nil {
+		return x.Epoch
+	}
+	return 0
+}
+
+type UpdateLeaderResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *UpdateLeaderResponse) Reset() {
+	*x = UpdateLeaderResponse{}
+	mi := &file_api_kv_storage_proto_msgTypes[33]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UpdateLeaderResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UpdateLeaderResponse) ProtoMessage() {}
+
+func (x *UpdateLeaderResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_api_kv_storage_proto_msgTypes[33]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UpdateLeaderResponse.ProtoReflect.Descriptor instead.
+func (*UpdateLeaderResponse) Descriptor() ([]byte, []int) {
+	return file_api_kv_storage_proto_rawDescGZIP(), []int{33}
+}
+
+type UpdateAddressesRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Addresses     []string               `protobuf:"bytes,1,rep,name=addresses,proto3" json:"addresses,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *UpdateAddressesRequest) Reset() {
+	*x = UpdateAddressesRequest{}
+	mi := &file_api_kv_storage_proto_msgTypes[34]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UpdateAddressesRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UpdateAddressesRequest) ProtoMessage() {}
+
+func (x *UpdateAddressesRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_kv_storage_proto_msgTypes[34]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UpdateAddressesRequest.ProtoReflect.Descriptor instead.
+func (*UpdateAddressesRequest) Descriptor() ([]byte, []int) {
+	return file_api_kv_storage_proto_rawDescGZIP(), []int{34}
+}
+
+func (x *UpdateAddressesRequest) GetAddresses() []string {
+	if x != nil {
+		return x.Addresses
+	}
+	return nil
+}
+
+type UpdateAddressesResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *UpdateAddressesResponse) Reset() {
+	*x = UpdateAddressesResponse{}
+	mi := &file_api_kv_storage_proto_msgTypes[35]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UpdateAddressesResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UpdateAddressesResponse) ProtoMessage() {}
+
+func (x *UpdateAddressesResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_api_kv_storage_proto_msgTypes[35]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UpdateAddressesResponse.ProtoReflect.Descriptor instead.
+func (*UpdateAddressesResponse) Descriptor() ([]byte, []int) {
+	return file_api_kv_storage_proto_rawDescGZIP(), []int{35}
+}
+
+// Нулевые значения означают отсутствие ограничения
+type NamespaceQuota struct {
+	state   protoimpl.MessageState `protogen:"open.v1"`
+	MaxKeys int64                  `protobuf:"varint,1,opt,name=max_keys,json=maxKeys,proto3" json:"max_keys,omitempty"`
+	// Суммарный размер ключей и значений в байтах
+	MaxBytes int64 `protobuf:"varint,2,opt,name=max_bytes,json=maxBytes,proto3" json:"max_bytes,omitempty"`
+	// Ограничение частоты запросов на каждой ноде
+	RequestsPerSecond float64 `protobuf:"fixed64,3,opt,name=requests_per_second,json=requestsPerSecond,proto3" json:"requests_per_second,omitempty"`
+	// Кодек сжатия значений: none, snappy или zstd. По умолчанию — настройка ноды
+	Compression   string `protobuf:"bytes,4,opt,name=compression,proto3" json:"compression,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *NamespaceQuota) Reset() {
+	*x = NamespaceQuota{}
+	mi := &file_api_kv_storage_proto_msgTypes[36]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *NamespaceQuota) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*NamespaceQuota) ProtoMessage() {}
+
+func (x *NamespaceQuota) ProtoReflect() protoreflect.Message {
+	mi := &file_api_kv_storage_proto_msgTypes[36]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use NamespaceQuota.ProtoReflect.Descriptor instead.
+func (*NamespaceQuota) Descriptor() ([]byte, []int) {
+	return file_api_kv_storage_proto_rawDescGZIP(), []int{36}
+}
+
+func (x *NamespaceQuota) GetMaxKeys() int64 {
+	if x != nil {
+		return x.MaxKeys
+	}
+	return 0
+}
+
+func (x *NamespaceQuota) GetMaxBytes() int64 {
+	if x != nil {
+		return x.MaxBytes
+	}
+	return 0
+}
+
+func (x *NamespaceQuota) GetRequestsPerSecond() float64 {
+	if x != nil {
+		return x.RequestsPerSecond
+	}
+	return 0
+}
+
+func (x *NamespaceQuota) GetCompression() string {
+	if x != nil {
+		return x.Compression
+	}
+	return ""
+}
+
+type Namespace struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Name          string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Quota         *NamespaceQuota        `protobuf:"bytes,2,opt,name=quota,proto3" json:"quota,omitempty"`
+	Keys          int64                  `protobuf:"varint,3,opt,name=keys,proto3" json:"keys,omitempty"`
+	Bytes         int64                  `protobuf:"varint,4,opt,name=bytes,proto3" json:"bytes,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Namespace) Reset() {
+	*x = Namespace{}
+	mi := &file_api_kv_storage_proto_msgTypes[37]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Namespace) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Namespace) ProtoMessage() {}
+
+func (x *Namespace) ProtoReflect() protoreflect.Message {
+	mi := &file_api_kv_storage_proto_msgTypes[37]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Namespace.ProtoReflect.Descriptor instead.
+func (*Namespace) Descriptor() ([]byte, []int) {
+	return file_api_kv_storage_proto_rawDescGZIP(), []int{37}
+}
+
+func (x *Namespace) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *Namespace) GetQuota() *NamespaceQuota {
+	if x != nil {
+		return x.Quota
+	}
+	return nil
+}
+
+func (x *Namespace) GetKeys() int64 {
+	if x != nil {
+		return x.Keys
+	}
+	return 0
+}
+
+func (x *Namespace) GetBytes() int64 {
+	if x != nil {
+		return x.Bytes
+	}
+	return 0
+}
+
+type CreateNamespaceRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Name          string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Quota         *NamespaceQuota        `protobuf:"bytes,2,opt,name=quota,proto3" json:"quota,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CreateNamespaceRequest) Reset() {
+	*x = CreateNamespaceRequest{}
+	mi := &file_api_kv_storage_proto_msgTypes[38]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CreateNamespaceRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CreateNamespaceRequest) ProtoMessage() {}
+
+func (x *CreateNamespaceRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_kv_storage_proto_msgTypes[38]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CreateNamespaceRequest.ProtoReflect.Descriptor instead.
+func (*CreateNamespaceRequest) Descriptor() ([]byte, []int) {
+	return file_api_kv_storage_proto_rawDescGZIP(), []int{38}
+}
+
+func (x *CreateNamespaceRequest) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *CreateNamespaceRequest) GetQuota() *NamespaceQuota {
+	if x != nil {
+		return x.Quota
+	}
+	return nil
+}
+
+type CreateNamespaceResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Revision      int64                  `protobuf:"varint,1,opt,name=revision,proto3" json:"revision,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CreateNamespaceResponse) Reset() {
+	*x = CreateNamespaceResponse{}
+	mi := &file_api_kv_storage_proto_msgTypes[39]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CreateNamespaceResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CreateNamespaceResponse) ProtoMessage() {}
+
+func (x *CreateNamespaceResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_api_kv_storage_proto_msgTypes[39]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CreateNamespaceResponse.ProtoReflect.Descriptor instead.
+func (*CreateNamespaceResponse) Descriptor() ([]byte, []int) {
+	return file_api_kv_storage_proto_rawDescGZIP(), []int{39}
+}
+
+func (x *CreateNamespaceResponse) GetRevision() int64 {
+	if x != nil {
+		return x.Revision
+	}
+	return 0
+}
+
+type ListNamespacesRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListNamespacesRequest) Reset() {
+	*x = ListNamespacesRequest{}
+	mi := &file_api_kv_storage_proto_msgTypes[40]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListNamespacesRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListNamespacesRequest) ProtoMessage() {}
+
+func (x *ListNamespacesRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_kv_storage_proto_msgTypes[40]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListNamespacesRequest.ProtoReflect.Descriptor instead.
+func (*ListNamespacesRequest) Descriptor() ([]byte, []int) {
+	return file_api_kv_storage_proto_rawDescGZIP(), []int{40}
+}
+
+type ListNamespacesResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Namespaces    []*Namespace           `protobuf:"bytes,1,rep,name=namespaces,proto3" json:"namespaces,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListNamespacesResponse) Reset() {
+	*x = ListNamespacesResponse{}
+	mi := &file_api_kv_storage_proto_msgTypes[41]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListNamespacesResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListNamespacesResponse) ProtoMessage() {}
+
+func (x *ListNamespacesResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_api_kv_storage_proto_msgTypes[41]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListNamespacesResponse.ProtoReflect.Descriptor instead.
+func (*ListNamespacesResponse) Descriptor() ([]byte, []int) {
+	return file_api_kv_storage_proto_rawDescGZIP(), []int{41}
+}
+
+func (x *ListNamespacesResponse) GetNamespaces() []*Namespace {
+	if x != nil {
+		return x.Namespaces
+	}
+	return nil
+}
+
+type DropNamespaceRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Name          string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DropNamespaceRequest) Reset() {
+	*x = DropNamespaceRequest{}
+	mi := &file_api_kv_storage_proto_msgTypes[42]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DropNamespaceRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DropNamespaceRequest) ProtoMessage() {}
+
+func (x *DropNamespaceRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_kv_storage_proto_msgTypes[42]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DropNamespaceRequest.ProtoReflect.Descriptor instead.
+func (*DropNamespaceRequest) Descriptor() ([]byte, []int) {
+	return file_api_kv_storage_proto_rawDescGZIP(), []int{42}
+}
+
+func (x *DropNamespaceRequest) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+type DropNamespaceResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	DeletedKeys   int64                  `protobuf:"varint,1,opt,name=deleted_keys,json=deletedKeys,proto3" json:"deleted_keys,omitempty"`
+	Revision      int64                  `protobuf:"varint,2,opt,name=revision,proto3" json:"revision,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DropNamespaceResponse) Reset() {
+	*x = DropNamespaceResponse{}
+	mi := &file_api_kv_storage_proto_msgTypes[43]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DropNamespaceResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DropNamespaceResponse) ProtoMessage() {}
+
+func (x *DropNamespaceResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_api_kv_storage_proto_msgTypes[43]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DropNamespaceResponse.ProtoReflect.Descriptor instead.
+func (*DropNamespaceResponse) Descriptor() ([]byte, []int) {
+	return file_api_kv_storage_proto_rawDescGZIP(), []int{43}
+}
+
+func (x *DropNamespaceResponse) GetDeletedKeys() int64 {
+	if x != nil {
+		return x.DeletedKeys
+	}
+	return 0
+}
+
+func (x *DropNamespaceResponse) GetRevision() int64 {
+	if x != nil {
+		return x.Revision
+	}
+	return 0
+}
+
+type HistoryRequest struct {
+	state     protoimpl.MessageState `protogen:"open.v1"`
+	Key       string                 `protobuf:"bytes,1,opt,name=key,proto3" json:"key,omitempty"`
+	Namespace string                 `protobuf:"bytes,2,opt,name=namespace,proto3" json:"namespace,omitempty"`
+	// Ревизия, с которой начать выдачу
+	StartRevision int64 `protobuf:"varint,3,opt,name=start_revision,json=startRevision,proto3" json:"start_revision,omitempty"`
+	Limit         int32 `protobuf:"varint,4,opt,name=limit,proto3" json:"limit,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *HistoryRequest) Reset() {
+	*x = HistoryRequest{}
+	mi := &file_api_kv_storage_proto_msgTypes[44]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *HistoryRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*HistoryRequest) ProtoMessage() {}
+
+func (x *HistoryRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_kv_storage_proto_msgTypes[44]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use HistoryRequest.ProtoReflect.Descriptor instead.
+func (*HistoryRequest) Descriptor() ([]byte, []int) {
+	return file_api_kv_storage_proto_rawDescGZIP(), []int{44}
+}
+
+func (x *HistoryRequest) GetKey() string {
+	if x != nil {
+		return x.Key
+	}
+	return ""
+}
+
+func (x *HistoryRequest) GetNamespace() string {
+	if x != nil {
+		return x.Namespace
+	}
+	return ""
+}
+
+func (x *HistoryRequest) GetStartRevision() int64 {
+	if x != nil {
+		return x.StartRevision
+	}
+	return 0
+}
+
+func (x *HistoryRequest) GetLimit() int32 {
+	if x != nil {
+		return x.Limit
+	}
+	return 0
+}
+
+type KeyVersion struct {
+	state    protoimpl.MessageState `protogen:"open.v1"`
+	Revision int64                  `protobuf:"varint,1,opt,name=revision,proto3" json:"revision,omitempty"`
+	Value    string                 `protobuf:"bytes,2,opt,name=value,proto3" json:"value,omitempty"`
+	// Ключ удалён в этой ревизии
+	Deleted bool `protobuf:"varint,3,opt,name=deleted,proto3" json:"deleted,omitempty"`
+	// Время истечения ключа в unix-наносекундах
+	Expiration int64 `protobuf:"varint,4,opt,name=expiration,proto3" json:"expiration,omitempty"`
+	// Время применения ревизии в unix-наносекундах
+	Time          int64 `protobuf:"varint,5,opt,name=time,proto3" json:"time,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *KeyVersion) Reset() {
+	*x = KeyVersion{}
+	mi := &file_api_kv_storage_proto_msgTypes[45]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *KeyVersion) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*KeyVersion) ProtoMessage() {}
+
+func (x *KeyVersion) ProtoReflect() protoreflect.Message {
+	mi := &file_api_kv_storage_proto_msgTypes[45]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use KeyVersion.ProtoReflect.Descriptor instead.
+func (*KeyVersion) Descriptor() ([]byte, []int) {
+	return file_api_kv_storage_proto_rawDescGZIP(), []int{45}
+}
+
+func (x *KeyVersion) GetRevision() int64 {
+	if x != nil {
+		return x.Revision
+	}
+	return 0
+}
+
+func (x *KeyVersion) GetValue() string {
+	if x != nil {
+		return x.Value
+	}
+	return ""
+}
+
+func (x *KeyVersion) GetDeleted() bool {
+	if x != nil {
+		return x.Deleted
+	}
+	return false
+}
+
+func (x *KeyVersion) GetExpiration() int64 {
+	if x != nil {
+		return x.Expiration
+	}
+	return 0
+}
+
+func (x *KeyVersion) GetTime() int64 {
+	if x != nil {
+		return x.Time
+	}
+	return 0
+}
+
+type HistoryResponse struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Версии в порядке возрастания ревизий
+	Versions []*KeyVersion `protobuf:"bytes,1,rep,name=versions,proto3" json:"versions,omitempty"`
+	// 0, если версий больше нет
+	NextStartRevision int64 `protobuf:"varint,2,opt,name=next_start_revision,json=nextStartRevision,proto3" json:"next_start_revision,omitempty"`
+	// Версии старше этой ревизии удалены компакцией
+	CompactRevision int64 `protobuf:"varint,3,opt,name=compact_revision,json=compactRevision,proto3" json:"compact_revision,omitempty"`
+	unknownFields   protoimpl.UnknownFields
+	sizeCache       protoimpl.SizeCache
+}
+
+func (x *HistoryResponse) Reset() {
+	*x = HistoryResponse{}
+	mi := &file_api_kv_storage_proto_msgTypes[46]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *HistoryResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*HistoryResponse) ProtoMessage() {}
+
+func (x *HistoryResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_api_kv_storage_proto_msgTypes[46]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use HistoryResponse.ProtoReflect.Descriptor instead.
+func (*HistoryResponse) Descriptor() ([]byte, []int) {
+	return file_api_kv_storage_proto_rawDescGZIP(), []int{46}
+}
+
+func (x *HistoryResponse) GetVersions() []*KeyVersion {
+	if x != nil {
+		return x.Versions
+	}
+	return nil
+}
+
+func (x *HistoryResponse) GetNextStartRevision() int64 {
+	if x != nil {
+		return x.NextStartRevision
+	}
+	return 0
+}
+
+func (x *HistoryResponse) GetCompactRevision() int64 {
+	if x != nil {
+		return x.CompactRevision
+	}
+	return 0
+}
+
+type BackupRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *BackupRequest) Reset() {
+	*x = BackupRequest{}
+	mi := &file_api_kv_storage_proto_msgTypes[47]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *BackupRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BackupRequest) ProtoMessage() {}
+
+func (x *BackupRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_kv_storage_proto_msgTypes[47]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BackupRequest.ProtoReflect.Descriptor instead.
+func (*BackupRequest) Descriptor() ([]byte, []int) {
+	return file_api_kv_storage_proto_rawDescGZIP(), []int{47}
+}
+
+// Резервная копия — это заголовок, пространства имён, записи ключей
+// в порядке пространств имён и ключей и завершающая запись
+type BackupRecord struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Types that are valid to be assigned to Record:
+	//
+	//	*BackupRecord_Header
+	//	*BackupRecord_Namespace
+	//	*BackupRecord_Entry
+	//	*BackupRecord_Chunk
+	//	*BackupRecord_Trailer
+	Record        isBackupRecord_Record `protobuf_oneof:"record"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *BackupRecord) Reset() {
+	*x = BackupRecord{}
+	mi := &file_api_kv_storage_proto_msgTypes[48]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *BackupRecord) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BackupRecord) ProtoMessage() {}
+
+func (x *BackupRecord) ProtoReflect() protoreflect.Message {
+	mi := &file_api_kv_storage_proto_msgTypes[48]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BackupRecord.ProtoReflect.Descriptor instead.
+func (*BackupRecord) Descriptor() ([]byte, []int) {
+	return file_api_kv_storage_proto_rawDescGZIP(), []int{48}
+}
+
+func (x *BackupRecord) GetRecord() isBackupRecord_Record {
+	if x != nil {
+		return x.Record
+	}
+	return nil
+}
+
+func (x *BackupRecord) GetHeader() *BackupHeader {
+	if x != nil {
+		if x, ok := x.Record.(*BackupRecord_Header); ok {
+			return x.Header
+		}
+	}
+	return nil
+}
+
+func (x *BackupRecord) GetNamespace() *BackupNamespace {
+	if x != nil {
+		if x, ok := x.Record.(*BackupRecord_Namespace); ok {
+			return x.Namespace
+		}
+	}
+	return nil
+}
+
+func (x *BackupRecord) GetEntry() *BackupEntry {
+	if x != nil {
+		if x, ok := x.Record.(*BackupRecord_Entry); ok {
+			return x.Entry
+		}
+	}
+	return nil
+}
+
+func (x *BackupRecord) GetChunk() []byte {
+	if x != nil {
+		if x, ok := x.Record.(*BackupRecord_Chunk); ok {
+			return x.Chunk
+		}
+	}
+	return nil
+}
+
+func (x *BackupRecord) GetTrailer() *BackupTrailer {
+	if x != nil {
+		if x, ok := x.Record.(*BackupRecord_Trailer); ok {
+			return x.Trailer
+		}
+	}
+	return nil
+}
+
+type isBackupRecord_Record interface {
+	isBackupRecord_Record()
+}
+
+type BackupRecord_Header struct {
+	Header *BackupHeader `protobuf:"bytes,1,opt,name=header,proto3,oneof"`
+}
+
+type BackupRecord_Namespace struct {
+	Namespace *BackupNamespace `protobuf:"bytes,2,opt,name=namespace,proto3,oneof"`
+}
+
+type BackupRecord_Entry struct {
+	Entry *BackupEntry `protobuf:"bytes,3,opt,name=entry,proto3,oneof"`
+}
+
+type BackupRecord_Chunk struct {
+	// Очередная часть большого значения предыдущей записи entry
+	Chunk []byte `protobuf:"bytes,4,opt,name=chunk,proto3,oneof"`
+}
+
+type BackupRecord_Trailer struct {
+	Trailer *BackupTrailer `protobuf:"bytes,5,opt,name=trailer,proto3,oneof"`
+}
+
+func (*BackupRecord_Header) isBackupRecord_Record() {}
+
+func (*BackupRecord_Namespace) isBackupRecord_Record() {}
+
+func (*BackupRecord_Entry) isBackupRecord_Record() {}
+
+func (*BackupRecord_Chunk) isBackupRecord_Record() {}
+
+func (*BackupRecord_Trailer) isBackupRecord_Record() {}
+
+type BackupHeader struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Версия формата
+	Version uint32 `protobuf:"varint,1,opt,name=version,proto3" json:"version,omitempty"`
+	// Ревизия данных, на которой снят снимок
+	Revision    int64 `protobuf:"varint,2,opt,name=revision,proto3" json:"revision,omitempty"`
+	LeaderEpoch int64 `protobuf:"varint,3,opt,name=leader_epoch,json=leaderEpoch,proto3" json:"leader_epoch,omitempty"`
+	// Время создания в unix-наносекундах
+	CreatedAt     int64  `protobuf:"varint,4,opt,name=created_at,json=createdAt,proto3" json:"created_at,omitempty"`
+	NodeId        string `protobuf:"bytes,5,opt,name=node_id,json=nodeId,proto3" json:"node_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *BackupHeader) Reset() {
+	*x = BackupHeader{}
+	mi := &file_api_kv_storage_proto_msgTypes[49]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *BackupHeader) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BackupHeader) ProtoMessage() {}
+
+func (x *BackupHeader) ProtoReflect() protoreflect.Message {
+	mi := &file_api_kv_storage_proto_msgTypes[49]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BackupHeader.ProtoReflect.Descriptor instead.
+func (*BackupHeader) Descriptor() ([]byte, []int) {
+	return file_api_kv_storage_proto_rawDescGZIP(), []int{49}
+}
+
+func (x *BackupHeader) GetVersion() uint32 {
+	if x != nil {
+		return x.Version
+	}
+	return 0
+}
+
+func (x *BackupHeader) GetRevision() int64 {
+	if x != nil {
+		return x.Revision
+	}
+	return 0
+}
+
+func (x *BackupHeader) GetLeaderEpoch() int64 {
+	if x != nil {
+		return x.LeaderEpoch
+	}
+	return 0
+}
+
+func (x *BackupHeader) GetCreatedAt() int64 {
+	if x != nil {
+		return x.CreatedAt
+	}
+	return 0
+}
+
+func (x *BackupHeader) GetNodeId() string {
+	if x != nil {
+		return x.NodeId
+	}
+	return ""
+}
+
+type BackupNamespace struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Name          string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Quota         *NamespaceQuota        `protobuf:"bytes,2,opt,name=quota,proto3" json:"quota,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *BackupNamespace) Reset() {
+	*x = BackupNamespace{}
+	mi := &file_api_kv_storage_proto_msgTypes[50]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *BackupNamespace) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BackupNamespace) ProtoMessage() {}
+
+func (x *BackupNamespace) ProtoReflect() protoreflect.Message {
+	mi := &file_api_kv_storage_proto_msgTypes[50]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BackupNamespace.ProtoReflect.Descriptor instead.
+func (*BackupNamespace) Descriptor() ([]byte, []int) {
+	return file_api_kv_storage_proto_rawDescGZIP(), []int{50}
+}
+
+func (x *BackupNamespace) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *BackupNamespace) GetQuota() *NamespaceQuota {
+	if x != nil {
+		return x.Quota
+	}
+	return nil
+}
+
+type BackupEntry struct {
+	state     protoimpl.MessageState `protogen:"open.v1"`
+	Namespace string                 `protobuf:"bytes,1,opt,name=namespace,proto3" json:"namespace,omitempty"`
+	Key       string                 `protobuf:"bytes,2,opt,name=key,proto3" json:"key,omitempty"`
+	// Значение без сжатия, пусто для большого значения
+	Value       []byte `protobuf:"bytes,3,opt,name=value,proto3" json:"value,omitempty"`
+	ContentType string `protobuf:"bytes,4,opt,name=content_type,json=contentType,proto3" json:"content_type,omitempty"`
+	// Время истечения ключа в unix-наносекундах
+	Expiration int64 `protobuf:"varint,5,opt,name=expiration,proto3" json:"expiration,omitempty"`
+	// Ревизия последнего изменения ключа
+	Revision int64 `protobuf:"varint,6,opt,name=revision,proto3" json:"revision,omitempty"`
+	// Большое значение размером size следует частями в записях chunk
+	Large         bool  `protobuf:"varint,7,opt,name=large,proto3" json:"large,omitempty"`
+	Size          int64 `protobuf:"varint,8,opt,name=size,proto3" json:"size,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *BackupEntry) Reset() {
+	*x = BackupEntry{}
+	mi := &file_api_kv_storage_proto_msgTypes[51]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *BackupEntry) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BackupEntry) ProtoMessage() {}
+
+func (x *BackupEntry) ProtoReflect() protoreflect.Message {
+	mi := &file_api_kv_storage_proto_msgTypes[51]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BackupEntry.ProtoReflect.Descriptor instead.
+func (*BackupEntry) Descriptor() ([]byte, []int) {
+	return file_api_kv_storage_proto_rawDescGZIP(), []int{51}
+}
+
+func (x *BackupEntry) GetNamespace() string {
+	if x != nil {
+		return x.Namespace
+	}
+	return ""
+}
+
+func (x *BackupEntry) GetKey() string {
+	if x != nil {
+		return x.Key
+	}
+	return ""
+}
+
+func (x *BackupEntry) GetValue() []byte {
+	if x != nil {
+		return x.Value
+	}
+	return nil
+}
+
+func (x *BackupEntry) GetContentType() string {
+	if x != nil {
+		return x.ContentType
+	}
+	return ""
+}
+
+func (x *BackupEntry) GetExpiration() int64 {
+	if x != nil {
+		return x.Expiration
+	}
+	return 0
+}
+
+func (x *BackupEntry) GetRevision() int64 {
+	if x != nil {
+		return x.Revision
+	}
+	return 0
+}
+
+func (x *BackupEntry) GetLarge() bool {
+	if x != nil {
+		return x.Large
+	}
+	return false
+}
+
+func (x *BackupEntry) GetSize() int64 {
+	if x != nil {
+		return x.Size
+	}
+	return 0
+}
+
+type BackupTrailer struct {
+	state      protoimpl.MessageState `protogen:"open.v1"`
+	Namespaces int64                  `protobuf:"varint,1,opt,name=namespaces,proto3" json:"namespaces,omitempty"`
+	Entries    int64                  `protobuf:"varint,2,opt,name=entries,proto3" json:"entries,omitempty"`
+	// SHA-256 пространств имён и записей, см. internal/backup
+	Checksum      []byte `protobuf:"bytes,3,opt,name=checksum,proto3" json:"checksum,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *BackupTrailer) Reset() {
+	*x = BackupTrailer{}
+	mi := &file_api_kv_storage_proto_msgTypes[52]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *LeMetaResponse) String() string {
+func (x *BackupTrailer) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*LeMetaResponse) ProtoMessage() {}
+func (*BackupTrailer) ProtoMessage() {}
 
-func (x *LeMetaResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_api_kv_storage_proto_msgTypes[11]
+func (x *BackupTrailer) ProtoReflect() protoreflect.Message {
+	mi := &file_api_kv_storage_proto_msgTypes[52]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -563,48 +3081,57 @@ func (x *LeMetaResponse) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use LeMetaResponse.ProtoReflect.Descriptor instead.
-func (*LeMetaResponse) Descriptor() ([]byte, []int) {
-	return file_api_kv_storage_proto_rawDescGZIP(), []int{11}
+// Deprecated: Use BackupTrailer.ProtoReflect.Descriptor instead.
+func (*BackupTrailer) Descriptor() ([]byte, []int) {
+	return file_api_kv_storage_proto_rawDescGZIP(), []int{52}
 }
 
-func (x *LeMetaResponse) GetNomadId() string {
+func (x *BackupTrailer) GetNamespaces() int64 {
 	if x != nil {
-		return x.NomadId
+		return x.Namespaces
 	}
-	return ""
+	return 0
 }
 
-func (x *LeMetaResponse) GetDataVersion() int64 {
+func (x *BackupTrailer) GetEntries() int64 {
 	if x != nil {
-		return x.DataVersion
+		return x.Entries
 	}
 	return 0
 }
 
-type UpdateLeaderRequest struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	NomadId       string                 `protobuf:"bytes,1,opt,name=nomad_id,json=nomadId,proto3" json:"nomad_id,omitempty"`
-	Address       string                 `protobuf:"bytes,2,opt,name=address,proto3" json:"address,omitempty"`
+func (x *BackupTrailer) GetChecksum() []byte {
+	if x != nil {
+		return x.Checksum
+	}
+	return nil
+}
+
+type RestoreResponse struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Ревизия данных после восстановления
+	Revision      int64 `protobuf:"varint,1,opt,name=revision,proto3" json:"revision,omitempty"`
+	Namespaces    int64 `protobuf:"varint,2,opt,name=namespaces,proto3" json:"namespaces,omitempty"`
+	Entries       int64 `protobuf:"varint,3,opt,name=entries,proto3" json:"entries,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *UpdateLeaderRequest) Reset() {
-	*x = UpdateLeaderRequest{}
-	mi := &file_api_kv_storage_proto_msgTypes[12]
+func (x *RestoreResponse) Reset() {
+	*x = RestoreResponse{}
+	mi := &file_api_kv_storage_proto_msgTypes[53]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *UpdateLeaderRequest) String() string {
+func (x *RestoreResponse) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*UpdateLeaderRequest) ProtoMessage() {}
+func (*RestoreResponse) ProtoMessage() {}
 
-func (x *UpdateLeaderRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_api_kv_storage_proto_msgTypes[12]
+func (x *RestoreResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_api_kv_storage_proto_msgTypes[53]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -615,46 +3142,144 @@ func (x *UpdateLeaderRequest) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use UpdateLeaderRequest.ProtoReflect.Descriptor instead.
-func (*UpdateLeaderRequest) Descriptor() ([]byte, []int) {
-	return file_api_kv_storage_proto_rawDescGZIP(), []int{12}
+// Deprecated: Use RestoreResponse.ProtoReflect.Descriptor instead.
+func (*RestoreResponse) Descriptor() ([]byte, []int) {
+	return file_api_kv_storage_proto_rawDescGZIP(), []int{53}
 }
 
-func (x *UpdateLeaderRequest) GetNomadId() string {
+func (x *RestoreResponse) GetRevision() int64 {
 	if x != nil {
-		return x.NomadId
+		return x.Revision
+	}
+	return 0
+}
+
+func (x *RestoreResponse) GetNamespaces() int64 {
+	if x != nil {
+		return x.Namespaces
+	}
+	return 0
+}
+
+func (x *RestoreResponse) GetEntries() int64 {
+	if x != nil {
+		return x.Entries
+	}
+	return 0
+}
+
+// Ключ с значением для выгрузки и загрузки
+type KeyValueRecord struct {
+	state       protoimpl.MessageState `protogen:"open.v1"`
+	Namespace   string                 `protobuf:"bytes,1,opt,name=namespace,proto3" json:"namespace,omitempty"`
+	Key         string                 `protobuf:"bytes,2,opt,name=key,proto3" json:"key,omitempty"`
+	Value       []byte                 `protobuf:"bytes,3,opt,name=value,proto3" json:"value,omitempty"`
+	ContentType string                 `protobuf:"bytes,4,opt,name=content_type,json=contentType,proto3" json:"content_type,omitempty"`
+	// Время истечения ключа в unix-наносекундах, 0 — без TTL
+	Expiration int64 `protobuf:"varint,5,opt,name=expiration,proto3" json:"expiration,omitempty"`
+	// Ревизия последнего изменения ключа, при загрузке не используется
+	Revision      int64 `protobuf:"varint,6,opt,name=revision,proto3" json:"revision,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *KeyValueRecord) Reset() {
+	*x = KeyValueRecord{}
+	mi := &file_api_kv_storage_proto_msgTypes[54]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *KeyValueRecord) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*KeyValueRecord) ProtoMessage() {}
+
+func (x *KeyValueRecord) ProtoReflect() protoreflect.Message {
+	mi := &file_api_kv_storage_proto_msgTypes[54]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use KeyValueRecord.ProtoReflect.Descriptor instead.
+func (*KeyValueRecord) Descriptor() ([]byte, []int) {
+	return file_api_kv_storage_proto_rawDescGZIP(), []int{54}
+}
+
+func (x *KeyValueRecord) GetNamespace() string {
+	if x != nil {
+		return x.Namespace
 	}
 	return ""
 }
 
-func (x *UpdateLeaderRequest) GetAddress() string {
+func (x *KeyValueRecord) GetKey() string {
 	if x != nil {
-		return x.Address
+		return x.Key
 	}
 	return ""
 }
 
-type UpdateLeaderResponse struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
+func (x *KeyValueRecord) GetValue() []byte {
+	if x != nil {
+		return x.Value
+	}
+	return nil
+}
+
+func (x *KeyValueRecord) GetContentType() string {
+	if x != nil {
+		return x.ContentType
+	}
+	return ""
+}
+
+func (x *KeyValueRecord) GetExpiration() int64 {
+	if x != nil {
+		return x.Expiration
+	}
+	return 0
+}
+
+func (x *KeyValueRecord) GetRevision() int64 {
+	if x != nil {
+		return x.Revision
+	}
+	return 0
+}
+
+type ExportRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Выгружаемые пространства имён, по умолчанию все
+	Namespaces []string `protobuf:"bytes,1,rep,name=namespaces,proto3" json:"namespaces,omitempty"`
+	// Выгружаются только ключи с этим префиксом
+	Prefix        string `protobuf:"bytes,2,opt,name=prefix,proto3" json:"prefix,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *UpdateLeaderResponse) Reset() {
-	*x = UpdateLeaderResponse{}
-	mi := &file_api_kv_storage_proto_msgTypes[13]
+func (x *ExportRequest) Reset() {
+	*x = ExportRequest{}
+	mi := &file_api_kv_storage_proto_msgTypes[55]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *UpdateLeaderResponse) String() string {
+func (x *ExportRequest) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*UpdateLeaderResponse) ProtoMessage() {}
+func (*ExportRequest) ProtoMessage() {}
 
-func (x *UpdateLeaderResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_api_kv_storage_proto_msgTypes[13]
+func (x *ExportRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_kv_storage_proto_msgTypes[55]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -665,33 +3290,50 @@ func (x *UpdateLeaderResponse) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use UpdateLeaderResponse.ProtoReflect.Descriptor instead.
-func (*UpdateLeaderResponse) Descriptor() ([]byte, []int) {
-	return file_api_kv_storage_proto_rawDescGZIP(), []int{13}
+// Deprecated: Use ExportRequest.ProtoReflect.Descriptor instead.
+func (*ExportRequest) Descriptor() ([]byte, []int) {
+	return file_api_kv_storage_proto_rawDescGZIP(), []int{55}
 }
 
-type UpdateAddressesRequest struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	Addresses     []string               `protobuf:"bytes,1,rep,name=addresses,proto3" json:"addresses,omitempty"`
+func (x *ExportRequest) GetNamespaces() []string {
+	if x != nil {
+		return x.Namespaces
+	}
+	return nil
+}
+
+func (x *ExportRequest) GetPrefix() string {
+	if x != nil {
+		return x.Prefix
+	}
+	return ""
+}
+
+type ExportResponse struct {
+	state   protoimpl.MessageState `protogen:"open.v1"`
+	Records []*KeyValueRecord      `protobuf:"bytes,1,rep,name=records,proto3" json:"records,omitempty"`
+	// Число пропущенных больших значений, передаётся в последнем сообщении.
+	// Большие значения переносятся через Backup
+	SkippedLarge  int64 `protobuf:"varint,2,opt,name=skipped_large,json=skippedLarge,proto3" json:"skipped_large,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *UpdateAddressesRequest) Reset() {
-	*x = UpdateAddressesRequest{}
-	mi := &file_api_kv_storage_proto_msgTypes[14]
+func (x *ExportResponse) Reset() {
+	*x = ExportResponse{}
+	mi := &file_api_kv_storage_proto_msgTypes[56]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *UpdateAddressesRequest) String() string {
+func (x *ExportResponse) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*UpdateAddressesRequest) ProtoMessage() {}
+func (*ExportResponse) ProtoMessage() {}
 
-func (x *UpdateAddressesRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_api_kv_storage_proto_msgTypes[14]
+func (x *ExportResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_api_kv_storage_proto_msgTypes[56]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -702,39 +3344,49 @@ func (x *UpdateAddressesRequest) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use UpdateAddressesRequest.ProtoReflect.Descriptor instead.
-func (*UpdateAddressesRequest) Descriptor() ([]byte, []int) {
-	return file_api_kv_storage_proto_rawDescGZIP(), []int{14}
+// Deprecated: Use ExportResponse.ProtoReflect.Descriptor instead.
+func (*ExportResponse) Descriptor() ([]byte, []int) {
+	return file_api_kv_storage_proto_rawDescGZIP(), []int{56}
 }
 
-func (x *UpdateAddressesRequest) GetAddresses() []string {
+func (x *ExportResponse) GetRecords() []*KeyValueRecord {
 	if x != nil {
-		return x.Addresses
+		return x.Records
 	}
 	return nil
 }
 
-type UpdateAddressesResponse struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
+func (x *ExportResponse) GetSkippedLarge() int64 {
+	if x != nil {
+		return x.SkippedLarge
+	}
+	return 0
+}
+
+type ImportRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Параметры загрузки, задаются в первом сообщении
+	Options       *ImportOptions    `protobuf:"bytes,1,opt,name=options,proto3" json:"options,omitempty"`
+	Records       []*KeyValueRecord `protobuf:"bytes,2,rep,name=records,proto3" json:"records,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *UpdateAddressesResponse) Reset() {
-	*x = UpdateAddressesResponse{}
-	mi := &file_api_kv_storage_proto_msgTypes[15]
+func (x *ImportRequest) Reset() {
+	*x = ImportRequest{}
+	mi := &file_api_kv_storage_proto_msgTypes[57]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *UpdateAddressesResponse) String() string {
+func (x *ImportRequest) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*UpdateAddressesResponse) ProtoMessage() {}
+func (*ImportRequest) ProtoMessage() {}
 
-func (x *UpdateAddressesResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_api_kv_storage_proto_msgTypes[15]
+func (x *ImportRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_kv_storage_proto_msgTypes[57]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -745,30 +3397,231 @@ func (x *UpdateAddressesResponse) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use UpdateAddressesResponse.ProtoReflect.Descriptor instead.
-func (*UpdateAddressesResponse) Descriptor() ([]byte, []int) {
-	return file_api_kv_storage_proto_rawDescGZIP(), []int{15}
+// Deprecated: Use ImportRequest.ProtoReflect.Descriptor instead.
+func (*ImportRequest) Descriptor() ([]byte, []int) {
+	return file_api_kv_storage_proto_rawDescGZIP(), []int{57}
+}
+
+func (x *ImportRequest) GetOptions() *ImportOptions {
+	if x != nil {
+		return x.Options
+	}
+	return nil
+}
+
+func (x *ImportRequest) GetRecords() []*KeyValueRecord {
+	if x != nil {
+		return x.Records
+	}
+	return nil
+}
+
+type ImportOptions struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Загружаются только ключи с этим префиксом, остальные пропускаются
+	Prefix string `protobuf:"bytes,1,opt,name=prefix,proto3" json:"prefix,omitempty"`
+	// Только проверить записи, ничего не сохраняя
+	DryRun        bool `protobuf:"varint,2,opt,name=dry_run,json=dryRun,proto3" json:"dry_run,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ImportOptions) Reset() {
+	*x = ImportOptions{}
+	mi := &file_api_kv_storage_proto_msgTypes[58]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ImportOptions) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ImportOptions) ProtoMessage() {}
+
+func (x *ImportOptions) ProtoReflect() protoreflect.Message {
+	mi := &file_api_kv_storage_proto_msgTypes[58]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ImportOptions.ProtoReflect.Descriptor instead.
+func (*ImportOptions) Descriptor() ([]byte, []int) {
+	return file_api_kv_storage_proto_rawDescGZIP(), []int{58}
+}
+
+func (x *ImportOptions) GetPrefix() string {
+	if x != nil {
+		return x.Prefix
+	}
+	return ""
+}
+
+func (x *ImportOptions) GetDryRun() bool {
+	if x != nil {
+		return x.DryRun
+	}
+	return false
+}
+
+type ImportResponse struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Число сохранённых ключей, при dry_run — прошедших проверку
+	Imported int64 `protobuf:"varint,1,opt,name=imported,proto3" json:"imported,omitempty"`
+	// Пропущены по префиксу
+	Skipped int64 `protobuf:"varint,2,opt,name=skipped,proto3" json:"skipped,omitempty"`
+	// Пропущены, так как срок их жизни уже истёк
+	Expired int64 `protobuf:"varint,3,opt,name=expired,proto3" json:"expired,omitempty"`
+	// Ревизия последней записи
+	Revision      int64 `protobuf:"varint,4,opt,name=revision,proto3" json:"revision,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ImportResponse) Reset() {
+	*x = ImportResponse{}
+	mi := &file_api_kv_storage_proto_msgTypes[59]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ImportResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ImportResponse) ProtoMessage() {}
+
+func (x *ImportResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_api_kv_storage_proto_msgTypes[59]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ImportResponse.ProtoReflect.Descriptor instead.
+func (*ImportResponse) Descriptor() ([]byte, []int) {
+	return file_api_kv_storage_proto_rawDescGZIP(), []int{59}
+}
+
+func (x *ImportResponse) GetImported() int64 {
+	if x != nil {
+		return x.Imported
+	}
+	return 0
+}
+
+func (x *ImportResponse) GetSkipped() int64 {
+	if x != nil {
+		return x.Skipped
+	}
+	return 0
+}
+
+func (x *ImportResponse) GetExpired() int64 {
+	if x != nil {
+		return x.Expired
+	}
+	return 0
+}
+
+func (x *ImportResponse) GetRevision() int64 {
+	if x != nil {
+		return x.Revision
+	}
+	return 0
 }
 
 var File_api_kv_storage_proto protoreflect.FileDescriptor
 
 const file_api_kv_storage_proto_rawDesc = "" +
 	"\n" +
-	"\x14api/kv-storage.proto\x12\x12kv_storage_service\x1a\x19google/protobuf/any.proto\"\x1e\n" +
+	"\x14api/kv-storage.proto\x12\x12kv_storage_service\x1a\x19google/protobuf/any.proto\"j\n" +
 	"\n" +
 	"GetRequest\x12\x10\n" +
-	"\x03key\x18\x01 \x01(\tR\x03key\"9\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x1c\n" +
+	"\tnamespace\x18\x02 \x01(\tR\tnamespace\x12\x1f\n" +
+	"\brevision\x18\x03 \x01(\x03H\x00R\brevision\x88\x01\x01B\v\n" +
+	"\t_revision\"9\n" +
 	"\vGetResponse\x12\x14\n" +
 	"\x05value\x18\x01 \x01(\tR\x05value\x12\x14\n" +
-	"\x05found\x18\x02 \x01(\bR\x05found\"e\n" +
+	"\x05found\x18\x02 \x01(\bR\x05found\"\xde\x04\n" +
 	"\n" +
 	"SetRequest\x12\x10\n" +
 	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
 	"\x05value\x18\x02 \x01(\tR\x05value\x12!\n" +
-	"\toperation\x18\x03 \x01(\tH\x00R\toperation\x88\x01\x01B\f\n" +
+	"\toperation\x18\x03 \x01(\tH\x00R\toperation\x88\x01\x01\x12#\n" +
+	"\n" +
+	"expiration\x18\x04 \x01(\x03H\x01R\n" +
+	"expiration\x88\x01\x01\x12\x19\n" +
+	"\x05lease\x18\x05 \x01(\x03H\x02R\x05lease\x88\x01\x01\x12U\n" +
+	"\rtrace_context\x18\x06 \x03(\v20.kv_storage_service.SetRequest.TraceContextEntryR\ftraceContext\x12\x1c\n" +
+	"\tnamespace\x18\a \x01(\tR\tnamespace\x12 \n" +
+	"\traw_value\x18\b \x01(\fH\x03R\brawValue\x88\x01\x01\x12!\n" +
+	"\fcontent_type\x18\t \x01(\tR\vcontentType\x12\x12\n" +
+	"\x04blob\x18\n" +
+	" \x01(\x03R\x04blob\x12\x12\n" +
+	"\x04size\x18\v \x01(\x03R\x04size\x12\x14\n" +
+	"\x05codec\x18\f \x01(\rR\x05codec\x12\x1a\n" +
+	"\brevision\x18\r \x01(\x03R\brevision\x12;\n" +
+	"\aentries\x18\x0e \x03(\v2!.kv_storage_service.SetBatchEntryR\aentries\x1a?\n" +
+	"\x11TraceContextEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01B\f\n" +
 	"\n" +
-	"_operation\"\r\n" +
-	"\vSetResponse\"#\n" +
+	"_operationB\r\n" +
+	"\v_expirationB\b\n" +
+	"\x06_leaseB\f\n" +
+	"\n" +
+	"_raw_value\"\x90\x01\n" +
+	"\rSetBatchEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\fR\x05value\x12!\n" +
+	"\fcontent_type\x18\x03 \x01(\tR\vcontentType\x12\x1e\n" +
+	"\n" +
+	"expiration\x18\x04 \x01(\x03R\n" +
+	"expiration\x12\x14\n" +
+	"\x05codec\x18\x05 \x01(\rR\x05codec\")\n" +
+	"\vSetResponse\x12\x1a\n" +
+	"\brevision\x18\x01 \x01(\x03R\brevision\"?\n" +
+	"\rDeleteRequest\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x1c\n" +
+	"\tnamespace\x18\x02 \x01(\tR\tnamespace\"F\n" +
+	"\x0eDeleteResponse\x12\x18\n" +
+	"\adeleted\x18\x01 \x01(\bR\adeleted\x12\x1a\n" +
+	"\brevision\x18\x02 \x01(\x03R\brevision\"2\n" +
+	"\bKeyValue\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value\"\xa8\x01\n" +
+	"\vScanRequest\x12\x16\n" +
+	"\x06prefix\x18\x01 \x01(\tR\x06prefix\x12\x1f\n" +
+	"\vstart_after\x18\x02 \x01(\tR\n" +
+	"startAfter\x12\x14\n" +
+	"\x05limit\x18\x03 \x01(\x05R\x05limit\x12\x1c\n" +
+	"\tnamespace\x18\x04 \x01(\tR\tnamespace\x12\x1f\n" +
+	"\brevision\x18\x05 \x01(\x03H\x00R\brevision\x88\x01\x01B\v\n" +
+	"\t_revision\"l\n" +
+	"\fScanResponse\x122\n" +
+	"\x05items\x18\x01 \x03(\v2\x1c.kv_storage_service.KeyValueR\x05items\x12(\n" +
+	"\x10next_start_after\x18\x02 \x01(\tR\x0enextStartAfter\"V\n" +
+	"\fWatchRequest\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x16\n" +
+	"\x06prefix\x18\x02 \x01(\bR\x06prefix\x12\x1c\n" +
+	"\tnamespace\x18\x03 \x01(\tR\tnamespace\"U\n" +
+	"\rWatchResponse\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value\x12\x1c\n" +
+	"\toperation\x18\x03 \x01(\tR\toperation\"#\n" +
 	"\rGossipRequest\x12\x12\n" +
 	"\x04node\x18\x01 \x01(\tR\x04node\"-\n" +
 	"\x0eGossipResponse\x12\x1b\n" +
@@ -782,25 +3635,199 @@ const file_api_kv_storage_proto_rawDesc = "" +
 	"\x14FetchFromSeedRequest\x12\x18\n" +
 	"\aaddress\x18\x01 \x01(\tR\aaddress\"-\n" +
 	"\x15FetchFromSeedResponse\x12\x14\n" +
-	"\x05peers\x18\x01 \x03(\tR\x05peers\"\x0f\n" +
-	"\rLeMetaRequest\"N\n" +
+	"\x05peers\x18\x01 \x03(\tR\x05peers\"\xca\x01\n" +
+	"\x0eCounterRequest\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x19\n" +
+	"\x05delta\x18\x02 \x01(\x03H\x00R\x05delta\x88\x01\x01\x12\x1a\n" +
+	"\x06ttl_ms\x18\x03 \x01(\x03H\x01R\x05ttlMs\x88\x01\x01\x12\x15\n" +
+	"\x03min\x18\x04 \x01(\x03H\x02R\x03min\x88\x01\x01\x12\x15\n" +
+	"\x03max\x18\x05 \x01(\x03H\x03R\x03max\x88\x01\x01\x12\x1c\n" +
+	"\tnamespace\x18\x06 \x01(\tR\tnamespaceB\b\n" +
+	"\x06_deltaB\t\n" +
+	"\a_ttl_msB\x06\n" +
+	"\x04_minB\x06\n" +
+	"\x04_max\"C\n" +
+	"\x0fCounterResponse\x12\x14\n" +
+	"\x05value\x18\x01 \x01(\x03R\x05value\x12\x1a\n" +
+	"\brevision\x18\x02 \x01(\x03R\brevision\"*\n" +
+	"\x11LeaseGrantRequest\x12\x15\n" +
+	"\x06ttl_ms\x18\x01 \x01(\x03R\x05ttlMs\";\n" +
+	"\x12LeaseGrantResponse\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\x03R\x02id\x12\x15\n" +
+	"\x06ttl_ms\x18\x02 \x01(\x03R\x05ttlMs\"'\n" +
+	"\x15LeaseKeepAliveRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\x03R\x02id\"?\n" +
+	"\x16LeaseKeepAliveResponse\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\x03R\x02id\x12\x15\n" +
+	"\x06ttl_ms\x18\x02 \x01(\x03R\x05ttlMs\"$\n" +
+	"\x12LeaseRevokeRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\x03R\x02id\"1\n" +
+	"\x13LeaseRevokeResponse\x12\x1a\n" +
+	"\brevision\x18\x01 \x01(\x03R\brevision\"U\n" +
+	"\vLockRequest\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\x12\x14\n" +
+	"\x05lease\x18\x02 \x01(\x03R\x05lease\x12\x1c\n" +
+	"\tnamespace\x18\x03 \x01(\tR\tnamespace\"E\n" +
+	"\fLockResponse\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12#\n" +
+	"\rfencing_token\x18\x02 \x01(\x03R\ffencingToken\"f\n" +
+	"\rUnlockRequest\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\x12#\n" +
+	"\rfencing_token\x18\x02 \x01(\x03R\ffencingToken\x12\x1c\n" +
+	"\tnamespace\x18\x03 \x01(\tR\tnamespace\",\n" +
+	"\x0eUnlockResponse\x12\x1a\n" +
+	"\brevision\x18\x01 \x01(\x03R\brevision\"\x0f\n" +
+	"\rLeMetaRequest\"q\n" +
 	"\x0eLeMetaResponse\x12\x19\n" +
 	"\bnomad_id\x18\x01 \x01(\tR\anomadId\x12!\n" +
-	"\fdata_version\x18\x02 \x01(\x03R\vdataVersion\"J\n" +
+	"\fdata_version\x18\x02 \x01(\x03R\vdataVersion\x12!\n" +
+	"\fleader_epoch\x18\x03 \x01(\x03R\vleaderEpoch\"`\n" +
 	"\x13UpdateLeaderRequest\x12\x19\n" +
 	"\bnomad_id\x18\x01 \x01(\tR\anomadId\x12\x18\n" +
-	"\aaddress\x18\x02 \x01(\tR\aaddress\"\x16\n" +
+	"\aaddress\x18\x02 \x01(\tR\aaddress\x12\x14\n" +
+	"\x05epoch\x18\x03 \x01(\x03R\x05epoch\"\x16\n" +
 	"\x14UpdateLeaderResponse\"6\n" +
 	"\x16UpdateAddressesRequest\x12\x1c\n" +
 	"\taddresses\x18\x01 \x03(\tR\taddresses\"\x19\n" +
-	"\x17UpdateAddressesResponse2\x93\x04\n" +
+	"\x17UpdateAddressesResponse\"\x9a\x01\n" +
+	"\x0eNamespaceQuota\x12\x19\n" +
+	"\bmax_keys\x18\x01 \x01(\x03R\amaxKeys\x12\x1b\n" +
+	"\tmax_bytes\x18\x02 \x01(\x03R\bmaxBytes\x12.\n" +
+	"\x13requests_per_second\x18\x03 \x01(\x01R\x11requestsPerSecond\x12 \n" +
+	"\vcompression\x18\x04 \x01(\tR\vcompression\"\x83\x01\n" +
+	"\tNamespace\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\x128\n" +
+	"\x05quota\x18\x02 \x01(\v2\".kv_storage_service.NamespaceQuotaR\x05quota\x12\x12\n" +
+	"\x04keys\x18\x03 \x01(\x03R\x04keys\x12\x14\n" +
+	"\x05bytes\x18\x04 \x01(\x03R\x05bytes\"f\n" +
+	"\x16CreateNamespaceRequest\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\x128\n" +
+	"\x05quota\x18\x02 \x01(\v2\".kv_storage_service.NamespaceQuotaR\x05quota\"5\n" +
+	"\x17CreateNamespaceResponse\x12\x1a\n" +
+	"\brevision\x18\x01 \x01(\x03R\brevision\"\x17\n" +
+	"\x15ListNamespacesRequest\"W\n" +
+	"\x16ListNamespacesResponse\x12=\n" +
+	"\n" +
+	"namespaces\x18\x01 \x03(\v2\x1d.kv_storage_service.NamespaceR\n" +
+	"namespaces\"*\n" +
+	"\x14DropNamespaceRequest\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\"V\n" +
+	"\x15DropNamespaceResponse\x12!\n" +
+	"\fdeleted_keys\x18\x01 \x01(\x03R\vdeletedKeys\x12\x1a\n" +
+	"\brevision\x18\x02 \x01(\x03R\brevision\"}\n" +
+	"\x0eHistoryRequest\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x1c\n" +
+	"\tnamespace\x18\x02 \x01(\tR\tnamespace\x12%\n" +
+	"\x0estart_revision\x18\x03 \x01(\x03R\rstartRevision\x12\x14\n" +
+	"\x05limit\x18\x04 \x01(\x05R\x05limit\"\x8c\x01\n" +
+	"\n" +
+	"KeyVersion\x12\x1a\n" +
+	"\brevision\x18\x01 \x01(\x03R\brevision\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value\x12\x18\n" +
+	"\adeleted\x18\x03 \x01(\bR\adeleted\x12\x1e\n" +
+	"\n" +
+	"expiration\x18\x04 \x01(\x03R\n" +
+	"expiration\x12\x12\n" +
+	"\x04time\x18\x05 \x01(\x03R\x04time\"\xa8\x01\n" +
+	"\x0fHistoryResponse\x12:\n" +
+	"\bversions\x18\x01 \x03(\v2\x1e.kv_storage_service.KeyVersionR\bversions\x12.\n" +
+	"\x13next_start_revision\x18\x02 \x01(\x03R\x11nextStartRevision\x12)\n" +
+	"\x10compact_revision\x18\x03 \x01(\x03R\x0fcompactRevision\"\x0f\n" +
+	"\rBackupRequest\"\xa9\x02\n" +
+	"\fBackupRecord\x12:\n" +
+	"\x06header\x18\x01 \x01(\v2 .kv_storage_service.BackupHeaderH\x00R\x06header\x12C\n" +
+	"\tnamespace\x18\x02 \x01(\v2#.kv_storage_service.BackupNamespaceH\x00R\tnamespace\x127\n" +
+	"\x05entry\x18\x03 \x01(\v2\x1f.kv_storage_service.BackupEntryH\x00R\x05entry\x12\x16\n" +
+	"\x05chunk\x18\x04 \x01(\fH\x00R\x05chunk\x12=\n" +
+	"\atrailer\x18\x05 \x01(\v2!.kv_storage_service.BackupTrailerH\x00R\atrailerB\b\n" +
+	"\x06record\"\x9f\x01\n" +
+	"\fBackupHeader\x12\x18\n" +
+	"\aversion\x18\x01 \x01(\rR\aversion\x12\x1a\n" +
+	"\brevision\x18\x02 \x01(\x03R\brevision\x12!\n" +
+	"\fleader_epoch\x18\x03 \x01(\x03R\vleaderEpoch\x12\x1d\n" +
+	"\n" +
+	"created_at\x18\x04 \x01(\x03R\tcreatedAt\x12\x17\n" +
+	"\anode_id\x18\x05 \x01(\tR\x06nodeId\"_\n" +
+	"\x0fBackupNamespace\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\x128\n" +
+	"\x05quota\x18\x02 \x01(\v2\".kv_storage_service.NamespaceQuotaR\x05quota\"\xdc\x01\n" +
+	"\vBackupEntry\x12\x1c\n" +
+	"\tnamespace\x18\x01 \x01(\tR\tnamespace\x12\x10\n" +
+	"\x03key\x18\x02 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x03 \x01(\fR\x05value\x12!\n" +
+	"\fcontent_type\x18\x04 \x01(\tR\vcontentType\x12\x1e\n" +
+	"\n" +
+	"expiration\x18\x05 \x01(\x03R\n" +
+	"expiration\x12\x1a\n" +
+	"\brevision\x18\x06 \x01(\x03R\brevision\x12\x14\n" +
+	"\x05large\x18\a \x01(\bR\x05large\x12\x12\n" +
+	"\x04size\x18\b \x01(\x03R\x04size\"e\n" +
+	"\rBackupTrailer\x12\x1e\n" +
+	"\n" +
+	"namespaces\x18\x01 \x01(\x03R\n" +
+	"namespaces\x12\x18\n" +
+	"\aentries\x18\x02 \x01(\x03R\aentries\x12\x1a\n" +
+	"\bchecksum\x18\x03 \x01(\fR\bchecksum\"g\n" +
+	"\x0fRestoreResponse\x12\x1a\n" +
+	"\brevision\x18\x01 \x01(\x03R\brevision\x12\x1e\n" +
+	"\n" +
+	"namespaces\x18\x02 \x01(\x03R\n" +
+	"namespaces\x12\x18\n" +
+	"\aentries\x18\x03 \x01(\x03R\aentries\"\xb5\x01\n" +
+	"\x0eKeyValueRecord\x12\x1c\n" +
+	"\tnamespace\x18\x01 \x01(\tR\tnamespace\x12\x10\n" +
+	"\x03key\x18\x02 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x03 \x01(\fR\x05value\x12!\n" +
+	"\fcontent_type\x18\x04 \x01(\tR\vcontentType\x12\x1e\n" +
+	"\n" +
+	"expiration\x18\x05 \x01(\x03R\n" +
+	"expiration\x12\x1a\n" +
+	"\brevision\x18\x06 \x01(\x03R\brevision\"G\n" +
+	"\rExportRequest\x12\x1e\n" +
+	"\n" +
+	"namespaces\x18\x01 \x03(\tR\n" +
+	"namespaces\x12\x16\n" +
+	"\x06prefix\x18\x02 \x01(\tR\x06prefix\"s\n" +
+	"\x0eExportResponse\x12<\n" +
+	"\arecords\x18\x01 \x03(\v2\".kv_storage_service.KeyValueRecordR\arecords\x12#\n" +
+	"\rskipped_large\x18\x02 \x01(\x03R\fskippedLarge\"\x8a\x01\n" +
+	"\rImportRequest\x12;\n" +
+	"\aoptions\x18\x01 \x01(\v2!.kv_storage_service.ImportOptionsR\aoptions\x12<\n" +
+	"\arecords\x18\x02 \x03(\v2\".kv_storage_service.KeyValueRecordR\arecords\"@\n" +
+	"\rImportOptions\x12\x16\n" +
+	"\x06prefix\x18\x01 \x01(\tR\x06prefix\x12\x17\n" +
+	"\adry_run\x18\x02 \x01(\bR\x06dryRun\"|\n" +
+	"\x0eImportResponse\x12\x1a\n" +
+	"\bimported\x18\x01 \x01(\x03R\bimported\x12\x18\n" +
+	"\askipped\x18\x02 \x01(\x03R\askipped\x12\x18\n" +
+	"\aexpired\x18\x03 \x01(\x03R\aexpired\x12\x1a\n" +
+	"\brevision\x18\x04 \x01(\x03R\brevision2\xcb\x10\n" +
 	"\x0fKeyValueStorage\x12F\n" +
 	"\x03Get\x12\x1e.kv_storage_service.GetRequest\x1a\x1f.kv_storage_service.GetResponse\x12F\n" +
 	"\x03Set\x12\x1e.kv_storage_service.SetRequest\x1a\x1f.kv_storage_service.SetResponse\x12P\n" +
 	"\tSetStream\x12\x1e.kv_storage_service.SetRequest\x1a\x1f.kv_storage_service.SetResponse(\x010\x01\x12O\n" +
 	"\x06LeMeta\x12!.kv_storage_service.LeMetaRequest\x1a\".kv_storage_service.LeMetaResponse\x12a\n" +
 	"\fUpdateLeader\x12'.kv_storage_service.UpdateLeaderRequest\x1a(.kv_storage_service.UpdateLeaderResponse\x12j\n" +
-	"\x0fUpdateAddresses\x12*.kv_storage_service.UpdateAddressesRequest\x1a+.kv_storage_service.UpdateAddressesResponseBQZOgithub.com/Na322Pr/kv-storage-service/pkg/kv-storage-service;kv_storage_serviceb\x06proto3"
+	"\x0fUpdateAddresses\x12*.kv_storage_service.UpdateAddressesRequest\x1a+.kv_storage_service.UpdateAddressesResponse\x12O\n" +
+	"\x06Delete\x12!.kv_storage_service.DeleteRequest\x1a\".kv_storage_service.DeleteResponse\x12I\n" +
+	"\x04Scan\x12\x1f.kv_storage_service.ScanRequest\x1a .kv_storage_service.ScanResponse\x12N\n" +
+	"\x05Watch\x12 .kv_storage_service.WatchRequest\x1a!.kv_storage_service.WatchResponse0\x01\x12T\n" +
+	"\tIncrement\x12\".kv_storage_service.CounterRequest\x1a#.kv_storage_service.CounterResponse\x12T\n" +
+	"\tDecrement\x12\".kv_storage_service.CounterRequest\x1a#.kv_storage_service.CounterResponse\x12[\n" +
+	"\n" +
+	"LeaseGrant\x12%.kv_storage_service.LeaseGrantRequest\x1a&.kv_storage_service.LeaseGrantResponse\x12k\n" +
+	"\x0eLeaseKeepAlive\x12).kv_storage_service.LeaseKeepAliveRequest\x1a*.kv_storage_service.LeaseKeepAliveResponse(\x010\x01\x12^\n" +
+	"\vLeaseRevoke\x12&.kv_storage_service.LeaseRevokeRequest\x1a'.kv_storage_service.LeaseRevokeResponse\x12I\n" +
+	"\x04Lock\x12\x1f.kv_storage_service.LockRequest\x1a .kv_storage_service.LockResponse\x12O\n" +
+	"\x06Unlock\x12!.kv_storage_service.UnlockRequest\x1a\".kv_storage_service.UnlockResponse\x12j\n" +
+	"\x0fCreateNamespace\x12*.kv_storage_service.CreateNamespaceRequest\x1a+.kv_storage_service.CreateNamespaceResponse\x12g\n" +
+	"\x0eListNamespaces\x12).kv_storage_service.ListNamespacesRequest\x1a*.kv_storage_service.ListNamespacesResponse\x12d\n" +
+	"\rDropNamespace\x12(.kv_storage_service.DropNamespaceRequest\x1a).kv_storage_service.DropNamespaceResponse\x12R\n" +
+	"\aHistory\x12\".kv_storage_service.HistoryRequest\x1a#.kv_storage_service.HistoryResponse\x12O\n" +
+	"\x06Backup\x12!.kv_storage_service.BackupRequest\x1a .kv_storage_service.BackupRecord0\x01\x12R\n" +
+	"\aRestore\x12 .kv_storage_service.BackupRecord\x1a#.kv_storage_service.RestoreResponse(\x01\x12Q\n" +
+	"\x06Export\x12!.kv_storage_service.ExportRequest\x1a\".kv_storage_service.ExportResponse0\x01\x12Q\n" +
+	"\x06Import\x12!.kv_storage_service.ImportRequest\x1a\".kv_storage_service.ImportResponse(\x01BQZOgithub.com/Na322Pr/kv-storage-service/pkg/kv-storage-service;kv_storage_serviceb\x06proto3"
 
 var (
 	file_api_kv_storage_proto_rawDescOnce sync.Once
@@ -814,43 +3841,139 @@ func file_api_kv_storage_proto_rawDescGZIP() []byte {
 	return file_api_kv_storage_proto_rawDescData
 }
 
-var file_api_kv_storage_proto_msgTypes = make([]protoimpl.MessageInfo, 16)
+var file_api_kv_storage_proto_msgTypes = make([]protoimpl.MessageInfo, 61)
 var file_api_kv_storage_proto_goTypes = []any{
 	(*GetRequest)(nil),              // 0: kv_storage_service.GetRequest
 	(*GetResponse)(nil),             // 1: kv_storage_service.GetResponse
 	(*SetRequest)(nil),              // 2: kv_storage_service.SetRequest
-	(*SetResponse)(nil),             // 3: kv_storage_service.SetResponse
-	(*GossipRequest)(nil),           // 4: kv_storage_service.GossipRequest
-	(*GossipResponse)(nil),          // 5: kv_storage_service.GossipResponse
-	(*LeaderVoteRequest)(nil),       // 6: kv_storage_service.LeaderVoteRequest
-	(*LeaderVoteResponse)(nil),      // 7: kv_storage_service.LeaderVoteResponse
-	(*FetchFromSeedRequest)(nil),    // 8: kv_storage_service.FetchFromSeedRequest
-	(*FetchFromSeedResponse)(nil),   // 9: kv_storage_service.FetchFromSeedResponse
-	(*LeMetaRequest)(nil),           // 10: kv_storage_service.LeMetaRequest
-	(*LeMetaResponse)(nil),          // 11: kv_storage_service.LeMetaResponse
-	(*UpdateLeaderRequest)(nil),     // 12: kv_storage_service.UpdateLeaderRequest
-	(*UpdateLeaderResponse)(nil),    // 13: kv_storage_service.UpdateLeaderResponse
-	(*UpdateAddressesRequest)(nil),  // 14: kv_storage_service.UpdateAddressesRequest
-	(*UpdateAddressesResponse)(nil), // 15: kv_storage_service.UpdateAddressesResponse
+	(*SetBatchEntry)(nil),           // 3: kv_storage_service.SetBatchEntry
+	(*SetResponse)(nil),             // 4: kv_storage_service.SetResponse
+	(*DeleteRequest)(nil),           // 5: kv_storage_service.DeleteRequest
+	(*DeleteResponse)(nil),          // 6: kv_storage_service.DeleteResponse
+	(*KeyValue)(nil),                // 7: kv_storage_service.KeyValue
+	(*ScanRequest)(nil),             // 8: kv_storage_service.ScanRequest
+	(*ScanResponse)(nil),            // 9: kv_storage_service.ScanResponse
+	(*WatchRequest)(nil),            // 10: kv_storage_service.WatchRequest
+	(*WatchResponse)(nil),           // 11: kv_storage_service.WatchResponse
+	(*GossipRequest)(nil),           // 12: kv_storage_service.GossipRequest
+	(*GossipResponse)(nil),          // 13: kv_storage_service.GossipResponse
+	(*LeaderVoteRequest)(nil),       // 14: kv_storage_service.LeaderVoteRequest
+	(*LeaderVoteResponse)(nil),      // 15: kv_storage_service.LeaderVoteResponse
+	(*FetchFromSeedRequest)(nil),    // 16: kv_storage_service.FetchFromSeedRequest
+	(*FetchFromSeedResponse)(nil),   // 17: kv_storage_service.FetchFromSeedResponse
+	(*CounterRequest)(nil),          // 18: kv_storage_service.CounterRequest
+	(*CounterResponse)(nil),         // 19: kv_storage_service.CounterResponse
+	(*LeaseGrantRequest)(nil),       // 20: kv_storage_service.LeaseGrantRequest
+	(*LeaseGrantResponse)(nil),      // 21: kv_storage_service.LeaseGrantResponse
+	(*LeaseKeepAliveRequest)(nil),   // 22: kv_storage_service.LeaseKeepAliveRequest
+	(*LeaseKeepAliveResponse)(nil),  // 23: kv_storage_service.LeaseKeepAliveResponse
+	(*LeaseRevokeRequest)(nil),      // 24: kv_storage_service.LeaseRevokeRequest
+	(*LeaseRevokeResponse)(nil),     // 25: kv_storage_service.LeaseRevokeResponse
+	(*LockRequest)(nil),             // 26: kv_storage_service.LockRequest
+	(*LockResponse)(nil),            // 27: kv_storage_service.LockResponse
+	(*UnlockRequest)(nil),           // 28: kv_storage_service.UnlockRequest
+	(*UnlockResponse)(nil),          // 29: kv_storage_service.UnlockResponse
+	(*LeMetaRequest)(nil),           // 30: kv_storage_service.LeMetaRequest
+	(*LeMetaResponse)(nil),          // 31: kv_storage_service.LeMetaResponse
+	(*UpdateLeaderRequest)(nil),     // 32: kv_storage_service.UpdateLeaderRequest
+	(*UpdateLeaderResponse)(nil),    // 33: kv_storage_service.UpdateLeaderResponse
+	(*UpdateAddressesRequest)(nil),  // 34: kv_storage_service.UpdateAddressesRequest
+	(*UpdateAddressesResponse)(nil), // 35: kv_storage_service.UpdateAddressesResponse
+	(*NamespaceQuota)(nil),          // 36: kv_storage_service.NamespaceQuota
+	(*Namespace)(nil),               // 37: kv_storage_service.Namespace
+	(*CreateNamespaceRequest)(nil),  // 38: kv_storage_service.CreateNamespaceRequest
+	(*CreateNamespaceResponse)(nil), // 39: kv_storage_service.CreateNamespaceResponse
+	(*ListNamespacesRequest)(nil),   // 40: kv_storage_service.ListNamespacesRequest
+	(*ListNamespacesResponse)(nil),  // 41: kv_storage_service.ListNamespacesResponse
+	(*DropNamespaceRequest)(nil),    // 42: kv_storage_service.DropNamespaceRequest
+	(*DropNamespaceResponse)(nil),   // 43: kv_storage_service.DropNamespaceResponse
+	(*HistoryRequest)(nil),          // 44: kv_storage_service.HistoryRequest
+	(*KeyVersion)(nil),              // 45: kv_storage_service.KeyVersion
+	(*HistoryResponse)(nil),         // 46: kv_storage_service.HistoryResponse
+	(*BackupRequest)(nil),           // 47: kv_storage_service.BackupRequest
+	(*BackupRecord)(nil),            // 48: kv_storage_service.BackupRecord
+	(*BackupHeader)(nil),            // 49: kv_storage_service.BackupHeader
+	(*BackupNamespace)(nil),         // 50: kv_storage_service.BackupNamespace
+	(*BackupEntry)(nil),             // 51: kv_storage_service.BackupEntry
+	(*BackupTrailer)(nil),           // 52: kv_storage_service.BackupTrailer
+	(*RestoreResponse)(nil),         // 53: kv_storage_service.RestoreResponse
+	(*KeyValueRecord)(nil),          // 54: kv_storage_service.KeyValueRecord
+	(*ExportRequest)(nil),           // 55: kv_storage_service.ExportRequest
+	(*ExportResponse)(nil),          // 56: kv_storage_service.ExportResponse
+	(*ImportRequest)(nil),           // 57: kv_storage_service.ImportRequest
+	(*ImportOptions)(nil),           // 58: kv_storage_service.ImportOptions
+	(*ImportResponse)(nil),          // 59: kv_storage_service.ImportResponse
+	nil,                             // 60: kv_storage_service.SetRequest.TraceContextEntry
 }
 var file_api_kv_storage_proto_depIdxs = []int32{
-	0,  // 0: kv_storage_service.KeyValueStorage.Get:input_type -> kv_storage_service.GetRequest
-	2,  // 1: kv_storage_service.KeyValueStorage.Set:input_type -> kv_storage_service.SetRequest
-	2,  // 2: kv_storage_service.KeyValueStorage.SetStream:input_type -> kv_storage_service.SetRequest
-	10, // 3: kv_storage_service.KeyValueStorage.LeMeta:input_type -> kv_storage_service.LeMetaRequest
-	12, // 4: kv_storage_service.KeyValueStorage.UpdateLeader:input_type -> kv_storage_service.UpdateLeaderRequest
-	14, // 5: kv_storage_service.KeyValueStorage.UpdateAddresses:input_type -> kv_storage_service.UpdateAddressesRequest
-	1,  // 6: kv_storage_service.KeyValueStorage.Get:output_type -> kv_storage_service.GetResponse
-	3,  // 7: kv_storage_service.KeyValueStorage.Set:output_type -> kv_storage_service.SetResponse
-	3,  // 8: kv_storage_service.KeyValueStorage.SetStream:output_type -> kv_storage_service.SetResponse
-	11, // 9: kv_storage_service.KeyValueStorage.LeMeta:output_type -> kv_storage_service.LeMetaResponse
-	13, // 10: kv_storage_service.KeyValueStorage.UpdateLeader:output_type -> kv_storage_service.UpdateLeaderResponse
-	15, // 11: kv_storage_service.KeyValueStorage.UpdateAddresses:output_type -> kv_storage_service.UpdateAddressesResponse
-	6,  // [6:12] is the sub-list for method output_type
-	0,  // [0:6] is the sub-list for method input_type
-	0,  // [0:0] is the sub-list for extension type_name
-	0,  // [0:0] is the sub-list for extension extendee
-	0,  // [0:0] is the sub-list for field type_name
+	60, // 0: kv_storage_service.SetRequest.trace_context:type_name -> kv_storage_service.SetRequest.TraceContextEntry
+	3,  // 1: kv_storage_service.SetRequest.entries:type_name -> kv_storage_service.SetBatchEntry
+	7,  // 2: kv_storage_service.ScanResponse.items:type_name -> kv_storage_service.KeyValue
+	36, // 3: kv_storage_service.Namespace.quota:type_name -> kv_storage_service.NamespaceQuota
+	36, // 4: kv_storage_service.CreateNamespaceRequest.quota:type_name -> kv_storage_service.NamespaceQuota
+	37, // 5: kv_storage_service.ListNamespacesResponse.namespaces:type_name -> kv_storage_service.Namespace
+	45, // 6: kv_storage_service.HistoryResponse.versions:type_name -> kv_storage_service.KeyVersion
+	49, // 7: kv_storage_service.BackupRecord.header:type_name -> kv_storage_service.BackupHeader
+	50, // 8: kv_storage_service.BackupRecord.namespace:type_name -> kv_storage_service.BackupNamespace
+	51, // 9: kv_storage_service.BackupRecord.entry:type_name -> kv_storage_service.BackupEntry
+	52, // 10: kv_storage_service.BackupRecord.trailer:type_name -> kv_storage_service.BackupTrailer
+	36, // 11: kv_storage_service.BackupNamespace.quota:type_name -> kv_storage_service.NamespaceQuota
+	54, // 12: kv_storage_service.ExportResponse.records:type_name -> kv_storage_service.KeyValueRecord
+	58, // 13: kv_storage_service.ImportRequest.options:type_name -> kv_storage_service.ImportOptions
+	54, // 14: kv_storage_service.ImportRequest.records:type_name -> kv_storage_service.KeyValueRecord
+	0,  // 15: kv_storage_service.KeyValueStorage.Get:input_type -> kv_storage_service.GetRequest
+	2,  // 16: kv_storage_service.KeyValueStorage.Set:input_type -> kv_storage_service.SetRequest
+	2,  // 17: kv_storage_service.KeyValueStorage.SetStream:input_type -> kv_storage_service.SetRequest
+	30, // 18: kv_storage_service.KeyValueStorage.LeMeta:input_type -> kv_storage_service.LeMetaRequest
+	32, // 19: kv_storage_service.KeyValueStorage.UpdateLeader:input_type -> kv_storage_service.UpdateLeaderRequest
+	34, // 20: kv_storage_service.KeyValueStorage.UpdateAddresses:input_type -> kv_storage_service.UpdateAddressesRequest
+	5,  // 21: kv_storage_service.KeyValueStorage.Delete:input_type -> kv_storage_service.DeleteRequest
+	8,  // 22: kv_storage_service.KeyValueStorage.Scan:input_type -> kv_storage_service.ScanRequest
+	10, // 23: kv_storage_service.KeyValueStorage.Watch:input_type -> kv_storage_service.WatchRequest
+	18, // 24: kv_storage_service.KeyValueStorage.Increment:input_type -> kv_storage_service.CounterRequest
+	18, // 25: kv_storage_service.KeyValueStorage.Decrement:input_type -> kv_storage_service.CounterRequest
+	20, // 26: kv_storage_service.KeyValueStorage.LeaseGrant:input_type -> kv_storage_service.LeaseGrantRequest
+	22, // 27: kv_storage_service.KeyValueStorage.LeaseKeepAlive:input_type -> kv_storage_service.LeaseKeepAliveRequest
+	24, // 28: kv_storage_service.KeyValueStorage.LeaseRevoke:input_type -> kv_storage_service.LeaseRevokeRequest
+	26, // 29: kv_storage_service.KeyValueStorage.Lock:input_type -> kv_storage_service.LockRequest
+	28, // 30: kv_storage_service.KeyValueStorage.Unlock:input_type -> kv_storage_service.UnlockRequest
+	38, // 31: kv_storage_service.KeyValueStorage.CreateNamespace:input_type -> kv_storage_service.CreateNamespaceRequest
+	40, // 32: kv_storage_service.KeyValueStorage.ListNamespaces:input_type -> kv_storage_service.ListNamespacesRequest
+	42, // 33: kv_storage_service.KeyValueStorage.DropNamespace:input_type -> kv_storage_service.DropNamespaceRequest
+	44, // 34: kv_storage_service.KeyValueStorage.History:input_type -> kv_storage_service.HistoryRequest
+	47, // 35: kv_storage_service.KeyValueStorage.Backup:input_type -> kv_storage_service.BackupRequest
+	48, // 36: kv_storage_service.KeyValueStorage.Restore:input_type -> kv_storage_service.BackupRecord
+	55, // 37: kv_storage_service.KeyValueStorage.Export:input_type -> kv_storage_service.ExportRequest
+	57, // 38: kv_storage_service.KeyValueStorage.Import:input_type -> kv_storage_service.ImportRequest
+	1,  // 39: kv_storage_service.KeyValueStorage.Get:output_type -> kv_storage_service.GetResponse
+	4,  // 40: kv_storage_service.KeyValueStorage.Set:output_type -> kv_storage_service.SetResponse
+	4,  // 41: kv_storage_service.KeyValueStorage.SetStream:output_type -> kv_storage_service.SetResponse
+	31, // 42: kv_storage_service.KeyValueStorage.LeMeta:output_type -> kv_storage_service.LeMetaResponse
+	33, // 43: kv_storage_service.KeyValueStorage.UpdateLeader:output_type -> kv_storage_service.UpdateLeaderResponse
+	35, // 44: kv_storage_service.KeyValueStorage.UpdateAddresses:output_type -> kv_storage_service.UpdateAddressesResponse
+	6,  // 45: kv_storage_service.KeyValueStorage.Delete:output_type -> kv_storage_service.DeleteResponse
+	9,  // 46: kv_storage_service.KeyValueStorage.Scan:output_type -> kv_storage_service.ScanResponse
+	11, // 47: kv_storage_service.KeyValueStorage.Watch:output_type -> kv_storage_service.WatchResponse
+	19, // 48: kv_storage_service.KeyValueStorage.Increment:output_type -> kv_storage_service.CounterResponse
+	19, // 49: kv_storage_service.KeyValueStorage.Decrement:output_type -> kv_storage_service.CounterResponse
+	21, // 50: kv_storage_service.KeyValueStorage.LeaseGrant:output_type -> kv_storage_service.LeaseGrantResponse
+	23, // 51: kv_storage_service.KeyValueStorage.LeaseKeepAlive:output_type -> kv_storage_service.LeaseKeepAliveResponse
+	25, // 52: kv_storage_service.KeyValueStorage.LeaseRevoke:output_type -> kv_storage_service.LeaseRevokeResponse
+	27, // 53: kv_storage_service.KeyValueStorage.Lock:output_type -> kv_storage_service.LockResponse
+	29, // 54: kv_storage_service.KeyValueStorage.Unlock:output_type -> kv_storage_service.UnlockResponse
+	39, // 55: kv_storage_service.KeyValueStorage.CreateNamespace:output_type -> kv_storage_service.CreateNamespaceResponse
+	41, // 56: kv_storage_service.KeyValueStorage.ListNamespaces:output_type -> kv_storage_service.ListNamespacesResponse
+	43, // 57: kv_storage_service.KeyValueStorage.DropNamespace:output_type -> kv_storage_service.DropNamespaceResponse
+	46, // 58: kv_storage_service.KeyValueStorage.History:output_type -> kv_storage_service.HistoryResponse
+	48, // 59: kv_storage_service.KeyValueStorage.Backup:output_type -> kv_storage_service.BackupRecord
+	53, // 60: kv_storage_service.KeyValueStorage.Restore:output_type -> kv_storage_service.RestoreResponse
+	56, // 61: kv_storage_service.KeyValueStorage.Export:output_type -> kv_storage_service.ExportResponse
+	59, // 62: kv_storage_service.KeyValueStorage.Import:output_type -> kv_storage_service.ImportResponse
+	39, // [39:63] is the sub-list for method output_type
+	15, // [15:39] is the sub-list for method input_type
+	15, // [15:15] is the sub-list for extension type_name
+	15, // [15:15] is the sub-list for extension extendee
+	0,  // [0:15] is the sub-list for field type_name
 }
 
 func init() { file_api_kv_storage_proto_init() }
@@ -858,14 +3981,24 @@ func file_api_kv_storage_proto_init() {
 	if File_api_kv_storage_proto != nil {
 		return
 	}
+	file_api_kv_storage_proto_msgTypes[0].OneofWrappers = []any{}
 	file_api_kv_storage_proto_msgTypes[2].OneofWrappers = []any{}
+	file_api_kv_storage_proto_msgTypes[8].OneofWrappers = []any{}
+	file_api_kv_storage_proto_msgTypes[18].OneofWrappers = []any{}
+	file_api_kv_storage_proto_msgTypes[48].OneofWrappers = []any{
+		(*BackupRecord_Header)(nil),
+		(*BackupRecord_Namespace)(nil),
+		(*BackupRecord_Entry)(nil),
+		(*BackupRecord_Chunk)(nil),
+		(*BackupRecord_Trailer)(nil),
+	}
 	type x struct{}
 	out := protoimpl.TypeBuilder{
 		File: protoimpl.DescBuilder{
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: unsafe.Slice(unsafe.StringData(file_api_kv_storage_proto_rawDesc), len(file_api_kv_storage_proto_rawDesc)),
 			NumEnums:      0,
-			NumMessages:   16,
+			NumMessages:   61,
 			NumExtensions: 0,
 			NumServices:   1,
 		},
@@ -0,0 +1,1217 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.6
+// 	protoc        (unknown)
+// source: api/v2/kv-storage.proto
+
+package kv_storage_service_v2
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type GetRequest struct {
+	state     protoimpl.MessageState `protogen:"open.v1"`
+	Key       string                 `protobuf:"bytes,1,opt,name=key,proto3" json:"key,omitempty"`
+	Namespace string                 `protobuf:"bytes,2,opt,name=namespace,proto3" json:"namespace,omitempty"`
+	// Чтение значения на момент ревизии, по умолчанию текущее значение
+	Revision      *int64 `protobuf:"varint,3,opt,name=revision,proto3,oneof" json:"revision,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetRequest) Reset() {
+	*x = GetRequest{}
+	mi := &file_api_v2_kv_storage_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetRequest) ProtoMessage() {}
+
+func (x *GetRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_v2_kv_storage_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetRequest.ProtoReflect.Descriptor instead.
+func (*GetRequest) Descriptor() ([]byte, []int) {
+	return file_api_v2_kv_storage_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *GetRequest) GetKey() string {
+	if x != nil {
+		return x.Key
+	}
+	return ""
+}
+
+func (x *GetRequest) GetNamespace() string {
+	if x != nil {
+		return x.Namespace
+	}
+	return ""
+}
+
+func (x *GetRequest) GetRevision() int64 {
+	if x != nil && x.Revision != nil {
+		return *x.Revision
+	}
+	return 0
+}
+
+type GetResponse struct {
+	state       protoimpl.MessageState `protogen:"open.v1"`
+	Value       []byte                 `protobuf:"bytes,1,opt,name=value,proto3" json:"value,omitempty"`
+	Found       bool                   `protobuf:"varint,2,opt,name=found,proto3" json:"found,omitempty"`
+	ContentType string                 `protobuf:"bytes,3,opt,name=content_type,json=contentType,proto3" json:"content_type,omitempty"`
+	// Ревизия последнего изменения ключа
+	Revision      int64 `protobuf:"varint,4,opt,name=revision,proto3" json:"revision,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetResponse) Reset() {
+	*x = GetResponse{}
+	mi := &file_api_v2_kv_storage_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetResponse) ProtoMessage() {}
+
+func (x *GetResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_api_v2_kv_storage_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetResponse.ProtoReflect.Descriptor instead.
+func (*GetResponse) Descriptor() ([]byte, []int) {
+	return file_api_v2_kv_storage_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *GetResponse) GetValue() []byte {
+	if x != nil {
+		return x.Value
+	}
+	return nil
+}
+
+func (x *GetResponse) GetFound() bool {
+	if x != nil {
+		return x.Found
+	}
+	return false
+}
+
+func (x *GetResponse) GetContentType() string {
+	if x != nil {
+		return x.ContentType
+	}
+	return ""
+}
+
+func (x *GetResponse) GetRevision() int64 {
+	if x != nil {
+		return x.Revision
+	}
+	return 0
+}
+
+type SetRequest struct {
+	state     protoimpl.MessageState `protogen:"open.v1"`
+	Key       string                 `protobuf:"bytes,1,opt,name=key,proto3" json:"key,omitempty"`
+	Namespace string                 `protobuf:"bytes,2,opt,name=namespace,proto3" json:"namespace,omitempty"`
+	Value     []byte                 `protobuf:"bytes,3,opt,name=value,proto3" json:"value,omitempty"`
+	// MIME-тип значения, необязателен
+	ContentType string `protobuf:"bytes,4,opt,name=content_type,json=contentType,proto3" json:"content_type,omitempty"`
+	// Время истечения ключа в unix-наносекундах
+	Expiration *int64 `protobuf:"varint,5,opt,name=expiration,proto3,oneof" json:"expiration,omitempty"`
+	// Аренда, при истечении которой ключ будет удалён
+	Lease         *int64 `protobuf:"varint,6,opt,name=lease,proto3,oneof" json:"lease,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SetRequest) Reset() {
+	*x = SetRequest{}
+	mi := &file_api_v2_kv_storage_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SetRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SetRequest) ProtoMessage() {}
+
+func (x *SetRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_v2_kv_storage_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SetRequest.ProtoReflect.Descriptor instead.
+func (*SetRequest) Descriptor() ([]byte, []int) {
+	return file_api_v2_kv_storage_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *SetRequest) GetKey() string {
+	if x != nil {
+		return x.Key
+	}
+	return ""
+}
+
+func (x *SetRequest) GetNamespace() string {
+	if x != nil {
+		return x.Namespace
+	}
+	return ""
+}
+
+func (x *SetRequest) GetValue() []byte {
+	if x != nil {
+		return x.Value
+	}
+	return nil
+}
+
+func (x *SetRequest) GetContentType() string {
+	if x != nil {
+		return x.ContentType
+	}
+	return ""
+}
+
+func (x *SetRequest) GetExpiration() int64 {
+	if x != nil && x.Expiration != nil {
+		return *x.Expiration
+	}
+	return 0
+}
+
+func (x *SetRequest) GetLease() int64 {
+	if x != nil && x.Lease != nil {
+		return *x.Lease
+	}
+	return 0
+}
+
+type SetResponse struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Ревизия, в которой применена запись
+	Revision      int64 `protobuf:"varint,1,opt,name=revision,proto3" json:"revision,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SetResponse) Reset() {
+	*x = SetResponse{}
+	mi := &file_api_v2_kv_storage_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SetResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SetResponse) ProtoMessage() {}
+
+func (x *SetResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_api_v2_kv_storage_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SetResponse.ProtoReflect.Descriptor instead.
+func (*SetResponse) Descriptor() ([]byte, []int) {
+	return file_api_v2_kv_storage_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *SetResponse) GetRevision() int64 {
+	if x != nil {
+		return x.Revision
+	}
+	return 0
+}
+
+type DeleteRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Key           string                 `protobuf:"bytes,1,opt,name=key,proto3" json:"key,omitempty"`
+	Namespace     string                 `protobuf:"bytes,2,opt,name=namespace,proto3" json:"namespace,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DeleteRequest) Reset() {
+	*x = DeleteRequest{}
+	mi := &file_api_v2_kv_storage_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DeleteRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DeleteRequest) ProtoMessage() {}
+
+func (x *DeleteRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_v2_kv_storage_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DeleteRequest.ProtoReflect.Descriptor instead.
+func (*DeleteRequest) Descriptor() ([]byte, []int) {
+	return file_api_v2_kv_storage_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *DeleteRequest) GetKey() string {
+	if x != nil {
+		return x.Key
+	}
+	return ""
+}
+
+func (x *DeleteRequest) GetNamespace() string {
+	if x != nil {
+		return x.Namespace
+	}
+	return ""
+}
+
+type DeleteResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Deleted       bool                   `protobuf:"varint,1,opt,name=deleted,proto3" json:"deleted,omitempty"`
+	Revision      int64                  `protobuf:"varint,2,opt,name=revision,proto3" json:"revision,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DeleteResponse) Reset() {
+	*x = DeleteResponse{}
+	mi := &file_api_v2_kv_storage_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DeleteResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DeleteResponse) ProtoMessage() {}
+
+func (x *DeleteResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_api_v2_kv_storage_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DeleteResponse.ProtoReflect.Descriptor instead.
+func (*DeleteResponse) Descriptor() ([]byte, []int) {
+	return file_api_v2_kv_storage_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *DeleteResponse) GetDeleted() bool {
+	if x != nil {
+		return x.Deleted
+	}
+	return false
+}
+
+func (x *DeleteResponse) GetRevision() int64 {
+	if x != nil {
+		return x.Revision
+	}
+	return 0
+}
+
+type ScanRequest struct {
+	state  protoimpl.MessageState `protogen:"open.v1"`
+	Prefix string                 `protobuf:"bytes,1,opt,name=prefix,proto3" json:"prefix,omitempty"`
+	// Ключ, после которого продолжить выдачу
+	StartAfter string `protobuf:"bytes,2,opt,name=start_after,json=startAfter,proto3" json:"start_after,omitempty"`
+	Limit      int32  `protobuf:"varint,3,opt,name=limit,proto3" json:"limit,omitempty"`
+	Namespace  string `protobuf:"bytes,4,opt,name=namespace,proto3" json:"namespace,omitempty"`
+	// Согласованный срез на момент ревизии, по умолчанию текущие значения
+	Revision      *int64 `protobuf:"varint,5,opt,name=revision,proto3,oneof" json:"revision,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ScanRequest) Reset() {
+	*x = ScanRequest{}
+	mi := &file_api_v2_kv_storage_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ScanRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ScanRequest) ProtoMessage() {}
+
+func (x *ScanRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_v2_kv_storage_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ScanRequest.ProtoReflect.Descriptor instead.
+func (*ScanRequest) Descriptor() ([]byte, []int) {
+	return file_api_v2_kv_storage_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *ScanRequest) GetPrefix() string {
+	if x != nil {
+		return x.Prefix
+	}
+	return ""
+}
+
+func (x *ScanRequest) GetStartAfter() string {
+	if x != nil {
+		return x.StartAfter
+	}
+	return ""
+}
+
+func (x *ScanRequest) GetLimit() int32 {
+	if x != nil {
+		return x.Limit
+	}
+	return 0
+}
+
+func (x *ScanRequest) GetNamespace() string {
+	if x != nil {
+		return x.Namespace
+	}
+	return ""
+}
+
+func (x *ScanRequest) GetRevision() int64 {
+	if x != nil && x.Revision != nil {
+		return *x.Revision
+	}
+	return 0
+}
+
+type KeyValue struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Key   string                 `protobuf:"bytes,1,opt,name=key,proto3" json:"key,omitempty"`
+	// Пусто для больших значений, они читаются через GetLarge
+	Value       []byte `protobuf:"bytes,2,opt,name=value,proto3" json:"value,omitempty"`
+	ContentType string `protobuf:"bytes,3,opt,name=content_type,json=contentType,proto3" json:"content_type,omitempty"`
+	Revision    int64  `protobuf:"varint,4,opt,name=revision,proto3" json:"revision,omitempty"`
+	// Значение хранится частями
+	Large bool `protobuf:"varint,5,opt,name=large,proto3" json:"large,omitempty"`
+	// Размер значения в байтах
+	Size          int64 `protobuf:"varint,6,opt,name=size,proto3" json:"size,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *KeyValue) Reset() {
+	*x = KeyValue{}
+	mi := &file_api_v2_kv_storage_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *KeyValue) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*KeyValue) ProtoMessage() {}
+
+func (x *KeyValue) ProtoReflect() protoreflect.Message {
+	mi := &file_api_v2_kv_storage_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use KeyValue.ProtoReflect.Descriptor instead.
+func (*KeyValue) Descriptor() ([]byte, []int) {
+	return file_api_v2_kv_storage_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *KeyValue) GetKey() string {
+	if x != nil {
+		return x.Key
+	}
+	return ""
+}
+
+func (x *KeyValue) GetValue() []byte {
+	if x != nil {
+		return x.Value
+	}
+	return nil
+}
+
+func (x *KeyValue) GetContentType() string {
+	if x != nil {
+		return x.ContentType
+	}
+	return ""
+}
+
+func (x *KeyValue) GetRevision() int64 {
+	if x != nil {
+		return x.Revision
+	}
+	return 0
+}
+
+func (x *KeyValue) GetLarge() bool {
+	if x != nil {
+		return x.Large
+	}
+	return false
+}
+
+func (x *KeyValue) GetSize() int64 {
+	if x != nil {
+		return x.Size
+	}
+	return 0
+}
+
+type ScanResponse struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Items []*KeyValue            `protobuf:"bytes,1,rep,name=items,proto3" json:"items,omitempty"`
+	// Пустая строка, если ключей больше нет
+	NextStartAfter string `protobuf:"bytes,2,opt,name=next_start_after,json=nextStartAfter,proto3" json:"next_start_after,omitempty"`
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *ScanResponse) Reset() {
+	*x = ScanResponse{}
+	mi := &file_api_v2_kv_storage_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ScanResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ScanResponse) ProtoMessage() {}
+
+func (x *ScanResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_api_v2_kv_storage_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ScanResponse.ProtoReflect.Descriptor instead.
+func (*ScanResponse) Descriptor() ([]byte, []int) {
+	return file_api_v2_kv_storage_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *ScanResponse) GetItems() []*KeyValue {
+	if x != nil {
+		return x.Items
+	}
+	return nil
+}
+
+func (x *ScanResponse) GetNextStartAfter() string {
+	if x != nil {
+		return x.NextStartAfter
+	}
+	return ""
+}
+
+type PutLargeHeader struct {
+	state       protoimpl.MessageState `protogen:"open.v1"`
+	Key         string                 `protobuf:"bytes,1,opt,name=key,proto3" json:"key,omitempty"`
+	Namespace   string                 `protobuf:"bytes,2,opt,name=namespace,proto3" json:"namespace,omitempty"`
+	ContentType string                 `protobuf:"bytes,3,opt,name=content_type,json=contentType,proto3" json:"content_type,omitempty"`
+	// Время истечения ключа в unix-наносекундах
+	Expiration *int64 `protobuf:"varint,4,opt,name=expiration,proto3,oneof" json:"expiration,omitempty"`
+	// Аренда, при истечении которой ключ будет удалён
+	Lease *int64 `protobuf:"varint,5,opt,name=lease,proto3,oneof" json:"lease,omitempty"`
+	// Размер значения, если известен заранее: запись сразу отклоняется при
+	// превышении лимита, а по завершении размер сверяется с полученным
+	Size          *int64 `protobuf:"varint,6,opt,name=size,proto3,oneof" json:"size,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PutLargeHeader) Reset() {
+	*x = PutLargeHeader{}
+	mi := &file_api_v2_kv_storage_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PutLargeHeader) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PutLargeHeader) ProtoMessage() {}
+
+func (x *PutLargeHeader) ProtoReflect() protoreflect.Message {
+	mi := &file_api_v2_kv_storage_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PutLargeHeader.ProtoReflect.Descriptor instead.
+func (*PutLargeHeader) Descriptor() ([]byte, []int) {
+	return file_api_v2_kv_storage_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *PutLargeHeader) GetKey() string {
+	if x != nil {
+		return x.Key
+	}
+	return ""
+}
+
+func (x *PutLargeHeader) GetNamespace() string {
+	if x != nil {
+		return x.Namespace
+	}
+	return ""
+}
+
+func (x *PutLargeHeader) GetContentType() string {
+	if x != nil {
+		return x.ContentType
+	}
+	return ""
+}
+
+func (x *PutLargeHeader) GetExpiration() int64 {
+	if x != nil && x.Expiration != nil {
+		return *x.Expiration
+	}
+	return 0
+}
+
+func (x *PutLargeHeader) GetLease() int64 {
+	if x != nil && x.Lease != nil {
+		return *x.Lease
+	}
+	return 0
+}
+
+func (x *PutLargeHeader) GetSize() int64 {
+	if x != nil && x.Size != nil {
+		return *x.Size
+	}
+	return 0
+}
+
+type PutLargeRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Types that are valid to be assigned to Message:
+	//
+	//	*PutLargeRequest_Header
+	//	*PutLargeRequest_Chunk
+	Message       isPutLargeRequest_Message `protobuf_oneof:"message"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PutLargeRequest) Reset() {
+	*x = PutLargeRequest{}
+	mi := &file_api_v2_kv_storage_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PutLargeRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PutLargeRequest) ProtoMessage() {}
+
+func (x *PutLargeRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_v2_kv_storage_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PutLargeRequest.ProtoReflect.Descriptor instead.
+func (*PutLargeRequest) Descriptor() ([]byte, []int) {
+	return file_api_v2_kv_storage_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *PutLargeRequest) GetMessage() isPutLargeRequest_Message {
+	if x != nil {
+		return x.Message
+	}
+	return nil
+}
+
+func (x *PutLargeRequest) GetHeader() *PutLargeHeader {
+	if x != nil {
+		if x, ok := x.Message.(*PutLargeRequest_Header); ok {
+			return x.Header
+		}
+	}
+	return nil
+}
+
+func (x *PutLargeRequest) GetChunk() []byte {
+	if x != nil {
+		if x, ok := x.Message.(*PutLargeRequest_Chunk); ok {
+			return x.Chunk
+		}
+	}
+	return nil
+}
+
+type isPutLargeRequest_Message interface {
+	isPutLargeRequest_Message()
+}
+
+type PutLargeRequest_Header struct {
+	Header *PutLargeHeader `protobuf:"bytes,1,opt,name=header,proto3,oneof"`
+}
+
+type PutLargeRequest_Chunk struct {
+	Chunk []byte `protobuf:"bytes,2,opt,name=chunk,proto3,oneof"`
+}
+
+func (*PutLargeRequest_Header) isPutLargeRequest_Message() {}
+
+func (*PutLargeRequest_Chunk) isPutLargeRequest_Message() {}
+
+type PutLargeResponse struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Ревизия, в которой применена запись
+	Revision      int64 `protobuf:"varint,1,opt,name=revision,proto3" json:"revision,omitempty"`
+	Size          int64 `protobuf:"varint,2,opt,name=size,proto3" json:"size,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PutLargeResponse) Reset() {
+	*x = PutLargeResponse{}
+	mi := &file_api_v2_kv_storage_proto_msgTypes[11]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PutLargeResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PutLargeResponse) ProtoMessage() {}
+
+func (x *PutLargeResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_api_v2_kv_storage_proto_msgTypes[11]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PutLargeResponse.ProtoReflect.Descriptor instead.
+func (*PutLargeResponse) Descriptor() ([]byte, []int) {
+	return file_api_v2_kv_storage_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *PutLargeResponse) GetRevision() int64 {
+	if x != nil {
+		return x.Revision
+	}
+	return 0
+}
+
+func (x *PutLargeResponse) GetSize() int64 {
+	if x != nil {
+		return x.Size
+	}
+	return 0
+}
+
+type GetLargeRequest struct {
+	state     protoimpl.MessageState `protogen:"open.v1"`
+	Key       string                 `protobuf:"bytes,1,opt,name=key,proto3" json:"key,omitempty"`
+	Namespace string                 `protobuf:"bytes,2,opt,name=namespace,proto3" json:"namespace,omitempty"`
+	// Чтение значения на момент ревизии, по умолчанию текущее значение
+	Revision      *int64 `protobuf:"varint,3,opt,name=revision,proto3,oneof" json:"revision,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetLargeRequest) Reset() {
+	*x = GetLargeRequest{}
+	mi := &file_api_v2_kv_storage_proto_msgTypes[12]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetLargeRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetLargeRequest) ProtoMessage() {}
+
+func (x *GetLargeRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_v2_kv_storage_proto_msgTypes[12]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetLargeRequest.ProtoReflect.Descriptor instead.
+func (*GetLargeRequest) Descriptor() ([]byte, []int) {
+	return file_api_v2_kv_storage_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *GetLargeRequest) GetKey() string {
+	if x != nil {
+		return x.Key
+	}
+	return ""
+}
+
+func (x *GetLargeRequest) GetNamespace() string {
+	if x != nil {
+		return x.Namespace
+	}
+	return ""
+}
+
+func (x *GetLargeRequest) GetRevision() int64 {
+	if x != nil && x.Revision != nil {
+		return *x.Revision
+	}
+	return 0
+}
+
+type GetLargeHeader struct {
+	state       protoimpl.MessageState `protogen:"open.v1"`
+	ContentType string                 `protobuf:"bytes,1,opt,name=content_type,json=contentType,proto3" json:"content_type,omitempty"`
+	Size        int64                  `protobuf:"varint,2,opt,name=size,proto3" json:"size,omitempty"`
+	// Ревизия последнего изменения ключа
+	Revision      int64 `protobuf:"varint,3,opt,name=revision,proto3" json:"revision,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetLargeHeader) Reset() {
+	*x = GetLargeHeader{}
+	mi := &file_api_v2_kv_storage_proto_msgTypes[13]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetLargeHeader) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetLargeHeader) ProtoMessage() {}
+
+func (x *GetLargeHeader) ProtoReflect() protoreflect.Message {
+	mi := &file_api_v2_kv_storage_proto_msgTypes[13]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetLargeHeader.ProtoReflect.Descriptor instead.
+func (*GetLargeHeader) Descriptor() ([]byte, []int) {
+	return file_api_v2_kv_storage_proto_rawDescGZIP(), []int{13}
+}
+
+func (x *GetLargeHeader) GetContentType() string {
+	if x != nil {
+		return x.ContentType
+	}
+	return ""
+}
+
+func (x *GetLargeHeader) GetSize() int64 {
+	if x != nil {
+		return x.Size
+	}
+	return 0
+}
+
+func (x *GetLargeHeader) GetRevision() int64 {
+	if x != nil {
+		return x.Revision
+	}
+	return 0
+}
+
+type GetLargeResponse struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Types that are valid to be assigned to Message:
+	//
+	//	*GetLargeResponse_Header
+	//	*GetLargeResponse_Chunk
+	Message       isGetLargeResponse_Message `protobuf_oneof:"message"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetLargeResponse) Reset() {
+	*x = GetLargeResponse{}
+	mi := &file_api_v2_kv_storage_proto_msgTypes[14]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetLargeResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetLargeResponse) ProtoMessage() {}
+
+func (x *GetLargeResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_api_v2_kv_storage_proto_msgTypes[14]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetLargeResponse.ProtoReflect.Descriptor instead.
+func (*GetLargeResponse) Descriptor() ([]byte, []int) {
+	return file_api_v2_kv_storage_proto_rawDescGZIP(), []int{14}
+}
+
+func (x *GetLargeResponse) GetMessage() isGetLargeResponse_Message {
+	if x != nil {
+		return x.Message
+	}
+	return nil
+}
+
+func (x *GetLargeResponse) GetHeader() *GetLargeHeader {
+	if x != nil {
+		if x, ok := x.Message.(*GetLargeResponse_Header); ok {
+			return x.Header
+		}
+	}
+	return nil
+}
+
+func (x *GetLargeResponse) GetChunk() []byte {
+	if x != nil {
+		if x, ok := x.Message.(*GetLargeResponse_Chunk); ok {
+			return x.Chunk
+		}
+	}
+	return nil
+}
+
+type isGetLargeResponse_Message interface {
+	isGetLargeResponse_Message()
+}
+
+type GetLargeResponse_Header struct {
+	Header *GetLargeHeader `protobuf:"bytes,1,opt,name=header,proto3,oneof"`
+}
+
+type GetLargeResponse_Chunk struct {
+	Chunk []byte `protobuf:"bytes,2,opt,name=chunk,proto3,oneof"`
+}
+
+func (*GetLargeResponse_Header) isGetLargeResponse_Message() {}
+
+func (*GetLargeResponse_Chunk) isGetLargeResponse_Message() {}
+
+var File_api_v2_kv_storage_proto protoreflect.FileDescriptor
+
+const file_api_v2_kv_storage_proto_rawDesc = "" +
+	"\n" +
+	"\x17api/v2/kv-storage.proto\x12\x15kv_storage_service.v2\"j\n" +
+	"\n" +
+	"GetRequest\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x1c\n" +
+	"\tnamespace\x18\x02 \x01(\tR\tnamespace\x12\x1f\n" +
+	"\brevision\x18\x03 \x01(\x03H\x00R\brevision\x88\x01\x01B\v\n" +
+	"\t_revision\"x\n" +
+	"\vGetResponse\x12\x14\n" +
+	"\x05value\x18\x01 \x01(\fR\x05value\x12\x14\n" +
+	"\x05found\x18\x02 \x01(\bR\x05found\x12!\n" +
+	"\fcontent_type\x18\x03 \x01(\tR\vcontentType\x12\x1a\n" +
+	"\brevision\x18\x04 \x01(\x03R\brevision\"\xce\x01\n" +
+	"\n" +
+	"SetRequest\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x1c\n" +
+	"\tnamespace\x18\x02 \x01(\tR\tnamespace\x12\x14\n" +
+	"\x05value\x18\x03 \x01(\fR\x05value\x12!\n" +
+	"\fcontent_type\x18\x04 \x01(\tR\vcontentType\x12#\n" +
+	"\n" +
+	"expiration\x18\x05 \x01(\x03H\x00R\n" +
+	"expiration\x88\x01\x01\x12\x19\n" +
+	"\x05lease\x18\x06 \x01(\x03H\x01R\x05lease\x88\x01\x01B\r\n" +
+	"\v_expirationB\b\n" +
+	"\x06_lease\")\n" +
+	"\vSetResponse\x12\x1a\n" +
+	"\brevision\x18\x01 \x01(\x03R\brevision\"?\n" +
+	"\rDeleteRequest\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x1c\n" +
+	"\tnamespace\x18\x02 \x01(\tR\tnamespace\"F\n" +
+	"\x0eDeleteResponse\x12\x18\n" +
+	"\adeleted\x18\x01 \x01(\bR\adeleted\x12\x1a\n" +
+	"\brevision\x18\x02 \x01(\x03R\brevision\"\xa8\x01\n" +
+	"\vScanRequest\x12\x16\n" +
+	"\x06prefix\x18\x01 \x01(\tR\x06prefix\x12\x1f\n" +
+	"\vstart_after\x18\x02 \x01(\tR\n" +
+	"startAfter\x12\x14\n" +
+	"\x05limit\x18\x03 \x01(\x05R\x05limit\x12\x1c\n" +
+	"\tnamespace\x18\x04 \x01(\tR\tnamespace\x12\x1f\n" +
+	"\brevision\x18\x05 \x01(\x03H\x00R\brevision\x88\x01\x01B\v\n" +
+	"\t_revision\"\x9b\x01\n" +
+	"\bKeyValue\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\fR\x05value\x12!\n" +
+	"\fcontent_type\x18\x03 \x01(\tR\vcontentType\x12\x1a\n" +
+	"\brevision\x18\x04 \x01(\x03R\brevision\x12\x14\n" +
+	"\x05large\x18\x05 \x01(\bR\x05large\x12\x12\n" +
+	"\x04size\x18\x06 \x01(\x03R\x04size\"o\n" +
+	"\fScanResponse\x125\n" +
+	"\x05items\x18\x01 \x03(\v2\x1f.kv_storage_service.v2.KeyValueR\x05items\x12(\n" +
+	"\x10next_start_after\x18\x02 \x01(\tR\x0enextStartAfter\"\xde\x01\n" +
+	"\x0ePutLargeHeader\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x1c\n" +
+	"\tnamespace\x18\x02 \x01(\tR\tnamespace\x12!\n" +
+	"\fcontent_type\x18\x03 \x01(\tR\vcontentType\x12#\n" +
+	"\n" +
+	"expiration\x18\x04 \x01(\x03H\x00R\n" +
+	"expiration\x88\x01\x01\x12\x19\n" +
+	"\x05lease\x18\x05 \x01(\x03H\x01R\x05lease\x88\x01\x01\x12\x17\n" +
+	"\x04size\x18\x06 \x01(\x03H\x02R\x04size\x88\x01\x01B\r\n" +
+	"\v_expirationB\b\n" +
+	"\x06_leaseB\a\n" +
+	"\x05_size\"u\n" +
+	"\x0fPutLargeRequest\x12?\n" +
+	"\x06header\x18\x01 \x01(\v2%.kv_storage_service.v2.PutLargeHeaderH\x00R\x06header\x12\x16\n" +
+	"\x05chunk\x18\x02 \x01(\fH\x00R\x05chunkB\t\n" +
+	"\amessage\"B\n" +
+	"\x10PutLargeResponse\x12\x1a\n" +
+	"\brevision\x18\x01 \x01(\x03R\brevision\x12\x12\n" +
+	"\x04size\x18\x02 \x01(\x03R\x04size\"o\n" +
+	"\x0fGetLargeRequest\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x1c\n" +
+	"\tnamespace\x18\x02 \x01(\tR\tnamespace\x12\x1f\n" +
+	"\brevision\x18\x03 \x01(\x03H\x00R\brevision\x88\x01\x01B\v\n" +
+	"\t_revision\"c\n" +
+	"\x0eGetLargeHeader\x12!\n" +
+	"\fcontent_type\x18\x01 \x01(\tR\vcontentType\x12\x12\n" +
+	"\x04size\x18\x02 \x01(\x03R\x04size\x12\x1a\n" +
+	"\brevision\x18\x03 \x01(\x03R\brevision\"v\n" +
+	"\x10GetLargeResponse\x12?\n" +
+	"\x06header\x18\x01 \x01(\v2%.kv_storage_service.v2.GetLargeHeaderH\x00R\x06header\x12\x16\n" +
+	"\x05chunk\x18\x02 \x01(\fH\x00R\x05chunkB\t\n" +
+	"\amessage2\x93\x04\n" +
+	"\x0fKeyValueStorage\x12L\n" +
+	"\x03Get\x12!.kv_storage_service.v2.GetRequest\x1a\".kv_storage_service.v2.GetResponse\x12L\n" +
+	"\x03Set\x12!.kv_storage_service.v2.SetRequest\x1a\".kv_storage_service.v2.SetResponse\x12U\n" +
+	"\x06Delete\x12$.kv_storage_service.v2.DeleteRequest\x1a%.kv_storage_service.v2.DeleteResponse\x12O\n" +
+	"\x04Scan\x12\".kv_storage_service.v2.ScanRequest\x1a#.kv_storage_service.v2.ScanResponse\x12]\n" +
+	"\bPutLarge\x12&.kv_storage_service.v2.PutLargeRequest\x1a'.kv_storage_service.v2.PutLargeResponse(\x01\x12]\n" +
+	"\bGetLarge\x12&.kv_storage_service.v2.GetLargeRequest\x1a'.kv_storage_service.v2.GetLargeResponse0\x01BHZFgithub.com/Na322Pr/kv-storage-service/pkg/api/v2;kv_storage_service_v2b\x06proto3"
+
+var (
+	file_api_v2_kv_storage_proto_rawDescOnce sync.Once
+	file_api_v2_kv_storage_proto_rawDescData []byte
+)
+
+func file_api_v2_kv_storage_proto_rawDescGZIP() []byte {
+	file_api_v2_kv_storage_proto_rawDescOnce.Do(func() {
+		file_api_v2_kv_storage_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_api_v2_kv_storage_proto_rawDesc), len(file_api_v2_kv_storage_proto_rawDesc)))
+	})
+	return file_api_v2_kv_storage_proto_rawDescData
+}
+
+var file_api_v2_kv_storage_proto_msgTypes = make([]protoimpl.MessageInfo, 15)
+var file_api_v2_kv_storage_proto_goTypes = []any{
+	(*GetRequest)(nil),       // 0: kv_storage_service.v2.GetRequest
+	(*GetResponse)(nil),      // 1: kv_storage_service.v2.GetResponse
+	(*SetRequest)(nil),       // 2: kv_storage_service.v2.SetRequest
+	(*SetResponse)(nil),      // 3: kv_storage_service.v2.SetResponse
+	(*DeleteRequest)(nil),    // 4: kv_storage_service.v2.DeleteRequest
+	(*DeleteResponse)(nil),   // 5: kv_storage_service.v2.DeleteResponse
+	(*ScanRequest)(nil),      // 6: kv_storage_service.v2.ScanRequest
+	(*KeyValue)(nil),         // 7: kv_storage_service.v2.KeyValue
+	(*ScanResponse)(nil),     // 8: kv_storage_service.v2.ScanResponse
+	(*PutLargeHeader)(nil),   // 9: kv_storage_service.v2.PutLargeHeader
+	(*PutLargeRequest)(nil),  // 10: kv_storage_service.v2.PutLargeRequest
+	(*PutLargeResponse)(nil), // 11: kv_storage_service.v2.PutLargeResponse
+	(*GetLargeRequest)(nil),  // 12: kv_storage_service.v2.GetLargeRequest
+	(*GetLargeHeader)(nil),   // 13: kv_storage_service.v2.GetLargeHeader
+	(*GetLargeResponse)(nil), // 14: kv_storage_service.v2.GetLargeResponse
+}
+var file_api_v2_kv_storage_proto_depIdxs = []int32{
+	7,  // 0: kv_storage_service.v2.ScanResponse.items:type_name -> kv_storage_service.v2.KeyValue
+	9,  // 1: kv_storage_service.v2.PutLargeRequest.header:type_name -> kv_storage_service.v2.PutLargeHeader
+	13, // 2: kv_storage_service.v2.GetLargeResponse.header:type_name -> kv_storage_service.v2.GetLargeHeader
+	0,  // 3: kv_storage_service.v2.KeyValueStorage.Get:input_type -> kv_storage_service.v2.GetRequest
+	2,  // 4: kv_storage_service.v2.KeyValueStorage.Set:input_type -> kv_storage_service.v2.SetRequest
+	4,  // 5: kv_storage_service.v2.KeyValueStorage.Delete:input_type -> kv_storage_service.v2.DeleteRequest
+	6,  // 6: kv_storage_service.v2.KeyValueStorage.Scan:input_type -> kv_storage_service.v2.ScanRequest
+	10, // 7: kv_storage_service.v2.KeyValueStorage.PutLarge:input_type -> kv_storage_service.v2.PutLargeRequest
+	12, // 8: kv_storage_service.v2.KeyValueStorage.GetLarge:input_type -> kv_storage_service.v2.GetLargeRequest
+	1,  // 9: kv_storage_service.v2.KeyValueStorage.Get:output_type -> kv_storage_service.v2.GetResponse
+	3,  // 10: kv_storage_service.v2.KeyValueStorage.Set:output_type -> kv_storage_service.v2.SetResponse
+	5,  // 11: kv_storage_service.v2.KeyValueStorage.Delete:output_type -> kv_storage_service.v2.DeleteResponse
+	8,  // 12: kv_storage_service.v2.KeyValueStorage.Scan:output_type -> kv_storage_service.v2.ScanResponse
+	11, // 13: kv_storage_service.v2.KeyValueStorage.PutLarge:output_type -> kv_storage_service.v2.PutLargeResponse
+	14, // 14: kv_storage_service.v2.KeyValueStorage.GetLarge:output_type -> kv_storage_service.v2.GetLargeResponse
+	9,  // [9:15] is the sub-list for method output_type
+	3,  // [3:9] is the sub-list for method input_type
+	3,  // [3:3] is the sub-list for extension type_name
+	3,  // [3:3] is the sub-list for extension extendee
+	0,  // [0:3] is the sub-list for field type_name
+}
+
+func init() { file_api_v2_kv_storage_proto_init() }
+func file_api_v2_kv_storage_proto_init() {
+	if File_api_v2_kv_storage_proto != nil {
+		return
+	}
+	file_api_v2_kv_storage_proto_msgTypes[0].OneofWrappers = []any{}
+	file_api_v2_kv_storage_proto_msgTypes[2].OneofWrappers = []any{}
+	file_api_v2_kv_storage_proto_msgTypes[6].OneofWrappers = []any{}
+	file_api_v2_kv_storage_proto_msgTypes[9].OneofWrappers = []any{}
+	file_api_v2_kv_storage_proto_msgTypes[10].OneofWrappers = []any{
+		(*PutLargeRequest_Header)(nil),
+		(*PutLargeRequest_Chunk)(nil),
+	}
+	file_api_v2_kv_storage_proto_msgTypes[12].OneofWrappers = []any{}
+	file_api_v2_kv_storage_proto_msgTypes[14].OneofWrappers = []any{
+		(*GetLargeResponse_Header)(nil),
+		(*GetLargeResponse_Chunk)(nil),
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_api_v2_kv_storage_proto_rawDesc), len(file_api_v2_kv_storage_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   15,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_api_v2_kv_storage_proto_goTypes,
+		DependencyIndexes: file_api_v2_kv_storage_proto_depIdxs,
+		MessageInfos:      file_api_v2_kv_storage_proto_msgTypes,
+	}.Build()
+	File_api_v2_kv_storage_proto = out.File
+	file_api_v2_kv_storage_proto_goTypes = nil
+	file_api_v2_kv_storage_proto_depIdxs = nil
+}
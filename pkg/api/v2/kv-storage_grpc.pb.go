@@ -0,0 +1,330 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.5.1
+// - protoc             (unknown)
+// source: api/v2/kv-storage.proto
+
+package kv_storage_service_v2
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	KeyValueStorage_Get_FullMethodName      = "/kv_storage_service.v2.KeyValueStorage/Get"
+	KeyValueStorage_Set_FullMethodName      = "/kv_storage_service.v2.KeyValueStorage/Set"
+	KeyValueStorage_Delete_FullMethodName   = "/kv_storage_service.v2.KeyValueStorage/Delete"
+	KeyValueStorage_Scan_FullMethodName     = "/kv_storage_service.v2.KeyValueStorage/Scan"
+	KeyValueStorage_PutLarge_FullMethodName = "/kv_storage_service.v2.KeyValueStorage/PutLarge"
+	KeyValueStorage_GetLarge_FullMethodName = "/kv_storage_service.v2.KeyValueStorage/GetLarge"
+)
+
+// KeyValueStorageClient is the client API for KeyValueStorage service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// Значения в API v2 — произвольные байты с необязательным MIME-типом.
+// Значения до сотен мегабайт передаются частями через PutLarge и GetLarge,
+// ни клиент, ни сервер не держат их в памяти целиком
+type KeyValueStorageClient interface {
+	// Получение значения. Большие значения читаются через GetLarge
+	Get(ctx context.Context, in *GetRequest, opts ...grpc.CallOption) (*GetResponse, error)
+	// Запись значения
+	Set(ctx context.Context, in *SetRequest, opts ...grpc.CallOption) (*SetResponse, error)
+	// Удаление ключа
+	Delete(ctx context.Context, in *DeleteRequest, opts ...grpc.CallOption) (*DeleteResponse, error)
+	// Получение ключей по префиксу в лексикографическом порядке
+	Scan(ctx context.Context, in *ScanRequest, opts ...grpc.CallOption) (*ScanResponse, error)
+	// Запись значения частями: первое сообщение содержит заголовок, следующие — части значения.
+	// Значение становится видимым после закрытия стрима клиентом
+	PutLarge(ctx context.Context, opts ...grpc.CallOption) (grpc.ClientStreamingClient[PutLargeRequest, PutLargeResponse], error)
+	// Чтение значения частями: первое сообщение содержит заголовок, следующие — части значения
+	GetLarge(ctx context.Context, in *GetLargeRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[GetLargeResponse], error)
+}
+
+type keyValueStorageClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewKeyValueStorageClient(cc grpc.ClientConnInterface) KeyValueStorageClient {
+	return &keyValueStorageClient{cc}
+}
+
+func (c *keyValueStorageClient) Get(ctx context.Context, in *GetRequest, opts ...grpc.CallOption) (*GetResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GetResponse)
+	err := c.cc.Invoke(ctx, KeyValueStorage_Get_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *keyValueStorageClient) Set(ctx context.Context, in *SetRequest, opts ...grpc.CallOption) (*SetResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(SetResponse)
+	err := c.cc.Invoke(ctx, KeyValueStorage_Set_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *keyValueStorageClient) Delete(ctx context.Context, in *DeleteRequest, opts ...grpc.CallOption) (*DeleteResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(DeleteResponse)
+	err := c.cc.Invoke(ctx, KeyValueStorage_Delete_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *keyValueStorageClient) Scan(ctx context.Context, in *ScanRequest, opts ...grpc.CallOption) (*ScanResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ScanResponse)
+	err := c.cc.Invoke(ctx, KeyValueStorage_Scan_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *keyValueStorageClient) PutLarge(ctx context.Context, opts ...grpc.CallOption) (grpc.ClientStreamingClient[PutLargeRequest, PutLargeResponse], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &KeyValueStorage_ServiceDesc.Streams[0], KeyValueStorage_PutLarge_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[PutLargeRequest, PutLargeResponse]{ClientStream: stream}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type KeyValueStorage_PutLargeClient = grpc.ClientStreamingClient[PutLargeRequest, PutLargeResponse]
+
+func (c *keyValueStorageClient) GetLarge(ctx context.Context, in *GetLargeRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[GetLargeResponse], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &KeyValueStorage_ServiceDesc.Streams[1], KeyValueStorage_GetLarge_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[GetLargeRequest, GetLargeResponse]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type KeyValueStorage_GetLargeClient = grpc.ServerStreamingClient[GetLargeResponse]
+
+// KeyValueStorageServer is the server API for KeyValueStorage service.
+// All implementations must embed UnimplementedKeyValueStorageServer
+// for forward compatibility.
+//
+// Значения в API v2 — произвольные байты с необязательным MIME-типом.
+// Значения до сотен мегабайт передаются частями через PutLarge и GetLarge,
+// ни клиент, ни сервер не держат их в памяти целиком
+type KeyValueStorageServer interface {
+	// Получение значения. Большие значения читаются через GetLarge
+	Get(context.Context, *GetRequest) (*GetResponse, error)
+	// Запись значения
+	Set(context.Context, *SetRequest) (*SetResponse, error)
+	// Удаление ключа
+	Delete(context.Context, *DeleteRequest) (*DeleteResponse, error)
+	// Получение ключей по префиксу в лексикографическом порядке
+	Scan(context.Context, *ScanRequest) (*ScanResponse, error)
+	// Запись значения частями: первое сообщение содержит заголовок, следующие — части значения.
+	// Значение становится видимым после закрытия стрима клиентом
+	PutLarge(grpc.ClientStreamingServer[PutLargeRequest, PutLargeResponse]) error
+	// Чтение значения частями: первое сообщение содержит заголовок, следующие — части значения
+	GetLarge(*GetLargeRequest, grpc.ServerStreamingServer[GetLargeResponse]) error
+	mustEmbedUnimplementedKeyValueStorageServer()
+}
+
+// UnimplementedKeyValueStorageServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedKeyValueStorageServer struct{}
+
+func (UnimplementedKeyValueStorageServer) Get(context.Context, *GetRequest) (*GetResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Get not implemented")
+}
+func (UnimplementedKeyValueStorageServer) Set(context.Context, *SetRequest) (*SetResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Set not implemented")
+}
+func (UnimplementedKeyValueStorageServer) Delete(context.Context, *DeleteRequest) (*DeleteResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Delete not implemented")
+}
+func (UnimplementedKeyValueStorageServer) Scan(context.Context, *ScanRequest) (*ScanResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Scan not implemented")
+}
+func (UnimplementedKeyValueStorageServer) PutLarge(grpc.ClientStreamingServer[PutLargeRequest, PutLargeResponse]) error {
+	return status.Errorf(codes.Unimplemented, "method PutLarge not implemented")
+}
+func (UnimplementedKeyValueStorageServer) GetLarge(*GetLargeRequest, grpc.ServerStreamingServer[GetLargeResponse]) error {
+	return status.Errorf(codes.Unimplemented, "method GetLarge not implemented")
+}
+func (UnimplementedKeyValueStorageServer) mustEmbedUnimplementedKeyValueStorageServer() {}
+func (UnimplementedKeyValueStorageServer) testEmbeddedByValue()                         {}
+
+// UnsafeKeyValueStorageServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to KeyValueStorageServer will
+// result in compilation errors.
+type UnsafeKeyValueStorageServer interface {
+	mustEmbedUnimplementedKeyValueStorageServer()
+}
+
+func RegisterKeyValueStorageServer(s grpc.ServiceRegistrar, srv KeyValueStorageServer) {
+	// If the following call pancis, it indicates UnimplementedKeyValueStorageServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&KeyValueStorage_ServiceDesc, srv)
+}
+
+func _KeyValueStorage_Get_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(KeyValueStorageServer).Get(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: KeyValueStorage_Get_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(KeyValueStorageServer).Get(ctx, req.(*GetRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _KeyValueStorage_Set_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SetRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(KeyValueStorageServer).Set(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: KeyValueStorage_Set_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(KeyValueStorageServer).Set(ctx, req.(*SetRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _KeyValueStorage_Delete_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeleteRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(KeyValueStorageServer).Delete(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: KeyValueStorage_Delete_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(KeyValueStorageServer).Delete(ctx, req.(*DeleteRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _KeyValueStorage_Scan_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ScanRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(KeyValueStorageServer).Scan(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: KeyValueStorage_Scan_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(KeyValueStorageServer).Scan(ctx, req.(*ScanRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _KeyValueStorage_PutLarge_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(KeyValueStorageServer).PutLarge(&grpc.GenericServerStream[PutLargeRequest, PutLargeResponse]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type KeyValueStorage_PutLargeServer = grpc.ClientStreamingServer[PutLargeRequest, PutLargeResponse]
+
+func _KeyValueStorage_GetLarge_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(GetLargeRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(KeyValueStorageServer).GetLarge(m, &grpc.GenericServerStream[GetLargeRequest, GetLargeResponse]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type KeyValueStorage_GetLargeServer = grpc.ServerStreamingServer[GetLargeResponse]
+
+// KeyValueStorage_ServiceDesc is the grpc.ServiceDesc for KeyValueStorage service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var KeyValueStorage_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "kv_storage_service.v2.KeyValueStorage",
+	HandlerType: (*KeyValueStorageServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Get",
+			Handler:    _KeyValueStorage_Get_Handler,
+		},
+		{
+			MethodName: "Set",
+			Handler:    _KeyValueStorage_Set_Handler,
+		},
+		{
+			MethodName: "Delete",
+			Handler:    _KeyValueStorage_Delete_Handler,
+		},
+		{
+			MethodName: "Scan",
+			Handler:    _KeyValueStorage_Scan_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "PutLarge",
+			Handler:       _KeyValueStorage_PutLarge_Handler,
+			ClientStreams: true,
+		},
+		{
+			StreamName:    "GetLarge",
+			Handler:       _KeyValueStorage_GetLarge_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "api/v2/kv-storage.proto",
+}